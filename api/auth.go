@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"go_todo/database"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const (
+    tokenContextKey contextKey = iota
+    userContextKey
+)
+
+func TokenFromContext(ctx context.Context) (database.Token, bool) {
+    token, ok := ctx.Value(tokenContextKey).(database.Token)
+    return token, ok
+}
+
+// UserFromContext is the user the token of the request acts for.
+func UserFromContext(ctx context.Context) (database.User, bool) {
+    user, ok := ctx.Value(userContextKey).(database.User)
+    return user, ok
+}
+
+// ScopeForMethod maps a request method to the scope needed to perform it,
+// reads only need the read scope while everything else mutates tasks.
+func ScopeForMethod(method string) string {
+    switch method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            return database.ScopeRead
+        default:
+            return database.ScopeWrite
+    }
+}
+
+// Authenticate requires a bearer token with the scope implied by the request
+// method, see RequireScope.
+func Authenticate(db database.DB, next http.Handler) http.Handler {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        RequireScope(db, ScopeForMethod(r.Method), next).ServeHTTP(w, r)
+    })
+}
+
+// RequireScope rejects requests without a valid bearer token granting scope,
+// the others are served as the user of the token. Requests that mutate data
+// are recorded in the token audit before they are served, they are refused
+// when that fails, and the entry gets the status they were answered with.
+func RequireScope(db database.DB, scope string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        value, ok := bearerToken(r)
+
+        if !ok {
+            w.Header().Set("WWW-Authenticate", `Bearer realm="go_todo"`)
+            http.Error(w, "missing bearer token", http.StatusUnauthorized)
+            return
+        }
+
+        token, err := database.AuthenticateTokenAction(db, value)
+
+        if err != nil {
+            w.Header().Set("WWW-Authenticate", `Bearer realm="go_todo", error="invalid_token"`)
+            http.Error(w, err.Error(), http.StatusUnauthorized)
+            return
+        }
+
+        if !token.HasScope(scope) {
+            http.Error(w, "token lacks the "+scope+" scope", http.StatusForbidden)
+            return
+        }
+
+        user, err := database.ListUserActionByID(db, *token.UserID)
+
+        if err != nil {
+            w.Header().Set("WWW-Authenticate", `Bearer realm="go_todo", error="invalid_token"`)
+            http.Error(w, "the user of the token doesn't exist", http.StatusUnauthorized)
+            return
+        }
+
+        ctx := context.WithValue(r.Context(), tokenContextKey, token)
+        ctx = context.WithValue(ctx, userContextKey, user)
+
+        if ScopeForMethod(r.Method) == database.ScopeRead {
+            next.ServeHTTP(w, r.WithContext(ctx))
+            return
+        }
+
+        auditID, err := database.AddTokenAuditAction(db, database.AddTokenAuditProp{
+            TokenID: token.ID,
+            Method: r.Method,
+            Path: r.URL.Path,
+        })
+
+        if err != nil {
+            http.Error(w, "couldn't audit the request, "+err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        next.ServeHTTP(recorder, r.WithContext(ctx))
+
+        if err := database.SetTokenAuditStatusAction(db, auditID, recorder.status); err != nil {
+            log.Printf("couldn't record the status of audit entry %d, %s", auditID, err)
+        }
+    })
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+    header := r.Header.Get("Authorization")
+    scheme, value, found := strings.Cut(header, " ")
+
+    if !found || !strings.EqualFold(scheme, "Bearer") || value == "" {
+        return "", false
+    }
+
+    return strings.TrimSpace(value), true
+}
+
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+    r.status = status
+    r.ResponseWriter.WriteHeader(status)
+}
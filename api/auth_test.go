@@ -0,0 +1,150 @@
+package api
+
+import (
+	"database/sql"
+	"go_todo/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireScope(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    user := mockUser(t, db)
+    _, readValue, err := database.AddTokenAction(db, database.AddTokenProp{Name: "reader", UserID: user.ID, Scopes: []string{database.ScopeRead}})
+
+    if err != nil {
+        t.Fatalf("error while mocking token, %s\n", err)
+    }
+
+    writer, writeValue, err := database.AddTokenAction(db, database.AddTokenProp{Name: "writer", UserID: user.ID, Scopes: []string{database.ScopeWrite}})
+
+    if err != nil {
+        t.Fatalf("error while mocking token, %s\n", err)
+    }
+
+    handler := Authenticate(db, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        if _, ok := TokenFromContext(r.Context()); !ok {
+            t.Error("expected the authenticated token to be in the request context")
+        }
+        if actor, ok := UserFromContext(r.Context()); !ok || actor.ID != user.ID {
+            t.Errorf("expected the user of the token in the request context, got %+v\n", actor)
+        }
+        w.WriteHeader(http.StatusNoContent)
+    }))
+
+    serve := func (method string, token string) int {
+        req := httptest.NewRequest(method, "/tasks/1", nil)
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        res := httptest.NewRecorder()
+        handler.ServeHTTP(res, req)
+        return res.Code
+    }
+
+    t.Run("Should reject requests without a token", func (t *testing.T) {
+        if code := serve(http.MethodGet, ""); code != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d\n", http.StatusUnauthorized, code)
+        }
+    })
+
+    t.Run("Should reject requests with an unknown token", func (t *testing.T) {
+        if code := serve(http.MethodGet, "gtd_asdf"); code != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d\n", http.StatusUnauthorized, code)
+        }
+    })
+
+    t.Run("Should reject the tokens which don't belong to a user", func (t *testing.T) {
+        token, value, _ := database.AddTokenAction(db, database.AddTokenProp{Name: "legacy", UserID: user.ID, Scopes: []string{database.ScopeRead}})
+        db.Exec("UPDATE tokens SET user_id = NULL WHERE id = $1;", token.ID)
+
+        if code := serve(http.MethodGet, value); code != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d\n", http.StatusUnauthorized, code)
+        }
+    })
+
+    t.Run("Should allow reads with a read token", func (t *testing.T) {
+        if code := serve(http.MethodGet, readValue); code != http.StatusNoContent {
+            t.Errorf("expected status %d, got %d\n", http.StatusNoContent, code)
+        }
+    })
+
+    t.Run("Should forbid mutations with a read token", func (t *testing.T) {
+        if code := serve(http.MethodDelete, readValue); code != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d\n", http.StatusForbidden, code)
+        }
+    })
+
+    t.Run("Should allow and audit mutations with a write token", func (t *testing.T) {
+        if code := serve(http.MethodDelete, writeValue); code != http.StatusNoContent {
+            t.Fatalf("expected status %d, got %d\n", http.StatusNoContent, code)
+        }
+
+        audit, err := database.ListTokenAuditAction(db)
+
+        if err != nil {
+            t.Fatalf("error while listing token audit, %s\n", err)
+        }
+
+        if len(audit) != 1 {
+            t.Fatalf("expected only the mutation to be audited, got %d entries\n", len(audit))
+        }
+
+        if audit[0].TokenID != writer.ID || audit[0].Method != http.MethodDelete || audit[0].Status != http.StatusNoContent {
+            t.Errorf("unexpected audit entry %+v\n", audit[0])
+        }
+    })
+
+    t.Run("Should refuse mutations it can't audit", func (t *testing.T) {
+        db.Exec("ALTER TABLE token_audit RENAME TO token_audit_gone;")
+        defer db.Exec("ALTER TABLE token_audit_gone RENAME TO token_audit;")
+
+        if code := serve(http.MethodDelete, writeValue); code != http.StatusInternalServerError {
+            t.Errorf("expected status %d, got %d\n", http.StatusInternalServerError, code)
+        }
+    })
+}
+
+func mockUser(t testing.TB, db database.DB) database.User {
+    t.Helper()
+    user, err := database.EnsureUserAction(db, "tester")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s\n", err)
+    }
+
+    return user
+}
+
+// mockToken returns the value of a token of user granting scope.
+func mockToken(t testing.TB, db database.DB, user database.User, scope string) string {
+    t.Helper()
+    _, value, err := database.AddTokenAction(db, database.AddTokenProp{Name: scope, UserID: user.ID, Scopes: []string{scope}})
+
+    if err != nil {
+        t.Fatalf("error while mocking token, %s\n", err)
+    }
+
+    return value
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
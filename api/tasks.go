@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"go_todo/database"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NewHandler serves the API as the user of the bearer token of each request,
+// the routes need the scope they are listed with:
+//
+//	GET /tasks/{id}/history  read   the changes of the task, the oldest first
+//	GET /views/{name}/tasks  read   the tasks of a view of the user, sorted by it
+//	GET /audit               admin  the mutations made with the tokens
+//
+// The other methods are answered 405 before the token is even checked, so
+// that only the requests a handler serves are audited.
+func NewHandler(db database.DB) http.Handler {
+    history := readsOnly(Authenticate(db, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        serveTaskHistory(db, w, r)
+    })))
+    viewTasks := readsOnly(Authenticate(db, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        serveViewTasks(db, w, r)
+    })))
+    audit := readsOnly(RequireScope(db, database.ScopeAdmin, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        serveTokenAudit(db, w, r)
+    })))
+
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+        switch {
+        case len(parts) == 3 && parts[0] == "tasks" && parts[2] == "history":
+            history.ServeHTTP(w, r)
+        case len(parts) == 3 && parts[0] == "views" && parts[2] == "tasks":
+            viewTasks.ServeHTTP(w, r)
+        case len(parts) == 1 && parts[0] == "audit":
+            audit.ServeHTTP(w, r)
+        default:
+            http.NotFound(w, r)
+        }
+    })
+}
+
+func serveTaskHistory(db database.DB, w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.Atoi(strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1])
+
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+
+    actor, _ := UserFromContext(r.Context())
+    changes, err := database.ListTaskHistoryAction(db, actor, id)
+
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    writeJSON(w, changes)
+}
+
+func serveTokenAudit(db database.DB, w http.ResponseWriter, r *http.Request) {
+    audit, err := database.ListTokenAuditAction(db)
+
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeJSON(w, audit)
+}
+
+// readsOnly answers 405 to the methods other than GET and HEAD.
+func readsOnly(next http.Handler) http.Handler {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"go_todo/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTaskHistory(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockUser(t, db)
+    token := mockToken(t, db, actor, database.ScopeRead)
+    task, err := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Water the plants"})
+
+    if err != nil {
+        t.Fatalf("error while mocking task, %s\n", err)
+    }
+
+    completed := true
+
+    if _, err := database.UpdateTaskAction(db, actor, task.ID, database.UpdateTaskProp{Completed: &completed}); err != nil {
+        t.Fatalf("error while mocking update, %s\n", err)
+    }
+
+    handler := NewHandler(db)
+
+    serve := func (method string, path string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, path, nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        res := httptest.NewRecorder()
+        handler.ServeHTTP(res, req)
+        return res
+    }
+
+    t.Run("Should answer the history of a task", func (t *testing.T) {
+        res := serve(http.MethodGet, "/tasks/"+strconv.Itoa(task.ID)+"/history")
+
+        if res.Code != http.StatusOK {
+            t.Fatalf("expected status %d, got %d\n", http.StatusOK, res.Code)
+        }
+
+        changes := make([]database.TaskChange, 0)
+
+        if err := json.Unmarshal(res.Body.Bytes(), &changes); err != nil {
+            t.Fatalf("error while decoding the history, %s\n", err)
+        }
+
+        if len(changes) != 2 || changes[0].Field != "name" || *changes[0].New != "Water the plants" || changes[1].Field != "completed" {
+            t.Errorf("expected the task to be named then completed, got %+v\n", changes)
+        }
+    })
+
+    t.Run("Should answer 404 for unknown tasks and paths", func (t *testing.T) {
+        for _, path := range []string{"/tasks/424242/history", "/tasks/abc/history", "/tasks/1", "/history"} {
+            if res := serve(http.MethodGet, path); res.Code != http.StatusNotFound {
+                t.Errorf("expected status %d for %s, got %d\n", http.StatusNotFound, path, res.Code)
+            }
+        }
+    })
+
+    t.Run("Should only allow reads and audit nothing", func (t *testing.T) {
+        token = mockToken(t, db, actor, database.ScopeWrite)
+        before, _ := database.ListTokenAuditAction(db)
+
+        if res := serve(http.MethodPost, "/tasks/"+strconv.Itoa(task.ID)+"/history"); res.Code != http.StatusMethodNotAllowed {
+            t.Errorf("expected status %d, got %d\n", http.StatusMethodNotAllowed, res.Code)
+        }
+
+        if after, _ := database.ListTokenAuditAction(db); len(after) != len(before) {
+            t.Errorf("expected the refused request not audited, got %d entries instead of %d\n", len(after), len(before))
+        }
+    })
+
+    t.Run("Should answer as the user of the token", func (t *testing.T) {
+        stranger, _ := database.EnsureUserAction(db, "stranger")
+        token = mockToken(t, db, stranger, database.ScopeRead)
+
+        if res := serve(http.MethodGet, "/tasks/"+strconv.Itoa(task.ID)+"/history"); res.Code != http.StatusNotFound {
+            t.Errorf("expected status %d for a task the user can't read, got %d\n", http.StatusNotFound, res.Code)
+        }
+    })
+}
+
+func TestHandlerAuthentication(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockUser(t, db)
+    handler := NewHandler(db)
+
+    serve := func (path string, token string) int {
+        req := httptest.NewRequest(http.MethodGet, path, nil)
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        res := httptest.NewRecorder()
+        handler.ServeHTTP(res, req)
+        return res.Code
+    }
+
+    t.Run("Should answer 401 without a valid token", func (t *testing.T) {
+        for _, path := range []string{"/tasks/1/history", "/audit"} {
+            for _, token := range []string{"", "gtd_asdf"} {
+                if code := serve(path, token); code != http.StatusUnauthorized {
+                    t.Errorf("expected status %d for %s with %q, got %d\n", http.StatusUnauthorized, path, token, code)
+                }
+            }
+        }
+    })
+
+    t.Run("Should answer 403 to the audit without the admin scope", func (t *testing.T) {
+        for _, scope := range []string{database.ScopeRead, database.ScopeWrite} {
+            if code := serve("/audit", mockToken(t, db, actor, scope)); code != http.StatusForbidden {
+                t.Errorf("expected status %d with a %s token, got %d\n", http.StatusForbidden, scope, code)
+            }
+        }
+    })
+
+    t.Run("Should answer the audit to admin tokens", func (t *testing.T) {
+        if code := serve("/audit", mockToken(t, db, actor, database.ScopeAdmin)); code != http.StatusOK {
+            t.Errorf("expected status %d, got %d\n", http.StatusOK, code)
+        }
+    })
+}
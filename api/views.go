@@ -0,0 +1,37 @@
+package api
+
+import (
+	"go_todo/database"
+	"go_todo/filter"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func serveViewTasks(db database.DB, w http.ResponseWriter, r *http.Request) {
+    name := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1]
+
+    actor, _ := UserFromContext(r.Context())
+    view, err := database.ListViewActionByName(db, actor, name)
+
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    props := database.ListTaskProps{}
+
+    if err := filter.ApplyView(&props, view, time.Now()); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    tasks, err := database.ListTasksAction(db, actor, props)
+
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeJSON(w, tasks)
+}
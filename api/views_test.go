@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"go_todo/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestViewTasks(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockUser(t, db)
+    handler := NewHandler(db)
+
+    for _, props := range []database.AddTaskProp{
+        {Name: "Fix the build", Priority: database.PriorityLow, Tags: []string{"work"}},
+        {Name: "Ship the release", Priority: database.PriorityHigh, Tags: []string{"work"}},
+        {Name: "Water the plants", Tags: []string{"home"}},
+    } {
+        if _, err := database.AddTaskAction(db, actor, props); err != nil {
+            t.Fatalf("error while mocking task, %s\n", err)
+        }
+    }
+
+    if _, err := database.SaveViewAction(db, actor, database.View{Name: "work", Filter: "tag:work", Sort: "priority,desc"}); err != nil {
+        t.Fatalf("error while mocking view, %s\n", err)
+    }
+
+    serve := func (method string, path string, token string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, path, nil)
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        res := httptest.NewRecorder()
+        handler.ServeHTTP(res, req)
+        return res
+    }
+
+    token := mockToken(t, db, actor, database.ScopeRead)
+
+    t.Run("Should answer the tasks of the view in its order", func (t *testing.T) {
+        res := serve(http.MethodGet, "/views/work/tasks", token)
+
+        if res.Code != http.StatusOK {
+            t.Fatalf("expected status %d, got %d\n", http.StatusOK, res.Code)
+        }
+
+        tasks := make([]database.Task, 0)
+
+        if err := json.Unmarshal(res.Body.Bytes(), &tasks); err != nil {
+            t.Fatalf("error while decoding the tasks, %s\n", err)
+        }
+
+        if len(tasks) != 2 || tasks[0].Name != "Ship the release" || tasks[1].Name != "Fix the build" {
+            t.Errorf("expected the work tasks by priority, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should answer 401 without a valid token", func (t *testing.T) {
+        if res := serve(http.MethodGet, "/views/work/tasks", ""); res.Code != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d\n", http.StatusUnauthorized, res.Code)
+        }
+    })
+
+    t.Run("Should answer 404 for the views of other users", func (t *testing.T) {
+        stranger, _ := database.EnsureUserAction(db, "stranger")
+
+        for _, path := range []string{"/views/work/tasks", "/views/unknown/tasks"} {
+            if res := serve(http.MethodGet, path, mockToken(t, db, stranger, database.ScopeRead)); res.Code != http.StatusNotFound {
+                t.Errorf("expected status %d for %s, got %d\n", http.StatusNotFound, path, res.Code)
+            }
+        }
+    })
+
+    t.Run("Should only allow reads", func (t *testing.T) {
+        if res := serve(http.MethodDelete, "/views/work/tasks", mockToken(t, db, actor, database.ScopeWrite)); res.Code != http.StatusMethodNotAllowed {
+            t.Errorf("expected status %d, got %d\n", http.StatusMethodNotAllowed, res.Code)
+        }
+    })
+}
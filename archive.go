@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/store"
+	"go_todo/webhook"
+	"os"
+	"time"
+)
+
+const AutoArchiveEnvVar = "GO_TODO_AUTO_ARCHIVE"
+
+func archiveCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "archive",
+        Summary: "Archive tasks, the completed ones by default, e.g. archive -older-than 14d",
+        Args: "[<...id|name>]",
+        MaxArgs: -1,
+        Flags: append(
+            []cli.Flag{
+                {Name: "older-than", Placeholder: "age", Usage: "only the tasks completed more than age ago, e.g. 14d, 2w or 12h"},
+            },
+            taskFilterFlags(db)...,
+        ),
+        CompleteArgs: taskCompletions(db),
+        Run: func (ctx *cli.Context) error { return archiveTasks(db, ctx) },
+    }
+}
+
+func unarchiveCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "unarchive",
+        Summary: "Bring archived tasks back to the list",
+        Args: "<...ids>",
+        MinArgs: 1,
+        MaxArgs: -1,
+        Run: func (ctx *cli.Context) error { return unarchiveTasks(db, ctx) },
+    }
+}
+
+func archiveTasks(db database.DB, ctx *cli.Context) error {
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    var ids []int
+
+    if len(ctx.Args()) > 0 {
+        for _, flag := range []string{"older-than", "completed", "assignee", "mine", "where"} {
+            if ctx.IsSet(flag) {
+                return ctx.Usagef("Provide either tasks or -%s, not both", flag)
+            }
+        }
+
+        ids, err = resolveTaskIDs(store.NewSQLStore(db), actor, ctx.Args())
+    } else {
+        ids, err = archivableTaskIDs(db, actor, ctx)
+    }
+
+    if err != nil {
+        return err
+    }
+
+    archived, err := database.ArchiveTaskBulkAction(db, actor, ids)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Archived %d tasks.", len(archived)))
+
+    for _, task := range archived {
+        notifyWebhooks(db, webhook.EventUpdated, task)
+    }
+
+    return nil
+}
+
+// archivableTaskIDs selects the tasks matching the filter flags, the
+// completed ones unless -completed says otherwise.
+func archivableTaskIDs(db database.DB, actor database.User, ctx *cli.Context) ([]int, error) {
+    props, err := taskFilterProps(db, ctx)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if props.WhereCompleted == nil {
+        completed := true
+        props.WhereCompleted = &completed
+    }
+
+    if age, ok := ctx.String("older-than"); ok {
+        before, ok := parseAge(age, time.Now())
+
+        if !ok {
+            return nil, ctx.Usagef("Invalid value '%s' for -older-than, expected an age like 14d, 2w or 12h", age)
+        }
+
+        props.CompletedBefore = &before
+    }
+
+    tasks, err := database.ListTasksAction(db, actor, props)
+
+    if err != nil {
+        return nil, err
+    }
+
+    ids := make([]int, len(tasks))
+
+    for idx, task := range tasks {
+        ids[idx] = task.ID
+    }
+
+    return ids, nil
+}
+
+func unarchiveTasks(db database.DB, ctx *cli.Context) error {
+    ids, err := parseIDs(ctx.Args())
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    unarchived, err := database.UnarchiveTaskBulkAction(db, actor, ids)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Unarchived %d tasks.", len(unarchived)))
+
+    for _, task := range unarchived {
+        notifyWebhooks(db, webhook.EventUpdated, task)
+    }
+
+    return nil
+}
+
+// autoArchive archives the tasks completed more than GO_TODO_AUTO_ARCHIVE
+// ago, e.g. 14d, before the commands that may change them, see changesTasks.
+func autoArchive(db database.DB) {
+    age := os.Getenv(AutoArchiveEnvVar)
+
+    if age == "" {
+        return
+    }
+
+    before, ok := parseAge(age, time.Now())
+
+    if !ok {
+        fmt.Fprintf(os.Stderr, "Error: invalid %s '%s', expected an age like 14d, 2w or 12h\n", AutoArchiveEnvVar, age)
+        return
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return
+    }
+
+    completed := true
+    tasks, err := database.ListTasksAction(db, actor, database.ListTaskProps{WhereCompleted: &completed, CompletedBefore: &before})
+
+    if err != nil || len(tasks) == 0 {
+        return
+    }
+
+    ids := make([]int, len(tasks))
+
+    for idx, task := range tasks {
+        ids[idx] = task.ID
+    }
+
+    if _, err := database.ArchiveTaskBulkAction(db, actor, ids); err != nil {
+        fmt.Fprintf(os.Stderr, "Error: auto-archive failed, %s\n", err)
+    }
+}
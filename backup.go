@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotsEnvVar enables the snapshots taken on startup, e.g. daily=7,weekly=4
+// keeps a snapshot of each of the last 7 days and of the last 4 weeks.
+const SnapshotsEnvVar = "GO_TODO_SNAPSHOTS"
+
+// SnapshotsFolder is where the snapshots are kept, next to task.db.
+const SnapshotsFolder = "snapshots"
+
+func backupCommand() *cli.Command {
+    return &cli.Command{
+        Name: "backup",
+        Summary: "Copy the database to a file, safe while other commands write to it",
+        Args: "[<path>]",
+        MaxArgs: 1,
+        ReadOnly: true,
+        Run: func (ctx *cli.Context) error { return backupDatabase("./", ctx) },
+    }
+}
+
+func restoreCommand() *cli.Command {
+    return &cli.Command{
+        Name: "restore",
+        Summary: "Replace the database with a backup, the current one is backed up first",
+        Args: "<file>",
+        MinArgs: 1,
+        MaxArgs: 1,
+        Run: func (ctx *cli.Context) error { return restoreDatabase("./", ctx) },
+    }
+}
+
+func backupDatabase(rootFolder string, ctx *cli.Context) error {
+    path := filepath.Join(rootFolder, "task-"+time.Now().Format("20060102-150405")+".db")
+
+    if len(ctx.Args()) == 1 {
+        path = ctx.Args()[0]
+    }
+
+    if err := database.BackupDatabase(rootFolder, path); err != nil {
+        return fmt.Errorf("couldn't back up the database, %w", err)
+    }
+
+    fmt.Printf("Backed up to %s\n", path)
+
+    return nil
+}
+
+func restoreDatabase(rootFolder string, ctx *cli.Context) error {
+    path := ctx.Args()[0]
+    previous := filepath.Join(rootFolder, "task-before-restore-"+time.Now().Format("20060102-150405")+".db")
+
+    if err := database.BackupDatabase(rootFolder, previous); err != nil {
+        return fmt.Errorf("couldn't back up the current database, %w", err)
+    }
+
+    if err := database.RestoreDatabase(rootFolder, path); err != nil {
+        os.Remove(previous)
+        return fmt.Errorf("couldn't restore %s, %w", path, err)
+    }
+
+    fmt.Printf("Restored %s, the previous database was saved to %s\n", path, previous)
+
+    return nil
+}
+
+// snapshotPolicy is the number of daily and weekly snapshots to keep.
+type snapshotPolicy struct {
+    daily int
+    weekly int
+}
+
+func parseSnapshotPolicy(value string) (snapshotPolicy, bool) {
+    policy := snapshotPolicy{}
+
+    for _, part := range strings.Split(value, ",") {
+        name, count, found := strings.Cut(strings.TrimSpace(part), "=")
+        n, err := strconv.Atoi(count)
+
+        if !found || err != nil || n < 0 {
+            return policy, false
+        }
+
+        switch name {
+        case "daily":
+            policy.daily = n
+        case "weekly":
+            policy.weekly = n
+        default:
+            return policy, false
+        }
+    }
+
+    return policy, true
+}
+
+// autoSnapshot takes the snapshots GO_TODO_SNAPSHOTS asks for before the
+// commands that may change the tasks, see changesTasks.
+func autoSnapshot(rootFolder string) {
+    value := os.Getenv(SnapshotsEnvVar)
+
+    if value == "" {
+        return
+    }
+
+    policy, ok := parseSnapshotPolicy(value)
+
+    if !ok {
+        fmt.Fprintf(os.Stderr, "Error: invalid %s '%s', expected e.g. daily=7,weekly=4\n", SnapshotsEnvVar, value)
+        return
+    }
+
+    if err := rotateSnapshots(rootFolder, filepath.Join(rootFolder, SnapshotsFolder), policy, time.Now()); err != nil {
+        fmt.Fprintf(os.Stderr, "Error: snapshot failed, %s\n", err)
+    }
+}
+
+// rotateSnapshots takes the snapshots of the day and of the week when
+// missing, then deletes the oldest ones beyond what policy keeps, all of them
+// for a kind it keeps none of. The names sort in the order the snapshots were
+// taken, e.g. daily-2026-10-19.db and weekly-2026-W42.db.
+func rotateSnapshots(rootFolder string, folder string, policy snapshotPolicy, now time.Time) error {
+    year, week := now.ISOWeek()
+    kinds := []struct {
+        prefix string
+        name string
+        keep int
+    }{
+        {"daily-", "daily-" + now.Format("2006-01-02") + ".db", policy.daily},
+        {"weekly-", fmt.Sprintf("weekly-%d-W%02d.db", year, week), policy.weekly},
+    }
+
+    if err := os.MkdirAll(folder, 0o755); err != nil {
+        return err
+    }
+
+    for _, kind := range kinds {
+        path := filepath.Join(folder, kind.name)
+
+        if _, err := os.Stat(path); kind.keep > 0 && os.IsNotExist(err) {
+            if err := database.BackupDatabase(rootFolder, path); err != nil {
+                return err
+            }
+        }
+
+        snapshots, err := filepath.Glob(filepath.Join(folder, kind.prefix+"*.db"))
+
+        if err != nil {
+            return err
+        }
+
+        sort.Strings(snapshots)
+
+        for len(snapshots) > kind.keep {
+            if err := os.Remove(snapshots[0]); err != nil {
+                return err
+            }
+            snapshots = snapshots[1:]
+        }
+    }
+
+    return nil
+}
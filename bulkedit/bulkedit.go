@@ -0,0 +1,226 @@
+// Package bulkedit turns tasks into text to edit and the edited text back into
+// the changes to apply. Each task is a line like
+//
+//   12 [ ] Pay rent !high 2026-11-01 every month +flat #finance @alice
+//
+// where the text after the checkbox is read like go_todo add reads it. Lines
+// without an ID create tasks, tasks whose line was removed are deleted.
+package bulkedit
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"go_todo/quickadd"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const header = `# Edit the tasks below, the text after the checkbox is read like go_todo add
+# reads it, e.g. 12 [ ] Pay rent !high 2026-11-01 every month +flat #finance
+# Mark done tasks with [x], remove a line to delete its task and add a line
+# without an ID to create one. Lines starting with # are ignored.
+
+`
+
+var linePattern = regexp.MustCompile(`^(?:(\d+)\s+)?\[([ xX])\]\s*(.*)$`)
+
+// Entry is a task as written in the edited text.
+type Entry struct {
+    Line int
+    // ID is 0 for new tasks.
+    ID int
+    quickadd.Parsed
+}
+
+// Render writes tasks in the format Parse reads, names maps user IDs to their
+// names for the assignees.
+func Render(tasks []database.Task, names map[int]string) string {
+    text := strings.Builder{}
+    text.WriteString(header)
+
+    for _, task := range tasks {
+        checkbox := "[ ]"
+        if task.Completed {
+            checkbox = "[x]"
+        }
+
+        fmt.Fprintf(&text, "%d %s %s\n", task.ID, checkbox, quickadd.Format(addTaskProp(task), assignee(task, names)))
+    }
+
+    return text.String()
+}
+
+// Parse reads the entries of an edited text, it reports every line it can't
+// read at once.
+func Parse(text string, now time.Time) ([]Entry, error) {
+    entries := make([]Entry, 0)
+    errs := make([]error, 0)
+    seen := make(map[int]int)
+
+    for idx, line := range strings.Split(text, "\n") {
+        number := idx + 1
+        line = strings.TrimSpace(line)
+
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        entry := Entry{Line: number}
+        content, completed := line, false
+
+        if match := linePattern.FindStringSubmatch(line); match != nil {
+            entry.ID, _ = strconv.Atoi(match[1])
+            content, completed = match[3], match[2] != " "
+        }
+
+        if previous, ok := seen[entry.ID]; ok && entry.ID != 0 {
+            errs = append(errs, fmt.Errorf("line %d: task %d is already on line %d", number, entry.ID, previous))
+            continue
+        }
+        seen[entry.ID] = number
+
+        parsed, err := quickadd.Parse(content, now)
+
+        if err != nil {
+            errs = append(errs, fmt.Errorf("line %d: %w", number, err))
+            continue
+        }
+
+        parsed.Completed = completed
+        entry.Parsed = parsed
+        entries = append(entries, entry)
+    }
+
+    return entries, errors.Join(errs...)
+}
+
+// Update is an existing task and what changed in it.
+type Update struct {
+    Task database.Task
+    Props database.UpdateTaskProp
+    // Assignee is nil when unchanged and empty to unassign.
+    Assignee *string
+}
+
+type Changes struct {
+    Created []Entry
+    Updated []Update
+    Deleted []database.Task
+}
+
+func (c Changes) Empty() bool {
+    return len(c.Created) == 0 && len(c.Updated) == 0 && len(c.Deleted) == 0
+}
+
+// Diff compares the tasks which were rendered to the edited entries.
+func Diff(tasks []database.Task, names map[int]string, entries []Entry) (Changes, error) {
+    changes := Changes{}
+    edited := make(map[int]Entry)
+
+    for _, entry := range entries {
+        if entry.ID == 0 {
+            changes.Created = append(changes.Created, entry)
+            continue
+        }
+        edited[entry.ID] = entry
+    }
+
+    byID := make(map[int]database.Task)
+
+    for _, task := range tasks {
+        byID[task.ID] = task
+        entry, ok := edited[task.ID]
+
+        if !ok {
+            changes.Deleted = append(changes.Deleted, task)
+            continue
+        }
+
+        if update, changed := diffTask(task, names, entry); changed {
+            changes.Updated = append(changes.Updated, update)
+        }
+    }
+
+    for _, entry := range entries {
+        if _, ok := byID[entry.ID]; entry.ID != 0 && !ok {
+            return Changes{}, fmt.Errorf("line %d: task %d wasn't being edited", entry.Line, entry.ID)
+        }
+    }
+
+    return changes, nil
+}
+
+func diffTask(task database.Task, names map[int]string, entry Entry) (Update, bool) {
+    update := Update{Task: task}
+    props := &update.Props
+    changed := false
+
+    if entry.Name != task.Name {
+        props.Name, changed = &entry.Name, true
+    }
+
+    if entry.Completed != task.Completed {
+        props.Completed, changed = &entry.Completed, true
+    }
+
+    if !sameTime(entry.DueAt, task.DueAt) {
+        dueAt := time.Time{}
+        if entry.DueAt != nil {
+            dueAt = *entry.DueAt
+        }
+        props.DueAt, changed = &dueAt, true
+    }
+
+    if entry.Priority != task.Priority {
+        props.Priority, changed = &entry.Priority, true
+    }
+
+    if !slices.Equal(entry.Tags, task.Tags) {
+        tags := entry.Tags
+        props.Tags, changed = &tags, true
+    }
+
+    if entry.Project != task.Project {
+        props.Project, changed = &entry.Project, true
+    }
+
+    if entry.Recurrence != task.Recurrence {
+        props.Recurrence, changed = &entry.Recurrence, true
+    }
+
+    if entry.Assignee != assignee(task, names) {
+        update.Assignee, changed = &entry.Assignee, true
+    }
+
+    return update, changed
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return a.Equal(*b)
+}
+
+func assignee(task database.Task, names map[int]string) string {
+    if task.AssigneeID == nil {
+        return ""
+    }
+    return names[*task.AssigneeID]
+}
+
+func addTaskProp(task database.Task) database.AddTaskProp {
+    return database.AddTaskProp{
+        Name: task.Name,
+        Completed: task.Completed,
+        DueAt: task.DueAt,
+        Priority: task.Priority,
+        Tags: task.Tags,
+        Project: task.Project,
+        Recurrence: task.Recurrence,
+    }
+}
@@ -0,0 +1,128 @@
+package bulkedit
+
+import (
+	"go_todo/database"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, time.October, 19, 14, 30, 0, 0, time.Local)
+
+func mockTasks() ([]database.Task, map[int]string) {
+    dueAt := time.Date(2026, time.November, 1, 9, 0, 0, 0, time.Local)
+    alice := 7
+
+    tasks := []database.Task{
+        {ID: 1, Name: "Pay rent", DueAt: &dueAt, Priority: database.PriorityHigh, Tags: []string{"finance"}, Recurrence: "every month"},
+        {ID: 2, Name: "Watch next friday", Completed: true, Project: "films", AssigneeID: &alice},
+        {ID: 3, Name: "Walk the dog"},
+    }
+
+    return tasks, map[int]string{alice: "alice"}
+}
+
+func TestRender(t *testing.T) {
+    tasks, names := mockTasks()
+    got := Render(tasks, names)
+    want := header +
+        "1 [ ] Pay rent !high 2026-11-01 09:00 every month #finance\n" +
+        "2 [x] \"Watch next friday\" +films @alice\n" +
+        "3 [ ] Walk the dog\n"
+
+    if got != want {
+        t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+    }
+}
+
+func TestDiff(t *testing.T) {
+    tasks, names := mockTasks()
+
+    diff := func (t *testing.T, text string) Changes {
+        t.Helper()
+        entries, err := Parse(text, now)
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s", err)
+        }
+
+        changes, err := Diff(tasks, names, entries)
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s", err)
+        }
+
+        return changes
+    }
+
+    t.Run("Should find no changes in the rendered text", func (t *testing.T) {
+        if changes := diff(t, Render(tasks, names)); !changes.Empty() {
+            t.Errorf("expected no changes, got %+v", changes)
+        }
+    })
+
+    t.Run("Should find the created, updated and deleted tasks", func (t *testing.T) {
+        changes := diff(t, strings.Join([]string{
+            "1 [x] Pay rent !low 2026-11-01 09:00 every month #finance #home",
+            "2 [x] \"Watch next friday\" +films",
+            "[ ] Buy milk tomorrow",
+            "Call mom @alice",
+        }, "\n"))
+
+        if len(changes.Deleted) != 1 || changes.Deleted[0].ID != 3 {
+            t.Errorf("expected task 3 to be deleted, got %+v", changes.Deleted)
+        }
+
+        if len(changes.Created) != 2 || changes.Created[0].Name != "Buy milk" || changes.Created[0].DueAt == nil || changes.Created[1].Assignee != "alice" {
+            t.Errorf("expected two tasks to be created, got %+v", changes.Created)
+        }
+
+        if len(changes.Updated) != 2 {
+            t.Fatalf("expected two tasks to be updated, got %+v", changes.Updated)
+        }
+
+        payRent, watch := changes.Updated[0].Props, changes.Updated[1]
+
+        if payRent.Name != nil || payRent.DueAt != nil || payRent.Recurrence != nil ||
+            !*payRent.Completed || *payRent.Priority != database.PriorityLow || strings.Join(*payRent.Tags, ",") != "finance,home" {
+            t.Errorf("expected only the status, priority and tags of task 1 to change, got %+v", payRent)
+        }
+
+        if watch.Assignee == nil || *watch.Assignee != "" || watch.Props != (database.UpdateTaskProp{}) {
+            t.Errorf("expected task 2 to be unassigned, got %+v", watch)
+        }
+    })
+
+    t.Run("Should clear the removed details", func (t *testing.T) {
+        changes := diff(t, "1 [ ] Pay rent\n2 [x] \"Watch next friday\" +films @alice\n3 [ ] Walk the dog")
+        props := changes.Updated[0].Props
+
+        if !props.DueAt.IsZero() || *props.Priority != database.PriorityNone || len(*props.Tags) != 0 || *props.Recurrence != "" {
+            t.Errorf("expected the details of task 1 to be cleared, got %+v", props)
+        }
+    })
+}
+
+func TestParseErrors(t *testing.T) {
+    tasks, names := mockTasks()
+
+    t.Run("Should report every line it can't read", func (t *testing.T) {
+        _, err := Parse("# comment\n1 [ ] Pay rent !low !high\n2 [x] Watch\n1 [ ] Pay rent\n[ ] #home", now)
+        want := "line 2: Priority given twice, '!low' and '!high'\n" +
+            "line 4: task 1 is already on line 2\n" +
+            "line 5: Task name is empty"
+
+        if err == nil || err.Error() != want {
+            t.Errorf("expected:\n%s\ngot:\n%v", want, err)
+        }
+    })
+
+    t.Run("Should refuse tasks which weren't being edited", func (t *testing.T) {
+        entries, _ := Parse("1 [ ] Pay rent\n42 [ ] Someone else's task", now)
+        _, err := Diff(tasks, names, entries)
+
+        if err == nil || err.Error() != "line 2: task 42 wasn't being edited" {
+            t.Errorf("expected an error about task 42, got %v", err)
+        }
+    })
+}
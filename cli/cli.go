@@ -0,0 +1,505 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type Kind int
+
+const (
+    // String flags take any value, restricted to Values when provided.
+    String Kind = iota
+    Int
+    // Bool flags take an explicit true or false value.
+    Bool
+    // Switch flags take no value, their presence sets them to true.
+    Switch
+)
+
+type Flag struct {
+    Name string
+    Short string
+    Kind Kind
+    Values []string
+    Placeholder string
+    // Synopsis replaces the bracketed placeholder of the usage line and help
+    // as is, for values with a syntax of their own.
+    Synopsis string
+    Required bool
+    Repeatable bool
+    Usage string
+    // Complete lists the values suggested by the shell completion, Values are
+    // suggested when it's nil.
+    Complete func() []Completion
+}
+
+func (f Flag) placeholder() string {
+    switch {
+        case f.Kind == Switch:
+            return ""
+        case f.Synopsis != "":
+            return f.Synopsis
+        case len(f.Values) > 0:
+            return "<" + strings.Join(f.Values, "|") + ">"
+        case f.Kind == Bool:
+            return "<true|false>"
+        case f.Placeholder != "":
+            return "<" + f.Placeholder + ">"
+        default:
+            return "<" + f.Name + ">"
+    }
+}
+
+func (f Flag) synopsis() string {
+    synopsis := "-" + f.Name
+
+    if placeholder := f.placeholder(); placeholder != "" {
+        synopsis += " " + placeholder
+    }
+
+    if f.Repeatable {
+        synopsis += "..."
+    }
+
+    if !f.Required {
+        synopsis = "[" + synopsis + "]"
+    }
+
+    return synopsis
+}
+
+type Command struct {
+    Name string
+    Aliases []string
+    Summary string
+    // Args describes the positional arguments in the usage line.
+    Args string
+    MinArgs int
+    // MaxArgs limits the positional arguments, a negative value means no
+    // limit.
+    MaxArgs int
+    Flags []Flag
+    Subcommands []*Command
+    // Hidden commands are left out of help and completion.
+    Hidden bool
+    // RawArgs disables flag parsing, every argument is positional.
+    RawArgs bool
+    // ReadOnly commands change nothing, see Find.
+    ReadOnly bool
+    // CompleteArgs lists the values suggested for the next positional argument
+    // given the ones already typed.
+    CompleteArgs func(args []string) []Completion
+    Run func(ctx *Context) error
+
+    parent *Command
+}
+
+var ErrHelp = errors.New("help requested")
+
+// UsageError is returned for command lines that don't match the command
+// definition, it is printed along with the usage line.
+type UsageError struct {
+    Command *Command
+    Message string
+}
+
+func (e *UsageError) Error() string {
+    return e.Message
+}
+
+func (c *Command) names() []string {
+    return append([]string{c.Name}, c.Aliases...)
+}
+
+func (c *Command) Path() string {
+    if c.parent == nil {
+        return c.Name
+    }
+    return c.parent.Path() + " " + c.Name
+}
+
+// Subcommand finds a direct subcommand by name or alias.
+func (c *Command) Subcommand(name string) *Command {
+    for _, sub := range c.Subcommands {
+        sub.parent = c
+        for _, candidate := range sub.names() {
+            if candidate == name {
+                return sub
+            }
+        }
+    }
+    return nil
+}
+
+// Lookup walks down the subcommands named by path.
+func (c *Command) Lookup(path ...string) *Command {
+    cmd := c
+
+    for _, name := range path {
+        if cmd = cmd.Subcommand(name); cmd == nil {
+            return nil
+        }
+    }
+
+    return cmd
+}
+
+func (c *Command) flag(name string) *Flag {
+    for idx := range c.Flags {
+        if c.Flags[idx].Name == name || (c.Flags[idx].Short != "" && c.Flags[idx].Short == name) {
+            return &c.Flags[idx]
+        }
+    }
+    return nil
+}
+
+func (c *Command) UsageLine() string {
+    parts := []string{"Usage: " + c.Path()}
+
+    if len(c.Subcommands) > 0 {
+        names := make([]string, 0, len(c.Subcommands))
+        for _, sub := range c.Subcommands {
+            if !sub.Hidden {
+                names = append(names, sub.Name)
+            }
+        }
+        parts = append(parts, "<"+strings.Join(names, "|")+">")
+    }
+
+    for _, flag := range c.Flags {
+        if flag.Required {
+            parts = append(parts, flag.synopsis())
+        }
+    }
+
+    for _, flag := range c.Flags {
+        if !flag.Required {
+            parts = append(parts, flag.synopsis())
+        }
+    }
+
+    if c.Args != "" {
+        parts = append(parts, c.Args)
+    }
+
+    return strings.Join(parts, " ")
+}
+
+// Help describes the command from its definition.
+func (c *Command) Help() string {
+    var help strings.Builder
+
+    help.WriteString(c.UsageLine() + "\n")
+
+    if c.Summary != "" {
+        help.WriteString("\n" + c.Summary + "\n")
+    }
+
+    if len(c.Aliases) > 0 {
+        help.WriteString("\nAliases: " + strings.Join(c.Aliases, ", ") + "\n")
+    }
+
+    table := tabwriter.NewWriter(&help, 0, 0, 3, ' ', 0)
+
+    if len(c.Subcommands) > 0 {
+        fmt.Fprint(table, "\nCommands:\n")
+        for _, sub := range c.Subcommands {
+            if sub.Hidden {
+                continue
+            }
+            fmt.Fprintf(table, "  %s\t%s\n", strings.Join(sub.names(), ", "), sub.Summary)
+        }
+    }
+
+    if len(c.Flags) > 0 {
+        fmt.Fprint(table, "\nFlags:\n")
+        for _, flag := range c.Flags {
+            names := "-" + flag.Name
+            if flag.Short != "" {
+                names = "-" + flag.Short + ", " + names
+            }
+            if placeholder := flag.placeholder(); placeholder != "" {
+                names += " " + placeholder
+            }
+            fmt.Fprintf(table, "  %s\t%s\n", names, flag.Usage)
+        }
+    }
+
+    table.Flush()
+
+    return help.String()
+}
+
+// Parse matches args against the flags and positional arguments of the
+// command. Flags are written -name, --name or -n for their short form, their
+// value either follows them or is attached with "=". Everything after "--"
+// is positional.
+func (c *Command) Parse(args []string) (*Context, error) {
+    ctx := &Context{Command: c, values: make(map[string][]string), args: make([]string, 0)}
+
+    if c.RawArgs {
+        ctx.args = append(ctx.args, args...)
+        return ctx, nil
+    }
+
+    for idx := 0; idx < len(args); idx++ {
+        arg := args[idx]
+
+        if arg == "--" {
+            ctx.args = append(ctx.args, args[idx+1:]...)
+            break
+        }
+
+        if !isFlag(arg) {
+            ctx.args = append(ctx.args, arg)
+            continue
+        }
+
+        name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+        flag := c.flag(name)
+
+        if flag == nil {
+            if name == "h" || name == "help" {
+                return nil, ErrHelp
+            }
+            return nil, c.unknownFlag(name)
+        }
+
+        if flag.Kind == Switch {
+            if !hasValue {
+                value = "true"
+            }
+        } else if !hasValue {
+            if idx + 1 >= len(args) {
+                return nil, &UsageError{c, fmt.Sprintf("Parameter -%s expects a value", flag.Name)}
+            }
+            idx++
+            value = args[idx]
+        }
+
+        if err := c.validate(flag, value); err != nil {
+            return nil, err
+        }
+
+        if _, set := ctx.values[flag.Name]; set && !flag.Repeatable {
+            return nil, &UsageError{c, fmt.Sprintf("Parameter -%s can only be provided once", flag.Name)}
+        }
+
+        ctx.values[flag.Name] = append(ctx.values[flag.Name], value)
+    }
+
+    for _, flag := range c.Flags {
+        if _, set := ctx.values[flag.Name]; flag.Required && !set {
+            return nil, &UsageError{c, fmt.Sprintf("Parameter -%s is required", flag.Name)}
+        }
+    }
+
+    if len(ctx.args) < c.MinArgs {
+        return nil, &UsageError{c, "Missing arguments"}
+    }
+
+    if c.MaxArgs >= 0 && len(ctx.args) > c.MaxArgs {
+        return nil, &UsageError{c, fmt.Sprintf("Unexpected argument '%s'", ctx.args[c.MaxArgs])}
+    }
+
+    return ctx, nil
+}
+
+func (c *Command) validate(flag *Flag, value string) error {
+    invalid := func (expected string) error {
+        return &UsageError{c, fmt.Sprintf("Invalid value '%s' for -%s, expected %s", value, flag.Name, expected)}
+    }
+
+    switch flag.Kind {
+        case Int:
+            if _, err := strconv.Atoi(value); err != nil {
+                return invalid("a number")
+            }
+        case Bool, Switch:
+            if value != "true" && value != "false" {
+                return invalid("true or false")
+            }
+    }
+
+    if len(flag.Values) > 0 && !include(flag.Values, value) {
+        return invalid("one of " + strings.Join(flag.Values, "|"))
+    }
+
+    return nil
+}
+
+func (c *Command) unknownFlag(name string) error {
+    candidates := make([]string, 0)
+
+    for _, flag := range c.Flags {
+        candidates = append(candidates, flag.Name)
+    }
+
+    message := fmt.Sprintf("Parameter -%s not recognized", name)
+
+    if suggestion, ok := Suggest(name, candidates); ok {
+        message += fmt.Sprintf(", did you mean -%s?", suggestion)
+    }
+
+    return &UsageError{c, message}
+}
+
+// Find walks down the subcommands named by args like Execute, returning the
+// command it would run and the arguments left for it, so that callers can
+// check e.g. ReadOnly first.
+func (c *Command) Find(args []string) (*Command, []string) {
+    cmd := c
+
+    for len(args) > 0 && len(cmd.Subcommands) > 0 {
+        sub := cmd.Subcommand(args[0])
+
+        if sub == nil {
+            break
+        }
+
+        cmd = sub
+        args = args[1:]
+    }
+
+    return cmd, args
+}
+
+// Execute runs the subcommand named by args, parse errors are printed along
+// with the usage of the command.
+func (c *Command) Execute(args []string) {
+    cmd, args := c.Find(args)
+
+    if cmd.Run == nil {
+        if len(args) > 0 {
+            fmt.Println(cmd.UnknownCommand(args[0]))
+            fmt.Println()
+        }
+        fmt.Print(cmd.Help())
+        return
+    }
+
+    ctx, err := cmd.Parse(args)
+
+    if err == ErrHelp {
+        fmt.Print(cmd.Help())
+        return
+    }
+
+    if err == nil {
+        err = cmd.Run(ctx)
+    }
+
+    var usageErr *UsageError
+
+    if errors.As(err, &usageErr) {
+        fmt.Println(usageErr)
+        fmt.Println(usageErr.Command.UsageLine())
+        return
+    }
+
+    if err != nil {
+        fmt.Printf("Error: %s\n", err)
+    }
+}
+
+// UnknownCommand describes a missing subcommand, suggesting the closest one.
+func (c *Command) UnknownCommand(name string) error {
+    candidates := make([]string, 0)
+
+    for _, sub := range c.Subcommands {
+        if !sub.Hidden {
+            candidates = append(candidates, sub.names()...)
+        }
+    }
+
+    message := fmt.Sprintf("Option %s doesn't exist", name)
+
+    if suggestion, ok := Suggest(name, candidates); ok {
+        message += fmt.Sprintf(", did you mean %s?", suggestion)
+    }
+
+    return errors.New(message)
+}
+
+type Context struct {
+    Command *Command
+    values map[string][]string
+    args []string
+}
+
+// Usagef reports a command line the flag definitions couldn't catch, it is
+// printed along with the usage of the command.
+func (ctx *Context) Usagef(format string, args ...any) error {
+    return &UsageError{ctx.Command, fmt.Sprintf(format, args...)}
+}
+
+func (ctx *Context) Args() []string {
+    return ctx.args
+}
+
+func (ctx *Context) Arg(idx int) string {
+    if idx >= len(ctx.args) {
+        return ""
+    }
+    return ctx.args[idx]
+}
+
+func (ctx *Context) IsSet(name string) bool {
+    _, set := ctx.values[name]
+    return set
+}
+
+func (ctx *Context) String(name string) (string, bool) {
+    values, set := ctx.values[name]
+
+    if !set {
+        return "", false
+    }
+
+    return values[len(values)-1], true
+}
+
+func (ctx *Context) Strings(name string) []string {
+    return ctx.values[name]
+}
+
+func (ctx *Context) Int(name string) (int, bool) {
+    value, set := ctx.String(name)
+
+    if !set {
+        return 0, false
+    }
+
+    number, _ := strconv.Atoi(value)
+
+    return number, true
+}
+
+func (ctx *Context) Bool(name string) (bool, bool) {
+    value, set := ctx.String(name)
+    return value == "true", set
+}
+
+// isFlag tells flags apart from positional values, "-" and negative numbers
+// are positional.
+func isFlag(arg string) bool {
+    return strings.HasPrefix(arg, "-") && arg != "-" && !isNumber(arg)
+}
+
+func isNumber(arg string) bool {
+    _, err := strconv.ParseFloat(arg, 64)
+    return err == nil
+}
+
+func include(set []string, val string) bool {
+    for _, setVal := range set {
+        if setVal == val {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestCommand() *Command {
+    return &Command{
+        Name: "go_todo",
+        Subcommands: []*Command{
+            {
+                Name: "add",
+                Aliases: []string{"a"},
+                Summary: "Add tasks",
+                Args: "[<text>]",
+                MaxArgs: -1,
+                Flags: []Flag{
+                    {Name: "name", Short: "n", Required: true, Usage: "name of the task"},
+                    {Name: "completed", Short: "c", Kind: Bool, Usage: "create the task as done"},
+                    {Name: "priority", Kind: Int, Usage: "priority of the task"},
+                    {Name: "tag", Repeatable: true, Usage: "tags of the task"},
+                    {Name: "sort", Values: []string{"asc", "desc"}, Usage: "order"},
+                    {Name: "mine", Kind: Switch, Usage: "only yours"},
+                },
+                Run: func (ctx *Context) error { return nil },
+            },
+        },
+    }
+}
+
+func TestParse(t *testing.T) {
+    add := newTestCommand().Lookup("a")
+
+    t.Run("Should accept the long, short, double dash and attached forms", func (t *testing.T) {
+        ctx, err := add.Parse([]string{"--name=first", "-c", "true", "-mine"})
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        name, _ := ctx.String("name")
+        completed, _ := ctx.Bool("completed")
+        mine, _ := ctx.Bool("mine")
+
+        if name != "first" || !completed || !mine {
+            t.Errorf("expected name first, completed and mine, got %s %t %t\n", name, completed, mine)
+        }
+    })
+
+    t.Run("Should keep positional values around flags", func (t *testing.T) {
+        ctx, err := add.Parse([]string{"buy", "-n", "x", "milk", "", "-1"})
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        if want := []string{"buy", "milk", "", "-1"}; !reflect.DeepEqual(ctx.Args(), want) {
+            t.Errorf("expected %q, got %q\n", want, ctx.Args())
+        }
+    })
+
+    t.Run("Should take negative numbers as values", func (t *testing.T) {
+        ctx, err := add.Parse([]string{"-n", "x", "-priority", "-2"})
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        if priority, _ := ctx.Int("priority"); priority != -2 {
+            t.Errorf("expected priority -2, got %d\n", priority)
+        }
+    })
+
+    t.Run("Should treat everything after -- as positional", func (t *testing.T) {
+        ctx, err := add.Parse([]string{"-n", "x", "--", "-name", "--"})
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        if want := []string{"-name", "--"}; !reflect.DeepEqual(ctx.Args(), want) {
+            t.Errorf("expected %q, got %q\n", want, ctx.Args())
+        }
+    })
+
+    t.Run("Should collect repeated flags", func (t *testing.T) {
+        ctx, err := add.Parse([]string{"-n", "x", "-tag", "home", "-tag=work"})
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        if want := []string{"home", "work"}; !reflect.DeepEqual(ctx.Strings("tag"), want) {
+            t.Errorf("expected %q, got %q\n", want, ctx.Strings("tag"))
+        }
+    })
+
+    cases := []struct {
+        name string
+        args []string
+        message string
+    }{
+        {"missing required flag", []string{"-c", "true"}, "Parameter -name is required"},
+        {"missing value", []string{"-name"}, "Parameter -name expects a value"},
+        {"invalid bool", []string{"-n", "x", "-c", "yes"}, "Invalid value 'yes' for -completed, expected true or false"},
+        {"invalid int", []string{"-n", "x", "-priority", "high"}, "Invalid value 'high' for -priority, expected a number"},
+        {"invalid enum", []string{"-n", "x", "-sort", "up"}, "Invalid value 'up' for -sort, expected one of asc|desc"},
+        {"repeated flag", []string{"-n", "x", "-n", "y"}, "Parameter -name can only be provided once"},
+        {"unknown flag", []string{"-asdf"}, "Parameter -asdf not recognized"},
+        {"typo", []string{"-nmae", "x"}, "Parameter -nmae not recognized, did you mean -name?"},
+    }
+
+    for _, c := range cases {
+        t.Run("Should reject "+c.name, func (t *testing.T) {
+            _, err := add.Parse(c.args)
+
+            var usageErr *UsageError
+            if !errors.As(err, &usageErr) || usageErr.Message != c.message {
+                t.Errorf("expected usage error %q, got %v\n", c.message, err)
+            }
+        })
+    }
+
+    t.Run("Should ask for help", func (t *testing.T) {
+        if _, err := add.Parse([]string{"--help"}); err != ErrHelp {
+            t.Errorf("expected ErrHelp, got %v\n", err)
+        }
+    })
+}
+
+func TestHelp(t *testing.T) {
+    app := newTestCommand()
+    add := app.Lookup("add")
+
+    want := "Usage: go_todo add -name <name> [-completed <true|false>] [-priority <priority>] [-tag <tag>...] [-sort <asc|desc>] [-mine] [<text>]"
+
+    if add.UsageLine() != want {
+        t.Errorf("expected:\n%s\ngot:\n%s\n", want, add.UsageLine())
+    }
+
+    help := add.Help()
+
+    for _, line := range []string{"Aliases: a", "  -n, -name <name>              name of the task", "  -mine                         only yours"} {
+        if !strings.Contains(help, line) {
+            t.Errorf("expected help to contain %q, got:\n%s\n", line, help)
+        }
+    }
+
+    if !strings.Contains(app.Help(), "  add, a   Add tasks") {
+        t.Errorf("expected the subcommands to be listed, got:\n%s\n", app.Help())
+    }
+
+    add.Flags = append(add.Flags, Flag{Name: "order", Synopsis: "<column>[,asc|desc]", Usage: "order by a column"})
+
+    if !strings.HasSuffix(add.UsageLine(), " [-order <column>[,asc|desc]] [<text>]") {
+        t.Errorf("expected the synopsis of -order as is, got:\n%s\n", add.UsageLine())
+    }
+}
+
+func TestSuggest(t *testing.T) {
+    candidates := []string{"add", "list", "delete", "update", "webhooks"}
+
+    cases := map[string]string{
+        "lsit": "list",
+        "delte": "delete",
+        "webhoks": "webhooks",
+        "asdf": "",
+        "x": "",
+    }
+
+    for input, want := range cases {
+        if got, _ := Suggest(input, candidates); got != want {
+            t.Errorf("expected suggestion for %s to be %q, got %q\n", input, want, got)
+        }
+    }
+
+    if err := newTestCommand().UnknownCommand("ad"); err.Error() != "Option ad doesn't exist, did you mean add?" {
+        t.Errorf("expected a suggestion, got %s\n", err)
+    }
+}
+
+func TestComplete(t *testing.T) {
+    app := newTestCommand()
+    app.Subcommands = append(app.Subcommands, &Command{
+        Name: "delete",
+        MaxArgs: -1,
+        CompleteArgs: func (args []string) []Completion {
+            return []Completion{{"1", "first"}, {"2", "second"}}
+        },
+        Run: func (ctx *Context) error { return nil },
+    }, &Command{Name: "secret", Hidden: true})
+
+    values := func (completions []Completion) string {
+        joined := make([]string, len(completions))
+        for idx, completion := range completions {
+            joined[idx] = completion.Value
+        }
+        return strings.Join(joined, " ")
+    }
+
+    cases := []struct {
+        words []string
+        want string
+    }{
+        {[]string{""}, "add delete"},
+        {[]string{"d"}, "delete"},
+        {[]string{"a", "-"}, "-name -completed -priority -tag -sort -mine"},
+        {[]string{"add", "-s"}, "-sort"},
+        {[]string{"add", "-sort", ""}, "asc desc"},
+        {[]string{"add", "-c", "f"}, "false"},
+        {[]string{"add", "--sort=d"}, "--sort=desc"},
+        {[]string{"add", "-mine", "-"}, "-name -completed -priority -tag -sort -mine"},
+        {[]string{"delete", "1", ""}, "1 2"},
+        {[]string{"delete", "--", "-"}, ""},
+    }
+
+    for _, c := range cases {
+        if got := values(app.Complete(c.words)); got != c.want {
+            t.Errorf("expected completions of %q to be %q, got %q\n", c.words, c.want, got)
+        }
+    }
+
+    if got := app.Complete([]string{"delete", "2"}); len(got) != 1 || got[0].String() != "2\tsecond" {
+        t.Errorf("expected the description along the value, got %v\n", got)
+    }
+}
+
+func TestScript(t *testing.T) {
+    app := newTestCommand()
+
+    for _, shell := range Shells {
+        script, err := app.Script(shell)
+
+        if err != nil {
+            t.Fatalf("unexpected error for %s, %s\n", shell, err)
+        }
+
+        if !strings.Contains(script, "go_todo __complete") {
+            t.Errorf("expected the %s script to call back the program, got:\n%s\n", shell, script)
+        }
+    }
+
+    if _, err := app.Script("powershell"); err == nil {
+        t.Error("expected unsupported shells to fail")
+    }
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompleteCommand is the hidden command the completion scripts call back
+// with the words typed so far.
+const CompleteCommand = "__complete"
+
+var Shells = []string{"bash", "zsh", "fish"}
+
+type Completion struct {
+    Value string
+    Description string
+}
+
+func (c Completion) String() string {
+    return c.Value + "\t" + c.Description
+}
+
+// Complete suggests values for the last of words, the words typed after the
+// program name, the last one possibly being empty.
+func (c *Command) Complete(words []string) []Completion {
+    if len(words) == 0 {
+        words = []string{""}
+    }
+
+    current, typed := words[len(words)-1], words[:len(words)-1]
+
+    cmd := c
+    idx := 0
+
+    for idx < len(typed) && len(cmd.Subcommands) > 0 {
+        sub := cmd.Subcommand(typed[idx])
+
+        if sub == nil || sub.Hidden {
+            break
+        }
+
+        cmd = sub
+        idx++
+    }
+
+    var pending *Flag
+    positional := make([]string, 0)
+    afterDashes := false
+
+    for _, word := range typed[idx:] {
+        switch {
+            case pending != nil:
+                pending = nil
+            case afterDashes || !isFlag(word):
+                positional = append(positional, word)
+            case word == "--":
+                afterDashes = true
+            default:
+                name, _, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+                if flag := cmd.flag(name); flag != nil && flag.Kind != Switch && !hasValue {
+                    pending = flag
+                }
+        }
+    }
+
+    if pending != nil {
+        return filter(pending.completions(), "", current)
+    }
+
+    if !afterDashes && strings.HasPrefix(current, "-") {
+        if name, value, hasValue := strings.Cut(strings.TrimLeft(current, "-"), "="); hasValue {
+            if flag := cmd.flag(name); flag != nil {
+                prefix := current[:len(current)-len(value)]
+                return filter(flag.completions(), prefix, current)
+            }
+            return nil
+        }
+
+        candidates := make([]Completion, 0, len(cmd.Flags))
+        for _, flag := range cmd.Flags {
+            candidates = append(candidates, Completion{"-" + flag.Name, flag.Usage})
+        }
+        return filter(candidates, "", current)
+    }
+
+    if len(cmd.Subcommands) > 0 && len(positional) == 0 {
+        candidates := make([]Completion, 0, len(cmd.Subcommands))
+        for _, sub := range cmd.Subcommands {
+            if !sub.Hidden {
+                candidates = append(candidates, Completion{sub.Name, sub.Summary})
+            }
+        }
+        return filter(candidates, "", current)
+    }
+
+    if cmd.CompleteArgs != nil && (cmd.MaxArgs < 0 || len(positional) < cmd.MaxArgs) {
+        return filter(cmd.CompleteArgs(positional), "", current)
+    }
+
+    return nil
+}
+
+func (f Flag) completions() []Completion {
+    if f.Complete != nil {
+        return f.Complete()
+    }
+
+    values := f.Values
+    if f.Kind == Bool {
+        values = []string{"true", "false"}
+    }
+
+    completions := make([]Completion, len(values))
+    for idx, value := range values {
+        completions[idx] = Completion{Value: value}
+    }
+
+    return completions
+}
+
+// filter keeps the candidates starting with current once prefix, the part of
+// the word that isn't a value, is prepended.
+func filter(candidates []Completion, prefix string, current string) []Completion {
+    matches := make([]Completion, 0)
+
+    for _, candidate := range candidates {
+        candidate.Value = prefix + candidate.Value
+        if strings.HasPrefix(candidate.Value, current) {
+            matches = append(matches, candidate)
+        }
+    }
+
+    return matches
+}
+
+// Script returns the completion script of shell for the program, the scripts
+// call the program back through CompleteCommand which prints one
+// "value<TAB>description" line per candidate.
+func (c *Command) Script(shell string) (string, error) {
+    var template string
+
+    switch shell {
+        case "bash":
+            template = bashScript
+        case "zsh":
+            template = zshScript
+        case "fish":
+            template = fishScript
+        default:
+            return "", fmt.Errorf("Unsupported shell '%s', expected one of %s", shell, strings.Join(Shells, "|"))
+    }
+
+    replacer := strings.NewReplacer("{{program}}", c.Name, "{{function}}", "_"+strings.ReplaceAll(c.Name, "-", "_"), "{{complete}}", CompleteCommand)
+
+    return replacer.Replace(template), nil
+}
+
+const bashScript = `# bash completion for {{program}}, load it with: source <({{program}} completion bash)
+{{function}}() {
+    local IFS=$'\n'
+    local candidates=($({{program}} {{complete}} "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=()
+    local candidate
+    for candidate in "${candidates[@]}"; do
+        COMPREPLY+=("${candidate%%$'\t'*}")
+    done
+}
+complete -o default -F {{function}} {{program}}
+`
+
+const zshScript = `#compdef {{program}}
+# zsh completion for {{program}}, load it with: source <({{program}} completion zsh)
+{{function}}() {
+    local -a candidates
+    local line value
+    for line in "${(@f)$({{program}} {{complete}} "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        candidates+=("${value//:/\\:}:${line#*$'\t'}")
+    done
+    _describe '{{program}}' candidates
+}
+compdef {{function}} {{program}}
+`
+
+const fishScript = `# fish completion for {{program}}, load it with: {{program}} completion fish | source
+function __{{function}}_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    {{program}} {{complete}} $tokens[2..-1] 2>/dev/null
+end
+complete -c {{program}} -f -a '(__{{function}}_complete)'
+`
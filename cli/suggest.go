@@ -0,0 +1,57 @@
+package cli
+
+// Suggest returns the candidate closest to input when it looks like a typo of
+// it, allowing one edit every three characters.
+func Suggest(input string, candidates []string) (string, bool) {
+    threshold := len(input) / 3
+    if threshold < 1 {
+        threshold = 1
+    }
+
+    best, bestDistance := "", threshold + 1
+
+    for _, candidate := range candidates {
+        if distance := editDistance(input, candidate); distance < bestDistance {
+            best, bestDistance = candidate, distance
+        }
+    }
+
+    return best, best != ""
+}
+
+// editDistance is the optimal string alignment distance between a and b,
+// swapping two adjacent characters counts as a single edit.
+func editDistance(a, b string) int {
+    rowsA, rowsB := []rune(a), []rune(b)
+    distances := make([][]int, len(rowsA)+1)
+
+    for i := range distances {
+        distances[i] = make([]int, len(rowsB)+1)
+        distances[i][0] = i
+    }
+
+    for j := range distances[0] {
+        distances[0][j] = j
+    }
+
+    for i := 1; i <= len(rowsA); i++ {
+        for j := 1; j <= len(rowsB); j++ {
+            cost := 1
+            if rowsA[i-1] == rowsB[j-1] {
+                cost = 0
+            }
+
+            distances[i][j] = min(
+                distances[i-1][j] + 1,
+                distances[i][j-1] + 1,
+                distances[i-1][j-1] + cost,
+            )
+
+            if i > 1 && j > 1 && rowsA[i-1] == rowsB[j-2] && rowsA[i-2] == rowsB[j-1] {
+                distances[i][j] = min(distances[i][j], distances[i-2][j-2] + 1)
+            }
+        }
+    }
+
+    return distances[len(rowsA)][len(rowsB)]
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func completionCommand(app *cli.Command) *cli.Command {
+    return &cli.Command{
+        Name: "completion",
+        Summary: "Print the completion script of a shell",
+        Args: "<" + strings.Join(cli.Shells, "|") + ">",
+        MinArgs: 1,
+        MaxArgs: 1,
+        CompleteArgs: func (args []string) []cli.Completion {
+            completions := make([]cli.Completion, len(cli.Shells))
+            for idx, shell := range cli.Shells {
+                completions[idx] = cli.Completion{Value: shell}
+            }
+            return completions
+        },
+        ReadOnly: true,
+        Run: func (ctx *cli.Context) error {
+            script, err := app.Script(ctx.Arg(0))
+
+            if err != nil {
+                return ctx.Usagef("%s", err)
+            }
+
+            fmt.Print(script)
+
+            return nil
+        },
+    }
+}
+
+// completeCommand is called back by the completion scripts with the words
+// typed so far.
+func completeCommand(app *cli.Command) *cli.Command {
+    return &cli.Command{
+        Name: cli.CompleteCommand,
+        Hidden: true,
+        RawArgs: true,
+        ReadOnly: true,
+        MaxArgs: -1,
+        Run: func (ctx *cli.Context) error {
+            for _, completion := range app.Complete(ctx.Args()) {
+                fmt.Println(completion)
+            }
+            return nil
+        },
+    }
+}
+
+// taskCompletions suggests the IDs of the tasks visible to the current user
+// which weren't typed yet, described by their names.
+func taskCompletions(db database.DB) func (args []string) []cli.Completion {
+    return func (args []string) []cli.Completion {
+        tasksStore, err := taskStore(db)
+
+        if err != nil {
+            return nil
+        }
+
+        actor, err := currentUser(db)
+
+        if err != nil {
+            return nil
+        }
+
+        tasks, err := tasksStore.ListTasks(actor, database.ListTaskProps{})
+
+        if err != nil {
+            return nil
+        }
+
+        completions := make([]cli.Completion, 0, len(tasks))
+
+        for _, task := range tasks {
+            id := strconv.Itoa(task.ID)
+
+            if Include(args, id) {
+                continue
+            }
+
+            completions = append(completions, cli.Completion{Value: id, Description: task.Name})
+        }
+
+        return completions
+    }
+}
+
+// projectCompletions suggests the projects of the tasks visible to the
+// current user.
+func projectCompletions(db database.DB) func () []cli.Completion {
+    return taskValueCompletions(db, database.ListProjectsAction, func (task database.Task) []string {
+        if task.Project == "" {
+            return nil
+        }
+        return []string{task.Project}
+    })
+}
+
+// tagCompletions suggests the tags of the tasks visible to the current user.
+func tagCompletions(db database.DB) func () []cli.Completion {
+    return taskValueCompletions(db, database.ListTagsAction, func (task database.Task) []string {
+        return task.Tags
+    })
+}
+
+// taskValueCompletions queries the distinct values with list when the tasks
+// are in db, and collects them from the tasks of the other stores.
+func taskValueCompletions(db database.DB, list func (database.DB, database.User) ([]string, error), values func (database.Task) []string) func () []cli.Completion {
+    return func () []cli.Completion {
+        actor, err := currentUser(db)
+
+        if err != nil {
+            return nil
+        }
+
+        var found []string
+
+        if isSQLStore(os.Getenv(StoreEnvVar)) {
+            found, err = list(db, actor)
+        } else {
+            found, err = storeTaskValues(db, actor, values)
+        }
+
+        if err != nil {
+            return nil
+        }
+
+        completions := make([]cli.Completion, len(found))
+
+        for idx, value := range found {
+            completions[idx] = cli.Completion{Value: value}
+        }
+
+        return completions
+    }
+}
+
+func storeTaskValues(db database.DB, actor database.User, values func (database.Task) []string) ([]string, error) {
+    tasksStore, err := taskStore(db)
+
+    if err != nil {
+        return nil, err
+    }
+
+    tasks, err := tasksStore.ListTasks(actor, database.ListTaskProps{})
+
+    if err != nil {
+        return nil, err
+    }
+
+    found := make([]string, 0)
+
+    for _, task := range tasks {
+        for _, value := range values(task) {
+            if !slices.Contains(found, value) {
+                found = append(found, value)
+            }
+        }
+    }
+    slices.Sort(found)
+
+    return found, nil
+}
+
+func userCompletions(db database.DB, extra ...string) func () []cli.Completion {
+    return func () []cli.Completion {
+        completions := make([]cli.Completion, 0)
+
+        for _, value := range extra {
+            completions = append(completions, cli.Completion{Value: value})
+        }
+
+        users, err := database.ListUsersAction(db)
+
+        if err != nil {
+            return completions
+        }
+
+        for _, user := range users {
+            completions = append(completions, cli.Completion{Value: user.Name})
+        }
+
+        return completions
+    }
+}
+
+func webhookCompletions(db database.DB) func (args []string) []cli.Completion {
+    return func (args []string) []cli.Completion {
+        hooks, err := database.ListWebhooksAction(db)
+
+        if err != nil {
+            return nil
+        }
+
+        completions := make([]cli.Completion, len(hooks))
+
+        for idx, hook := range hooks {
+            completions[idx] = cli.Completion{Value: strconv.Itoa(hook.ID), Description: hook.URL}
+        }
+
+        return completions
+    }
+}
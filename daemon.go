@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"go_todo/cli"
+	"go_todo/daemon"
+	"go_todo/database"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func daemonCommand() *cli.Command {
+    return &cli.Command{
+        Name: "daemon",
+        Summary: "Serve the database to other invocations",
+        Run: func (ctx *cli.Context) error {
+            runDaemon("./")
+            return nil
+        },
+    }
+}
+
+// openDatabase goes through the daemon when one is running so concurrent
+// invocations don't fight over the database file.
+func openDatabase(rootFolder string) (*sql.DB, error) {
+    if db, err := daemon.Connect(rootFolder); err == nil {
+        return db, nil
+    }
+
+    return database.OpenDatabase(rootFolder)
+}
+
+func runDaemon(rootFolder string) {
+    db, err := database.OpenDatabase(rootFolder)
+
+    if err != nil {
+        fmt.Printf("error while opening the database: %s\n", err)
+        return
+    }
+    defer db.Close()
+
+    // every client holds a connection of its own
+    db.SetMaxOpenConns(0)
+
+    socketPath := daemon.SocketPath(rootFolder)
+    listener, err := daemon.Listen(socketPath)
+
+    if err != nil {
+        fmt.Printf("error while starting the daemon: %s\n", err)
+        return
+    }
+
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+    go func () {
+        <-signals
+        listener.Close()
+    }()
+
+    defer startWebhookDelivery(db)()
+
+    fmt.Printf("Daemon listening on %s\n", socketPath)
+
+    server := &daemon.Server{DB: db}
+
+    if err := server.Serve(listener); err != nil {
+        fmt.Printf("error while serving: %s\n", err)
+    }
+
+    os.Remove(socketPath)
+}
@@ -0,0 +1,291 @@
+package daemon
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"go_todo/database"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+    now := time.Now().UTC()
+    values := []any{nil, int64(42), 4.2, true, "name", []byte("bytes"), now}
+
+    for _, value := range values {
+        encoded, err := EncodeValue(value)
+
+        if err != nil {
+            t.Fatalf("error while encoding %v, %s\n", value, err)
+        }
+
+        decoded := encoded.Decode()
+
+        if bytes, ok := value.([]byte); ok {
+            if string(decoded.([]byte)) != string(bytes) {
+                t.Errorf("expected %s, got %v\n", bytes, decoded)
+            }
+            continue
+        }
+
+        if decoded != value {
+            t.Errorf("expected %v (%T), got %v (%T)\n", value, value, decoded, decoded)
+        }
+    }
+
+    if _, err := EncodeValue(42); err == nil {
+        t.Error("expected non driver values to be rejected")
+    }
+}
+
+func TestDaemon(t *testing.T) {
+    rootFolder := startDaemon(t, 0, 0)
+
+    client, err := Connect(rootFolder)
+
+    if err != nil {
+        t.Fatalf("error while connecting to the daemon, %s\n", err)
+    }
+    defer client.Close()
+
+    t.Run("Should run the database actions through the daemon", func (t *testing.T) {
+        tx, err := client.Begin()
+
+        if err != nil {
+            t.Fatalf("error while acquiring transaction, %s\n", err)
+        }
+        defer tx.Rollback()
+
+        actor, err := database.EnsureUserAction(tx, "tester")
+
+        if err != nil {
+            t.Fatalf("error while creating user, %s\n", err)
+        }
+
+        task, err := database.AddTaskAction(tx, actor, database.AddTaskProp{Name: "Through the daemon", Completed: true})
+
+        if err != nil {
+            t.Fatalf("error while adding task, %s\n", err)
+        }
+
+        listed, err := database.ListTaskActionByID(tx, actor, uint(task.ID))
+
+        if err != nil {
+            t.Fatalf("error while listing task, %s\n", err)
+        }
+
+        if listed.Name != "Through the daemon" || !listed.Completed {
+            t.Errorf("expected the added task back, got %+v\n", listed)
+        }
+
+        delCount, err := database.DeleteTaskBulkAction(tx, actor, []int{task.ID})
+
+        if err != nil || delCount != 1 {
+            t.Errorf("expected one task to be deleted, got %d (%v)\n", delCount, err)
+        }
+    })
+
+    t.Run("Should report no rows as sql.ErrNoRows", func (t *testing.T) {
+        _, err := database.ListTaskActionByID(client, database.User{}, 69)
+
+        if err != sql.ErrNoRows {
+            t.Errorf("expected sql.ErrNoRows, got %v\n", err)
+        }
+    })
+
+    t.Run("Should discard rolled back changes", func (t *testing.T) {
+        tx, _ := client.Begin()
+        actor, _ := database.EnsureUserAction(tx, "rolled back")
+        tx.Rollback()
+
+        users, err := database.ListUsersAction(client)
+
+        if err != nil {
+            t.Fatalf("error while listing users, %s\n", err)
+        }
+
+        for _, user := range users {
+            if user.ID == actor.ID && user.Name == "rolled back" {
+                t.Error("expected the user to be rolled back")
+            }
+        }
+    })
+
+    t.Run("Should queue concurrent transactions", func (t *testing.T) {
+        var wg sync.WaitGroup
+        errs := make(chan error, 10)
+
+        for i := 0; i < 10; i++ {
+            wg.Add(1)
+            go func () {
+                defer wg.Done()
+                tx, err := client.Begin()
+                if err != nil {
+                    errs <- err
+                    return
+                }
+                defer tx.Rollback()
+
+                actor, err := database.EnsureUserAction(tx, "tester")
+                if err != nil {
+                    errs <- err
+                    return
+                }
+
+                if _, err := database.AddTaskAction(tx, actor, database.AddTaskProp{Name: "Concurrent"}); err != nil {
+                    errs <- err
+                }
+            }()
+        }
+
+        wg.Wait()
+        close(errs)
+
+        for err := range errs {
+            t.Errorf("expected concurrent transactions to succeed, got %s\n", err)
+        }
+    })
+}
+
+func TestIdleTransaction(t *testing.T) {
+    rootFolder := startDaemon(t, 200 * time.Millisecond, 100 * time.Millisecond)
+
+    client, err := Connect(rootFolder)
+
+    if err != nil {
+        t.Fatalf("error while connecting to the daemon, %s\n", err)
+    }
+    defer client.Close()
+
+    idle, err := client.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    if _, err := database.EnsureUserAction(idle, "idle"); err != nil {
+        t.Fatalf("error while creating user, %s\n", err)
+    }
+
+    t.Run("Should serve the other clients while a transaction is idle", func (t *testing.T) {
+        if _, err := database.ListUsersAction(client); err != nil {
+            t.Errorf("expected the read to go on, got %s\n", err)
+        }
+    })
+
+    t.Run("Should keep an idle transaction nobody waits for", func (t *testing.T) {
+        time.Sleep(400 * time.Millisecond)
+
+        if _, err := database.EnsureUserAction(idle, "kept"); err != nil {
+            t.Errorf("expected the transaction to go on, got %s\n", err)
+        }
+    })
+
+    t.Run("Should roll back an idle transaction another client waits for", func (t *testing.T) {
+        time.Sleep(400 * time.Millisecond)
+
+        if _, err := database.EnsureUserAction(client, "waiting"); err != nil {
+            t.Fatalf("expected the write lock to be released, got %s\n", err)
+        }
+
+        if _, err := database.EnsureUserAction(idle, "late"); err == nil || !strings.Contains(err.Error(), "its changes are lost") {
+            t.Errorf("expected the statements of the expired transaction to tell, got %v\n", err)
+        }
+
+        if err := idle.Commit(); err == nil {
+            t.Error("expected the commit of the expired transaction to fail")
+        }
+
+        users, _ := database.ListUsersAction(client)
+
+        for _, user := range users {
+            if user.Name == "idle" || user.Name == "kept" || user.Name == "late" {
+                t.Errorf("expected %s to be rolled back\n", user.Name)
+            }
+        }
+    })
+}
+
+func TestCall(t *testing.T) {
+    t.Run("Should retry when the request wasn't sent", func (t *testing.T) {
+        server, client := net.Pipe()
+        server.Close()
+
+        if err := newConn(client).call("ping", nil, nil); err != driver.ErrBadConn {
+            t.Errorf("expected driver.ErrBadConn, got %v\n", err)
+        }
+    })
+
+    t.Run("Should not retry a request the daemon may have run", func (t *testing.T) {
+        server, client := net.Pipe()
+
+        go func () {
+            bufio.NewReader(server).ReadBytes('\n')
+            server.Close()
+        }()
+
+        c := newConn(client)
+        err := c.call("exec", StatementParams{Query: "DELETE FROM tasks"}, nil)
+
+        if err == nil || err == driver.ErrBadConn {
+            t.Errorf("expected the raw error, got %v\n", err)
+        }
+
+        if c.IsValid() {
+            t.Error("expected the connection to be dropped")
+        }
+    })
+}
+
+func TestListen(t *testing.T) {
+    rootFolder := startDaemon(t, 0, 0)
+
+    if _, err := Listen(SocketPath(rootFolder)); err == nil {
+        t.Error("expected a second daemon to be refused")
+    }
+}
+
+func TestConnect(t *testing.T) {
+    if _, err := Connect(t.TempDir()); err == nil {
+        t.Error("expected connecting without a running daemon to fail")
+    }
+}
+
+// startDaemon serves ../task.db, with the usual busy timeout when
+// busyTimeout is zero.
+func startDaemon(t testing.TB, idleTimeout time.Duration, busyTimeout time.Duration) string {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if busyTimeout != 0 {
+        params := strings.Replace(database.ConnectionParams, "_busy_timeout=5000", fmt.Sprintf("_busy_timeout=%d", busyTimeout.Milliseconds()), 1)
+        db, err = sql.Open(database.DriverName, "file:../task.db?"+params)
+    }
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    db.SetMaxOpenConns(0)
+
+    rootFolder := t.TempDir()
+    listener, err := Listen(filepath.Join(rootFolder, "task.sock"))
+
+    if err != nil {
+        t.Fatalf("error while listening, %s\n", err)
+    }
+
+    go (&Server{DB: db, TxIdleTimeout: idleTimeout}).Serve(listener)
+
+    t.Cleanup(func () {
+        listener.Close()
+        db.Close()
+    })
+
+    return rootFolder
+}
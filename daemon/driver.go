@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"go_todo/jsonrpc"
+	"io"
+	"net"
+	"time"
+)
+
+// DriverName is the database/sql driver forwarding statements to a running
+// daemon, its data source name is the path of the daemon socket.
+const DriverName = "go_todo_daemon"
+
+func init() {
+    sql.Register(DriverName, Driver{})
+}
+
+// Connect opens a database backed by the daemon listening in rootFolder, it
+// fails if no daemon is running so callers can fall back to the database file.
+func Connect(rootFolder string) (*sql.DB, error) {
+    socketPath := SocketPath(rootFolder)
+
+    conn, err := net.DialTimeout("unix", socketPath, time.Second)
+
+    if err != nil {
+        return nil, err
+    }
+    conn.Close()
+
+    db, err := sql.Open(DriverName, socketPath)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := db.Ping(); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    return db, nil
+}
+
+type Driver struct{}
+
+func (Driver) Open(socketPath string) (driver.Conn, error) {
+    netConn, err := net.Dial("unix", socketPath)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return newConn(netConn), nil
+}
+
+func newConn(netConn net.Conn) *conn {
+    counted := &countingConn{Conn: netConn}
+
+    return &conn{client: jsonrpc.NewClient(counted), socket: counted}
+}
+
+type conn struct {
+    client *jsonrpc.Client
+    socket *countingConn
+    // broken is set once a call failed midway, the connection is out of sync
+    // with the daemon.
+    broken bool
+}
+
+// countingConn counts the bytes written to the daemon.
+type countingConn struct {
+    net.Conn
+    written int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+    n, err := c.Conn.Write(p)
+    c.written += int64(n)
+    return n, err
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+    return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+    return c.client.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+    if err := c.call("begin", nil, nil); err != nil {
+        return nil, err
+    }
+    return &tx{conn: c}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+    return c.call("ping", nil, nil)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    params, err := statementParams(query, args)
+
+    if err != nil {
+        return nil, err
+    }
+
+    result := ExecResult{}
+
+    if err := c.call("exec", params, &result); err != nil {
+        return nil, err
+    }
+
+    return execResult(result), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    params, err := statementParams(query, args)
+
+    if err != nil {
+        return nil, err
+    }
+
+    result := QueryResult{}
+
+    if err := c.call("query", params, &result); err != nil {
+        return nil, err
+    }
+
+    return &rows{result: result}, nil
+}
+
+// IsValid tells the pool to drop the connections broken by a failed call.
+func (c *conn) IsValid() bool {
+    return !c.broken
+}
+
+// call answers driver.ErrBadConn, which makes database/sql retry on another
+// connection, only when the request didn't reach the socket. Otherwise the
+// daemon may have run it and the error is returned as it is, the connection
+// is dropped either way.
+func (c *conn) call(method string, params any, result any) error {
+    written := c.socket.written
+    err := c.client.Call(method, params, result)
+
+    if _, ok := err.(*jsonrpc.Error); err != nil && !ok {
+        c.broken = true
+
+        if c.socket.written == written {
+            return driver.ErrBadConn
+        }
+    }
+
+    return err
+}
+
+type stmt struct {
+    conn *conn
+    query string
+}
+
+func (s *stmt) Close() error {
+    return nil
+}
+
+func (s *stmt) NumInput() int {
+    return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+    return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+    return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+type tx struct {
+    conn *conn
+}
+
+func (t *tx) Commit() error {
+    return t.conn.call("commit", nil, nil)
+}
+
+func (t *tx) Rollback() error {
+    return t.conn.call("rollback", nil, nil)
+}
+
+type rows struct {
+    result QueryResult
+    index int
+}
+
+func (r *rows) Columns() []string {
+    return r.result.Columns
+}
+
+func (r *rows) Close() error {
+    return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+    if r.index >= len(r.result.Rows) {
+        return io.EOF
+    }
+
+    for idx, value := range r.result.Rows[r.index] {
+        dest[idx] = value.Decode()
+    }
+    r.index++
+
+    return nil
+}
+
+type execResult ExecResult
+
+func (r execResult) LastInsertId() (int64, error) {
+    return r.LastInsertID, nil
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+    return r.AffectedRows, nil
+}
+
+func statementParams(query string, args []driver.NamedValue) (StatementParams, error) {
+    params := StatementParams{Query: query, Args: make([]Value, len(args))}
+
+    for idx, arg := range args {
+        value, err := EncodeValue(arg.Value)
+        if err != nil {
+            return StatementParams{}, err
+        }
+        params.Args[idx] = value
+    }
+
+    return params, nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+    named := make([]driver.NamedValue, len(args))
+
+    for idx, arg := range args {
+        named[idx] = driver.NamedValue{Ordinal: idx + 1, Value: arg}
+    }
+
+    return named
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// Value carries a driver.Value over JSON keeping its type, only one of the
+// fields is set and none of them for NULL.
+type Value struct {
+    Int *int64 `json:"int,omitempty"`
+    Float *float64 `json:"float,omitempty"`
+    Bool *bool `json:"bool,omitempty"`
+    String *string `json:"string,omitempty"`
+    Bytes []byte `json:"bytes,omitempty"`
+    Time *time.Time `json:"time,omitempty"`
+}
+
+func EncodeValue(v any) (Value, error) {
+    switch value := v.(type) {
+        case nil:
+            return Value{}, nil
+        case int64:
+            return Value{Int: &value}, nil
+        case float64:
+            return Value{Float: &value}, nil
+        case bool:
+            return Value{Bool: &value}, nil
+        case string:
+            return Value{String: &value}, nil
+        case []byte:
+            return Value{Bytes: append([]byte{}, value...)}, nil
+        case time.Time:
+            return Value{Time: &value}, nil
+        default:
+            return Value{}, fmt.Errorf("unsupported value type %T", v)
+    }
+}
+
+func (v Value) Decode() any {
+    switch {
+        case v.Int != nil:
+            return *v.Int
+        case v.Float != nil:
+            return *v.Float
+        case v.Bool != nil:
+            return *v.Bool
+        case v.String != nil:
+            return *v.String
+        case v.Bytes != nil:
+            return v.Bytes
+        case v.Time != nil:
+            return *v.Time
+        default:
+            return nil
+    }
+}
+
+type StatementParams struct {
+    Query string `json:"query"`
+    Args []Value `json:"args"`
+}
+
+type QueryResult struct {
+    Columns []string `json:"columns"`
+    Rows [][]Value `json:"rows"`
+}
+
+type ExecResult struct {
+    LastInsertID int64 `json:"last_insert_id"`
+    AffectedRows int64 `json:"rows_affected"`
+}
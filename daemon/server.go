@@ -0,0 +1,398 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"go_todo/jsonrpc"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+func SocketPath(rootFolder string) string {
+    return filepath.Join(rootFolder, "task.sock")
+}
+
+// Listen creates the daemon socket, a socket left behind by a daemon that
+// didn't shut down cleanly is replaced.
+func Listen(socketPath string) (net.Listener, error) {
+    if _, err := os.Stat(socketPath); err == nil {
+        if conn, err := net.Dial("unix", socketPath); err == nil {
+            conn.Close()
+            return nil, errors.New("daemon is already running")
+        }
+
+        if err := os.Remove(socketPath); err != nil {
+            return nil, err
+        }
+    }
+
+    listener, err := net.Listen("unix", socketPath)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := os.Chmod(socketPath, 0600); err != nil {
+        listener.Close()
+        return nil, err
+    }
+
+    return listener, nil
+}
+
+// DefaultTxIdleTimeout is how long a client may leave its transaction idle
+// before it can be rolled back to let the other clients write.
+const DefaultTxIdleTimeout = 30 * time.Second
+
+// Server owns the database and runs the statements sent by the clients, each
+// client on a connection of its own so that the reads of one don't wait for
+// the transaction of another. The statements refused for the database being
+// busy are retried like those of a database.RetryDB. A transaction idle for
+// longer than TxIdleTimeout is only rolled back when it keeps another client
+// from writing, its client is told so by the statements which follow.
+type Server struct {
+    DB *sql.DB
+    // TxIdleTimeout is DefaultTxIdleTimeout when zero.
+    TxIdleTimeout time.Duration
+
+    mu sync.Mutex
+    sessions map[*session]bool
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+    for {
+        conn, err := listener.Accept()
+
+        if err != nil {
+            if errors.Is(err, net.ErrClosed) {
+                return nil
+            }
+            return err
+        }
+
+        go s.serveConn(conn)
+    }
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+    defer conn.Close()
+
+    dbConn, err := s.DB.Conn(context.Background())
+
+    if err != nil {
+        return
+    }
+
+    client := &session{server: s, conn: dbConn, idleTimeout: s.TxIdleTimeout}
+
+    if client.idleTimeout == 0 {
+        client.idleTimeout = DefaultTxIdleTimeout
+    }
+
+    s.mu.Lock()
+    if s.sessions == nil {
+        s.sessions = make(map[*session]bool)
+    }
+    s.sessions[client] = true
+    s.mu.Unlock()
+
+    defer func () {
+        s.mu.Lock()
+        delete(s.sessions, client)
+        s.mu.Unlock()
+        client.close()
+    }()
+
+    rpc := jsonrpc.NewServer()
+    rpc.Register("ping", client.ping)
+    rpc.Register("query", client.query)
+    rpc.Register("exec", client.exec)
+    rpc.Register("begin", client.begin)
+    rpc.Register("commit", client.commit)
+    rpc.Register("rollback", client.rollback)
+
+    rpc.ServeConn(conn)
+}
+
+// expireIdle rolls back the transactions of the other sessions which have
+// been idle too long, one of them holds the write lock waiting wants.
+func (s *Server) expireIdle(waiting *session) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for other := range s.sessions {
+        if other != waiting {
+            other.expire()
+        }
+    }
+}
+
+// session holds the connection and the transaction of a client, statements
+// run inside the transaction while it is open.
+type session struct {
+    server *Server
+    mu sync.Mutex
+    conn *sql.Conn
+    tx *sql.Tx
+    idleTimeout time.Duration
+    lastUsed time.Time
+    // expired is set when the transaction was rolled back for being idle,
+    // the statements of the client fail until it ends the transaction.
+    expired bool
+}
+
+type statementTarget interface {
+    QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+    ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+var errIdleTransaction = errors.New("transaction rolled back")
+
+func (s *session) idleError() error {
+    return fmt.Errorf("%w, it was idle for more than %s while another client waited to write, its changes are lost", errIdleTransaction, s.idleTimeout)
+}
+
+// use runs fn on the transaction, or on the connection retrying it while the
+// database is busy.
+func (s *session) use(fn func (target statementTarget) error) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.expired {
+        return s.idleError()
+    }
+
+    if s.tx == nil {
+        return s.retryBusy(func () error { return fn(s.conn) })
+    }
+
+    err := fn(s.tx)
+    s.lastUsed = time.Now()
+
+    return err
+}
+
+// retryBusy retries op while the database is busy, expiring the idle
+// transactions which may hold the write lock in between.
+func (s *session) retryBusy(op func () error) error {
+    return database.RetryBusy(func () error {
+        err := op()
+
+        if database.IsBusy(err) {
+            s.server.expireIdle(s)
+        }
+
+        return err
+    })
+}
+
+// expire rolls the transaction back if it has been idle too long. A session
+// running a statement isn't idle, it's skipped without waiting for it.
+func (s *session) expire() {
+    if !s.mu.TryLock() {
+        return
+    }
+    defer s.mu.Unlock()
+
+    if s.tx == nil || time.Since(s.lastUsed) < s.idleTimeout {
+        return
+    }
+
+    s.tx.Rollback()
+    s.tx = nil
+    s.expired = true
+}
+
+// endTransaction forgets the transaction, it fails with errIdleTransaction
+// if it had expired.
+func (s *session) endTransaction() (*sql.Tx, error) {
+    if s.expired {
+        s.expired = false
+        return nil, s.idleError()
+    }
+
+    if s.tx == nil {
+        return nil, errors.New("no transaction in progress")
+    }
+
+    tx := s.tx
+    s.tx = nil
+
+    return tx, nil
+}
+
+func (s *session) close() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.tx != nil {
+        s.tx.Rollback()
+    }
+
+    s.conn.Close()
+}
+
+func (s *session) ping(params json.RawMessage) (any, error) {
+    return "pong", nil
+}
+
+func (s *session) query(params json.RawMessage) (any, error) {
+    statement := StatementParams{}
+
+    if err := jsonrpc.DecodeParams(params, &statement); err != nil {
+        return nil, err
+    }
+
+    result := QueryResult{Rows: make([][]Value, 0)}
+
+    err := s.use(func (target statementTarget) error {
+        rows, err := target.QueryContext(context.Background(), statement.Query, decodeArgs(statement.Args)...)
+
+        if err != nil {
+            return err
+        }
+        defer rows.Close()
+
+        if result.Columns, err = rows.Columns(); err != nil {
+            return err
+        }
+
+        for rows.Next() {
+            values := make([]any, len(result.Columns))
+            pointers := make([]any, len(result.Columns))
+            for idx := range values {
+                pointers[idx] = &values[idx]
+            }
+
+            if err := rows.Scan(pointers...); err != nil {
+                return err
+            }
+
+            row := make([]Value, len(result.Columns))
+            for idx, value := range values {
+                encoded, err := EncodeValue(value)
+                if err != nil {
+                    return err
+                }
+                row[idx] = encoded
+            }
+
+            result.Rows = append(result.Rows, row)
+        }
+
+        return rows.Err()
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (s *session) exec(params json.RawMessage) (any, error) {
+    statement := StatementParams{}
+
+    if err := jsonrpc.DecodeParams(params, &statement); err != nil {
+        return nil, err
+    }
+
+    result := ExecResult{}
+
+    err := s.use(func (target statementTarget) error {
+        res, err := target.ExecContext(context.Background(), statement.Query, decodeArgs(statement.Args)...)
+
+        if err != nil {
+            return err
+        }
+
+        if result.LastInsertID, err = res.LastInsertId(); err != nil {
+            return err
+        }
+
+        result.AffectedRows, err = res.RowsAffected()
+
+        return err
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (s *session) begin(params json.RawMessage) (any, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.tx != nil || s.expired {
+        return nil, errors.New("transaction already in progress")
+    }
+
+    err := s.retryBusy(func () error {
+        tx, err := s.conn.BeginTx(context.Background(), nil)
+        s.tx = tx
+        return err
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    s.lastUsed = time.Now()
+
+    return true, nil
+}
+
+func (s *session) commit(params json.RawMessage) (any, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    tx, err := s.endTransaction()
+
+    if err != nil {
+        return nil, err
+    }
+
+    err = tx.Commit()
+
+    return err == nil, err
+}
+
+// rollback succeeds for an expired transaction, it's rolled back already.
+func (s *session) rollback(params json.RawMessage) (any, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    tx, err := s.endTransaction()
+
+    if errors.Is(err, errIdleTransaction) {
+        return true, nil
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    err = tx.Rollback()
+
+    return err == nil, err
+}
+
+func decodeArgs(values []Value) []any {
+    args := make([]any, len(values))
+
+    for idx, value := range values {
+        args[idx] = value.Decode()
+    }
+
+    return args
+}
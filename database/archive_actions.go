@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+)
+
+// ArchiveTaskBulkAction archives tasks, they can still be updated.
+const ARCHIVE_TASKS_SQL = "UPDATE tasks SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE ID IN (%s) AND deleted_at IS NULL AND archived_at IS NULL AND %s RETURNING " + TASK_COLUMNS + ";"
+const LIST_ARCHIVABLE_TASKS_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE ID IN (%s) AND deleted_at IS NULL AND archived_at IS NULL AND %s ORDER BY id;"
+func ArchiveTaskBulkAction(db DB, actor User, IDs []int) ([]Task, error) {
+    condition := fmt.Sprintf(CAN_WRITE_TASK_SQL, "$1")
+    archived := make([]Task, 0)
+
+    err := InTransaction(db, func (tx DB) error {
+        before, err := queryTasks(tx, fmt.Sprintf(LIST_ARCHIVABLE_TASKS_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil || len(before) == 0 {
+            return err
+        }
+
+        if archived, err = queryTasks(tx, fmt.Sprintf(ARCHIVE_TASKS_SQL, idList(IDs), condition), actor.ID); err != nil {
+            return err
+        }
+
+        return recordOperation(tx, actor, OperationArchive, before, archived)
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return archived, nil
+}
+
+// UnarchiveTaskBulkAction brings archived tasks back to the lists.
+const LIST_ARCHIVED_TASKS_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE ID IN (%s) AND deleted_at IS NULL AND archived_at IS NOT NULL AND %s ORDER BY id;"
+const UNARCHIVE_TASKS_SQL = "UPDATE tasks SET archived_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE ID IN (%s) AND deleted_at IS NULL AND archived_at IS NOT NULL AND %s RETURNING " + TASK_COLUMNS + ";"
+func UnarchiveTaskBulkAction(db DB, actor User, IDs []int) ([]Task, error) {
+    condition := fmt.Sprintf(CAN_WRITE_TASK_SQL, "$1")
+    unarchived := make([]Task, 0)
+
+    err := InTransaction(db, func (tx DB) error {
+        archived, err := queryTasks(tx, fmt.Sprintf(LIST_ARCHIVED_TASKS_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil || len(archived) == 0 {
+            return err
+        }
+
+        if unarchived, err = queryTasks(tx, fmt.Sprintf(UNARCHIVE_TASKS_SQL, idList(IDs), condition), actor.ID); err != nil {
+            return err
+        }
+
+        return recordOperation(tx, actor, OperationUnarchive, archived, unarchived)
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return unarchived, nil
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// BackupDatabase copies the database at rootFolder to path with the online
+// backup API of SQLite, so the copy is consistent even while other processes
+// write to the database. An existing file at path is overwritten.
+func BackupDatabase(rootFolder string, path string) error {
+    src, err := OpenDatabase(rootFolder)
+
+    if err != nil {
+        return err
+    }
+    defer src.Close()
+
+    if err := src.Ping(); err != nil {
+        return err
+    }
+
+    dst, err := sql.Open(DriverName, path)
+
+    if err != nil {
+        return err
+    }
+    defer dst.Close()
+
+    return copyDatabase(dst, src)
+}
+
+// ErrSchemaMismatch is returned when restoring a backup made at another
+// version of the migrations.
+var ErrSchemaMismatch = errors.New("Schema version mismatch")
+
+// RestoreDatabase replaces the database at rootFolder with the backup at path,
+// once the backup passed the integrity check and is at the schema version of
+// the database.
+func RestoreDatabase(rootFolder string, path string) error {
+    if _, err := os.Stat(path); err != nil {
+        return err
+    }
+
+    backup, err := sql.Open(DriverName, "file:"+path+"?mode=ro")
+
+    if err != nil {
+        return err
+    }
+    defer backup.Close()
+
+    // The integrity check of the FTS4 index writes, so it runs on a copy.
+    src, err := sql.Open(DriverName, ":memory:")
+
+    if err != nil {
+        return err
+    }
+    defer src.Close()
+
+    // Every connection has its own in-memory database.
+    src.SetMaxOpenConns(1)
+
+    if err := copyDatabase(src, backup); err != nil {
+        return fmt.Errorf("%s is damaged, %w", path, err)
+    }
+
+    if err := CheckIntegrity(src); err != nil {
+        return fmt.Errorf("%s is damaged, %w", path, err)
+    }
+
+    backupVersion, err := SchemaVersion(src)
+
+    if err != nil {
+        return fmt.Errorf("%s isn't a task database, %w", path, err)
+    }
+
+    dst, err := OpenDatabase(rootFolder)
+
+    if err != nil {
+        return err
+    }
+    defer dst.Close()
+
+    if version, err := SchemaVersion(dst); err == nil && version != backupVersion {
+        return fmt.Errorf("%w, the backup is at %d and the database at %d", ErrSchemaMismatch, backupVersion, version)
+    }
+
+    return copyDatabase(dst, src)
+}
+
+// CheckIntegrity runs PRAGMA integrity_check and returns the problems it found.
+func CheckIntegrity(db DB) error {
+    lines, err := integrityErrors(db)
+
+    if err != nil {
+        return err
+    }
+
+    if len(lines) > 0 {
+        return errors.New("integrity check failed:\n" + strings.Join(lines, "\n"))
+    }
+
+    return nil
+}
+
+// SchemaVersion is the last migration applied to the database.
+const SCHEMA_VERSION_SQL = "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied;"
+func SchemaVersion(db DB) (int64, error) {
+    var version int64
+    err := queryRow(db, SCHEMA_VERSION_SQL).Scan(&version)
+
+    return version, err
+}
+
+// copyDatabase copies every page of src over dst in one step, holding a read
+// lock on src so the copy is a consistent snapshot.
+func copyDatabase(dst *sql.DB, src *sql.DB) error {
+    ctx := context.Background()
+
+    dstConn, err := dst.Conn(ctx)
+
+    if err != nil {
+        return err
+    }
+    defer dstConn.Close()
+
+    srcConn, err := src.Conn(ctx)
+
+    if err != nil {
+        return err
+    }
+    defer srcConn.Close()
+
+    return dstConn.Raw(func (dstDriverConn any) error {
+        return srcConn.Raw(func (srcDriverConn any) error {
+            dstSQLite, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+            srcSQLite, ok2 := srcDriverConn.(*sqlite3.SQLiteConn)
+
+            if !ok || !ok2 {
+                return errors.New("backups need SQLite connections")
+            }
+
+            backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+
+            if err != nil {
+                return err
+            }
+
+            if _, err := backup.Step(-1); err != nil {
+                backup.Close()
+                return err
+            }
+
+            return backup.Finish()
+        })
+    })
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AddTaskBulkAction adds every task or none of them, they are journaled as one
+// operation so that a single undo removes them all.
+func AddTaskBulkAction(db DB, actor User, props []AddTaskProp) ([]Task, error) {
+    tasks := make([]Task, 0, len(props))
+
+    err := InTransaction(db, func (tx DB) error {
+        for idx, prop := range props {
+            task, err := addTask(tx, actor, prop)
+
+            if err != nil {
+                return fmt.Errorf("couldn't add task %d '%s', %w", idx + 1, prop.Name, err)
+            }
+
+            tasks = append(tasks, task)
+        }
+
+        if len(tasks) == 0 {
+            return nil
+        }
+
+        return recordOperation(tx, actor, OperationAdd, nil, tasks)
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return tasks, nil
+}
+
+// UpdateTaskIDsBulkAction applies payload to the tasks with the given IDs, all
+// of them or none, a task the actor can read but not write fails the whole
+// update.
+func UpdateTaskIDsBulkAction(db DB, actor User, IDs []int, payload UpdateTaskProp) ([]Task, error) {
+    var updated []Task
+
+    err := InTransaction(db, func (tx DB) error {
+        var err error
+        updated, err = updateTaskBulk(tx, actor, IDs, payload)
+        return err
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return updated, nil
+}
+
+// updateTaskBulk updates the tasks one by one and journals them as one
+// operation, run it in a transaction.
+func updateTaskBulk(db DB, actor User, IDs []int, payload UpdateTaskProp) ([]Task, error) {
+    before := make([]Task, 0, len(IDs))
+    after := make([]Task, 0, len(IDs))
+
+    for _, id := range IDs {
+        existingTask, task, err := updateTask(db, actor, id, payload)
+
+        if errors.Is(err, ErrPermissionDenied) {
+            return nil, fmt.Errorf("%w on task %d", err, id)
+        }
+
+        if err != nil {
+            return nil, fmt.Errorf("couldn't update task %d, %w", id, err)
+        }
+
+        before = append(before, existingTask)
+        after = append(after, task)
+    }
+
+    if len(after) == 0 {
+        return after, nil
+    }
+
+    if err := recordOperation(db, actor, OperationUpdate, before, after); err != nil {
+        return nil, err
+    }
+
+    return after, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ConnDB runs every statement on the same connection of a pool, retrying
+// like RetryDB.
+type ConnDB struct {
+    *sql.Conn
+}
+
+// PinConnection takes a connection of the pool of db for good, the caller
+// closes it to give it back. Other DBs, e.g. transactions, already run on a
+// single connection and are returned as they are.
+func PinConnection(db DB) (DB, func () error, error) {
+    var pool *sql.DB
+
+    switch db := db.(type) {
+    case *RetryDB:
+        pool = db.DB
+    case *sql.DB:
+        pool = db
+    default:
+        return db, func () error { return nil }, nil
+    }
+
+    conn, err := pool.Conn(context.Background())
+
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return &ConnDB{Conn: conn}, conn.Close, nil
+}
+
+func (db *ConnDB) Exec(query string, args ...any) (sql.Result, error) {
+    var result sql.Result
+
+    err := RetryBusy(func () error {
+        var err error
+        result, err = db.ExecContext(context.Background(), query, args...)
+        return err
+    })
+
+    return result, err
+}
+
+func (db *ConnDB) Query(query string, args ...any) (*sql.Rows, error) {
+    var rows *sql.Rows
+
+    err := RetryBusy(func () error {
+        var err error
+        rows, err = db.QueryContext(context.Background(), query, args...)
+        return err
+    })
+
+    return rows, err
+}
+
+func (db *ConnDB) QueryRow(query string, args ...any) *sql.Row {
+    var row *sql.Row
+
+    RetryBusy(func () error {
+        row = db.QueryRowContext(context.Background(), query, args...)
+        return row.Err()
+    })
+
+    return row
+}
+
+func (db *ConnDB) Begin() (*sql.Tx, error) {
+    var tx *sql.Tx
+
+    err := RetryBusy(func () error {
+        var err error
+        tx, err = db.BeginTx(context.Background(), nil)
+        return err
+    })
+
+    return tx, err
+}
@@ -3,16 +3,72 @@ package database
 import (
 	"database/sql"
 	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+// DriverName is sqlite3 with a REGEXP operator, see regexpMatch.
+const DriverName = "sqlite3_go_todo"
+
+func init() {
+    sql.Register(DriverName, &sqlite3.SQLiteDriver{
+        ConnectHook: func (conn *sqlite3.SQLiteConn) error {
+            return conn.RegisterFunc("regexp", regexpMatch, true)
+        },
+    })
+}
+
 type DB interface{
     QueryRow(query string, args ...any) *sql.Row
     Query(query string, args ...any) (*sql.Rows, error)
     Exec(query string, args ...any) (sql.Result, error)
 }
 
+// ConnectionParams configure every connection to task.db. WAL journaling lets
+// readers go on while someone writes, writers wait up to the busy timeout for
+// each other rather than failing right away, and transactions take the write
+// lock when they begin so that two of them can't deadlock upgrading their
+// read locks. SQLite only enforces foreign keys when asked to.
+const ConnectionParams = "_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"
+
+// MaxOpenConns bounds the connections of a process, there is a single writer
+// at a time anyway.
+const MaxOpenConns = 4
+
 func OpenDatabase(rootFolder string) (*sql.DB, error) {
-    return sql.Open("sqlite3", filepath.Join(rootFolder, "task.db"))
+    db, err := sql.Open(DriverName, "file:"+filepath.Join(rootFolder, "task.db")+"?"+ConnectionParams)
+
+    if err != nil {
+        return nil, err
+    }
+
+    db.SetMaxOpenConns(MaxOpenConns)
+    db.SetMaxIdleConns(MaxOpenConns)
+    db.SetConnMaxIdleTime(time.Minute)
+
+    return db, nil
+}
+
+// patterns caches the compiled patterns since the function runs once per row.
+var patterns sync.Map
+
+// regexpMatch implements value REGEXP pattern with the syntax of the regexp
+// package.
+func regexpMatch(pattern string, value string) (bool, error) {
+    compiled, ok := patterns.Load(pattern)
+
+    if !ok {
+        re, err := regexp.Compile(pattern)
+
+        if err != nil {
+            return false, err
+        }
+
+        compiled, _ = patterns.LoadOrStore(pattern, re)
+    }
+
+    return compiled.(*regexp.Regexp).MatchString(value), nil
 }
@@ -2,7 +2,15 @@ package database
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestOpenDatabase(t *testing.T) {
@@ -17,6 +25,196 @@ func TestOpenDatabase(t *testing.T) {
     if pingErr != nil {
         t.Error("error with database connection, couldn't ping database")
     }
+
+    var journalMode string
+    var foreignKeys bool
+    db.QueryRow("PRAGMA journal_mode;").Scan(&journalMode)
+    db.QueryRow("PRAGMA foreign_keys;").Scan(&foreignKeys)
+
+    if journalMode != "wal" || !foreignKeys {
+        t.Errorf("expected WAL journaling and foreign keys, got %s and %t\n", journalMode, foreignKeys)
+    }
+}
+
+func TestConcurrentWrites(t *testing.T) {
+    root := t.TempDir()
+
+    if err := BackupDatabase("../", filepath.Join(root, "task.db")); err != nil {
+        t.Fatalf("error while copying the database, %s\n", err)
+    }
+
+    // Two handles stand for two processes writing at once.
+    handles := make([]*RetryDB, 2)
+
+    for idx := range handles {
+        db, err := OpenDatabase(root)
+
+        if err != nil {
+            t.Fatalf("error while opening the database, %s\n", err)
+        }
+        defer db.Close()
+
+        handles[idx] = WithRetry(db)
+    }
+
+    actor := mockUser(t, handles[0])
+    workers, iterations := 8, 25
+    errs := make(chan error, 2 * workers * iterations)
+    var wg sync.WaitGroup
+
+    for _, db := range handles {
+        for worker := 0; worker < workers; worker++ {
+            wg.Add(1)
+
+            // Half the workers write in transactions, which read before
+            // writing like the commands do.
+            go func (db *RetryDB, transactional bool) {
+                defer wg.Done()
+                completed := true
+
+                for i := 0; i < iterations; i++ {
+                    var conn DB = db
+                    var tx *sql.Tx
+
+                    if transactional {
+                        var err error
+                        if tx, err = db.Begin(); err != nil {
+                            errs <- err
+                            continue
+                        }
+                        conn = tx
+                        ListTasksAction(conn, actor, ListTaskProps{})
+                    }
+
+                    task, err := AddTaskAction(conn, actor, AddTaskProp{Name: fmt.Sprintf("Task %d", i)})
+
+                    if err == nil {
+                        _, err = UpdateTaskAction(conn, actor, task.ID, UpdateTaskProp{Completed: &completed})
+                    }
+
+                    if tx != nil {
+                        if err != nil {
+                            tx.Rollback()
+                        } else {
+                            err = tx.Commit()
+                        }
+                    }
+
+                    if err != nil {
+                        errs <- err
+                    }
+                }
+            }(db, worker % 2 == 0)
+        }
+    }
+
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        t.Errorf("expected no error while writing concurrently, got %s\n", err)
+    }
+
+    var count int
+    handles[0].QueryRow("SELECT COUNT(*) FROM tasks WHERE completed;").Scan(&count)
+
+    if count != 2 * workers * iterations {
+        t.Errorf("expected %d completed tasks, got %d\n", 2 * workers * iterations, count)
+    }
+}
+
+func TestPinConnection(t *testing.T) {
+    db, err := OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    pinned, release, err := PinConnection(WithRetry(db))
+
+    if err != nil {
+        t.Fatalf("error while pinning a connection, %s\n", err)
+    }
+    defer release()
+
+    t.Run("Should run every statement on the same connection", func (t *testing.T) {
+        // Temporary tables only exist on the connection which created them.
+        if _, err := pinned.Exec("CREATE TEMP TABLE pinned (value TEXT);"); err != nil {
+            t.Fatalf("error while creating the table, %s\n", err)
+        }
+
+        for idx := 0; idx < 2 * MaxOpenConns; idx++ {
+            if _, err := pinned.Exec("INSERT INTO pinned (value) VALUES ($1);", "row"); err != nil {
+                t.Fatalf("error while inserting, %s\n", err)
+            }
+        }
+
+        var count int
+
+        if err := queryRow(pinned, "SELECT count(*) FROM pinned;").Scan(&count); err != nil || count != 2 * MaxOpenConns {
+            t.Errorf("expected %d rows, got %d (%v)\n", 2 * MaxOpenConns, count, err)
+        }
+    })
+
+    t.Run("Should return the transactions as they are", func (t *testing.T) {
+        tx, _ := db.Begin()
+        defer tx.Rollback()
+
+        if same, _, _ := PinConnection(tx); same != tx {
+            t.Errorf("expected the transaction back, got %T\n", same)
+        }
+    })
+}
+
+func TestRetryDB(t *testing.T) {
+    root := t.TempDir()
+    path := filepath.Join(root, "task.db")
+
+    if err := BackupDatabase("../", path); err != nil {
+        t.Fatalf("error while copying the database, %s\n", err)
+    }
+
+    holder, err := OpenDatabase(root)
+
+    if err != nil {
+        t.Fatalf("error while opening the database, %s\n", err)
+    }
+    defer holder.Close()
+
+    // Without a busy timeout SQLite refuses right away, only the retries
+    // wait for the write lock.
+    impatient, err := sql.Open(DriverName, "file:"+path+"?_journal_mode=WAL&_busy_timeout=0")
+
+    if err != nil {
+        t.Fatalf("error while opening the database, %s\n", err)
+    }
+    defer impatient.Close()
+
+    actor := mockUser(t, holder)
+
+    t.Run("Should retry a write whose row is refused while scanned", func (t *testing.T) {
+        tx, err := holder.Begin()
+
+        if err != nil {
+            t.Fatalf("error while taking the write lock, %s\n", err)
+        }
+
+        if _, err := SaveViewAction(impatient, actor, View{Name: "refused"}); !IsBusy(err) {
+            t.Fatalf("expected the database to be busy, got %v\n", err)
+        }
+
+        go func () {
+            time.Sleep(150 * time.Millisecond)
+            tx.Commit()
+        }()
+
+        view, err := SaveViewAction(WithRetry(impatient), actor, View{Name: "retried"})
+
+        if err != nil || view.Name != "retried" {
+            t.Errorf("expected the view saved once the lock was released, got %+v, %v\n", view, err)
+        }
+    })
 }
 
 func TestAddTaskAction(t *testing.T) {
@@ -24,7 +222,7 @@ func TestAddTaskAction(t *testing.T) {
 
     defer tx.Rollback()
 
-    task, err := AddTaskAction(tx, AddTaskProp{"Practice Go", true})
+    task, err := AddTaskAction(tx, mockUser(t, tx), AddTaskProp{Name: "Practice Go", Completed: true})
 
     if err != nil {
         t.Fatalf("error while adding the task to the database, %s\n", err)
@@ -43,6 +241,48 @@ func TestAddTaskAction(t *testing.T) {
     }
 }
 
+func TestTaskActionsJournal(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    task, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Journaled"})
+
+    if _, err := tx.Exec("CREATE TEMP TRIGGER refuse_operations BEFORE INSERT ON operations BEGIN SELECT RAISE(ABORT, 'journal unavailable'); END;"); err != nil {
+        t.Fatalf("error while breaking the journal, %s\n", err)
+    }
+
+    t.Run("Should not add a task it couldn't journal", func (t *testing.T) {
+        if _, err := AddTaskAction(tx, actor, AddTaskProp{Name: "Unjournaled"}); err == nil {
+            t.Fatal("expected the journal error")
+        }
+
+        tasks, _ := ListTasksAction(tx, actor, ListTaskProps{})
+
+        for _, listed := range tasks {
+            if listed.Name == "Unjournaled" {
+                t.Errorf("expected the task to be rolled back, got %+v\n", listed)
+            }
+        }
+    })
+
+    t.Run("Should not update or delete a task it couldn't journal", func (t *testing.T) {
+        name := "Renamed"
+
+        if _, err := UpdateTaskAction(tx, actor, task.ID, UpdateTaskProp{Name: &name}); err == nil {
+            t.Error("expected the journal error when updating")
+        }
+
+        if _, err := DeleteTaskBulkAction(tx, actor, []int{task.ID}); err == nil {
+            t.Error("expected the journal error when deleting")
+        }
+
+        if listed, err := ListTaskActionByID(tx, actor, uint(task.ID)); err != nil || listed.Name != "Journaled" {
+            t.Errorf("expected the task unchanged, got %+v, %v\n", listed, err)
+        }
+    })
+}
+
 func TestUpdateTaskAction(t *testing.T) {
     tx := getDBTransaction(t)
     
@@ -53,7 +293,7 @@ func TestUpdateTaskAction(t *testing.T) {
         name := "Test"
         payload.Name = &name
 
-        _, err := UpdateTaskAction(tx, 69, payload)
+        _, err := UpdateTaskAction(tx, mockUser(t, tx), 69, payload)
 
         if err == nil {
             t.Fatalf("should have failed with unexistent task")
@@ -71,7 +311,7 @@ func TestUpdateTaskAction(t *testing.T) {
         name := "Updated task"
         payload.Name = &name
 
-        updatedTask, err := UpdateTaskAction(tx, task.ID, payload)
+        updatedTask, err := UpdateTaskAction(tx, mockUser(t, tx), task.ID, payload)
 
         if err != nil {
             t.Fatalf("error while updating task, %s\n", err)
@@ -88,7 +328,7 @@ func TestUpdateTaskAction(t *testing.T) {
         completed := true
         payload.Completed = &completed
 
-        updatedTask, err := UpdateTaskAction(tx, task.ID, payload)
+        updatedTask, err := UpdateTaskAction(tx, mockUser(t, tx), task.ID, payload)
 
         if err != nil {
             t.Fatalf("error while updating task, %s\n", err)
@@ -98,6 +338,46 @@ func TestUpdateTaskAction(t *testing.T) {
             t.Errorf("expected task status to be %t, but got %t\n", *payload.Completed, updatedTask.Completed)
         }
     })
+    t.Run("Testing updating and clearing task details", func(t *testing.T) {
+        task := mockTask(t, tx)
+        dueAt := time.Date(2030, time.January, 2, 9, 0, 0, 0, time.Local)
+        priority := PriorityHigh
+        tags := []string{"finance", "home"}
+        project := "flat"
+        recurrence := "every month"
+
+        updatedTask, err := UpdateTaskAction(tx, mockUser(t, tx), task.ID, UpdateTaskProp{
+            DueAt: &dueAt,
+            Priority: &priority,
+            Tags: &tags,
+            Project: &project,
+            Recurrence: &recurrence,
+        })
+
+        if err != nil {
+            t.Fatalf("error while updating task, %s\n", err)
+        }
+
+        if updatedTask.DueAt == nil || !updatedTask.DueAt.Equal(dueAt) || updatedTask.Priority != priority ||
+            !reflect.DeepEqual(updatedTask.Tags, tags) || updatedTask.Project != project || updatedTask.Recurrence != recurrence {
+            t.Errorf("expected the details to be updated, got %+v\n", updatedTask)
+        }
+
+        noTags, empty := []string{}, ""
+        updatedTask, err = UpdateTaskAction(tx, mockUser(t, tx), task.ID, UpdateTaskProp{
+            DueAt: &time.Time{},
+            Tags: &noTags,
+            Recurrence: &empty,
+        })
+
+        if err != nil {
+            t.Fatalf("error while updating task, %s\n", err)
+        }
+
+        if updatedTask.DueAt != nil || updatedTask.Tags != nil || updatedTask.Recurrence != "" || updatedTask.Project != project {
+            t.Errorf("expected the due date, tags and recurrence to be cleared, got %+v\n", updatedTask)
+        }
+    })
 }
 
 func TestDeleteTaskBulkAction(t *testing.T) {
@@ -107,7 +387,7 @@ func TestDeleteTaskBulkAction(t *testing.T) {
     task := mockTask(t, tx)
     task2 := mockTask(t, tx)
 
-    delCount, err := DeleteTaskBulkAction(tx, []int{task.ID, task2.ID})
+    delCount, err := DeleteTaskBulkAction(tx, mockUser(t, tx), []int{task.ID, task2.ID})
 
     if err != nil {
         t.Fatalf("error while deleting task from the database, %s\n", err)
@@ -118,112 +398,1216 @@ func TestDeleteTaskBulkAction(t *testing.T) {
     }
 }
 
-func TestListTaskAction(t *testing.T) {
+func TestRestoreTaskAction(t *testing.T) {
     tx := getDBTransaction(t)
     defer tx.Rollback()
 
-    _, err := AddTaskAction(tx, AddTaskProp{
-        "Test",
-        true,
-    })
-
-    mockTask(t, tx)
+    actor := mockUser(t, tx)
+    task := mockTask(t, tx)
     mockTask(t, tx)
 
+    if _, err := DeleteTaskBulkAction(tx, actor, []int{task.ID}); err != nil {
+        t.Fatalf("error while deleting task from the database, %s\n", err)
+    }
+
+    restored, err := RestoreTaskAction(tx, actor, task)
+
     if err != nil {
-        t.Fatalf("error while mocking tasks for test")
+        t.Fatalf("error while restoring task, %s\n", err)
     }
 
-    t.Run("Should apply the provided filters to the query", func(t *testing.T) {
-        completed := false
+    if restored.ID != task.ID || restored.Name != task.Name {
+        t.Errorf("expected the task to be restored as it was, got %+v\n", restored)
+    }
 
-        sort := [2]string{"id", "desc"}
-        tasks, err := ListTasksAction(tx, ListTaskProps{
-            WhereCompleted: &completed,
-            SortBy: &sort,
-        })
+    if _, err := ListTaskActionByID(tx, actor, uint(task.ID)); err != nil {
+        t.Errorf("expected the restored task to be listed, %s\n", err)
+    }
+}
+
+func TestJournalActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    names := func () []string {
+        tasks, _ := ListTasksAction(tx, actor, ListTaskProps{})
+        names := make([]string, 0)
+        for _, task := range tasks {
+            names = append(names, task.Name)
+        }
+        return names
+    }
+
+    first, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Pay rent", Tags: []string{"finance"}})
+    second, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Walk the dog"})
+    renamed := "Pay the rent"
+    UpdateTaskAction(tx, actor, first.ID, UpdateTaskProp{Name: &renamed})
+    DeleteTaskBulkAction(tx, actor, []int{first.ID, second.ID})
+
+    t.Run("Should journal every change, the latest first", func (t *testing.T) {
+        operations, err := ListOperationsAction(tx, actor, 0)
 
         if err != nil {
-            t.Fatalf("error while listing tasks from the database, %s\n", err)
+            t.Fatalf("error while listing the operations, %s\n", err)
         }
 
-        if len(tasks) != 2 {
-            t.Errorf("expected 2 itens in the list but got %d\n", len(tasks))
+        actions := make([]string, 0)
+        for _, operation := range operations {
+            actions = append(actions, operation.Action)
         }
 
-        if tasks[0].ID != 3 {
-            t.Errorf("the list should be reversed but the id of the first id is %d, instead of 3.\n", tasks[0].ID)
+        if strings.Join(actions, ",") != "delete,update,add,add" || len(operations[0].Before) != 2 {
+            t.Errorf("expected the bulk delete, the update and the additions, got %+v\n", operations)
         }
     })
 
-    t.Run("Should return a list of all tasks", func(t *testing.T) {
-        tasks, err := ListTasksAction(tx, ListTaskProps{})
+    t.Run("Should undo a bulk delete then an update", func (t *testing.T) {
+        if _, err := UndoAction(tx, actor, 2); err != nil {
+            t.Fatalf("error while undoing, %s\n", err)
+        }
 
-        if err != nil {
-            t.Fatalf("error while listing tasks, %s\n", err)
+        if got := strings.Join(names(), ","); got != "Pay rent,Walk the dog" {
+            t.Errorf("expected both tasks as they were, got %s\n", got)
         }
 
-        if len(tasks) != 3 {
-            t.Errorf("expected a list with all the tasks in the database, but got %d\n", len(tasks))
+        restored, _ := ListTaskActionByID(tx, actor, uint(first.ID))
+
+        if !reflect.DeepEqual(restored.Tags, []string{"finance"}) {
+            t.Errorf("expected the tags to be restored, got %+v\n", restored)
         }
     })
-}
 
-func TestListTaskActionByID (t *testing.T) {
-    db := getDBTransaction(t)
-    defer db.Rollback()
+    t.Run("Should redo in order", func (t *testing.T) {
+        operations, err := RedoAction(tx, actor, 1)
 
-    task := mockTask(t, db)
+        if err != nil || len(operations) != 1 || operations[0].Action != OperationUpdate {
+            t.Fatalf("expected to redo the update, got %+v, %v\n", operations, err)
+        }
 
-    t.Run("Should return nil if didn't find the task with the provided ID", func (t *testing.T) {
-        _, err := ListTaskActionByID(db, 69)
+        if got := strings.Join(names(), ","); got != "Pay the rent,Walk the dog" {
+            t.Errorf("expected the rename to be redone, got %s\n", got)
+        }
+    })
 
-        if err == nil {
-            t.Errorf("expected to receive an ErrNoRow got: %v\n", err)
+    t.Run("Should forget the undone changes after a new one", func (t *testing.T) {
+        completed := true
+        UpdateTaskAction(tx, actor, second.ID, UpdateTaskProp{Completed: &completed})
+
+        if _, err := RedoAction(tx, actor, 1); err != ErrNothingToRedo {
+            t.Errorf("expected nothing to redo, got %v\n", err)
         }
     })
 
-    t.Run("Should return the task with the provede ID", func (t *testing.T) {
-        mockedTask, err := ListTaskActionByID(db, uint(task.ID))
+    t.Run("Should refuse to undo over a change made by someone else", func (t *testing.T) {
+        task, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Shared"})
 
-        if err != nil {
-            t.Fatalf("received an error while listing task %d\n", err)
+        if _, err := tx.Exec("UPDATE tasks SET name = 'Renamed elsewhere' WHERE id = $1", task.ID); err != nil {
+            t.Fatalf("error while renaming the task, %s\n", err)
         }
 
-        if mockedTask.ID != task.ID {
-            t.Errorf("expected returned task to have the same ID of the mocked one, got %d\n", mockedTask.ID)
+        if _, err := UndoAction(tx, actor, 1); err == nil || !strings.Contains(err.Error(), "changed since") {
+            t.Errorf("expected a conflict, got %v\n", err)
         }
     })
 }
 
-func getDBTransaction(t testing.TB) (*sql.Tx) {
-    t.Helper()
-    db, err := OpenDatabase("../")
+func TestTrashActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
 
-    if err != nil {
-        t.Fatalf("error while connecting to the database, %s\n", err)
+    actor := mockUser(t, tx)
+    first := mockTask(t, tx)
+    second := mockTask(t, tx)
+
+    if deleted, err := DeleteTaskBulkAction(tx, actor, []int{first.ID, second.ID}); err != nil || deleted != 2 {
+        t.Fatalf("expected 2 tasks to be deleted, got %d, %v\n", deleted, err)
     }
-    defer db.Close()
 
-    tx, err := db.Begin()
+    t.Run("Should keep the deleted tasks in the trash only", func (t *testing.T) {
+        tasks, _ := ListTasksAction(tx, actor, ListTaskProps{})
+        trashed, _ := ListTasksAction(tx, actor, ListTaskProps{Trashed: true})
 
-    if err != nil {
-        t.Fatalf("error while acquiring transaction, %s\n", err)
-    }
+        if len(tasks) != 0 || len(trashed) != 2 || trashed[0].DeletedAt == nil {
+            t.Errorf("expected both tasks in the trash, got %+v and %+v\n", tasks, trashed)
+        }
 
-    return tx
+        if _, err := ListTaskActionByID(tx, actor, uint(first.ID)); err == nil {
+            t.Error("expected a deleted task not to be found by its ID")
+        }
+
+        if deleted, _ := DeleteTaskBulkAction(tx, actor, []int{first.ID}); deleted != 0 {
+            t.Errorf("expected a deleted task not to be deleted again, got %d\n", deleted)
+        }
+    })
+
+    t.Run("Should restore tasks from the trash", func (t *testing.T) {
+        if restored, err := RestoreTaskBulkAction(tx, actor, []int{first.ID, 69}); err != nil || restored != 1 {
+            t.Fatalf("expected 1 task to be restored, got %d, %v\n", restored, err)
+        }
+
+        if task, err := ListTaskActionByID(tx, actor, uint(first.ID)); err != nil || task.DeletedAt != nil {
+            t.Errorf("expected the task to be back, got %+v, %v\n", task, err)
+        }
+    })
+
+    t.Run("Should only purge the tasks deleted before the given time", func (t *testing.T) {
+        if purged, err := PurgeTrashAction(tx, actor, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+            t.Errorf("expected nothing to be purged, got %d, %v\n", purged, err)
+        }
+
+        if purged, err := PurgeTrashAction(tx, actor, time.Time{}); err != nil || purged != 1 {
+            t.Errorf("expected the trash to be emptied, got %d, %v\n", purged, err)
+        }
+
+        var count int
+        tx.QueryRow("SELECT count(*) FROM tasks WHERE id = $1", second.ID).Scan(&count)
+
+        if count != 0 {
+            t.Error("expected the purged task to be gone for good")
+        }
+    })
+
+    t.Run("Should undo a purge back into the trash", func (t *testing.T) {
+        if _, err := UndoAction(tx, actor, 1); err != nil {
+            t.Fatalf("error while undoing, %s\n", err)
+        }
+
+        if trashed, _ := ListTasksAction(tx, actor, ListTaskProps{Trashed: true}); len(trashed) != 1 || trashed[0].ID != second.ID {
+            t.Errorf("expected the purged task back in the trash, got %+v\n", trashed)
+        }
+    })
 }
 
-func mockTask(t testing.TB, db DB) Task {
-    t.Helper()
-    task, err := AddTaskAction(db, AddTaskProp{
-        "Test",
-        false,
+func TestArchiveActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    done, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Paint the fence", Completed: true})
+    pending := mockTask(t, tx)
+    completed := true
+
+    t.Run("Should record when tasks are completed", func (t *testing.T) {
+        if done.CompletedAt == nil || pending.CompletedAt != nil {
+            t.Fatalf("expected only the completed task to have a completion time, got %+v and %+v\n", done, pending)
+        }
+
+        earlier, later := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+        if tasks, _ := ListTasksAction(tx, actor, ListTaskProps{CompletedBefore: &earlier}); len(tasks) != 0 {
+            t.Errorf("expected no task completed an hour ago, got %+v\n", tasks)
+        }
+
+        if tasks, _ := ListTasksAction(tx, actor, ListTaskProps{CompletedBefore: &later}); len(tasks) != 1 || tasks[0].ID != done.ID {
+            t.Errorf("expected the completed task, got %+v\n", tasks)
+        }
+
+        updated, _ := UpdateTaskAction(tx, actor, done.ID, UpdateTaskProp{Completed: &completed})
+
+        if updated.CompletedAt == nil || !updated.CompletedAt.Equal(*done.CompletedAt) {
+            t.Errorf("expected the completion time to be kept, got %+v\n", updated)
+        }
     })
 
-    if err != nil {
-        t.Fatalf("error while mocking task, %s\n", err)
+    t.Run("Should leave the archived tasks out of the lists only", func (t *testing.T) {
+        if archived, err := ArchiveTaskBulkAction(tx, actor, []int{done.ID, 69}); err != nil || len(archived) != 1 || archived[0].ArchivedAt == nil {
+            t.Fatalf("expected the task to be archived, got %+v, %v\n", archived, err)
+        }
+
+        if tasks, _ := ListTasksAction(tx, actor, ListTaskProps{}); len(tasks) != 1 || tasks[0].ID != pending.ID {
+            t.Errorf("expected only the pending task, got %+v\n", tasks)
+        }
+
+        if tasks, _ := ListTasksAction(tx, actor, ListTaskProps{Archived: true}); len(tasks) != 1 || tasks[0].ID != done.ID {
+            t.Errorf("expected only the archived task, got %+v\n", tasks)
+        }
+
+        if results, _ := SearchTasksAction(tx, actor, SearchProps{Query: "fence"}); len(results) != 1 || results[0].ID != done.ID {
+            t.Errorf("expected the archived task to be found, got %+v\n", results)
+        }
+    })
+
+    t.Run("Should unarchive tasks and undo it", func (t *testing.T) {
+        if unarchived, err := UnarchiveTaskBulkAction(tx, actor, []int{done.ID, pending.ID}); err != nil || len(unarchived) != 1 {
+            t.Fatalf("expected the task to be unarchived, got %+v, %v\n", unarchived, err)
+        }
+
+        if _, err := UndoAction(tx, actor, 1); err != nil {
+            t.Fatalf("error while undoing, %s\n", err)
+        }
+
+        if tasks, _ := ListTasksAction(tx, actor, ListTaskProps{Archived: true}); len(tasks) != 1 {
+            t.Errorf("expected the task to be archived again, got %+v\n", tasks)
+        }
+    })
+}
+
+func TestTaskHistoryActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    task, _ := AddTaskAction(tx, actor, AddTaskProp{Name: "Water the plants", Priority: PriorityHigh})
+    fields := func (changes []TaskChange) string {
+        names := make([]string, len(changes))
+        for idx, change := range changes {
+            names[idx] = change.Field
+        }
+        return strings.Join(names, ",")
     }
 
-    return task
+    t.Run("Should stamp the creation and the updates of tasks", func (t *testing.T) {
+        if task.CreatedAt == nil || task.UpdatedAt == nil || task.CompletedAt != nil {
+            t.Fatalf("expected the task to have creation and update times only, got %+v\n", task)
+        }
+
+        name := "Water the garden"
+        updated, _ := UpdateTaskAction(tx, actor, task.ID, UpdateTaskProp{Name: &name})
+
+        if updated.UpdatedAt == nil || updated.UpdatedAt.Before(*task.UpdatedAt) || !updated.CreatedAt.Equal(*task.CreatedAt) {
+            t.Errorf("expected the update time to move on only, got %+v\n", updated)
+        }
+    })
+
+    t.Run("Should record the fields set and changed", func (t *testing.T) {
+        changes, err := ListTaskHistoryAction(tx, actor, task.ID)
+
+        if err != nil {
+            t.Fatalf("error while listing the history, %s\n", err)
+        }
+
+        if got := fields(changes); got != "name,priority,name" {
+            t.Fatalf("expected the name and priority then the rename, got %s\n", got)
+        }
+
+        if changes[0].Old != nil || *changes[1].New != "high" || *changes[2].Old != "Water the plants" || *changes[2].New != "Water the garden" {
+            t.Errorf("unexpected values in %+v\n", changes)
+        }
+
+        if changes[2].ActorID == nil || *changes[2].ActorID != actor.ID {
+            t.Errorf("expected the change to be made by the actor, got %+v\n", changes[2])
+        }
+    })
+
+    t.Run("Should record deletions, undos and keep the history of trashed tasks", func (t *testing.T) {
+        DeleteTaskBulkAction(tx, actor, []int{task.ID})
+
+        if _, err := UndoAction(tx, actor, 1); err != nil {
+            t.Fatalf("error while undoing, %s\n", err)
+        }
+
+        changes, _ := ListTaskHistoryAction(tx, actor, task.ID)
+
+        if got := fields(changes); got != "name,priority,name,deleted,deleted" {
+            t.Fatalf("expected the deletion and its undo, got %s\n", got)
+        }
+
+        if changes[3].New == nil || changes[4].New != nil {
+            t.Errorf("expected the task to be deleted then restored, got %+v and %+v\n", changes[3], changes[4])
+        }
+
+        DeleteTaskBulkAction(tx, actor, []int{task.ID})
+
+        if changes, err := ListTaskHistoryAction(tx, actor, task.ID); err != nil || len(changes) != 6 {
+            t.Errorf("expected the history of the trashed task, got %+v, %v\n", changes, err)
+        }
+    })
+
+    t.Run("Should drop the history of purged tasks", func (t *testing.T) {
+        PurgeTrashAction(tx, actor, time.Time{})
+
+        if _, err := ListTaskHistoryAction(tx, actor, task.ID); err == nil {
+            t.Error("expected an error for a purged task")
+        }
+
+        var count int
+        tx.QueryRow("SELECT COUNT(*) FROM task_changes WHERE task_id = $1;", task.ID).Scan(&count)
+
+        if count != 0 {
+            t.Errorf("expected no change left, got %d\n", count)
+        }
+    })
+}
+
+func TestBackupDatabase(t *testing.T) {
+    root := t.TempDir()
+    backup := filepath.Join(root, "backup.db")
+
+    if err := BackupDatabase("../", filepath.Join(root, "task.db")); err != nil {
+        t.Fatalf("error while copying the database, %s\n", err)
+    }
+
+    db, err := OpenDatabase(root)
+
+    if err != nil {
+        t.Fatalf("error while opening the copy, %s\n", err)
+    }
+    defer db.Close()
+
+    task := mockTask(t, db)
+
+    t.Run("Should back up a consistent copy of the database", func (t *testing.T) {
+        if err := BackupDatabase(root, backup); err != nil {
+            t.Fatalf("error while backing up, %s\n", err)
+        }
+
+        backupDB, _ := sql.Open(DriverName, backup)
+        defer backupDB.Close()
+
+        if err := CheckIntegrity(backupDB); err != nil {
+            t.Errorf("expected a healthy backup, got %s\n", err)
+        }
+
+        if _, err := ListTaskActionByID(backupDB, mockUser(t, backupDB), uint(task.ID)); err != nil {
+            t.Errorf("expected the task in the backup, got %s\n", err)
+        }
+    })
+
+    t.Run("Should restore a backup over the database", func (t *testing.T) {
+        db.Exec("DELETE FROM tasks;")
+
+        if err := RestoreDatabase(root, backup); err != nil {
+            t.Fatalf("error while restoring, %s\n", err)
+        }
+
+        if _, err := ListTaskActionByID(db, mockUser(t, db), uint(task.ID)); err != nil {
+            t.Errorf("expected the task to be back, got %s\n", err)
+        }
+    })
+
+    t.Run("Should refuse backups at another schema version", func (t *testing.T) {
+        backupDB, _ := sql.Open(DriverName, backup)
+        backupDB.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (99991231000000, 1);")
+        backupDB.Close()
+
+        if err := RestoreDatabase(root, backup); !errors.Is(err, ErrSchemaMismatch) {
+            t.Errorf("expected a schema mismatch, got %v\n", err)
+        }
+    })
+
+    t.Run("Should refuse damaged backups", func (t *testing.T) {
+        damaged := filepath.Join(root, "damaged.db")
+        os.WriteFile(damaged, []byte("not a database at all, not even close to one"), 0o644)
+
+        if err := RestoreDatabase(root, damaged); err == nil {
+            t.Error("expected an error for a damaged backup")
+        }
+
+        if _, err := ListTaskActionByID(db, mockUser(t, db), uint(task.ID)); err != nil {
+            t.Errorf("expected the database to be left alone, got %s\n", err)
+        }
+    })
+}
+
+func TestDoctorAction(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    t.Run("Should find nothing wrong with a healthy database", func (t *testing.T) {
+        if problems, err := DoctorAction(tx, false); err != nil || len(problems) != 0 {
+            t.Fatalf("expected no problem, got %+v, %v\n", problems, err)
+        }
+    })
+
+    // Foreign keys are enforced, the checks are deferred to a commit that
+    // never comes to mock the orphans.
+    tx.Exec("PRAGMA defer_foreign_keys = ON;")
+    actor := mockUser(t, tx)
+    task := mockTask(t, tx)
+    tx.Exec("UPDATE tasks SET assignee_id = 424242, owner_id = 424243 WHERE id = $1;", task.ID)
+    tx.Exec("INSERT INTO shares (owner_id, user_id, permission) VALUES ($1, 424242, 'read');", actor.ID)
+    tx.Exec("INSERT INTO task_changes (task_id, field) VALUES (424242, 'name');")
+
+    t.Run("Should report the orphaned rows and how to fix them", func (t *testing.T) {
+        problems, err := DoctorAction(tx, false)
+
+        if err != nil {
+            t.Fatalf("error while checking, %s\n", err)
+        }
+
+        want := []Problem{
+            {Check: ForeignKeyCheck, Description: "1 rows of shares reference missing users in user_id", Fix: "delete the rows"},
+            {Check: ForeignKeyCheck, Description: "1 rows of tasks reference missing users in assignee_id", Fix: "clear assignee_id"},
+            {Check: ForeignKeyCheck, Description: "1 rows of tasks reference missing users in owner_id"},
+            {Check: OrphanCheck, Description: "1 task changes belong to missing tasks", Fix: "delete the changes"},
+        }
+
+        if !reflect.DeepEqual(problems, want) {
+            t.Errorf("expected: %+v, got: %+v\n", want, problems)
+        }
+    })
+
+    t.Run("Should fix what can be fixed safely", func (t *testing.T) {
+        if _, err := DoctorAction(tx, true); err != nil {
+            t.Fatalf("error while fixing, %s\n", err)
+        }
+
+        problems, _ := DoctorAction(tx, false)
+
+        if len(problems) != 1 || problems[0].Fix != "" {
+            t.Errorf("expected only the missing owner to be left, got %+v\n", problems)
+        }
+    })
+}
+
+func TestBulkActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    missing := 424242
+    tagged := func (arg func (value any) string) string { return "tags = " + arg("work") }
+    count := func () int {
+        tasks, _ := ListTasksAction(tx, actor, ListTaskProps{})
+        return len(tasks)
+    }
+
+    t.Run("Should add every task or none of them", func (t *testing.T) {
+        if _, err := AddTaskBulkAction(tx, actor, []AddTaskProp{{Name: "Fine"}, {Name: "Broken", AssigneeID: &missing}}); err == nil {
+            t.Fatal("expected an error for the task assigned to a missing user")
+        }
+
+        if got := count(); got != 0 {
+            t.Fatalf("expected no task to be added, got %d\n", got)
+        }
+
+        tasks, err := AddTaskBulkAction(tx, actor, []AddTaskProp{{Name: "Write report", Tags: []string{"work"}}, {Name: "Call client", Tags: []string{"work"}}, {Name: "Buy milk"}})
+
+        if err != nil || len(tasks) != 3 {
+            t.Fatalf("expected the tasks to be added, got %+v, %v\n", tasks, err)
+        }
+    })
+
+    t.Run("Should update the tasks with the given IDs", func (t *testing.T) {
+        completed := true
+        work, _ := ListTasksAction(tx, actor, ListTaskProps{Where: tagged})
+        tasks, err := UpdateTaskIDsBulkAction(tx, actor, []int{work[0].ID, work[1].ID}, UpdateTaskProp{Completed: &completed})
+
+        if err != nil || len(tasks) != 2 || !tasks[0].Completed || !tasks[1].Completed {
+            t.Fatalf("expected both work tasks to be completed, got %+v, %v\n", tasks, err)
+        }
+
+        pending, _ := ListTasksAction(tx, actor, ListTaskProps{WhereCompleted: new(bool)})
+
+        if len(pending) != 1 || pending[0].Name != "Buy milk" {
+            t.Errorf("expected the other task to be left alone, got %+v\n", pending)
+        }
+    })
+
+    t.Run("Should update every task or none of them", func (t *testing.T) {
+        tasks, _ := ListTasksAction(tx, actor, ListTaskProps{})
+        project := "home"
+
+        if _, err := UpdateTaskIDsBulkAction(tx, actor, []int{tasks[0].ID, missing}, UpdateTaskProp{Project: &project}); err == nil {
+            t.Fatal("expected an error for the missing task")
+        }
+
+        if task, _ := ListTaskActionByID(tx, actor, uint(tasks[0].ID)); task.Project != "" {
+            t.Fatalf("expected the task to stay where it was, got %+v\n", task)
+        }
+
+        moved, err := UpdateTaskIDsBulkAction(tx, actor, []int{tasks[0].ID, tasks[1].ID}, UpdateTaskProp{Project: &project})
+
+        if err != nil || len(moved) != 2 || moved[0].Project != "home" || moved[1].Project != "home" {
+            t.Errorf("expected the tasks to be moved, got %+v, %v\n", moved, err)
+        }
+    })
+
+    t.Run("Should undo a bulk operation at once", func (t *testing.T) {
+        for i := 0; i < 3; i++ {
+            if _, err := UndoAction(tx, actor, 1); err != nil {
+                t.Fatalf("error while undoing, %s\n", err)
+            }
+        }
+
+        if got := count(); got != 0 {
+            t.Errorf("expected the added tasks to be gone, got %d\n", got)
+        }
+    })
+}
+
+func TestListTaskAction(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    _, err := AddTaskAction(tx, mockUser(t, tx), AddTaskProp{
+        Name: "Test",
+        Completed: true,
+    })
+
+    mockTask(t, tx)
+    mockTask(t, tx)
+
+    if err != nil {
+        t.Fatalf("error while mocking tasks for test")
+    }
+
+    t.Run("Should apply the provided filters to the query", func(t *testing.T) {
+        completed := false
+
+        tasks, err := ListTasksAction(tx, mockUser(t, tx), ListTaskProps{
+            WhereCompleted: &completed,
+            SortBy: []SortKey{{Column: "id", Desc: true}},
+        })
+
+        if err != nil {
+            t.Fatalf("error while listing tasks from the database, %s\n", err)
+        }
+
+        if len(tasks) != 2 {
+            t.Errorf("expected 2 itens in the list but got %d\n", len(tasks))
+        }
+
+        if tasks[0].ID != 3 {
+            t.Errorf("the list should be reversed but the id of the first id is %d, instead of 3.\n", tasks[0].ID)
+        }
+    })
+
+    t.Run("Should bind the values of the where condition", func(t *testing.T) {
+        tasks, err := ListTasksAction(tx, mockUser(t, tx), ListTaskProps{
+            Where: func (arg func (any) string) string {
+                return fmt.Sprintf("completed = %s OR id = %s", arg(true), arg(3))
+            },
+            SortBy: []SortKey{{Column: "completed"}, {Column: "name", Desc: true}},
+        })
+
+        if err != nil {
+            t.Fatalf("error while listing tasks, %s\n", err)
+        }
+
+        if len(tasks) != 2 || tasks[0].ID != 3 || !tasks[1].Completed {
+            t.Errorf("expected task 3 then the completed task, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should refuse to sort by unknown columns", func(t *testing.T) {
+        _, err := ListTasksAction(tx, mockUser(t, tx), ListTaskProps{
+            SortBy: []SortKey{{Column: "owner_id; DROP TABLE tasks"}},
+        })
+
+        if err == nil {
+            t.Errorf("expected an error\n")
+        }
+    })
+
+    t.Run("Should return a list of all tasks", func(t *testing.T) {
+        tasks, err := ListTasksAction(tx, mockUser(t, tx), ListTaskProps{})
+
+        if err != nil {
+            t.Fatalf("error while listing tasks, %s\n", err)
+        }
+
+        if len(tasks) != 3 {
+            t.Errorf("expected a list with all the tasks in the database, but got %d\n", len(tasks))
+        }
+    })
+}
+
+func TestListTaskActionByID (t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    task := mockTask(t, db)
+
+    t.Run("Should return nil if didn't find the task with the provided ID", func (t *testing.T) {
+        _, err := ListTaskActionByID(db, mockUser(t, db), 69)
+
+        if err == nil {
+            t.Errorf("expected to receive an ErrNoRow got: %v\n", err)
+        }
+    })
+
+    t.Run("Should return the task with the provede ID", func (t *testing.T) {
+        mockedTask, err := ListTaskActionByID(db, mockUser(t, db), uint(task.ID))
+
+        if err != nil {
+            t.Fatalf("received an error while listing task %d\n", err)
+        }
+
+        if mockedTask.ID != task.ID {
+            t.Errorf("expected returned task to have the same ID of the mocked one, got %d\n", mockedTask.ID)
+        }
+    })
+}
+
+func TestResolveTaskAction(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockUser(t, db)
+    tasks := make([]Task, 0)
+
+    for _, name := range []string{"Pay rent", "Rent a car", "Rent", "Paint the fence"} {
+        task, err := AddTaskAction(db, actor, AddTaskProp{Name: name})
+
+        if err != nil {
+            t.Fatalf("error while adding a task, %s\n", err)
+        }
+
+        tasks = append(tasks, task)
+    }
+
+    tests := []struct {
+        ref string
+        want int
+    }{
+        {fmt.Sprint(tasks[1].ID), tasks[1].ID},
+        {"RENT", tasks[2].ID},
+        {"a car", tasks[1].ID},
+        {"ptfnc", tasks[3].ID},
+    }
+
+    for _, test := range tests {
+        t.Run(fmt.Sprintf("Should resolve '%s'", test.ref), func (t *testing.T) {
+            task, err := ResolveTaskAction(db, actor, test.ref)
+
+            if err != nil || task.ID != test.want {
+                t.Errorf("expected task %d, got %d, %v\n", test.want, task.ID, err)
+            }
+        })
+    }
+
+    t.Run("Should rank the candidates of an ambiguous reference", func (t *testing.T) {
+        _, err := ResolveTaskAction(db, actor, "pa")
+        ambiguous, ok := err.(*AmbiguousTaskError)
+
+        if !ok {
+            t.Fatalf("expected an ambiguous reference, got %v\n", err)
+        }
+
+        if len(ambiguous.Candidates) != 2 || ambiguous.Candidates[0].ID != tasks[0].ID || ambiguous.Candidates[1].ID != tasks[3].ID {
+            t.Errorf("expected Pay rent then Paint the fence, got %+v\n", ambiguous.Candidates)
+        }
+    })
+
+    t.Run("Should fail when nothing matches", func (t *testing.T) {
+        for _, ref := range []string{"groceries", "69", ""} {
+            if _, err := ResolveTaskAction(db, actor, ref); err == nil {
+                t.Errorf("expected an error for '%s'\n", ref)
+            }
+        }
+    })
+
+    t.Run("Should reject the IDs below 1 rather than match names", func (t *testing.T) {
+        if _, err := AddTaskAction(db, actor, AddTaskProp{Name: "Call 0800-3"}); err != nil {
+            t.Fatalf("error while adding a task, %s\n", err)
+        }
+
+        for _, ref := range []string{"0", "-3"} {
+            if _, err := ResolveTaskAction(db, actor, ref); err == nil || !strings.Contains(err.Error(), "IDs start at 1") {
+                t.Errorf("expected '%s' rejected as an ID, got %v\n", ref, err)
+            }
+        }
+    })
+}
+
+func TestTaskPermissions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    owner, _ := EnsureUserAction(tx, "owner")
+    other, _ := EnsureUserAction(tx, "other")
+    assignee, _ := EnsureUserAction(tx, "assignee")
+
+    task, err := AddTaskAction(tx, owner, AddTaskProp{Name: "Private", AssigneeID: &assignee.ID})
+
+    if err != nil {
+        t.Fatalf("error while adding task, %s\n", err)
+    }
+
+    name := "Renamed"
+
+    t.Run("Should hide tasks from users they aren't shared with", func (t *testing.T) {
+        tasks, err := ListTasksAction(tx, other, ListTaskProps{})
+
+        if err != nil {
+            t.Fatalf("error while listing tasks, %s\n", err)
+        }
+
+        if len(tasks) != 0 {
+            t.Errorf("expected no visible tasks, got %d\n", len(tasks))
+        }
+
+        if _, err := UpdateTaskAction(tx, other, task.ID, UpdateTaskProp{Name: &name}); err == nil {
+            t.Error("expected update to fail for a user without access")
+        }
+    })
+
+    t.Run("Should let the assignee update but not delete the task", func (t *testing.T) {
+        if _, err := UpdateTaskAction(tx, assignee, task.ID, UpdateTaskProp{Name: &name}); err != nil {
+            t.Errorf("expected assignee to update the task, got %s\n", err)
+        }
+
+        delCount, err := DeleteTaskBulkAction(tx, assignee, []int{task.ID})
+
+        if err != nil || delCount != 0 {
+            t.Errorf("expected assignee not to delete the task, deleted %d\n", delCount)
+        }
+    })
+
+    t.Run("Should only let project shares reach the tasks of the project", func (t *testing.T) {
+        flat, err := AddTaskAction(tx, owner, AddTaskProp{Name: "Pay rent", Project: "flat"})
+
+        if err != nil {
+            t.Fatalf("error while adding task, %s\n", err)
+        }
+
+        if err := ShareTasksAction(tx, owner, other, PermissionWrite, "flat"); err != nil {
+            t.Fatalf("error while sharing tasks, %s\n", err)
+        }
+
+        if _, err := ListTaskActionByID(tx, other, uint(task.ID)); err == nil {
+            t.Error("expected the task out of the project hidden")
+        }
+
+        if _, err := UpdateTaskAction(tx, other, flat.ID, UpdateTaskProp{Name: &name}); err != nil {
+            t.Errorf("expected the task of the project updated, got %s\n", err)
+        }
+
+        if err := UnshareTasksAction(tx, owner, other, "flat"); err != nil {
+            t.Fatalf("error while revoking the share, %s\n", err)
+        }
+
+        if _, err := ListTaskActionByID(tx, other, uint(flat.ID)); err == nil {
+            t.Error("expected the task of the project hidden once revoked")
+        }
+    })
+
+    t.Run("Should only let read shares read the task", func (t *testing.T) {
+        if err := ShareTasksAction(tx, owner, other, PermissionRead, ""); err != nil {
+            t.Fatalf("error while sharing tasks, %s\n", err)
+        }
+
+        if _, err := ListTaskActionByID(tx, other, uint(task.ID)); err != nil {
+            t.Errorf("expected shared task to be visible, got %s\n", err)
+        }
+
+        if _, err := UpdateTaskAction(tx, other, task.ID, UpdateTaskProp{Name: &name}); err != ErrPermissionDenied {
+            t.Errorf("expected %s, got %v\n", ErrPermissionDenied, err)
+        }
+    })
+
+    t.Run("Should let write shares delete the task", func (t *testing.T) {
+        if err := ShareTasksAction(tx, owner, other, PermissionWrite, ""); err != nil {
+            t.Fatalf("error while sharing tasks, %s\n", err)
+        }
+
+        delCount, err := DeleteTaskBulkAction(tx, other, []int{task.ID})
+
+        if err != nil || delCount != 1 {
+            t.Errorf("expected task to be deleted, deleted %d\n", delCount)
+        }
+    })
+}
+
+func TestSearchTasksAction(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    actor := mockUser(t, tx)
+    other, _ := EnsureUserAction(tx, "other")
+    ids := make(map[string]int)
+
+    for _, props := range []AddTaskProp{
+        {Name: "Pay rent", Notes: "Transfer it to the landlord before the 5th"},
+        {Name: "Call the landlord"},
+        {Name: "Rental car", Notes: "Compare the prices, then pay the deposit online"},
+        {Name: "Buy milk"},
+    } {
+        task, err := AddTaskAction(tx, actor, props)
+
+        if err != nil {
+            t.Fatalf("error while adding task, %s\n", err)
+        }
+
+        ids[task.Name] = task.ID
+    }
+
+    search := func (t *testing.T, user User, query string) []SearchResult {
+        t.Helper()
+        results, err := SearchTasksAction(tx, user, SearchProps{Query: query, Highlight: [2]string{"[", "]"}})
+
+        if err != nil {
+            t.Fatalf("error while searching %q, %s\n", query, err)
+        }
+
+        return results
+    }
+
+    names := func (results []SearchResult) []string {
+        found := make([]string, 0)
+        for _, result := range results {
+            found = append(found, result.Name)
+        }
+        return found
+    }
+
+    t.Run("Should rank name matches before notes matches", func (t *testing.T) {
+        results := search(t, actor, "Landlord")
+
+        if got := names(results); !reflect.DeepEqual(got, []string{"Call the landlord", "Pay rent"}) {
+            t.Fatalf("expected the task named after the landlord first, got %v\n", got)
+        }
+
+        if results[0].Highlighted != "Call the [landlord]" || results[0].Snippet != "" {
+            t.Errorf("expected the name to be highlighted, got %+v\n", results[0])
+        }
+
+        if !strings.Contains(results[1].Snippet, "the [landlord] before") {
+            t.Errorf("expected a highlighted snippet of the notes, got %q\n", results[1].Snippet)
+        }
+    })
+
+    t.Run("Should match whole words, prefixes and phrases", func (t *testing.T) {
+        if got := names(search(t, actor, "rent")); !reflect.DeepEqual(got, []string{"Pay rent"}) {
+            t.Errorf("expected only Pay rent, got %v\n", got)
+        }
+
+        if got := names(search(t, actor, "rent*")); !reflect.DeepEqual(got, []string{"Pay rent", "Rental car"}) {
+            t.Errorf("expected both rent tasks, got %v\n", got)
+        }
+
+        if got := names(search(t, actor, `"pay the deposit" -`)); !reflect.DeepEqual(got, []string{"Rental car"}) {
+            t.Errorf("expected only the car rental, got %v\n", got)
+        }
+
+        if got := names(search(t, actor, "pay deposit")); !reflect.DeepEqual(got, []string{"Rental car"}) {
+            t.Errorf("expected every word to be required, got %v\n", got)
+        }
+    })
+
+    t.Run("Should keep the search in sync with the tasks", func (t *testing.T) {
+        name := "Buy oat milk"
+
+        if _, err := UpdateTaskAction(tx, actor, ids["Buy milk"], UpdateTaskProp{Name: &name}); err != nil {
+            t.Fatalf("error while updating task, %s\n", err)
+        }
+
+        if _, err := DeleteTaskBulkAction(tx, actor, []int{ids["Call the landlord"]}); err != nil {
+            t.Fatalf("error while deleting task, %s\n", err)
+        }
+
+        if got := names(search(t, actor, "oat")); !reflect.DeepEqual(got, []string{name}) {
+            t.Errorf("expected the renamed task, got %v\n", got)
+        }
+
+        if got := names(search(t, actor, "landlord")); !reflect.DeepEqual(got, []string{"Pay rent"}) {
+            t.Errorf("expected the deleted task to be gone, got %v\n", got)
+        }
+    })
+
+    t.Run("Should only find the tasks the user can read", func (t *testing.T) {
+        if got := search(t, other, "rent*"); len(got) != 0 {
+            t.Errorf("expected no results, got %v\n", names(got))
+        }
+    })
+
+    t.Run("Should find the same tasks scanning them as with the index", func (t *testing.T) {
+        for _, query := range []string{"landlord", "rent*", `"pay the deposit"`, "pay", "oat milk"} {
+            indexed := search(t, actor, query)
+            scanned, err := SearchTasksAction(tx, actor, SearchProps{Query: query, Highlight: [2]string{"[", "]"}, Scan: true})
+
+            if err != nil || !reflect.DeepEqual(scanned, indexed) {
+                t.Errorf("%s: expected %v scanning, got %v, %v\n", query, names(indexed), names(scanned), err)
+            }
+        }
+    })
+
+    t.Run("Should refuse empty queries", func (t *testing.T) {
+        if _, err := SearchTasksAction(tx, actor, SearchProps{Query: " \" - "}); err != ErrEmptySearch {
+            t.Errorf("expected %s, got %v\n", ErrEmptySearch, err)
+        }
+    })
+}
+
+func TestViewActions(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    owner, _ := EnsureUserAction(tx, "owner")
+    other, _ := EnsureUserAction(tx, "other")
+
+    t.Run("Should save and replace views by name", func (t *testing.T) {
+        if _, err := SaveViewAction(tx, owner, View{Name: "today", Filter: "due=today"}); err != nil {
+            t.Fatalf("error while saving view, %s\n", err)
+        }
+
+        saved, err := SaveViewAction(tx, owner, View{Name: "today", Filter: "due<=today", Sort: "priority,desc", Format: "ids"})
+
+        if err != nil {
+            t.Fatalf("error while replacing view, %s\n", err)
+        }
+
+        view, err := ListViewActionByName(tx, owner, "today")
+
+        if err != nil || view != saved || view.Filter != "due<=today" {
+            t.Errorf("expected the replaced view %+v, got %+v, %v\n", saved, view, err)
+        }
+    })
+
+    t.Run("Should refuse invalid names and formats", func (t *testing.T) {
+        if _, err := SaveViewAction(tx, owner, View{Name: "@today"}); err == nil {
+            t.Error("expected an error for the name @today")
+        }
+
+        if _, err := SaveViewAction(tx, owner, View{Name: "today", Format: "xml"}); err == nil {
+            t.Error("expected an error for the format xml")
+        }
+    })
+
+    t.Run("Should keep views to their owner", func (t *testing.T) {
+        views, err := ListViewsAction(tx, other)
+
+        if err != nil || len(views) != 0 {
+            t.Errorf("expected no views, got %+v, %v\n", views, err)
+        }
+
+        if err := DeleteViewAction(tx, other, "today"); err == nil {
+            t.Error("expected deleting someone else's view to fail")
+        }
+
+        if err := DeleteViewAction(tx, owner, "today"); err != nil {
+            t.Errorf("error while deleting view, %s\n", err)
+        }
+    })
+}
+
+func TestAuthenticateTokenAction(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    user := mockUser(t, tx)
+
+    t.Run("Should authenticate a freshly created token", func (t *testing.T) {
+        token, value, err := AddTokenAction(tx, AddTokenProp{Name: "ci", UserID: user.ID, Scopes: []string{ScopeWrite}})
+
+        if err != nil {
+            t.Fatalf("error while creating token, %s\n", err)
+        }
+
+        authenticated, err := AuthenticateTokenAction(tx, value)
+
+        if err != nil {
+            t.Fatalf("error while authenticating token, %s\n", err)
+        }
+
+        if authenticated.ID != token.ID {
+            t.Errorf("expected token %d to be authenticated, got %d\n", token.ID, authenticated.ID)
+        }
+
+        if !authenticated.HasScope(ScopeRead) || authenticated.HasScope(ScopeAdmin) {
+            t.Errorf("expected write scope to imply read but not admin, got %v\n", authenticated.Scopes)
+        }
+
+        if authenticated.UserID == nil || *authenticated.UserID != user.ID {
+            t.Errorf("expected the token to belong to user %d, got %v\n", user.ID, authenticated.UserID)
+        }
+    })
+
+    t.Run("Should refuse tokens without a user", func (t *testing.T) {
+        if _, _, err := AddTokenAction(tx, AddTokenProp{Name: "nobody", Scopes: []string{ScopeRead}}); err == nil {
+            t.Error("expected an error creating a token without a user")
+        }
+
+        token, value, _ := AddTokenAction(tx, AddTokenProp{Name: "legacy", UserID: user.ID, Scopes: []string{ScopeRead}})
+        tx.Exec("UPDATE tokens SET user_id = NULL WHERE id = $1;", token.ID)
+
+        if _, err := AuthenticateTokenAction(tx, value); err != ErrTokenWithoutUser {
+            t.Errorf("expected %s, got %v\n", ErrTokenWithoutUser, err)
+        }
+    })
+
+    t.Run("Should reject unknown tokens", func (t *testing.T) {
+        _, err := AuthenticateTokenAction(tx, "gtd_asdf")
+
+        if err != ErrInvalidToken {
+            t.Errorf("expected %s, got %v\n", ErrInvalidToken, err)
+        }
+    })
+
+    t.Run("Should reject revoked tokens", func (t *testing.T) {
+        token, value, _ := AddTokenAction(tx, AddTokenProp{Name: "revoked", UserID: user.ID, Scopes: []string{ScopeRead}})
+
+        if err := RevokeTokenAction(tx, token.ID); err != nil {
+            t.Fatalf("error while revoking token, %s\n", err)
+        }
+
+        _, err := AuthenticateTokenAction(tx, value)
+
+        if err != ErrRevokedToken {
+            t.Errorf("expected %s, got %v\n", ErrRevokedToken, err)
+        }
+    })
+
+    t.Run("Should reject expired tokens", func (t *testing.T) {
+        expiresAt := time.Now().Add(-time.Hour)
+        _, value, _ := AddTokenAction(tx, AddTokenProp{Name: "expired", UserID: user.ID, Scopes: []string{ScopeRead}, ExpiresAt: &expiresAt})
+
+        _, err := AuthenticateTokenAction(tx, value)
+
+        if err != ErrExpiredToken {
+            t.Errorf("expected %s, got %v\n", ErrExpiredToken, err)
+        }
+    })
+}
+
+func TestPostgresMigrations(t *testing.T) {
+    t.Run("Should have a PostgreSQL migration for every SQLite one", func (t *testing.T) {
+        sqlite, err := migrations(SQLiteMigrations)
+
+        if err != nil {
+            t.Fatalf("error while listing the SQLite migrations, %s\n", err)
+        }
+
+        postgres, err := migrations(PostgresMigrations)
+
+        if err != nil {
+            t.Fatalf("error while listing the PostgreSQL migrations, %s\n", err)
+        }
+
+        if len(sqlite) != len(postgres) {
+            t.Fatalf("expected %d PostgreSQL migrations, got %d\n", len(sqlite), len(postgres))
+        }
+
+        for idx := range sqlite {
+            if sqlite[idx].Name != postgres[idx].Name {
+                t.Errorf("expected migration %s, got %s\n", sqlite[idx].Name, postgres[idx].Name)
+            }
+
+            if strings.TrimSpace(postgres[idx].Up) == "" {
+                t.Errorf("expected statements in %s\n", postgres[idx].Name)
+            }
+        }
+    })
+
+    t.Run("Should migrate a PostgreSQL database once", func (t *testing.T) {
+        db, err := OpenPostgres(postgresDSN(t))
+
+        if err != nil {
+            t.Fatalf("error while opening the database, %s\n", err)
+        }
+        defer db.Close()
+
+        if err := MigratePostgres(db); err != nil {
+            t.Fatalf("error while migrating again, %s\n", err)
+        }
+
+        list, _ := migrations(PostgresMigrations)
+        version, err := SchemaVersion(db)
+
+        if err != nil || version != list[len(list) - 1].Version {
+            t.Errorf("expected version %d, got %d, %v\n", list[len(list) - 1].Version, version, err)
+        }
+
+        var count int
+        db.QueryRow("SELECT COUNT(*) FROM goose_db_version;").Scan(&count)
+
+        if count != len(list) + 1 {
+            t.Errorf("expected %d versions, got %d\n", len(list) + 1, count)
+        }
+    })
+
+    t.Run("Should run the actions on PostgreSQL", func (t *testing.T) {
+        db, err := OpenPostgres(postgresDSN(t))
+
+        if err != nil {
+            t.Fatalf("error while opening the database, %s\n", err)
+        }
+        defer db.Close()
+
+        actor := mockUser(t, db)
+        task := mockTask(t, db)
+        name := "Renamed"
+
+        if _, err := UpdateTaskAction(db, actor, task.ID, UpdateTaskProp{Name: &name}); err != nil {
+            t.Fatalf("error while updating, %s\n", err)
+        }
+
+        found, err := ResolveTaskAction(db, actor, "renamed")
+
+        if err != nil || found.ID != task.ID || found.Name != "Renamed" {
+            t.Errorf("expected to find the renamed task, got %+v, %v\n", found, err)
+        }
+    })
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
+
+func mockTask(t testing.TB, db DB) Task {
+    t.Helper()
+    task, err := AddTaskAction(db, mockUser(t, db), AddTaskProp{
+        Name: "Test",
+        Completed: false,
+    })
+
+    if err != nil {
+        t.Fatalf("error while mocking task, %s\n", err)
+    }
+
+    return task
+}
+
+func mockUser(t testing.TB, db DB) User {
+    t.Helper()
+    user, err := EnsureUserAction(db, "tester")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s\n", err)
+    }
+
+    return user
+}
+
+// postgresDSN is the URL of an empty schema in the PostgreSQL database
+// GO_TODO_TEST_POSTGRES names, the test is skipped without one.
+func postgresDSN(t testing.TB) string {
+    t.Helper()
+    dsn := os.Getenv("GO_TODO_TEST_POSTGRES")
+
+    if dsn == "" {
+        t.Skip("GO_TODO_TEST_POSTGRES isn't set")
+    }
+
+    db, err := sql.Open(PostgresDriverName, dsn)
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+
+    schema := fmt.Sprintf("go_todo_test_%d", time.Now().UnixNano())
+
+    if _, err := db.Exec("CREATE SCHEMA " + schema + ";"); err != nil {
+        db.Close()
+        t.Fatalf("error while creating schema %s, %s\n", schema, err)
+    }
+
+    t.Cleanup(func () {
+        db.Exec("DROP SCHEMA " + schema + " CASCADE;")
+        db.Close()
+    })
+
+    separator := "?"
+
+    if strings.Contains(dsn, "?") {
+        separator = "&"
+    }
+
+    return dsn + separator + "search_path=" + schema
 }
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+    IntegrityCheck = "integrity"
+    SchemaCheck = "schema"
+    ForeignKeyCheck = "foreign keys"
+    OrphanCheck = "orphans"
+)
+
+// DoctorChecks are the checks DoctorAction runs, in order.
+var DoctorChecks = []string{IntegrityCheck, SchemaCheck, ForeignKeyCheck, OrphanCheck}
+
+// Problem is something wrong DoctorAction found, Fix describes how it can be
+// fixed safely and is empty when it can't.
+type Problem struct {
+    Check string `json:"check"`
+    Description string `json:"description"`
+    Fix string `json:"fix,omitempty"`
+    Fixed bool `json:"fixed"`
+}
+
+// DoctorAction checks the health of the database and fixes what it safely
+// can when fix is set. Run it in a transaction so that the fixes are made all
+// together or not at all.
+func DoctorAction(db DB, fix bool) ([]Problem, error) {
+    problems := make([]Problem, 0)
+    checks := []func (DB, bool) ([]Problem, error){checkIntegrity, checkSchema, checkForeignKeys, checkOrphans}
+
+    for _, check := range checks {
+        found, err := check(db, fix)
+
+        if err != nil {
+            return nil, err
+        }
+
+        problems = append(problems, found...)
+    }
+
+    return problems, nil
+}
+
+// Damaged pages can't be fixed in place, restoring a backup is the way out.
+func checkIntegrity(db DB, fix bool) ([]Problem, error) {
+    lines, err := integrityErrors(db)
+
+    if err != nil {
+        return nil, err
+    }
+
+    problems := make([]Problem, len(lines))
+
+    for idx, line := range lines {
+        problems[idx] = Problem{Check: IntegrityCheck, Description: line}
+    }
+
+    return problems, nil
+}
+
+// integrityErrors lists what PRAGMA integrity_check found wrong.
+func integrityErrors(db DB) ([]string, error) {
+    rows, err := db.Query("PRAGMA integrity_check;")
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := make([]string, 0)
+
+    for rows.Next() {
+        var line string
+
+        if err := rows.Scan(&line); err != nil {
+            return nil, err
+        }
+
+        if line != "ok" {
+            lines = append(lines, line)
+        }
+    }
+
+    return lines, rows.Err()
+}
+
+// checkSchema applies the embedded migrations to an empty database and
+// compares its tables and columns with the ones of db. Tables the migrations
+// don't create, e.g. the search index, are left alone.
+func checkSchema(db DB, fix bool) ([]Problem, error) {
+    list, err := migrations(SQLiteMigrations)
+
+    if err != nil {
+        return nil, err
+    }
+
+    problems := make([]Problem, 0)
+    applied, err := appliedVersions(db)
+
+    if err != nil {
+        problems = append(problems, Problem{Check: SchemaCheck, Description: "goose_db_version is missing, the migrations were never applied"})
+    }
+
+    expected, err := sql.Open(DriverName, ":memory:")
+
+    if err != nil {
+        return nil, err
+    }
+    defer expected.Close()
+
+    // Every connection has its own in-memory database.
+    expected.SetMaxOpenConns(1)
+
+    for _, migration := range list {
+        if applied != nil && !applied[migration.Version] {
+            problems = append(problems, Problem{Check: SchemaCheck, Description: fmt.Sprintf("migration %s isn't applied", migration.Name)})
+        }
+
+        if _, err := expected.Exec(migration.Up); err != nil {
+            return nil, fmt.Errorf("couldn't apply migration %s, %w", migration.Name, err)
+        }
+    }
+
+    tables, err := tableNames(expected)
+
+    if err != nil {
+        return nil, err
+    }
+
+    for _, table := range tables {
+        want, err := columnNames(expected, table)
+
+        if err != nil {
+            return nil, err
+        }
+
+        got, err := columnNames(db, table)
+
+        if err != nil {
+            return nil, err
+        }
+
+        if len(got) == 0 {
+            problems = append(problems, Problem{Check: SchemaCheck, Description: fmt.Sprintf("table %s is missing", table)})
+            continue
+        }
+
+        for _, column := range want {
+            if !slices.Contains(got, column) {
+                problems = append(problems, Problem{Check: SchemaCheck, Description: fmt.Sprintf("column %s.%s is missing", table, column)})
+            }
+        }
+    }
+
+    return problems, nil
+}
+
+func appliedVersions(db DB) (map[int64]bool, error) {
+    rows, err := db.Query("SELECT version_id FROM goose_db_version WHERE is_applied;")
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    versions := make(map[int64]bool)
+
+    for rows.Next() {
+        var version int64
+
+        if err := rows.Scan(&version); err != nil {
+            return nil, err
+        }
+
+        versions[version] = true
+    }
+
+    return versions, rows.Err()
+}
+
+func tableNames(db DB) ([]string, error) {
+    rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;")
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    names := make([]string, 0)
+
+    for rows.Next() {
+        var name string
+
+        if err := rows.Scan(&name); err != nil {
+            return nil, err
+        }
+
+        names = append(names, name)
+    }
+
+    return names, rows.Err()
+}
+
+// columnNames is empty when the table doesn't exist.
+func columnNames(db DB, table string) ([]string, error) {
+    rows, err := db.Query("SELECT name FROM pragma_table_info($1);", table)
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    names := make([]string, 0)
+
+    for rows.Next() {
+        var name string
+
+        if err := rows.Scan(&name); err != nil {
+            return nil, err
+        }
+
+        names = append(names, name)
+    }
+
+    return names, rows.Err()
+}
+
+// foreignKeyFixes are the safe fixes of the rows referencing a missing row,
+// by table and column. Tasks keep their missing owner since clearing it would
+// share them with everyone, and the token audit is kept as it is.
+var foreignKeyFixes = map[string]string{
+    "tasks.assignee_id": "clear",
+    "task_changes.actor_id": "clear",
+    "shares.owner_id": "delete",
+    "shares.user_id": "delete",
+    "views.owner_id": "delete",
+    "operations.actor_id": "delete",
+    "webhook_deliveries.webhook_id": "delete",
+    "webhook_dead_letters.webhook_id": "delete",
+    "webhook_queue.webhook_id": "delete",
+}
+
+// checkForeignKeys finds the rows referencing missing rows, SQLite doesn't
+// enforce the foreign keys unless asked to.
+func checkForeignKeys(db DB, fix bool) ([]Problem, error) {
+    rows, err := db.Query("SELECT fk.\"table\", fk.rowid, fk.parent, list.\"from\" FROM pragma_foreign_key_check() AS fk JOIN pragma_foreign_key_list(fk.\"table\") AS list ON list.id = fk.fkid WHERE fk.rowid IS NOT NULL;")
+
+    if err != nil {
+        return nil, err
+    }
+
+    violations := make(map[string][]int)
+    parents := make(map[string]string)
+
+    for rows.Next() {
+        var table, parent, column string
+        var rowid int
+
+        if err := rows.Scan(&table, &rowid, &parent, &column); err != nil {
+            rows.Close()
+            return nil, err
+        }
+
+        key := table + "." + column
+        violations[key] = append(violations[key], rowid)
+        parents[key] = parent
+    }
+
+    rows.Close()
+
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    keys := make([]string, 0, len(violations))
+
+    for key := range violations {
+        keys = append(keys, key)
+    }
+
+    sort.Strings(keys)
+    problems := make([]Problem, 0, len(keys))
+
+    for _, key := range keys {
+        table, column, _ := strings.Cut(key, ".")
+        rowids := violations[key]
+        problem := Problem{
+            Check: ForeignKeyCheck,
+            Description: fmt.Sprintf("%d rows of %s reference missing %s in %s", len(rowids), table, parents[key], column),
+        }
+
+        var statement string
+
+        switch foreignKeyFixes[key] {
+        case "clear":
+            problem.Fix = "clear " + column
+            statement = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE rowid IN (%s);", table, column, idList(rowids))
+        case "delete":
+            problem.Fix = "delete the rows"
+            statement = fmt.Sprintf("DELETE FROM %s WHERE rowid IN (%s);", table, idList(rowids))
+        }
+
+        if fix && statement != "" {
+            if _, err := db.Exec(statement); err != nil {
+                return nil, err
+            }
+            problem.Fixed = true
+        }
+
+        problems = append(problems, problem)
+    }
+
+    return problems, nil
+}
+
+// The history of a task is dropped when it's purged, changes left without
+// their task are of no use.
+const COUNT_ORPHAN_CHANGES_SQL = "SELECT COUNT(*) FROM task_changes WHERE task_id NOT IN (SELECT id FROM tasks);"
+const DELETE_ORPHAN_CHANGES_SQL = "DELETE FROM task_changes WHERE task_id NOT IN (SELECT id FROM tasks);"
+
+func checkOrphans(db DB, fix bool) ([]Problem, error) {
+    var count int
+
+    if err := queryRow(db, COUNT_ORPHAN_CHANGES_SQL).Scan(&count); err != nil || count == 0 {
+        // Without task_changes the schema check already complained.
+        return nil, nil
+    }
+
+    problem := Problem{
+        Check: OrphanCheck,
+        Description: fmt.Sprintf("%d task changes belong to missing tasks", count),
+        Fix: "delete the changes",
+    }
+
+    if fix {
+        if _, err := db.Exec(DELETE_ORPHAN_CHANGES_SQL); err != nil {
+            return nil, err
+        }
+        problem.Fixed = true
+    }
+
+    return []Problem{problem}, nil
+}
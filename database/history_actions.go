@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskChange is the change of one field of a task, Old is nil when the field
+// was set and New when it was cleared.
+type TaskChange struct {
+    ID int `json:"id"`
+    TaskID int `json:"task_id"`
+    ActorID *int `json:"actor_id,omitempty"`
+    Field string `json:"field"`
+    Old *string `json:"old,omitempty"`
+    New *string `json:"new,omitempty"`
+    ChangedAt time.Time `json:"changed_at"`
+}
+
+// HistoryFields are the fields recorded in the history of the tasks, in the
+// order add shows them.
+var HistoryFields = []string{"name", "completed", "assignee", "due", "priority", "tags", "project", "recurrence", "notes", "archived", "deleted"}
+
+// historyValues are the values of the HistoryFields of task, nil when empty.
+// Due dates are in RFC 3339, assignees are user IDs.
+func historyValues(task Task) map[string]*string {
+    values := make(map[string]*string)
+    set := func (field string, value string) {
+        if value != "" {
+            values[field] = &value
+        }
+    }
+    flag := func (field string, on bool) {
+        if on {
+            set(field, "true")
+        }
+    }
+
+    set("name", task.Name)
+    flag("completed", task.Completed)
+
+    if task.AssigneeID != nil {
+        set("assignee", strconv.Itoa(*task.AssigneeID))
+    }
+
+    if task.DueAt != nil {
+        set("due", task.DueAt.UTC().Format(time.RFC3339))
+    }
+
+    if task.Priority > PriorityNone && task.Priority < len(PriorityNames) {
+        set("priority", PriorityNames[task.Priority])
+    }
+
+    set("tags", strings.Join(task.Tags, ","))
+    set("project", task.Project)
+    set("recurrence", task.Recurrence)
+    set("notes", task.Notes)
+    flag("archived", task.ArchivedAt != nil)
+    flag("deleted", task.DeletedAt != nil)
+
+    return values
+}
+
+const ADD_TASK_CHANGE_SQL = "INSERT INTO task_changes (task_id, actor_id, field, old_value, new_value) VALUES ($1,$2,$3,$4,$5);"
+const DELETE_TASK_CHANGES_SQL = "DELETE FROM task_changes WHERE task_id = $1;"
+
+// recordChanges writes the history of the tasks going from their before
+// images to their after images. Tasks without a before image were created,
+// their fields are recorded as set, tasks without an after image are gone for
+// good and so is their history.
+func recordChanges(db DB, actor User, before []Task, after []Task) error {
+    previous := make(map[int]Task)
+
+    for _, task := range before {
+        previous[task.ID] = task
+    }
+
+    for _, task := range after {
+        old := make(map[string]*string)
+
+        if image, ok := previous[task.ID]; ok {
+            old = historyValues(image)
+            delete(previous, task.ID)
+        }
+
+        current := historyValues(task)
+
+        for _, field := range HistoryFields {
+            if sameValue(old[field], current[field]) {
+                continue
+            }
+
+            if _, err := db.Exec(ADD_TASK_CHANGE_SQL, task.ID, actor.ID, field, old[field], current[field]); err != nil {
+                return err
+            }
+        }
+    }
+
+    for id := range previous {
+        if _, err := db.Exec(DELETE_TASK_CHANGES_SQL, id); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func sameValue(left *string, right *string) bool {
+    if left == nil || right == nil {
+        return left == right
+    }
+
+    return *left == *right
+}
+
+// ListTaskHistoryAction lists the changes of a task the actor can read, the
+// oldest first. Tasks in the trash keep their history until purged.
+const GET_TASK_WITH_TRASHED_SQL = "SELECT id FROM tasks WHERE id = $1 AND %s;"
+const LIST_TASK_CHANGES_SQL = "SELECT id, task_id, actor_id, field, old_value, new_value, changed_at FROM task_changes WHERE task_id = $1 ORDER BY id;"
+func ListTaskHistoryAction(db DB, actor User, taskID int) ([]TaskChange, error) {
+    var id int
+
+    if err := queryRow(db, fmt.Sprintf(GET_TASK_WITH_TRASHED_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$2")), taskID, actor.ID).Scan(&id); err != nil {
+        return nil, errors.New("Task doesn't exist")
+    }
+
+    rows, err := db.Query(LIST_TASK_CHANGES_SQL, taskID)
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    changes := make([]TaskChange, 0)
+
+    for rows.Next() {
+        change := TaskChange{}
+
+        if err := rows.Scan(&change.ID, &change.TaskID, &change.ActorID, &change.Field, &change.Old, &change.New, &change.ChangedAt); err != nil {
+            return nil, err
+        }
+
+        changes = append(changes, change)
+    }
+
+    return changes, rows.Err()
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operation is a change of tasks recorded in the journal, Before and After
+// are the images of the tasks it changed, a created task has no image before
+// and a purged one none after.
+type Operation struct {
+    ID int `json:"id"`
+    Action string `json:"action"`
+    Before []Task `json:"before"`
+    After []Task `json:"after"`
+    Undone bool `json:"undone"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+    OperationAdd = "add"
+    OperationUpdate = "update"
+    OperationDelete = "delete"
+    OperationRestore = "restore"
+    OperationPurge = "purge"
+    OperationArchive = "archive"
+    OperationUnarchive = "unarchive"
+)
+
+// JournalSize is the number of operations kept for each user.
+const JournalSize = 200
+
+var ErrNothingToUndo = errors.New("Nothing to undo")
+var ErrNothingToRedo = errors.New("Nothing to redo")
+
+const ADD_OPERATION_SQL = "INSERT INTO operations (actor_id, action, before, after) VALUES ($1,$2,$3,$4);"
+
+// Undone operations can't be redone once something else changed.
+const CLEAR_UNDONE_OPERATIONS_SQL = "DELETE FROM operations WHERE actor_id = $1 AND undone;"
+const PRUNE_OPERATIONS_SQL = "DELETE FROM operations WHERE actor_id = $1 AND id NOT IN (SELECT id FROM operations WHERE actor_id = $1 ORDER BY id DESC LIMIT $2);"
+
+// recordOperation journals a change made by actor and writes it to the
+// history of the tasks.
+func recordOperation(db DB, actor User, action string, before []Task, after []Task) error {
+    if err := recordChanges(db, actor, before, after); err != nil {
+        return err
+    }
+
+    beforeImage, err := json.Marshal(before)
+
+    if err != nil {
+        return err
+    }
+
+    afterImage, err := json.Marshal(after)
+
+    if err != nil {
+        return err
+    }
+
+    if _, err := db.Exec(CLEAR_UNDONE_OPERATIONS_SQL, actor.ID); err != nil {
+        return err
+    }
+
+    if _, err := db.Exec(ADD_OPERATION_SQL, actor.ID, action, string(beforeImage), string(afterImage)); err != nil {
+        return err
+    }
+
+    _, err = db.Exec(PRUNE_OPERATIONS_SQL, actor.ID, JournalSize)
+
+    return err
+}
+
+const OPERATION_COLUMNS = "id, action, before, after, undone, created_at"
+const LIST_OPERATIONS_SQL = "SELECT " + OPERATION_COLUMNS + " FROM operations WHERE actor_id = $1 ORDER BY id DESC LIMIT $2;"
+
+// ListOperationsAction lists the last operations of actor, the latest first,
+// limit 0 lists the whole journal.
+func ListOperationsAction(db DB, actor User, limit int) ([]Operation, error) {
+    if limit <= 0 {
+        limit = JournalSize
+    }
+
+    return queryOperations(db, LIST_OPERATIONS_SQL, actor.ID, limit)
+}
+
+const LAST_DONE_OPERATIONS_SQL = "SELECT " + OPERATION_COLUMNS + " FROM operations WHERE actor_id = $1 AND NOT undone ORDER BY id DESC LIMIT $2;"
+const FIRST_UNDONE_OPERATIONS_SQL = "SELECT " + OPERATION_COLUMNS + " FROM operations WHERE actor_id = $1 AND undone ORDER BY id LIMIT $2;"
+const MARK_OPERATION_SQL = "UPDATE operations SET undone = $1 WHERE id = $2;"
+
+// UndoAction reverts the last count operations of actor, the latest first.
+// A task changed since by someone else makes it fail, nothing is reverted
+// then.
+func UndoAction(db DB, actor User, count int) ([]Operation, error) {
+    return replayOperations(db, actor, LAST_DONE_OPERATIONS_SQL, count, true)
+}
+
+// RedoAction replays the last count undone operations of actor, in the order
+// they were first made.
+func RedoAction(db DB, actor User, count int) ([]Operation, error) {
+    return replayOperations(db, actor, FIRST_UNDONE_OPERATIONS_SQL, count, false)
+}
+
+// replayOperations undoes or redoes the operations query lists, all of them
+// or none.
+func replayOperations(db DB, actor User, query string, count int, undo bool) ([]Operation, error) {
+    var operations []Operation
+
+    err := InTransaction(db, func (tx DB) error {
+        var err error
+
+        if operations, err = queryOperations(tx, query, actor.ID, count); err != nil {
+            return err
+        }
+
+        if len(operations) == 0 && undo {
+            return ErrNothingToUndo
+        }
+
+        if len(operations) == 0 {
+            return ErrNothingToRedo
+        }
+
+        for _, operation := range operations {
+            from, to, verb := operation.Before, operation.After, "redo"
+
+            if undo {
+                from, to, verb = operation.After, operation.Before, "undo"
+            }
+
+            if err := applyImages(tx, actor, from, to); err != nil {
+                return fmt.Errorf("couldn't %s operation %d, %w", verb, operation.ID, err)
+            }
+
+            if _, err := tx.Exec(MARK_OPERATION_SQL, undo, operation.ID); err != nil {
+                return err
+            }
+        }
+
+        return nil
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return operations, nil
+}
+
+func queryOperations(db DB, query string, args ...any) ([]Operation, error) {
+    rows, err := db.Query(query, args...)
+
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    operations := make([]Operation, 0)
+
+    for rows.Next() {
+        operation := Operation{}
+        var before, after string
+
+        if err := rows.Scan(&operation.ID, &operation.Action, &before, &after, &operation.Undone, &operation.CreatedAt); err != nil {
+            return nil, err
+        }
+
+        if err := json.Unmarshal([]byte(before), &operation.Before); err != nil {
+            return nil, err
+        }
+
+        if err := json.Unmarshal([]byte(after), &operation.After); err != nil {
+            return nil, err
+        }
+
+        operations = append(operations, operation)
+    }
+
+    return operations, rows.Err()
+}
+
+const GET_TASK_IMAGE_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE id = $1;"
+var WRITE_TASK_IMAGE_SQL = fmt.Sprintf("UPDATE tasks SET (%s) = (%s) WHERE id = $1;", TASK_COLUMNS, placeholders(1, len(taskImageValues(Task{}))))
+const DELETE_TASK_IMAGE_SQL = "DELETE FROM tasks WHERE id = $1;"
+
+// applyImages brings the tasks from their from images to their to images,
+// every task must still be as in its from image. The changes are written to
+// the history of the tasks as made by actor.
+func applyImages(db DB, actor User, from []Task, to []Task) error {
+    fromImages := make(map[int]*Task)
+    toImages := make(map[int]*Task)
+    ids := make([]int, 0)
+
+    for idx := range from {
+        fromImages[from[idx].ID] = &from[idx]
+        ids = append(ids, from[idx].ID)
+    }
+
+    for idx := range to {
+        if _, ok := fromImages[to[idx].ID]; !ok {
+            ids = append(ids, to[idx].ID)
+        }
+        toImages[to[idx].ID] = &to[idx]
+    }
+
+    for _, id := range ids {
+        current, err := scanTask(queryRow(db, GET_TASK_IMAGE_SQL, id))
+
+        if err != nil && err != sql.ErrNoRows {
+            return err
+        }
+
+        if err := checkImage(id, fromImages[id], current, err == nil); err != nil {
+            return err
+        }
+    }
+
+    for _, id := range ids {
+        image := toImages[id]
+        var err error
+
+        switch {
+        case image == nil:
+            _, err = db.Exec(DELETE_TASK_IMAGE_SQL, id)
+        case fromImages[id] == nil:
+            _, err = restoreTask(db, *image)
+        default:
+            _, err = db.Exec(WRITE_TASK_IMAGE_SQL, taskImageValues(*image)...)
+        }
+
+        if err != nil {
+            return err
+        }
+    }
+
+    return recordChanges(db, actor, from, to)
+}
+
+func checkImage(id int, expected *Task, current Task, exists bool) error {
+    if expected == nil {
+        if exists {
+            return fmt.Errorf("task %d exists again", id)
+        }
+        return nil
+    }
+
+    if !exists {
+        return fmt.Errorf("task %d doesn't exist anymore", id)
+    }
+
+    expectedImage, _ := json.Marshal(expected)
+    currentImage, _ := json.Marshal(current)
+
+    if string(expectedImage) != string(currentImage) {
+        return fmt.Errorf("task %d changed since", id)
+    }
+
+    return nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The migrations are applied with goose, they are embedded to check the
+// schema of a database against them, see DoctorAction, and to migrate the
+// PostgreSQL databases, see MigratePostgres. Both dialects have their own
+// migrations with the same versions and names.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+const (
+    SQLiteMigrations = "migrations"
+    PostgresMigrations = "migrations/postgres"
+)
+
+type migration struct {
+    Version int64
+    Name string
+    Up string
+}
+
+// migrations lists the embedded migrations of a dialect by version, with the
+// statements of their goose Up section.
+func migrations(folder string) ([]migration, error) {
+    entries, err := migrationFiles.ReadDir(folder)
+
+    if err != nil {
+        return nil, err
+    }
+
+    list := make([]migration, 0, len(entries))
+
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+
+        content, err := migrationFiles.ReadFile(path.Join(folder, entry.Name()))
+
+        if err != nil {
+            return nil, err
+        }
+
+        prefix, _, _ := strings.Cut(entry.Name(), "_")
+        version, err := strconv.ParseInt(prefix, 10, 64)
+
+        if err != nil {
+            continue
+        }
+
+        list = append(list, migration{Version: version, Name: entry.Name(), Up: upSection(string(content))})
+    }
+
+    sort.Slice(list, func (i, j int) bool { return list[i].Version < list[j].Version })
+
+    return list, nil
+}
+
+func upSection(content string) string {
+    up := false
+    statements := make([]string, 0)
+
+    for _, line := range strings.Split(content, "\n") {
+        switch {
+        case strings.HasPrefix(line, "-- +goose Up"):
+            up = true
+        case strings.HasPrefix(line, "-- +goose Down"):
+            up = false
+        case up && !strings.HasPrefix(line, "-- +goose"):
+            statements = append(statements, line)
+        }
+    }
+
+    return strings.Join(statements, "\n")
+}
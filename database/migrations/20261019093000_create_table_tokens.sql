@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tokens (
+    id INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE token_audit (
+    id INTEGER NOT NULL PRIMARY KEY,
+    token_id INTEGER NOT NULL REFERENCES tokens(id),
+    method VARCHAR(16) NOT NULL,
+    path VARCHAR(2048) NOT NULL,
+    status INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE token_audit;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE tokens;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users (
+    id INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE shares (
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    permission VARCHAR(16) NOT NULL,
+    PRIMARY KEY (owner_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN owner_id INTEGER REFERENCES users(id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN assignee_id INTEGER REFERENCES users(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN assignee_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN owner_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE shares;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE users;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN tags VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN project VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN recurrence VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN recurrence;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN project;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN tags;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN priority;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN due_at;
+-- +goose StatementEnd
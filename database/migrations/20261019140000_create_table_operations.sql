@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE operations (
+    id INTEGER NOT NULL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users(id),
+    action VARCHAR(16) NOT NULL,
+    before TEXT NOT NULL DEFAULT '[]',
+    after TEXT NOT NULL DEFAULT '[]',
+    undone BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX operations_actor_id ON operations (actor_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE operations;
+-- +goose StatementEnd
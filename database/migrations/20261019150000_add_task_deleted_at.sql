@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+ALTER TABLE tasks DROP COLUMN deleted_at;
+-- +goose StatementEnd
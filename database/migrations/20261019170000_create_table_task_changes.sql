@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP;
+UPDATE tasks SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+CREATE TABLE task_changes (
+    id INTEGER NOT NULL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    actor_id INTEGER REFERENCES users(id),
+    field VARCHAR(32) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX task_changes_task_id ON task_changes (task_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_changes;
+ALTER TABLE tasks DROP COLUMN updated_at;
+ALTER TABLE tasks DROP COLUMN created_at;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An empty project shares every task of the owner, SQLite can't change the
+-- primary key so the table is copied.
+CREATE TABLE shares_scoped (
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    permission VARCHAR(16) NOT NULL,
+    project VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (owner_id, user_id, project)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO shares_scoped (owner_id, user_id, permission) SELECT owner_id, user_id, permission FROM shares;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE shares;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares_scoped RENAME TO shares;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE shares_unscoped (
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    permission VARCHAR(16) NOT NULL,
+    PRIMARY KEY (owner_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- The project shares widen to every task of the owner, the write permission
+-- wins.
+INSERT INTO shares_unscoped (owner_id, user_id, permission) SELECT owner_id, user_id, MAX(permission) FROM shares GROUP BY owner_id, user_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE shares;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares_unscoped RENAME TO shares;
+-- +goose StatementEnd
@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The FTS5 index the first searches of the builds with the sqlite_fts5 tag
+-- created, FTS4 is compiled in every build.
+DROP TRIGGER IF EXISTS tasks_fts_insert;
+DROP TRIGGER IF EXISTS tasks_fts_delete;
+DROP TRIGGER IF EXISTS tasks_fts_update;
+DROP TABLE IF EXISTS tasks_fts;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE VIRTUAL TABLE tasks_fts USING fts4(
+    name, notes, content='tasks', tokenize=unicode61 "remove_diacritics=2"
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- The index reads the old values of a row from the tasks table, so they're
+-- removed before the row changes and added back after.
+CREATE TRIGGER tasks_fts_before_update BEFORE UPDATE OF name, notes ON tasks BEGIN
+    DELETE FROM tasks_fts WHERE docid = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_before_delete BEFORE DELETE ON tasks BEGIN
+    DELETE FROM tasks_fts WHERE docid = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_after_update AFTER UPDATE OF name, notes ON tasks BEGIN
+    INSERT INTO tasks_fts (docid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tasks_fts_after_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts (docid, name, notes) VALUES (new.id, new.name, new.notes);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER tasks_fts_after_insert;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER tasks_fts_after_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER tasks_fts_before_delete;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER tasks_fts_before_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE tasks_fts;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tasks (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255),
+    completed BOOLEAN
+);
+-- +goose StatementEnd
+
+-- SQLite has instr and the regexp function behind its REGEXP operator, the
+-- conditions of the filter package call them.
+-- +goose StatementBegin
+CREATE FUNCTION instr(haystack TEXT, needle TEXT) RETURNS INTEGER LANGUAGE SQL IMMUTABLE AS $$
+    SELECT strpos(haystack, needle);
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION regexp(pattern TEXT, subject TEXT) RETURNS BOOLEAN LANGUAGE SQL IMMUTABLE AS $$
+    SELECT subject ~ pattern;
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION regexp(TEXT, TEXT);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP FUNCTION instr(TEXT, TEXT);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE tasks;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE views (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    name VARCHAR(64) NOT NULL,
+    filter TEXT NOT NULL DEFAULT '',
+    sort VARCHAR(255) NOT NULL DEFAULT '',
+    format VARCHAR(16) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (owner_id, name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE views;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN archived_at TIMESTAMPTZ;
+-- The tasks completed before are taken as completed now.
+UPDATE tasks SET completed_at = CURRENT_TIMESTAMP WHERE completed;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tasks DROP COLUMN archived_at;
+ALTER TABLE tasks DROP COLUMN completed_at;
+-- +goose StatementEnd
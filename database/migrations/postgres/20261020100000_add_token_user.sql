@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The tokens created before act for no one, they have to be created again.
+ALTER TABLE tokens ADD COLUMN user_id INTEGER REFERENCES users(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tokens DROP COLUMN user_id;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An empty project shares every task of the owner.
+ALTER TABLE shares ADD COLUMN project VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares DROP CONSTRAINT shares_pkey;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares ADD PRIMARY KEY (owner_id, user_id, project);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The project shares widen to every task of the owner, the write permission
+-- wins.
+DELETE FROM shares s USING shares o WHERE s.owner_id = o.owner_id AND s.user_id = o.user_id AND s.project <> o.project AND (s.permission < o.permission OR (s.permission = o.permission AND s.project > o.project));
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares DROP CONSTRAINT shares_pkey;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares DROP COLUMN project;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE shares ADD PRIMARY KEY (owner_id, user_id);
+-- +goose StatementEnd
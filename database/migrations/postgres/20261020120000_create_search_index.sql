@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- PostgreSQL has no FTS4, its searches scan the tasks, see SearchProps.
+SELECT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd
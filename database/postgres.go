@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresDriverName is the lib/pq driver. The queries of the actions run on
+// PostgreSQL as they are, the functions SQLite has and PostgreSQL lacks are
+// created by its migrations.
+const PostgresDriverName = "postgres"
+
+// IsPostgresDSN tells whether dsn is the URL of a PostgreSQL database, e.g.
+// postgres://todo@db.example.com/todo?sslmode=disable.
+func IsPostgresDSN(dsn string) bool {
+    return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// OpenPostgres connects to the PostgreSQL database at dsn and applies the
+// migrations it misses.
+func OpenPostgres(dsn string) (*sql.DB, error) {
+    db, err := sql.Open(PostgresDriverName, dsn)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := MigratePostgres(db); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    return db, nil
+}
+
+// Every process migrating the database waits for this lock, so that only the
+// first one applies the missing migrations.
+const MIGRATION_LOCK_SQL = "SELECT pg_advisory_xact_lock(5183742009);"
+
+// goose_db_version is the table goose keeps the applied versions in, it can
+// migrate the database as well.
+const CREATE_VERSION_TABLE_SQL = `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT now()
+);`
+const COUNT_VERSIONS_SQL = "SELECT COUNT(*) FROM goose_db_version;"
+const ADD_VERSION_SQL = "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE);"
+
+// MigratePostgres applies the embedded PostgreSQL migrations missing from db,
+// all of them or none.
+func MigratePostgres(db *sql.DB) error {
+    list, err := migrations(PostgresMigrations)
+
+    if err != nil {
+        return err
+    }
+
+    return InTransaction(db, func (tx DB) error {
+        if _, err := tx.Exec(MIGRATION_LOCK_SQL); err != nil {
+            return err
+        }
+
+        if _, err := tx.Exec(CREATE_VERSION_TABLE_SQL); err != nil {
+            return err
+        }
+
+        var count int
+
+        if err := tx.QueryRow(COUNT_VERSIONS_SQL).Scan(&count); err != nil {
+            return err
+        }
+
+        // goose starts with version 0.
+        if count == 0 {
+            if _, err := tx.Exec(ADD_VERSION_SQL, 0); err != nil {
+                return err
+            }
+        }
+
+        applied, err := appliedVersions(tx)
+
+        if err != nil {
+            return err
+        }
+
+        for _, migration := range list {
+            if applied[migration.Version] {
+                continue
+            }
+
+            if _, err := tx.Exec(migration.Up); err != nil {
+                return fmt.Errorf("couldn't apply migration %s, %w", migration.Name, err)
+            }
+
+            if _, err := tx.Exec(ADD_VERSION_SQL, migration.Version); err != nil {
+                return err
+            }
+        }
+
+        return nil
+    })
+}
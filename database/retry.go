@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// BusyRetries is how many times a statement is retried when the database
+// stays busy past the busy timeout, waiting twice longer each time.
+const BusyRetries = 5
+const BusyBackoff = 50 * time.Millisecond
+
+// IsBusy reports whether err comes from the database being locked by another
+// connection.
+func IsBusy(err error) bool {
+    var sqliteErr sqlite3.Error
+
+    if errors.As(err, &sqliteErr) {
+        return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+    }
+
+    return false
+}
+
+// RetryBusy runs op until it isn't refused for the database being busy, with
+// an exponential backoff and some jitter so that the waiting writers don't
+// retry in lockstep.
+func RetryBusy(op func () error) error {
+    backoff := BusyBackoff
+
+    for attempt := 0; ; attempt++ {
+        err := op()
+
+        if !IsBusy(err) || attempt == BusyRetries {
+            return err
+        }
+
+        time.Sleep(backoff + time.Duration(rand.Int63n(int64(backoff))))
+        backoff *= 2
+    }
+}
+
+// RetryDB retries the statements and the transactions refused for the
+// database being busy. Statements run in a transaction aren't retried, it
+// holds the write lock from the start, see ConnectionParams.
+type RetryDB struct {
+    *sql.DB
+}
+
+// WithRetry wraps db so that the actions retry when it's busy.
+func WithRetry(db *sql.DB) *RetryDB {
+    return &RetryDB{DB: db}
+}
+
+func (db *RetryDB) Exec(query string, args ...any) (sql.Result, error) {
+    var result sql.Result
+
+    err := RetryBusy(func () error {
+        var err error
+        result, err = db.DB.Exec(query, args...)
+        return err
+    })
+
+    return result, err
+}
+
+// Query only retries the errors met before reading the rows, the ones met
+// reading them are left to the caller.
+func (db *RetryDB) Query(query string, args ...any) (*sql.Rows, error) {
+    var rows *sql.Rows
+
+    err := RetryBusy(func () error {
+        var err error
+        rows, err = db.DB.Query(query, args...)
+        return err
+    })
+
+    return rows, err
+}
+
+// QueryRow retries like Query. The errors of a *sql.Row only show when it's
+// scanned though, e.g. those of INSERT ... RETURNING, the actions query their
+// rows with queryRow which retries them.
+func (db *RetryDB) QueryRow(query string, args ...any) *sql.Row {
+    var row *sql.Row
+
+    RetryBusy(func () error {
+        row = db.DB.QueryRow(query, args...)
+        return row.Err()
+    })
+
+    return row
+}
+
+func (db *RetryDB) Begin() (*sql.Tx, error) {
+    var tx *sql.Tx
+
+    err := RetryBusy(func () error {
+        var err error
+        tx, err = db.DB.Begin()
+        return err
+    })
+
+    return tx, err
+}
+
+// queryRow queries a row of db, the query and the scan are retried together
+// when db is a RetryDB or a ConnDB.
+func queryRow(db DB, query string, args ...any) scanner {
+    return retryRow{db: db, query: query, args: args}
+}
+
+type retryRow struct {
+    db DB
+    query string
+    args []any
+}
+
+func (r retryRow) Scan(dest ...any) error {
+    switch db := r.db.(type) {
+    case *RetryDB:
+        return RetryBusy(func () error {
+            return db.DB.QueryRow(r.query, r.args...).Scan(dest...)
+        })
+    case *ConnDB:
+        return RetryBusy(func () error {
+            return db.QueryRowContext(context.Background(), r.query, r.args...).Scan(dest...)
+        })
+    }
+
+    return r.db.QueryRow(r.query, r.args...).Scan(dest...)
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchProps describe a full-text search. Query is made of words, "quoted
+// phrases" and prefixes like rent*, a task matches when its name or notes
+// contain all of them.
+type SearchProps struct {
+    Query string
+    // Highlight goes around the matched words, e.g. ANSI bold.
+    Highlight [2]string
+    // Limit caps the number of results, 0 keeps them all.
+    Limit int
+    // Scan matches the query against every task rather than the ones the
+    // index of task.db finds, PostgreSQL databases have no index.
+    Scan bool
+}
+
+// SearchResult is a task matching a search, best matches come first.
+type SearchResult struct {
+    Task
+    // Highlighted is the name with the matched words highlighted.
+    Highlighted string `json:"highlighted"`
+    // Snippet is the part of the notes around the matched words, empty when
+    // the notes didn't match.
+    Snippet string `json:"snippet,omitempty"`
+}
+
+var ErrEmptySearch = errors.New("Search query is empty")
+
+// searchTerm is a word or a phrase, Prefix terms also match longer words.
+type searchTerm struct {
+    Words []string
+    Prefix bool
+}
+
+// parseSearchQuery splits query into terms, words joined by punctuation like
+// rent-a-car are read as a phrase and an unbalanced quote ends with the query.
+func parseSearchQuery(query string) []searchTerm {
+    terms := make([]searchTerm, 0)
+    runes := []rune(query)
+
+    for idx := 0; idx < len(runes); {
+        if unicode.IsSpace(runes[idx]) {
+            idx++
+            continue
+        }
+
+        phrase := runes[idx] == '"'
+
+        if phrase {
+            idx++
+        }
+
+        end := idx
+
+        for end < len(runes) && runes[end] != '"' && (phrase || !unicode.IsSpace(runes[end])) {
+            end++
+        }
+
+        text, next := string(runes[idx:end]), end
+
+        if phrase && next < len(runes) {
+            next++
+        }
+
+        prefix := strings.HasSuffix(text, "*")
+
+        if next < len(runes) && runes[next] == '*' {
+            prefix = true
+            next++
+        }
+
+        if words := searchWords(text); len(words) > 0 {
+            terms = append(terms, searchTerm{Words: words, Prefix: prefix})
+        }
+
+        idx = next
+    }
+
+    return terms
+}
+
+func searchWords(text string) []string {
+    words := make([]string, 0)
+
+    for _, word := range tokenize(text) {
+        words = append(words, word.text)
+    }
+
+    return words
+}
+
+// searchWord is a lowercased word of a text and its byte offsets in it.
+type searchWord struct {
+    text string
+    start int
+    end int
+}
+
+// tokenize splits text into words of letters and digits like the unicode61
+// tokenizer of the index does, minus the removal of diacritics.
+func tokenize(text string) []searchWord {
+    words := make([]searchWord, 0)
+    start := -1
+
+    for idx, r := range text + " " {
+        isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+
+        if isWordRune && start == -1 {
+            start = idx
+        }
+
+        if !isWordRune && start != -1 {
+            words = append(words, searchWord{strings.ToLower(text[start:idx]), start, idx})
+            start = -1
+        }
+    }
+
+    return words
+}
+
+// find returns the byte ranges where the term appears among words.
+func (t searchTerm) find(words []searchWord) [][2]int {
+    matches := make([][2]int, 0)
+
+    for idx := 0; idx + len(t.Words) <= len(words); idx++ {
+        if t.matchesAt(words, idx) {
+            matches = append(matches, [2]int{words[idx].start, words[idx+len(t.Words)-1].end})
+        }
+    }
+
+    return matches
+}
+
+func (t searchTerm) matchesAt(words []searchWord, idx int) bool {
+    for offset, word := range t.Words {
+        last := offset == len(t.Words) - 1
+
+        if words[idx+offset].text != word && !(last && t.Prefix && strings.HasPrefix(words[idx+offset].text, word)) {
+            return false
+        }
+    }
+
+    return true
+}
+
+// matchQuery quotes every term so that the index reads its words as they are
+// and never as operators.
+func matchQuery(terms []searchTerm) string {
+    parts := make([]string, len(terms))
+
+    for idx, term := range terms {
+        parts[idx] = `"` + strings.Join(term.Words, " ")
+
+        if term.Prefix {
+            parts[idx] += "*"
+        }
+
+        parts[idx] += `"`
+    }
+
+    return strings.Join(parts, " ")
+}
+
+// snippetWords is the length of a snippet, like the FTS5 one.
+const snippetWords = 12
+
+// SEARCH_INDEX_SQL keeps the tasks the FTS4 index of task.db finds, they
+// contain the words of the query once folded the way the index folds them.
+const SEARCH_INDEX_SQL = "id IN (SELECT docid FROM tasks_fts WHERE tasks_fts MATCH %s)"
+
+// SearchTasksAction finds the tasks actor can read matching the query, names
+// weigh ten times more than notes in the ranking. The index narrows the tasks
+// down, then the terms are matched in the candidates to rank and highlight
+// them.
+func SearchTasksAction(db DB, actor User, props SearchProps) ([]SearchResult, error) {
+    terms := parseSearchQuery(props.Query)
+
+    if len(terms) == 0 {
+        return []SearchResult{}, ErrEmptySearch
+    }
+
+    filter := ListTaskProps{WithArchived: true}
+
+    if !props.Scan {
+        filter.Where = func (arg func (value any) string) string {
+            return fmt.Sprintf(SEARCH_INDEX_SQL, arg(matchQuery(terms)))
+        }
+    }
+
+    tasks, err := ListTasksAction(db, actor, filter)
+
+    if err != nil {
+        return []SearchResult{}, err
+    }
+
+    results := make([]SearchResult, 0)
+    scores := make(map[int]int)
+
+    for _, task := range tasks {
+        nameWords, notesWords := tokenize(task.Name), tokenize(task.Notes)
+        nameMatches, notesMatches := make([][2]int, 0), make([][2]int, 0)
+        score := 0
+
+        for _, term := range terms {
+            inName, inNotes := term.find(nameWords), term.find(notesWords)
+
+            if len(inName) == 0 && len(inNotes) == 0 {
+                score = -1
+                break
+            }
+
+            score += 10 * len(inName) + len(inNotes)
+            nameMatches = append(nameMatches, inName...)
+            notesMatches = append(notesMatches, inNotes...)
+        }
+
+        if score < 0 {
+            continue
+        }
+
+        scores[task.ID] = score
+        results = append(results, SearchResult{
+            Task: task,
+            Highlighted: highlight(task.Name, nameMatches, props.Highlight),
+            Snippet: snippet(task.Notes, notesWords, notesMatches, props.Highlight),
+        })
+    }
+
+    sort.SliceStable(results, func (i int, j int) bool {
+        return scores[results[i].ID] > scores[results[j].ID]
+    })
+
+    if props.Limit > 0 && len(results) > props.Limit {
+        results = results[:props.Limit]
+    }
+
+    return results, nil
+}
+
+// highlight wraps the matched byte ranges of text in markers.
+func highlight(text string, matches [][2]int, markers [2]string) string {
+    sort.Slice(matches, func (i int, j int) bool { return matches[i][0] < matches[j][0] })
+
+    highlighted := strings.Builder{}
+    last := 0
+
+    for _, match := range matches {
+        if match[0] < last {
+            continue
+        }
+
+        highlighted.WriteString(text[last:match[0]])
+        highlighted.WriteString(markers[0] + text[match[0]:match[1]] + markers[1])
+        last = match[1]
+    }
+
+    highlighted.WriteString(text[last:])
+
+    return highlighted.String()
+}
+
+// snippet is the highlighted words of text around its first match, empty
+// when nothing matched.
+func snippet(text string, words []searchWord, matches [][2]int, markers [2]string) string {
+    if len(matches) == 0 {
+        return ""
+    }
+
+    first := matches[0][0]
+
+    for _, match := range matches {
+        first = min(first, match[0])
+    }
+
+    firstWord := 0
+
+    for idx, word := range words {
+        if word.start == first {
+            firstWord = idx
+        }
+    }
+
+    from := max(0, min(firstWord - snippetWords / 4, len(words) - snippetWords))
+    to := min(len(words), from + snippetWords)
+    start, end := words[from].start, words[to-1].end
+
+    inside := make([][2]int, 0)
+
+    for _, match := range matches {
+        if match[0] >= start && match[1] <= end {
+            inside = append(inside, [2]int{match[0] - start, match[1] - start})
+        }
+    }
+
+    result := highlight(text[start:end], inside, markers)
+
+    if from > 0 {
+        result = "…" + result
+    }
+
+    if to < len(words) {
+        result += "…"
+    }
+
+    return result
+}
@@ -1,32 +1,86 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 )
 
 type Task struct {
-    ID int
-    Name string
-    Completed bool
+    ID int `json:"id"`
+    Name string `json:"name"`
+    Completed bool `json:"completed"`
+    OwnerID *int `json:"owner_id,omitempty"`
+    AssigneeID *int `json:"assignee_id,omitempty"`
+    DueAt *time.Time `json:"due_at,omitempty"`
+    Priority int `json:"priority,omitempty"`
+    Tags []string `json:"tags,omitempty"`
+    Project string `json:"project,omitempty"`
+    // Recurrence describes how the task repeats, e.g. "every 2 weeks" or
+    // "every monday", empty when it doesn't.
+    Recurrence string `json:"recurrence,omitempty"`
+    Notes string `json:"notes,omitempty"`
+    // DeletedAt is when the task was moved to the trash.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+    CreatedAt *time.Time `json:"created_at,omitempty"`
+    UpdatedAt *time.Time `json:"updated_at,omitempty"`
+    CompletedAt *time.Time `json:"completed_at,omitempty"`
+    // ArchivedAt is when the task was archived, archived tasks are left out
+    // of the lists but not of the searches.
+    ArchivedAt *time.Time `json:"archived_at,omitempty"`
 }
 
+const (
+    PriorityNone = iota
+    PriorityLow
+    PriorityMedium
+    PriorityHigh
+)
+
+var PriorityNames = []string{"none", "low", "medium", "high"}
+
+const TASK_COLUMNS = "id, name, completed, owner_id, assignee_id, due_at, priority, tags, project, recurrence, notes, deleted_at, completed_at, archived_at, created_at, updated_at"
+
+// Tasks without an owner predate user accounts and stay accessible to
+// everyone. Otherwise the owner, the assignee and the users the owner shared
+// all their tasks or the project of the task with can read a task, the
+// clauses expect the acting user ID as
+// their only parameter.
+const CAN_READ_TASK_SQL = "(owner_id IS NULL OR owner_id = %[1]s OR assignee_id = %[1]s OR owner_id IN (SELECT owner_id FROM shares WHERE user_id = %[1]s AND project = '') OR (owner_id, project) IN (SELECT owner_id, project FROM shares WHERE user_id = %[1]s))"
+const CAN_WRITE_TASK_SQL = "(owner_id IS NULL OR owner_id = %[1]s OR assignee_id = %[1]s OR owner_id IN (SELECT owner_id FROM shares WHERE user_id = %[1]s AND project = '' AND permission = 'write') OR (owner_id, project) IN (SELECT owner_id, project FROM shares WHERE user_id = %[1]s AND permission = 'write'))"
+const CAN_DELETE_TASK_SQL = "(owner_id IS NULL OR owner_id = %[1]s OR owner_id IN (SELECT owner_id FROM shares WHERE user_id = %[1]s AND project = '' AND permission = 'write') OR (owner_id, project) IN (SELECT owner_id, project FROM shares WHERE user_id = %[1]s AND permission = 'write'))"
+
 type AddTaskProp struct {
     Name string
     Completed bool
+    AssigneeID *int
+    DueAt *time.Time
+    Priority int
+    Tags []string
+    Project string
+    Recurrence string
+    Notes string
 }
 
-const ADD_TASK_SQL = "INSERT INTO tasks (name,completed) VALUES ($1,$2) RETURNING *;"
+const ADD_TASK_SQL = "INSERT INTO tasks (name,completed,owner_id,assignee_id,due_at,priority,tags,project,recurrence,notes,completed_at,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,CASE WHEN $2 THEN CURRENT_TIMESTAMP END,CURRENT_TIMESTAMP,CURRENT_TIMESTAMP) RETURNING " + TASK_COLUMNS + ";"
 
-func AddTaskAction(db DB, props AddTaskProp) (Task, error) {
-    row := db.QueryRow(ADD_TASK_SQL, props.Name, props.Completed)
-    task := Task{}
+// AddTaskAction adds the task and journals it in a transaction, so that a
+// task is never added without its journal entry.
+func AddTaskAction(db DB, actor User, props AddTaskProp) (Task, error) {
+    var task Task
 
-    err := row.Scan(
-        &task.ID,
-        &task.Name,
-        &task.Completed,
-    )
+    err := InTransaction(db, func (tx DB) error {
+        var err error
+
+        if task, err = addTask(tx, actor, props); err != nil {
+            return err
+        }
+
+        return recordOperation(tx, actor, OperationAdd, nil, []Task{task})
+    })
 
     if err != nil {
         return Task{}, err
@@ -34,57 +88,218 @@ func AddTaskAction(db DB, props AddTaskProp) (Task, error) {
 
     return task, nil
 }
+
+// ValidateTags refuses the tags which wouldn't survive being stored as a
+// comma separated list.
+func ValidateTags(tags []string) error {
+    for _, tag := range tags {
+        if tag == "" || strings.Contains(tag, ",") {
+            return fmt.Errorf("Invalid tag '%s', tags can't be empty or contain commas", tag)
+        }
+    }
+
+    return nil
+}
+
+// addTask adds a task without journaling it.
+func addTask(db DB, actor User, props AddTaskProp) (Task, error) {
+    if err := ValidateTags(props.Tags); err != nil {
+        return Task{}, err
+    }
+
+    row := queryRow(db, 
+        ADD_TASK_SQL,
+        props.Name,
+        props.Completed,
+        actor.ID,
+        props.AssigneeID,
+        timeValue(props.DueAt),
+        props.Priority,
+        strings.Join(props.Tags, ","),
+        props.Project,
+        props.Recurrence,
+        props.Notes,
+    )
+
+    return scanTask(row)
+}
+
 type UpdateTaskProp struct {
     Name *string
     Completed *bool
+    // A zero AssigneeID unassigns the task.
+    AssigneeID *int
+    // A zero DueAt removes the due date.
+    DueAt *time.Time
+    Priority *int
+    Tags *[]string
+    Project *string
+    Recurrence *string
+    Notes *string
 }
 
-const UPDATE_TASK_SQL = "UPDATE tasks SET %s WHERE id = $1 RETURNING *;"
+const UPDATE_TASK_SQL = "UPDATE tasks SET %s WHERE id = %s AND deleted_at IS NULL AND %s RETURNING " + TASK_COLUMNS + ";"
 
-const GET_TASK_SQL = "SELECT * FROM tasks WHERE id = $1;"
-func UpdateTaskAction(db DB, taskID int, payload UpdateTaskProp) (Task, error) {
-    existingRow := db.QueryRow(GET_TASK_SQL, taskID)
+const GET_TASK_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE id = $1 AND deleted_at IS NULL AND %s;"
+func UpdateTaskAction(db DB, actor User, taskID int, payload UpdateTaskProp) (Task, error) {
+    var task Task
 
-    task := Task{}
+    err := InTransaction(db, func (tx DB) error {
+        existingTask, updated, err := updateTask(tx, actor, taskID, payload)
 
-    existingRowErr := existingRow.Scan(
-        &task.ID,
-        &task.Name,
-        &task.Completed,
-    )
+        if err != nil {
+            return err
+        }
+
+        task = updated
+
+        return recordOperation(tx, actor, OperationUpdate, []Task{existingTask}, []Task{task})
+    })
+
+    if err != nil {
+        return Task{}, err
+    }
+
+    return task, nil
+}
+
+// updateTask updates a task without journaling it, it returns the task as it
+// was before and as it is after.
+func updateTask(db DB, actor User, taskID int, payload UpdateTaskProp) (Task, Task, error) {
+    if payload.Tags != nil {
+        if err := ValidateTags(*payload.Tags); err != nil {
+            return Task{}, Task{}, err
+        }
+    }
+
+    existingRow := queryRow(db, fmt.Sprintf(GET_TASK_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$2")), taskID, actor.ID)
+
+    existingTask, existingRowErr := scanTask(existingRow)
 
     if existingRowErr != nil {
-        return Task{}, errors.New("Task doesn't exist")
+        return Task{}, Task{}, errors.New("Task doesn't exist")
+    }
+
+    columns := make([]string, 0)
+    args := make([]any, 0)
+
+    if payload.Name != nil {
+        args = append(args, *payload.Name)
+        columns = append(columns, fmt.Sprintf("name = $%d", len(args)))
     }
 
-    query := ""
+    if payload.Completed != nil {
+        args = append(args, *payload.Completed)
+        columns = append(columns, fmt.Sprintf("completed = $%[1]d", len(args)))
+        // Completing a completed task keeps the time it was completed at.
+        columns = append(columns, fmt.Sprintf("completed_at = CASE WHEN $%[1]d THEN COALESCE(completed_at, CURRENT_TIMESTAMP) END", len(args)))
+    }
 
-    if payload.Name != nil && payload.Completed != nil {
-        query = fmt.Sprintf("name = '%s', completed = %t", *payload.Name, *payload.Completed)
-    } else if payload.Name != nil && payload.Completed == nil {
-        query = fmt.Sprintf("name = '%s'", *payload.Name)
-    } else {
-        query = fmt.Sprintf("completed = %t", *payload.Completed)
+    if payload.AssigneeID != nil {
+        var assigneeID any
+        if *payload.AssigneeID != 0 {
+            assigneeID = *payload.AssigneeID
+        }
+        args = append(args, assigneeID)
+        columns = append(columns, fmt.Sprintf("assignee_id = $%d", len(args)))
     }
 
-    updatedQuery := fmt.Sprintf(UPDATE_TASK_SQL, query)
+    if payload.DueAt != nil {
+        var dueAt any
+        if !payload.DueAt.IsZero() {
+            dueAt = timeValue(payload.DueAt)
+        }
+        args = append(args, dueAt)
+        columns = append(columns, fmt.Sprintf("due_at = $%d", len(args)))
+    }
 
-    row := db.QueryRow(updatedQuery, taskID)
+    if payload.Priority != nil {
+        args = append(args, *payload.Priority)
+        columns = append(columns, fmt.Sprintf("priority = $%d", len(args)))
+    }
 
-    scanErr := row.Scan(
-        &task.ID,
-        &task.Name,
-        &task.Completed,
+    if payload.Tags != nil {
+        args = append(args, strings.Join(*payload.Tags, ","))
+        columns = append(columns, fmt.Sprintf("tags = $%d", len(args)))
+    }
+
+    if payload.Project != nil {
+        args = append(args, *payload.Project)
+        columns = append(columns, fmt.Sprintf("project = $%d", len(args)))
+    }
+
+    if payload.Recurrence != nil {
+        args = append(args, *payload.Recurrence)
+        columns = append(columns, fmt.Sprintf("recurrence = $%d", len(args)))
+    }
+
+    if payload.Notes != nil {
+        args = append(args, *payload.Notes)
+        columns = append(columns, fmt.Sprintf("notes = $%d", len(args)))
+    }
+
+    if len(columns) == 0 {
+        return Task{}, Task{}, errors.New("Nothing to update")
+    }
+
+    columns = append(columns, "updated_at = CURRENT_TIMESTAMP")
+
+    // SQLite numbers $N parameters by their first appearance in the query,
+    // so the task and actor IDs must come after the updated columns.
+    args = append(args, taskID, actor.ID)
+    updatedQuery := fmt.Sprintf(
+        UPDATE_TASK_SQL,
+        strings.Join(columns, ", "),
+        fmt.Sprintf("$%d", len(args) - 1),
+        fmt.Sprintf(CAN_WRITE_TASK_SQL, fmt.Sprintf("$%d", len(args))),
     )
 
+    task, scanErr := scanTask(queryRow(db, updatedQuery, args...))
+
+    if scanErr == sql.ErrNoRows {
+        return Task{}, Task{}, ErrPermissionDenied
+    }
+
     if scanErr != nil {
-        return Task{}, scanErr
+        return Task{}, Task{}, scanErr
     }
 
-    return task, nil
+    return existingTask, task, nil
 }
-const DELETE_TASK_SQL = "DELETE FROM tasks WHERE ID IN (%s);"
-func DeleteTaskBulkAction(db DB, IDs []int) (int, error) {
+
+// Deleted tasks go to the trash until it's emptied, see PurgeTrashAction.
+const DELETE_TASK_SQL = "UPDATE tasks SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE ID IN (%s) AND deleted_at IS NULL AND %s RETURNING " + TASK_COLUMNS + ";"
+const LIST_DELETABLE_TASKS_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE ID IN (%s) AND deleted_at IS NULL AND %s ORDER BY id;"
+func DeleteTaskBulkAction(db DB, actor User, IDs []int) (int, error) {
+    condition := fmt.Sprintf(CAN_DELETE_TASK_SQL, "$1")
+    deleted := 0
+
+    err := InTransaction(db, func (tx DB) error {
+        before, err := queryTasks(tx, fmt.Sprintf(LIST_DELETABLE_TASKS_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil || len(before) == 0 {
+            return err
+        }
+
+        trashed, err := queryTasks(tx, fmt.Sprintf(DELETE_TASK_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil {
+            return err
+        }
+
+        deleted = len(trashed)
+
+        return recordOperation(tx, actor, OperationDelete, before, trashed)
+    })
+
+    if err != nil {
+        return 0, err
+    }
+
+    return deleted, nil
+}
+
+func idList(IDs []int) string {
     ids := ""
 
     for idx, id := range IDs {
@@ -95,75 +310,385 @@ func DeleteTaskBulkAction(db DB, IDs []int) (int, error) {
         ids += fmt.Sprintf("%d", id)
     }
 
-    result, err := db.Exec(fmt.Sprintf(DELETE_TASK_SQL, ids))
+    return ids
+}
+
+// RestoreTaskAction puts back a deleted task with its original ID, e.g. to
+// undo a deletion, from the trash or as it was when it was purged.
+var RESTORE_TASK_SQL = fmt.Sprintf("INSERT INTO tasks (%s) VALUES (%s) RETURNING %s;", TASK_COLUMNS, placeholders(1, len(taskImageValues(Task{}))), TASK_COLUMNS)
+func RestoreTaskAction(db DB, actor User, task Task) (Task, error) {
+    var restoredTask Task
+
+    err := InTransaction(db, func (tx DB) error {
+        restored, err := RestoreTaskBulkAction(tx, actor, []int{task.ID})
+
+        if err != nil || restored == 1 {
+            if err != nil {
+                return err
+            }
+
+            restoredTask, err = ListTaskActionByID(tx, actor, uint(task.ID))
+            return err
+        }
+
+        task.DeletedAt = nil
+
+        if restoredTask, err = restoreTask(tx, task); err != nil {
+            return err
+        }
+
+        return recordOperation(tx, actor, OperationRestore, nil, []Task{restoredTask})
+    })
 
     if err != nil {
-        return 0, err
+        return Task{}, err
     }
 
-    delCount, err := result.RowsAffected()
+    return restoredTask, nil
+}
 
-    if err != nil {
-        return 0, err
+func restoreTask(db DB, task Task) (Task, error) {
+    return scanTask(queryRow(db, RESTORE_TASK_SQL, taskImageValues(task)...))
+}
+
+// taskImageValues are the values of the TASK_COLUMNS of task, as written.
+func taskImageValues(task Task) []any {
+    return []any{
+        task.ID,
+        task.Name,
+        task.Completed,
+        task.OwnerID,
+        task.AssigneeID,
+        timeValue(task.DueAt),
+        task.Priority,
+        strings.Join(task.Tags, ","),
+        task.Project,
+        task.Recurrence,
+        task.Notes,
+        timeValue(task.DeletedAt),
+        timeValue(task.CompletedAt),
+        timeValue(task.ArchivedAt),
+        timeValue(task.CreatedAt),
+        timeValue(task.UpdatedAt),
     }
+}
 
-    return int(delCount), nil
+// placeholders lists count placeholders from $start.
+func placeholders(start int, count int) string {
+    list := make([]string, count)
+
+    for idx := range list {
+        list[idx] = fmt.Sprintf("$%d", start + idx)
+    }
+
+    return strings.Join(list, ",")
 }
 
 type ListTaskProps struct {
     WhereCompleted *bool
-    SortBy *[2]string
+    WhereAssigneeID *int
+    WhereProject *string
+    // WhereTag keeps the tasks carrying that tag, tags are lowercase.
+    WhereTag *string
+    // OnlyMine keeps the tasks owned by or assigned to the acting user.
+    OnlyMine bool
+    // Where is an additional condition, see the filter package.
+    Where Condition
+    // Trashed lists the deleted tasks instead of the others.
+    Trashed bool
+    // Archived lists the archived tasks instead of the others, WithArchived
+    // lists them along the others.
+    Archived bool
+    WithArchived bool
+    // CompletedBefore keeps the tasks completed before that time.
+    CompletedBefore *time.Time
+    SortBy []SortKey
+}
+
+// Condition writes a SQL condition on the tasks table, arg binds a value and
+// returns its placeholder.
+type Condition func (arg func (value any) string) string
+
+type SortKey struct {
+    Column string
+    Desc bool
 }
 
-const LIST_TASKS_SQL = "SELECT * FROM tasks"
+// SortColumns are the columns tasks can be sorted by, tasks without a due
+// date come last either way.
+var SortColumns = map[string]string{
+    "id": "id",
+    "name": "name",
+    "completed": "completed",
+    "priority": "priority",
+    "due": "due_at IS NULL, due_at",
+    "project": "project",
+}
+
+func orderBy(keys []SortKey) (string, error) {
+    terms := make([]string, 0, len(keys) + 1)
+
+    for _, key := range keys {
+        column, ok := SortColumns[key.Column]
+
+        if !ok {
+            return "", fmt.Errorf("Can't sort by '%s'", key.Column)
+        }
+
+        if key.Desc {
+            column += " DESC"
+        }
+
+        terms = append(terms, column)
+    }
+
+    // Ties keep the order of creation.
+    if keys[len(keys)-1].Column != "id" {
+        terms = append(terms, "id")
+    }
+
+    return "ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+const LIST_TASKS_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks"
+
+func ListTasksAction(db DB, actor User, props ListTaskProps) ([]Task, error) {
+    args := []any{actor.ID}
+    filters := fmt.Sprintf("WHERE %s", fmt.Sprintf(CAN_READ_TASK_SQL, "$1"))
+
+    switch {
+    case props.Trashed:
+        filters += " AND deleted_at IS NOT NULL"
+    case props.Archived:
+        filters += " AND deleted_at IS NULL AND archived_at IS NOT NULL"
+    case props.WithArchived:
+        filters += " AND deleted_at IS NULL"
+    default:
+        filters += " AND deleted_at IS NULL AND archived_at IS NULL"
+    }
 
-func ListTasksAction(db DB, props ListTaskProps) ([]Task, error) {
-    var filters string
     if props.WhereCompleted != nil {
-        filters = fmt.Sprintf("WHERE completed = %t", *props.WhereCompleted)
+        args = append(args, *props.WhereCompleted)
+        filters = fmt.Sprintf("%s AND completed = $%d", filters, len(args))
+    }
+
+    if props.WhereAssigneeID != nil {
+        args = append(args, *props.WhereAssigneeID)
+        filters = fmt.Sprintf("%s AND assignee_id = $%d", filters, len(args))
     }
 
-    if props.SortBy != nil {
-        filters = fmt.Sprintf("%s ORDER BY %s %s", filters, props.SortBy[0], props.SortBy[1])
+    if props.WhereProject != nil {
+        args = append(args, *props.WhereProject)
+        filters = fmt.Sprintf("%s AND project = $%d", filters, len(args))
+    }
+
+    if props.WhereTag != nil {
+        args = append(args, *props.WhereTag)
+        filters = fmt.Sprintf("%s AND instr(',' || tags || ',', ',' || $%d || ',') > 0", filters, len(args))
+    }
+
+    if props.CompletedBefore != nil {
+        args = append(args, props.CompletedBefore.UTC())
+        filters = fmt.Sprintf("%s AND completed AND completed_at < $%d", filters, len(args))
+    }
+
+    if props.OnlyMine {
+        filters = fmt.Sprintf("%s AND (owner_id = $1 OR assignee_id = $1)", filters)
+    }
+
+    if props.Where != nil {
+        condition := props.Where(func (value any) string {
+            args = append(args, value)
+            return fmt.Sprintf("$%d", len(args))
+        })
+        filters = fmt.Sprintf("%s AND (%s)", filters, condition)
+    }
+
+    if len(props.SortBy) > 0 {
+        order, err := orderBy(props.SortBy)
+
+        if err != nil {
+            return []Task{}, err
+        }
+
+        filters = fmt.Sprintf("%s %s", filters, order)
     }
 
     query := fmt.Sprintf("%s %s;", LIST_TASKS_SQL, filters)
 
-    rows, err := db.Query(query)
+    return queryTasks(db, query, args...)
+}
+
+func queryTasks(db DB, query string, args ...any) ([]Task, error) {
+    rows, err := db.Query(query, args...)
 
     if err != nil {
         return []Task{}, err
     }
+    defer rows.Close()
 
     tasks := make([]Task, 0)
 
     for rows.Next() {
-        task := Task{}
-        if scanErr := rows.Scan(&task.ID, &task.Name, &task.Completed); scanErr != nil {
+        task, scanErr := scanTask(rows)
+        if scanErr != nil {
             return []Task{}, scanErr
         }
         tasks = append(tasks, task)
     }
 
-    return tasks, nil
+    return tasks, rows.Err()
+}
+
+const LIST_PROJECTS_SQL = "SELECT DISTINCT project FROM tasks WHERE project != '' AND deleted_at IS NULL AND %s ORDER BY project;"
+
+// ListProjectsAction lists the projects of the tasks the actor can read.
+func ListProjectsAction(db DB, actor User) ([]string, error) {
+    return queryStrings(db, fmt.Sprintf(LIST_PROJECTS_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$1")), actor.ID)
+}
+
+const LIST_TAGS_SQL = "SELECT DISTINCT tags FROM tasks WHERE tags != '' AND deleted_at IS NULL AND %s;"
+
+// ListTagsAction lists the tags of the tasks the actor can read, sorted.
+func ListTagsAction(db DB, actor User) ([]string, error) {
+    lists, err := queryStrings(db, fmt.Sprintf(LIST_TAGS_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$1")), actor.ID)
+
+    if err != nil {
+        return []string{}, err
+    }
+
+    tags := make([]string, 0)
+
+    for _, list := range lists {
+        for _, tag := range strings.Split(list, ",") {
+            if !slices.Contains(tags, tag) {
+                tags = append(tags, tag)
+            }
+        }
+    }
+    slices.Sort(tags)
+
+    return tags, nil
+}
+
+func queryStrings(db DB, query string, args ...any) ([]string, error) {
+    rows, err := db.Query(query, args...)
+
+    if err != nil {
+        return []string{}, err
+    }
+    defer rows.Close()
+
+    values := make([]string, 0)
+
+    for rows.Next() {
+        var value string
+        if err := rows.Scan(&value); err != nil {
+            return []string{}, err
+        }
+        values = append(values, value)
+    }
+
+    return values, rows.Err()
 }
 
-const LIST_TASK_ID_SQL = "SELECT * FROM tasks WHERE id = $1;"
+const LIST_TASK_ID_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE id = $1 AND deleted_at IS NULL AND %s;"
+
+func ListTaskActionByID(db DB, actor User, ID uint) (Task, error) {
+    row := queryRow(db, fmt.Sprintf(LIST_TASK_ID_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$2")), ID, actor.ID)
 
-func ListTaskActionByID(db DB, ID uint) (Task, error) {
-    row := db.QueryRow(LIST_TASK_ID_SQL, ID)
+    task, err := scanTask(row)
 
+    if err != nil {
+        return Task{}, err
+    }
+
+    return task, nil
+}
+
+const LIST_TASK_ID_WITH_TRASHED_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE id = $1 AND %s;"
+
+// ListTaskActionByIDWithTrashed is ListTaskActionByID finding the tasks in the
+// trash as well.
+func ListTaskActionByIDWithTrashed(db DB, actor User, ID uint) (Task, error) {
+    return scanTask(queryRow(db, fmt.Sprintf(LIST_TASK_ID_WITH_TRASHED_SQL, fmt.Sprintf(CAN_READ_TASK_SQL, "$2")), ID, actor.ID))
+}
+
+// timeValue stores due dates and the other times in UTC.
+func timeValue(dueAt *time.Time) any {
+    if dueAt == nil {
+        return nil
+    }
+    return dueAt.UTC()
+}
+
+func scanTask(row scanner) (Task, error) {
     task := Task{}
+    var ownerID, assigneeID sql.NullInt64
+    var dueAt, deletedAt, completedAt, archivedAt, createdAt, updatedAt sql.NullTime
+    var tags string
 
     err := row.Scan(
         &task.ID,
         &task.Name,
         &task.Completed,
+        &ownerID,
+        &assigneeID,
+        &dueAt,
+        &task.Priority,
+        &tags,
+        &task.Project,
+        &task.Recurrence,
+        &task.Notes,
+        &deletedAt,
+        &completedAt,
+        &archivedAt,
+        &createdAt,
+        &updatedAt,
     )
 
     if err != nil {
         return Task{}, err
     }
 
+    if ownerID.Valid {
+        id := int(ownerID.Int64)
+        task.OwnerID = &id
+    }
+
+    if assigneeID.Valid {
+        id := int(assigneeID.Int64)
+        task.AssigneeID = &id
+    }
+
+    if dueAt.Valid {
+        local := dueAt.Time.Local()
+        task.DueAt = &local
+    }
+
+    if deletedAt.Valid {
+        task.DeletedAt = &deletedAt.Time
+    }
+
+    if completedAt.Valid {
+        task.CompletedAt = &completedAt.Time
+    }
+
+    if archivedAt.Valid {
+        task.ArchivedAt = &archivedAt.Time
+    }
+
+    if createdAt.Valid {
+        task.CreatedAt = &createdAt.Time
+    }
+
+    if updatedAt.Valid {
+        task.UpdatedAt = &updatedAt.Time
+    }
+
+    if tags != "" {
+        task.Tags = strings.Split(tags, ",")
+    }
+
     return task, nil
 }
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_todo/fuzzy"
+	"strconv"
+	"strings"
+)
+
+// AmbiguousTaskError is returned when a reference matches several tasks,
+// Candidates are ranked best first.
+type AmbiguousTaskError struct {
+    Ref string
+    Candidates []Task
+}
+
+// maxListedCandidates caps the candidates written in the error message.
+const maxListedCandidates = 5
+
+func (e *AmbiguousTaskError) Error() string {
+    lines := []string{fmt.Sprintf("'%s' matches %d tasks, use an ID or a longer name:", e.Ref, len(e.Candidates))}
+
+    for idx, task := range e.Candidates {
+        if idx == maxListedCandidates {
+            lines = append(lines, fmt.Sprintf("  and %d more", len(e.Candidates) - idx))
+            break
+        }
+
+        lines = append(lines, fmt.Sprintf("  %d. %s", task.ID, task.Name))
+    }
+
+    return strings.Join(lines, "\n")
+}
+
+// IsTaskID reports whether ref is an ID rather than a part of a name.
+func IsTaskID(ref string) bool {
+    id, err := strconv.Atoi(ref)
+    return err == nil && id > 0
+}
+
+// ErrTaskNotFound is returned for the tasks that don't exist and for the ones
+// the actor can't read.
+var ErrTaskNotFound = errors.New("Task doesn't exist")
+
+// ResolveTaskAction finds the task a reference designates among the tasks
+// actor can read. A reference is an ID or a fragment of the name matched
+// fuzzily, an exact name then a fragment found as is win over the looser
+// matches.
+func ResolveTaskAction(db DB, actor User, ref string) (Task, error) {
+    get := func (id int) (Task, error) {
+        task, err := ListTaskActionByID(db, actor, uint(id))
+
+        if err == sql.ErrNoRows {
+            return Task{}, ErrTaskNotFound
+        }
+
+        return task, err
+    }
+    list := func () ([]Task, error) { return ListTasksAction(db, actor, ListTaskProps{}) }
+
+    return ResolveTask(ref, get, list)
+}
+
+// ResolveTask resolves ref like ResolveTaskAction, get returns the task with an
+// ID or ErrTaskNotFound and list the tasks a name is matched against.
+func ResolveTask(ref string, get func (id int) (Task, error), list func () ([]Task, error)) (Task, error) {
+    ref = strings.TrimSpace(ref)
+
+    if id, err := strconv.Atoi(ref); err == nil {
+        if id <= 0 {
+            return Task{}, fmt.Errorf("Invalid task ID %d, IDs start at 1", id)
+        }
+
+        task, err := get(id)
+
+        if errors.Is(err, ErrTaskNotFound) {
+            return Task{}, fmt.Errorf("Task %d doesn't exist", id)
+        }
+
+        return task, err
+    }
+
+    if ref == "" {
+        return Task{}, fmt.Errorf("Expected a task ID or name")
+    }
+
+    tasks, err := list()
+
+    if err != nil {
+        return Task{}, err
+    }
+
+    return MatchTask(tasks, ref)
+}
+
+// MatchTask finds the task whose name ref designates among tasks, see
+// ResolveTaskAction.
+func MatchTask(tasks []Task, ref string) (Task, error) {
+    names := make([]string, len(tasks))
+
+    for idx, task := range tasks {
+        names[idx] = task.Name
+    }
+
+    candidates := make([]Task, 0)
+
+    for _, idx := range fuzzy.Filter(ref, names) {
+        candidates = append(candidates, tasks[idx])
+    }
+
+    if len(candidates) == 0 {
+        return Task{}, fmt.Errorf("No task matches '%s'", ref)
+    }
+
+    lowerRef := strings.ToLower(ref)
+    narrowings := []func (Task) bool{
+        func (task Task) bool { return strings.ToLower(task.Name) == lowerRef },
+        func (task Task) bool { return strings.Contains(strings.ToLower(task.Name), lowerRef) },
+        func (task Task) bool { return true },
+    }
+
+    for _, keep := range narrowings {
+        kept := make([]Task, 0, 1)
+
+        for _, task := range candidates {
+            if keep(task) {
+                kept = append(kept, task)
+            }
+        }
+
+        if len(kept) == 1 {
+            return kept[0], nil
+        }
+
+        if len(kept) > 1 {
+            return Task{}, &AmbiguousTaskError{Ref: ref, Candidates: kept}
+        }
+    }
+
+    return Task{}, fmt.Errorf("No task matches '%s'", ref)
+}
@@ -0,0 +1,280 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+    ScopeRead = "read"
+    ScopeWrite = "write"
+    ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+const TokenPrefix = "gtd_"
+
+var ErrInvalidToken = errors.New("Invalid token")
+var ErrExpiredToken = errors.New("Token expired")
+var ErrRevokedToken = errors.New("Token revoked")
+var ErrTokenWithoutUser = errors.New("Token doesn't belong to a user, create a new one")
+
+type Token struct {
+    ID int
+    Name string
+    // UserID is the user the token acts for, the tokens created before they
+    // belonged to a user have none and are refused.
+    UserID *int
+    Scopes []string
+    ExpiresAt *time.Time
+    RevokedAt *time.Time
+    CreatedAt time.Time
+}
+
+// HasScope reports whether the token grants scope, admin implies write and
+// write implies read.
+func (t Token) HasScope(scope string) bool {
+    for _, granted := range t.Scopes {
+        if granted == scope || granted == ScopeAdmin {
+            return true
+        }
+        if granted == ScopeWrite && scope == ScopeRead {
+            return true
+        }
+    }
+    return false
+}
+
+type AddTokenProp struct {
+    Name string
+    UserID int
+    Scopes []string
+    ExpiresAt *time.Time
+}
+
+const TOKEN_COLUMNS = "id, name, user_id, scopes, expires_at, revoked_at, created_at"
+
+const ADD_TOKEN_SQL = "INSERT INTO tokens (name,user_id,hash,scopes,expires_at) VALUES ($1,$2,$3,$4,$5) RETURNING " + TOKEN_COLUMNS + ";"
+
+// AddTokenAction returns the created token along with its plain text value,
+// only the hash of the value is stored so it can't be shown again.
+func AddTokenAction(db DB, props AddTokenProp) (Token, string, error) {
+    if len(props.Scopes) == 0 {
+        return Token{}, "", errors.New("Token must have at least one scope")
+    }
+
+    if props.UserID == 0 {
+        return Token{}, "", errors.New("Token must belong to a user")
+    }
+
+    buff := make([]byte, 32)
+
+    if _, err := rand.Read(buff); err != nil {
+        return Token{}, "", err
+    }
+
+    value := TokenPrefix + hex.EncodeToString(buff)
+
+    var expiresAt any
+    if props.ExpiresAt != nil {
+        expiresAt = props.ExpiresAt.UTC()
+    }
+
+    row := queryRow(db, ADD_TOKEN_SQL, props.Name, props.UserID, HashToken(value), strings.Join(props.Scopes, ","), expiresAt)
+
+    token, err := scanToken(row)
+
+    if err != nil {
+        return Token{}, "", err
+    }
+
+    return token, value, nil
+}
+
+func HashToken(value string) string {
+    sum := sha256.Sum256([]byte(value))
+    return hex.EncodeToString(sum[:])
+}
+
+const LIST_TOKENS_SQL = "SELECT " + TOKEN_COLUMNS + " FROM tokens ORDER BY id;"
+
+func ListTokensAction(db DB) ([]Token, error) {
+    rows, err := db.Query(LIST_TOKENS_SQL)
+
+    if err != nil {
+        return []Token{}, err
+    }
+    defer rows.Close()
+
+    tokens := make([]Token, 0)
+
+    for rows.Next() {
+        token, scanErr := scanToken(rows)
+        if scanErr != nil {
+            return []Token{}, scanErr
+        }
+        tokens = append(tokens, token)
+    }
+
+    return tokens, rows.Err()
+}
+
+const REVOKE_TOKEN_SQL = "UPDATE tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL;"
+
+func RevokeTokenAction(db DB, ID int) error {
+    result, err := db.Exec(REVOKE_TOKEN_SQL, ID)
+
+    if err != nil {
+        return err
+    }
+
+    updateCount, err := result.RowsAffected()
+
+    if err != nil {
+        return err
+    }
+
+    if updateCount == 0 {
+        return errors.New("Token doesn't exist or is already revoked")
+    }
+
+    return nil
+}
+
+const GET_TOKEN_HASH_SQL = "SELECT " + TOKEN_COLUMNS + " FROM tokens WHERE hash = $1;"
+
+// AuthenticateTokenAction looks up the token matching the plain text value
+// and fails if it is unknown, revoked, expired or doesn't belong to a user.
+func AuthenticateTokenAction(db DB, value string) (Token, error) {
+    token, err := scanToken(queryRow(db, GET_TOKEN_HASH_SQL, HashToken(value)))
+
+    if err == sql.ErrNoRows {
+        return Token{}, ErrInvalidToken
+    }
+
+    if err != nil {
+        return Token{}, err
+    }
+
+    if token.RevokedAt != nil {
+        return Token{}, ErrRevokedToken
+    }
+
+    if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+        return Token{}, ErrExpiredToken
+    }
+
+    if token.UserID == nil {
+        return Token{}, ErrTokenWithoutUser
+    }
+
+    return token, nil
+}
+
+type TokenAudit struct {
+    ID int
+    TokenID int
+    Method string
+    Path string
+    Status int
+    CreatedAt time.Time
+}
+
+type AddTokenAuditProp struct {
+    TokenID int
+    Method string
+    Path string
+    Status int
+}
+
+const ADD_TOKEN_AUDIT_SQL = "INSERT INTO token_audit (token_id,method,path,status) VALUES ($1,$2,$3,$4) RETURNING id;"
+
+// AddTokenAuditAction returns the ID of the entry, a request is audited
+// before it's served with a status of 0, see SetTokenAuditStatusAction.
+func AddTokenAuditAction(db DB, props AddTokenAuditProp) (int, error) {
+    var ID int
+    err := queryRow(db, ADD_TOKEN_AUDIT_SQL, props.TokenID, props.Method, props.Path, props.Status).Scan(&ID)
+    return ID, err
+}
+
+const SET_TOKEN_AUDIT_STATUS_SQL = "UPDATE token_audit SET status = $1 WHERE id = $2;"
+
+func SetTokenAuditStatusAction(db DB, ID int, status int) error {
+    _, err := db.Exec(SET_TOKEN_AUDIT_STATUS_SQL, status, ID)
+    return err
+}
+
+const LIST_TOKEN_AUDIT_SQL = "SELECT id, token_id, method, path, status, created_at FROM token_audit ORDER BY id;"
+
+func ListTokenAuditAction(db DB) ([]TokenAudit, error) {
+    rows, err := db.Query(LIST_TOKEN_AUDIT_SQL)
+
+    if err != nil {
+        return []TokenAudit{}, err
+    }
+    defer rows.Close()
+
+    audit := make([]TokenAudit, 0)
+
+    for rows.Next() {
+        entry := TokenAudit{}
+        scanErr := rows.Scan(
+            &entry.ID,
+            &entry.TokenID,
+            &entry.Method,
+            &entry.Path,
+            &entry.Status,
+            &entry.CreatedAt,
+        )
+        if scanErr != nil {
+            return []TokenAudit{}, scanErr
+        }
+        audit = append(audit, entry)
+    }
+
+    return audit, rows.Err()
+}
+
+func scanToken(row scanner) (Token, error) {
+    token := Token{}
+    var scopes string
+    var userID sql.NullInt64
+    var expiresAt, revokedAt sql.NullTime
+
+    err := row.Scan(
+        &token.ID,
+        &token.Name,
+        &userID,
+        &scopes,
+        &expiresAt,
+        &revokedAt,
+        &token.CreatedAt,
+    )
+
+    if err != nil {
+        return Token{}, err
+    }
+
+    token.Scopes = strings.Split(scopes, ",")
+
+    if userID.Valid {
+        id := int(userID.Int64)
+        token.UserID = &id
+    }
+
+    if expiresAt.Valid {
+        token.ExpiresAt = &expiresAt.Time
+    }
+
+    if revokedAt.Valid {
+        token.RevokedAt = &revokedAt.Time
+    }
+
+    return token, nil
+}
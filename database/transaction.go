@@ -0,0 +1,75 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Tx is a transaction, or a savepoint of the transaction it was begun in.
+type Tx struct {
+    DB
+    commit func () error
+    rollback func () error
+}
+
+// Begin starts a transaction on db, or a savepoint when db is already a
+// transaction.
+func Begin(db DB) (*Tx, error) {
+    if beginner, ok := db.(interface{ Begin() (*sql.Tx, error) }); ok {
+        tx, err := beginner.Begin()
+
+        if err != nil {
+            return nil, err
+        }
+
+        return &Tx{DB: tx, commit: tx.Commit, rollback: tx.Rollback}, nil
+    }
+
+    if db == nil {
+        return nil, errors.New("The connection doesn't support transactions")
+    }
+
+    if _, err := db.Exec("SAVEPOINT go_todo"); err != nil {
+        return nil, err
+    }
+
+    return &Tx{
+        DB: db,
+        commit: func () error {
+            _, err := db.Exec("RELEASE go_todo")
+            return err
+        },
+        rollback: func () error {
+            if _, err := db.Exec("ROLLBACK TO go_todo"); err != nil {
+                return err
+            }
+            _, err := db.Exec("RELEASE go_todo")
+            return err
+        },
+    }, nil
+}
+
+func (t *Tx) Commit() error {
+    return t.commit()
+}
+
+func (t *Tx) Rollback() error {
+    return t.rollback()
+}
+
+// InTransaction runs fn in a transaction committed when it succeeds and
+// rolled back otherwise, in a savepoint when db is already a transaction.
+func InTransaction(db DB, fn func (DB) error) error {
+    tx, err := Begin(db)
+
+    if err != nil {
+        return err
+    }
+
+    if err := fn(tx.DB); err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    return tx.Commit()
+}
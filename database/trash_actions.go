@@ -0,0 +1,69 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestoreTaskBulkAction takes tasks out of the trash.
+const RESTORE_TRASHED_TASKS_SQL = "UPDATE tasks SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE ID IN (%s) AND deleted_at IS NOT NULL AND %s RETURNING " + TASK_COLUMNS + ";"
+const LIST_TRASHED_TASKS_SQL = "SELECT " + TASK_COLUMNS + " FROM tasks WHERE ID IN (%s) AND deleted_at IS NOT NULL AND %s ORDER BY id;"
+func RestoreTaskBulkAction(db DB, actor User, IDs []int) (int, error) {
+    condition := fmt.Sprintf(CAN_DELETE_TASK_SQL, "$1")
+    count := 0
+
+    err := InTransaction(db, func (tx DB) error {
+        trashed, err := queryTasks(tx, fmt.Sprintf(LIST_TRASHED_TASKS_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil || len(trashed) == 0 {
+            return err
+        }
+
+        restored, err := queryTasks(tx, fmt.Sprintf(RESTORE_TRASHED_TASKS_SQL, idList(IDs), condition), actor.ID)
+
+        if err != nil {
+            return err
+        }
+
+        count = len(restored)
+
+        return recordOperation(tx, actor, OperationRestore, trashed, restored)
+    })
+
+    if err != nil {
+        return 0, err
+    }
+
+    return count, nil
+}
+
+// PurgeTrashAction deletes for good the tasks moved to the trash before
+// the given time, or all of them when it's zero.
+const PURGE_TRASH_SQL = "DELETE FROM tasks WHERE deleted_at IS NOT NULL AND ($1 IS NULL OR deleted_at < $1) AND %s RETURNING " + TASK_COLUMNS + ";"
+func PurgeTrashAction(db DB, actor User, before time.Time) (int, error) {
+    var cutoff any
+
+    if !before.IsZero() {
+        cutoff = before.UTC()
+    }
+
+    count := 0
+
+    err := InTransaction(db, func (tx DB) error {
+        purged, err := queryTasks(tx, fmt.Sprintf(PURGE_TRASH_SQL, fmt.Sprintf(CAN_DELETE_TASK_SQL, "$2")), cutoff, actor.ID)
+
+        if err != nil || len(purged) == 0 {
+            return err
+        }
+
+        count = len(purged)
+
+        return recordOperation(tx, actor, OperationPurge, purged, nil)
+    })
+
+    if err != nil {
+        return 0, err
+    }
+
+    return count, nil
+}
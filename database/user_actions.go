@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+    PermissionRead = "read"
+    PermissionWrite = "write"
+)
+
+var ErrPermissionDenied = errors.New("Permission denied")
+
+type User struct {
+    ID int `json:"id"`
+    Name string `json:"name"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+const ADD_USER_SQL = "INSERT INTO users (name) VALUES ($1) RETURNING id, name, created_at;"
+
+func AddUserAction(db DB, name string) (User, error) {
+    if name == "" {
+        return User{}, errors.New("User name can't be empty")
+    }
+
+    return scanUser(queryRow(db, ADD_USER_SQL, name))
+}
+
+const GET_USER_NAME_SQL = "SELECT id, name, created_at FROM users WHERE name = $1;"
+
+func ListUserActionByName(db DB, name string) (User, error) {
+    user, err := scanUser(queryRow(db, GET_USER_NAME_SQL, name))
+
+    if err == sql.ErrNoRows {
+        return User{}, errors.New("User doesn't exist")
+    }
+
+    return user, err
+}
+
+const GET_USER_ID_SQL = "SELECT id, name, created_at FROM users WHERE id = $1;"
+
+func ListUserActionByID(db DB, ID int) (User, error) {
+    user, err := scanUser(queryRow(db, GET_USER_ID_SQL, ID))
+
+    if err == sql.ErrNoRows {
+        return User{}, errors.New("User doesn't exist")
+    }
+
+    return user, err
+}
+
+// EnsureUserAction returns the user with the provided name creating it on
+// its first use. Two processes may create it at once, the insert leaves the
+// user created by the other one alone.
+const ENSURE_USER_SQL = "INSERT INTO users (name) VALUES ($1) ON CONFLICT (name) DO NOTHING;"
+func EnsureUserAction(db DB, name string) (User, error) {
+    user, err := scanUser(queryRow(db, GET_USER_NAME_SQL, name))
+
+    if err != sql.ErrNoRows {
+        return user, err
+    }
+
+    if name == "" {
+        return User{}, errors.New("User name can't be empty")
+    }
+
+    if _, err := db.Exec(ENSURE_USER_SQL, name); err != nil {
+        return User{}, err
+    }
+
+    return scanUser(queryRow(db, GET_USER_NAME_SQL, name))
+}
+
+const LIST_USERS_SQL = "SELECT id, name, created_at FROM users ORDER BY id;"
+
+func ListUsersAction(db DB) ([]User, error) {
+    rows, err := db.Query(LIST_USERS_SQL)
+
+    if err != nil {
+        return []User{}, err
+    }
+    defer rows.Close()
+
+    users := make([]User, 0)
+
+    for rows.Next() {
+        user, scanErr := scanUser(rows)
+        if scanErr != nil {
+            return []User{}, scanErr
+        }
+        users = append(users, user)
+    }
+
+    return users, rows.Err()
+}
+
+// Share grants User access to the tasks of the owner in Project, or to all of
+// them when Project is empty.
+type Share struct {
+    OwnerID int
+    User User
+    Permission string
+    Project string
+}
+
+const SHARE_TASKS_SQL = "INSERT INTO shares (owner_id,user_id,permission,project) VALUES ($1,$2,$3,$4) ON CONFLICT (owner_id,user_id,project) DO UPDATE SET permission = excluded.permission;"
+
+// ShareTasksAction grants user access to the tasks owned by owner in project,
+// or to every one of them when project is empty.
+func ShareTasksAction(db DB, owner User, user User, permission string, project string) error {
+    if permission != PermissionRead && permission != PermissionWrite {
+        return errors.New("Permission must be read or write")
+    }
+
+    if owner.ID == user.ID {
+        return errors.New("Can't share tasks with their owner")
+    }
+
+    _, err := db.Exec(SHARE_TASKS_SQL, owner.ID, user.ID, permission, project)
+    return err
+}
+
+const UNSHARE_TASKS_SQL = "DELETE FROM shares WHERE owner_id = $1 AND user_id = $2 AND ($3 = '' OR project = $3);"
+
+// UnshareTasksAction revokes the share of project with user, an empty project
+// revokes all of them.
+func UnshareTasksAction(db DB, owner User, user User, project string) error {
+    result, err := db.Exec(UNSHARE_TASKS_SQL, owner.ID, user.ID, project)
+
+    if err != nil {
+        return err
+    }
+
+    delCount, err := result.RowsAffected()
+
+    if err != nil {
+        return err
+    }
+
+    if delCount == 0 {
+        return errors.New("Tasks aren't shared with this user")
+    }
+
+    return nil
+}
+
+const LIST_SHARES_SQL = "SELECT s.owner_id, u.id, u.name, u.created_at, s.permission, s.project FROM shares s JOIN users u ON u.id = s.user_id WHERE s.owner_id = $1 ORDER BY u.name, s.project;"
+
+func ListSharesAction(db DB, owner User) ([]Share, error) {
+    rows, err := db.Query(LIST_SHARES_SQL, owner.ID)
+
+    if err != nil {
+        return []Share{}, err
+    }
+    defer rows.Close()
+
+    shares := make([]Share, 0)
+
+    for rows.Next() {
+        share := Share{}
+        scanErr := rows.Scan(
+            &share.OwnerID,
+            &share.User.ID,
+            &share.User.Name,
+            &share.User.CreatedAt,
+            &share.Permission,
+            &share.Project,
+        )
+        if scanErr != nil {
+            return []Share{}, scanErr
+        }
+        shares = append(shares, share)
+    }
+
+    return shares, rows.Err()
+}
+
+func scanUser(row scanner) (User, error) {
+    user := User{}
+
+    err := row.Scan(
+        &user.ID,
+        &user.Name,
+        &user.CreatedAt,
+    )
+
+    if err != nil {
+        return User{}, err
+    }
+
+    return user, nil
+}
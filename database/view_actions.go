@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ViewFormats are the output formats of go_todo list, empty is the default.
+var ViewFormats = []string{"text", "json", "ids"}
+
+var viewNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// View is a list of tasks saved under a name by its owner. Filter and Sort
+// are read by the filter package.
+type View struct {
+    ID int `json:"id"`
+    Name string `json:"name"`
+    Filter string `json:"filter,omitempty"`
+    Sort string `json:"sort,omitempty"`
+    Format string `json:"format,omitempty"`
+}
+
+const VIEW_COLUMNS = "id, name, filter, sort, format"
+
+const SAVE_VIEW_SQL = `INSERT INTO views (owner_id, name, filter, sort, format) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (owner_id, name) DO UPDATE SET filter = excluded.filter, sort = excluded.sort, format = excluded.format
+RETURNING ` + VIEW_COLUMNS + ";"
+
+// SaveViewAction creates the view or replaces the one with the same name.
+func SaveViewAction(db DB, actor User, view View) (View, error) {
+    if !viewNamePattern.MatchString(view.Name) {
+        return View{}, fmt.Errorf("Invalid view name '%s', use letters, digits, - and _", view.Name)
+    }
+
+    if view.Format != "" && !slices.Contains(ViewFormats, view.Format) {
+        return View{}, fmt.Errorf("Invalid format '%s', expected %s", view.Format, strings.Join(ViewFormats, "|"))
+    }
+
+    return scanView(queryRow(db, SAVE_VIEW_SQL, actor.ID, view.Name, view.Filter, view.Sort, view.Format))
+}
+
+const LIST_VIEWS_SQL = "SELECT " + VIEW_COLUMNS + " FROM views WHERE owner_id = $1 ORDER BY name;"
+
+func ListViewsAction(db DB, actor User) ([]View, error) {
+    rows, err := db.Query(LIST_VIEWS_SQL, actor.ID)
+
+    if err != nil {
+        return []View{}, err
+    }
+    defer rows.Close()
+
+    views := make([]View, 0)
+
+    for rows.Next() {
+        view, scanErr := scanView(rows)
+        if scanErr != nil {
+            return []View{}, scanErr
+        }
+        views = append(views, view)
+    }
+
+    return views, rows.Err()
+}
+
+const GET_VIEW_NAME_SQL = "SELECT " + VIEW_COLUMNS + " FROM views WHERE owner_id = $1 AND name = $2;"
+
+func ListViewActionByName(db DB, actor User, name string) (View, error) {
+    view, err := scanView(queryRow(db, GET_VIEW_NAME_SQL, actor.ID, name))
+
+    if err == sql.ErrNoRows {
+        return View{}, fmt.Errorf("View '%s' doesn't exist", name)
+    }
+
+    return view, err
+}
+
+const DELETE_VIEW_SQL = "DELETE FROM views WHERE owner_id = $1 AND name = $2;"
+
+func DeleteViewAction(db DB, actor User, name string) error {
+    result, err := db.Exec(DELETE_VIEW_SQL, actor.ID, name)
+
+    if err != nil {
+        return err
+    }
+
+    if count, _ := result.RowsAffected(); count == 0 {
+        return fmt.Errorf("View '%s' doesn't exist", name)
+    }
+
+    return nil
+}
+
+func scanView(row scanner) (View, error) {
+    view := View{}
+    err := row.Scan(&view.ID, &view.Name, &view.Filter, &view.Sort, &view.Format)
+
+    if err != nil {
+        return View{}, err
+    }
+
+    return view, nil
+}
@@ -0,0 +1,450 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrWebhookNotFound = errors.New("Webhook doesn't exist")
+
+type Webhook struct {
+    ID int
+    URL string
+    Secret string
+    Events []string
+}
+
+func (w Webhook) Subscribed(event string) bool {
+    for _, subscribed := range w.Events {
+        if subscribed == event {
+            return true
+        }
+    }
+    return false
+}
+
+type AddWebhookProp struct {
+    URL string
+    Secret string
+    Events []string
+}
+
+const ADD_WEBHOOK_SQL = "INSERT INTO webhooks (url,secret,events) VALUES ($1,$2,$3) RETURNING id, url, secret, events;"
+
+func AddWebhookAction(db DB, props AddWebhookProp) (Webhook, error) {
+    if len(props.Events) == 0 {
+        return Webhook{}, errors.New("Webhook must subscribe to at least one event")
+    }
+
+    row := queryRow(db, ADD_WEBHOOK_SQL, props.URL, props.Secret, strings.Join(props.Events, ","))
+
+    return scanWebhook(row)
+}
+
+const LIST_WEBHOOKS_SQL = "SELECT id, url, secret, events FROM webhooks ORDER BY id;"
+
+func ListWebhooksAction(db DB) ([]Webhook, error) {
+    rows, err := db.Query(LIST_WEBHOOKS_SQL)
+
+    if err != nil {
+        return []Webhook{}, err
+    }
+    defer rows.Close()
+
+    webhooks := make([]Webhook, 0)
+
+    for rows.Next() {
+        webhook, scanErr := scanWebhook(rows)
+        if scanErr != nil {
+            return []Webhook{}, scanErr
+        }
+        webhooks = append(webhooks, webhook)
+    }
+
+    return webhooks, rows.Err()
+}
+
+func ListWebhooksForEventAction(db DB, event string) ([]Webhook, error) {
+    webhooks, err := ListWebhooksAction(db)
+
+    if err != nil {
+        return []Webhook{}, err
+    }
+
+    subscribed := make([]Webhook, 0)
+
+    for _, webhook := range webhooks {
+        if webhook.Subscribed(event) {
+            subscribed = append(subscribed, webhook)
+        }
+    }
+
+    return subscribed, nil
+}
+
+const LIST_WEBHOOK_ID_SQL = "SELECT id, url, secret, events FROM webhooks WHERE id = $1;"
+
+func ListWebhookActionByID(db DB, ID int) (Webhook, error) {
+    webhook, err := scanWebhook(queryRow(db, LIST_WEBHOOK_ID_SQL, ID))
+
+    if errors.Is(err, sql.ErrNoRows) {
+        return Webhook{}, ErrWebhookNotFound
+    }
+
+    if err != nil {
+        return Webhook{}, err
+    }
+
+    return webhook, nil
+}
+
+const DELETE_WEBHOOK_SQL = "DELETE FROM webhooks WHERE id = $1;"
+const DELETE_WEBHOOK_DELIVERIES_SQL = "DELETE FROM webhook_deliveries WHERE webhook_id = $1;"
+const DELETE_WEBHOOK_DEAD_LETTERS_SQL = "DELETE FROM webhook_dead_letters WHERE webhook_id = $1;"
+const DELETE_WEBHOOK_QUEUE_SQL = "DELETE FROM webhook_queue WHERE webhook_id = $1;"
+
+func DeleteWebhookAction(db DB, ID int) error {
+    for _, query := range []string{DELETE_WEBHOOK_DELIVERIES_SQL, DELETE_WEBHOOK_DEAD_LETTERS_SQL, DELETE_WEBHOOK_QUEUE_SQL} {
+        if _, err := db.Exec(query, ID); err != nil {
+            return err
+        }
+    }
+
+    result, err := db.Exec(DELETE_WEBHOOK_SQL, ID)
+
+    if err != nil {
+        return err
+    }
+
+    delCount, err := result.RowsAffected()
+
+    if err != nil {
+        return err
+    }
+
+    if delCount == 0 {
+        return ErrWebhookNotFound
+    }
+
+    return nil
+}
+
+type WebhookDelivery struct {
+    ID int
+    WebhookID int
+    Event string
+    Payload string
+    StatusCode int
+    Attempts int
+    DeliveredAt time.Time
+}
+
+type AddWebhookDeliveryProp struct {
+    WebhookID int
+    Event string
+    Payload string
+    StatusCode int
+    Attempts int
+}
+
+const ADD_WEBHOOK_DELIVERY_SQL = "INSERT INTO webhook_deliveries (webhook_id,event,payload,status_code,attempts) VALUES ($1,$2,$3,$4,$5) RETURNING id, webhook_id, event, payload, status_code, attempts, delivered_at;"
+
+func AddWebhookDeliveryAction(db DB, props AddWebhookDeliveryProp) (WebhookDelivery, error) {
+    row := queryRow(db, ADD_WEBHOOK_DELIVERY_SQL, props.WebhookID, props.Event, props.Payload, props.StatusCode, props.Attempts)
+    delivery := WebhookDelivery{}
+
+    err := row.Scan(
+        &delivery.ID,
+        &delivery.WebhookID,
+        &delivery.Event,
+        &delivery.Payload,
+        &delivery.StatusCode,
+        &delivery.Attempts,
+        &delivery.DeliveredAt,
+    )
+
+    if err != nil {
+        return WebhookDelivery{}, err
+    }
+
+    return delivery, nil
+}
+
+const LIST_WEBHOOK_DELIVERIES_SQL = "SELECT id, webhook_id, event, payload, status_code, attempts, delivered_at FROM webhook_deliveries ORDER BY id;"
+
+func ListWebhookDeliveriesAction(db DB) ([]WebhookDelivery, error) {
+    rows, err := db.Query(LIST_WEBHOOK_DELIVERIES_SQL)
+
+    if err != nil {
+        return []WebhookDelivery{}, err
+    }
+    defer rows.Close()
+
+    deliveries := make([]WebhookDelivery, 0)
+
+    for rows.Next() {
+        delivery := WebhookDelivery{}
+        scanErr := rows.Scan(
+            &delivery.ID,
+            &delivery.WebhookID,
+            &delivery.Event,
+            &delivery.Payload,
+            &delivery.StatusCode,
+            &delivery.Attempts,
+            &delivery.DeliveredAt,
+        )
+        if scanErr != nil {
+            return []WebhookDelivery{}, scanErr
+        }
+        deliveries = append(deliveries, delivery)
+    }
+
+    return deliveries, rows.Err()
+}
+
+type WebhookDeadLetter struct {
+    ID int
+    WebhookID int
+    Event string
+    Payload string
+    LastError string
+    Attempts int
+    FailedAt time.Time
+}
+
+type AddWebhookDeadLetterProp struct {
+    WebhookID int
+    Event string
+    Payload string
+    LastError string
+    Attempts int
+}
+
+const ADD_WEBHOOK_DEAD_LETTER_SQL = "INSERT INTO webhook_dead_letters (webhook_id,event,payload,last_error,attempts) VALUES ($1,$2,$3,$4,$5) RETURNING id, webhook_id, event, payload, last_error, attempts, failed_at;"
+
+func AddWebhookDeadLetterAction(db DB, props AddWebhookDeadLetterProp) (WebhookDeadLetter, error) {
+    row := queryRow(db, ADD_WEBHOOK_DEAD_LETTER_SQL, props.WebhookID, props.Event, props.Payload, props.LastError, props.Attempts)
+
+    return scanWebhookDeadLetter(row)
+}
+
+const LIST_WEBHOOK_DEAD_LETTERS_SQL = "SELECT id, webhook_id, event, payload, last_error, attempts, failed_at FROM webhook_dead_letters ORDER BY id;"
+
+func ListWebhookDeadLettersAction(db DB) ([]WebhookDeadLetter, error) {
+    rows, err := db.Query(LIST_WEBHOOK_DEAD_LETTERS_SQL)
+
+    if err != nil {
+        return []WebhookDeadLetter{}, err
+    }
+    defer rows.Close()
+
+    deadLetters := make([]WebhookDeadLetter, 0)
+
+    for rows.Next() {
+        deadLetter, scanErr := scanWebhookDeadLetter(rows)
+        if scanErr != nil {
+            return []WebhookDeadLetter{}, scanErr
+        }
+        deadLetters = append(deadLetters, deadLetter)
+    }
+
+    return deadLetters, rows.Err()
+}
+
+const LIST_WEBHOOK_DEAD_LETTER_ID_SQL = "SELECT id, webhook_id, event, payload, last_error, attempts, failed_at FROM webhook_dead_letters WHERE id = $1;"
+
+func ListWebhookDeadLetterActionByID(db DB, ID int) (WebhookDeadLetter, error) {
+    deadLetter, err := scanWebhookDeadLetter(queryRow(db, LIST_WEBHOOK_DEAD_LETTER_ID_SQL, ID))
+
+    if err != nil {
+        return WebhookDeadLetter{}, errors.New("Dead letter doesn't exist")
+    }
+
+    return deadLetter, nil
+}
+
+const DELETE_WEBHOOK_DEAD_LETTER_SQL = "DELETE FROM webhook_dead_letters WHERE id = $1;"
+
+func DeleteWebhookDeadLetterAction(db DB, ID int) error {
+    _, err := db.Exec(DELETE_WEBHOOK_DEAD_LETTER_SQL, ID)
+    return err
+}
+
+// FAIL_WEBHOOK_DEAD_LETTER_SQL records one more failed delivery of a dead
+// letter, e.g. a replay.
+const FAIL_WEBHOOK_DEAD_LETTER_SQL = "UPDATE webhook_dead_letters SET attempts = attempts + 1, last_error = $1, failed_at = CURRENT_TIMESTAMP WHERE id = $2;"
+
+func FailWebhookDeadLetterAction(db DB, ID int, lastError string) error {
+    _, err := db.Exec(FAIL_WEBHOOK_DEAD_LETTER_SQL, lastError, ID)
+    return err
+}
+
+// QueuedWebhookEvent is an event waiting to be delivered to a webhook, the
+// deliveries happen outside the commands changing the tasks.
+type QueuedWebhookEvent struct {
+    ID int
+    WebhookID int
+    Event string
+    Payload string
+    // Attempts is the number of failed deliveries so far.
+    Attempts int
+    LastError string
+    NextAttemptAt time.Time
+}
+
+type AddQueuedWebhookEventProp struct {
+    WebhookID int
+    Event string
+    Payload string
+    NextAttemptAt time.Time
+}
+
+const QUEUED_WEBHOOK_EVENT_COLUMNS = "id, webhook_id, event, payload, attempts, last_error, next_attempt_at"
+
+const ADD_QUEUED_WEBHOOK_EVENT_SQL = "INSERT INTO webhook_queue (webhook_id,event,payload,next_attempt_at) VALUES ($1,$2,$3,$4) RETURNING " + QUEUED_WEBHOOK_EVENT_COLUMNS + ";"
+
+func AddQueuedWebhookEventAction(db DB, props AddQueuedWebhookEventProp) (QueuedWebhookEvent, error) {
+    row := queryRow(db, ADD_QUEUED_WEBHOOK_EVENT_SQL, props.WebhookID, props.Event, props.Payload, queueTime(props.NextAttemptAt))
+
+    return scanQueuedWebhookEvent(row)
+}
+
+const LIST_QUEUED_WEBHOOK_EVENTS_SQL = "SELECT " + QUEUED_WEBHOOK_EVENT_COLUMNS + " FROM webhook_queue ORDER BY id;"
+const LIST_DUE_WEBHOOK_EVENTS_SQL = "SELECT " + QUEUED_WEBHOOK_EVENT_COLUMNS + " FROM webhook_queue WHERE next_attempt_at <= $1 ORDER BY id LIMIT $2;"
+
+func ListQueuedWebhookEventsAction(db DB) ([]QueuedWebhookEvent, error) {
+    return listQueuedWebhookEvents(db, LIST_QUEUED_WEBHOOK_EVENTS_SQL)
+}
+
+// ListDueWebhookEventsAction lists at most limit events whose next delivery
+// is due at now.
+func ListDueWebhookEventsAction(db DB, now time.Time, limit int) ([]QueuedWebhookEvent, error) {
+    return listQueuedWebhookEvents(db, LIST_DUE_WEBHOOK_EVENTS_SQL, queueTime(now), limit)
+}
+
+func listQueuedWebhookEvents(db DB, query string, args ...any) ([]QueuedWebhookEvent, error) {
+    rows, err := db.Query(query, args...)
+
+    if err != nil {
+        return []QueuedWebhookEvent{}, err
+    }
+    defer rows.Close()
+
+    events := make([]QueuedWebhookEvent, 0)
+
+    for rows.Next() {
+        event, scanErr := scanQueuedWebhookEvent(rows)
+        if scanErr != nil {
+            return []QueuedWebhookEvent{}, scanErr
+        }
+        events = append(events, event)
+    }
+
+    return events, rows.Err()
+}
+
+const CLAIM_QUEUED_WEBHOOK_EVENT_SQL = "UPDATE webhook_queue SET next_attempt_at = $1 WHERE id = $2 AND next_attempt_at <= $3;"
+
+// ClaimQueuedWebhookEventAction postpones a due event to until, telling
+// whether it was still due. The processes delivering the queue claim the
+// events first so that only one of them delivers each, an event whose
+// delivery was interrupted is due again at until.
+func ClaimQueuedWebhookEventAction(db DB, ID int, now time.Time, until time.Time) (bool, error) {
+    result, err := db.Exec(CLAIM_QUEUED_WEBHOOK_EVENT_SQL, queueTime(until), ID, queueTime(now))
+
+    if err != nil {
+        return false, err
+    }
+
+    count, err := result.RowsAffected()
+
+    return count == 1, err
+}
+
+const RETRY_QUEUED_WEBHOOK_EVENT_SQL = "UPDATE webhook_queue SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2 WHERE id = $3;"
+
+// RetryQueuedWebhookEventAction records a failed delivery, the next one is
+// at next.
+func RetryQueuedWebhookEventAction(db DB, ID int, lastError string, next time.Time) error {
+    _, err := db.Exec(RETRY_QUEUED_WEBHOOK_EVENT_SQL, lastError, queueTime(next), ID)
+    return err
+}
+
+const DELETE_QUEUED_WEBHOOK_EVENT_SQL = "DELETE FROM webhook_queue WHERE id = $1;"
+
+func DeleteQueuedWebhookEventAction(db DB, ID int) error {
+    _, err := db.Exec(DELETE_QUEUED_WEBHOOK_EVENT_SQL, ID)
+    return err
+}
+
+// queueTime is stored in UTC to the second, SQLite compares the times of the
+// queue as text.
+func queueTime(at time.Time) time.Time {
+    return at.UTC().Truncate(time.Second)
+}
+
+type scanner interface {
+    Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (Webhook, error) {
+    webhook := Webhook{}
+    var events string
+
+    err := row.Scan(
+        &webhook.ID,
+        &webhook.URL,
+        &webhook.Secret,
+        &events,
+    )
+
+    if err != nil {
+        return Webhook{}, err
+    }
+
+    webhook.Events = strings.Split(events, ",")
+
+    return webhook, nil
+}
+
+func scanWebhookDeadLetter(row scanner) (WebhookDeadLetter, error) {
+    deadLetter := WebhookDeadLetter{}
+
+    err := row.Scan(
+        &deadLetter.ID,
+        &deadLetter.WebhookID,
+        &deadLetter.Event,
+        &deadLetter.Payload,
+        &deadLetter.LastError,
+        &deadLetter.Attempts,
+        &deadLetter.FailedAt,
+    )
+
+    if err != nil {
+        return WebhookDeadLetter{}, err
+    }
+
+    return deadLetter, nil
+}
+
+func scanQueuedWebhookEvent(row scanner) (QueuedWebhookEvent, error) {
+    event := QueuedWebhookEvent{}
+
+    err := row.Scan(
+        &event.ID,
+        &event.WebhookID,
+        &event.Event,
+        &event.Payload,
+        &event.Attempts,
+        &event.LastError,
+        &event.NextAttemptAt,
+    )
+
+    if err != nil {
+        return QueuedWebhookEvent{}, err
+    }
+
+    return event, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+)
+
+func doctorCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "doctor",
+        Summary: "Check the health of the database, e.g. after a crash",
+        Flags: []cli.Flag{
+            {Name: "fix", Kind: cli.Switch, Usage: "fix the problems that can be fixed safely"},
+        },
+        Run: func (ctx *cli.Context) error { return runDoctor(db, ctx) },
+    }
+}
+
+func runDoctor(db database.DB, ctx *cli.Context) error {
+    fix, _ := ctx.Bool("fix")
+    tx, err := beginTransaction(db)
+
+    if err != nil {
+        return err
+    }
+
+    problems, err := database.DoctorAction(tx, fix)
+
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+
+    fixable, fixed := 0, 0
+
+    for _, check := range database.DoctorChecks {
+        found := make([]database.Problem, 0)
+
+        for _, problem := range problems {
+            if problem.Check == check {
+                found = append(found, problem)
+            }
+        }
+
+        if len(found) == 0 {
+            fmt.Printf("%s: ok\n", check)
+            continue
+        }
+
+        fmt.Printf("%s: %d problems\n", check, len(found))
+
+        for _, problem := range found {
+            switch {
+            case problem.Fixed:
+                fixed++
+                fmt.Printf("  %s, fixed: %s\n", problem.Description, problem.Fix)
+            case problem.Fix != "":
+                fixable++
+                fmt.Printf("  %s, fix: %s\n", problem.Description, problem.Fix)
+            default:
+                fmt.Printf("  %s\n", problem.Description)
+            }
+        }
+    }
+
+    if fixed > 0 {
+        fmt.Printf("Fixed %d problems.\n", fixed)
+    }
+
+    if fixable > 0 {
+        fmt.Printf("Run doctor -fix to fix %d problems.\n", fixable)
+    }
+
+    if left := len(problems) - fixed - fixable; left > 0 {
+        fmt.Printf("%d problems can't be fixed here, run the migrations or restore a backup.\n", left)
+    }
+
+    return nil
+}
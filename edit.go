@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/bulkedit"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/webhook"
+	"os"
+	"os/exec"
+	"time"
+)
+
+func editCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "edit",
+        Aliases: []string{"e"},
+        Summary: "Edit tasks in $EDITOR, the changes are saved together",
+        Flags: taskFilterFlags(db),
+        Run: func (ctx *cli.Context) error { return editTasks(db, ctx) },
+    }
+}
+
+func editTasks(db database.DB, ctx *cli.Context) error {
+    props, err := taskFilterProps(db, ctx)
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    tasks, err := database.ListTasksAction(db, actor, props)
+
+    if err != nil {
+        return err
+    }
+
+    names := userNames(db)
+    path, err := writeTempFile(bulkedit.Render(tasks, names))
+
+    if err != nil {
+        return err
+    }
+
+    if err := runEditor(path); err != nil {
+        os.Remove(path)
+        return fmt.Errorf("the editor failed, %w", err)
+    }
+
+    content, err := os.ReadFile(path)
+
+    if err != nil {
+        return err
+    }
+
+    changes, err := diffEdits(tasks, names, string(content))
+
+    if err == nil {
+        err = applyEdits(db, actor, changes)
+    }
+
+    if err != nil {
+        return fmt.Errorf("%w\nNothing was changed, the edits are kept in %s", err, path)
+    }
+
+    os.Remove(path)
+
+    return nil
+}
+
+func diffEdits(tasks []database.Task, names map[int]string, content string) (bulkedit.Changes, error) {
+    entries, err := bulkedit.Parse(content, time.Now())
+
+    if err != nil {
+        return bulkedit.Changes{}, err
+    }
+
+    return bulkedit.Diff(tasks, names, entries)
+}
+
+// applyEdits saves every change or none of them then prints a summary.
+func applyEdits(db database.DB, actor database.User, changes bulkedit.Changes) error {
+    if changes.Empty() {
+        fmt.Println("No changes.")
+        return nil
+    }
+
+    tx, err := beginTransaction(db)
+
+    if err != nil {
+        return err
+    }
+
+    summary, err := applyChanges(tx, actor, changes)
+
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+
+    for _, line := range summary {
+        fmt.Println(line)
+    }
+
+    fmt.Printf("%d created, %d updated, %d deleted.\n", len(changes.Created), len(changes.Updated), len(changes.Deleted))
+
+    return nil
+}
+
+func applyChanges(db database.DB, actor database.User, changes bulkedit.Changes) ([]string, error) {
+    summary := make([]string, 0)
+
+    for _, task := range changes.Deleted {
+        count, err := database.DeleteTaskBulkAction(db, actor, []int{task.ID})
+
+        if err == nil && count == 0 {
+            err = database.ErrPermissionDenied
+        }
+
+        if err != nil {
+            return nil, fmt.Errorf("couldn't delete task %d, %w", task.ID, err)
+        }
+
+        summary = append(summary, fmt.Sprintf("Deleted task %d: %s", task.ID, task.Name))
+        notifyWebhooks(db, webhook.EventDeleted, task)
+    }
+
+    for _, update := range changes.Updated {
+        if update.Assignee != nil {
+            assigneeID, err := assigneeID(db, *update.Assignee)
+
+            if err != nil {
+                return nil, fmt.Errorf("couldn't update task %d, %w", update.Task.ID, err)
+            }
+
+            update.Props.AssigneeID = &assigneeID
+        }
+
+        updated, err := database.UpdateTaskAction(db, actor, update.Task.ID, update.Props)
+
+        if err != nil {
+            return nil, fmt.Errorf("couldn't update task %d, %w", update.Task.ID, err)
+        }
+
+        summary = append(summary, fmt.Sprintf("Updated task %d: %s", updated.ID, updated.Name))
+
+        for _, event := range webhook.EventsForUpdate(update.Task, updated) {
+            notifyWebhooks(db, event, updated)
+        }
+    }
+
+    for _, entry := range changes.Created {
+        props := entry.AddTaskProp
+
+        if entry.Assignee != "" {
+            assigneeID, err := assigneeID(db, entry.Assignee)
+
+            if err != nil {
+                return nil, fmt.Errorf("couldn't create %s on line %d, %w", entry.Name, entry.Line, err)
+            }
+
+            props.AssigneeID = &assigneeID
+        }
+
+        created, err := database.AddTaskAction(db, actor, props)
+
+        if err != nil {
+            return nil, fmt.Errorf("couldn't create %s on line %d, %w", entry.Name, entry.Line, err)
+        }
+
+        summary = append(summary, fmt.Sprintf("Created task %d: %s", created.ID, created.Name))
+        notifyWebhooks(db, webhook.EventCreated, created)
+    }
+
+    return summary, nil
+}
+
+// assigneeID resolves a user name, an empty name is 0 which unassigns.
+func assigneeID(db database.DB, name string) (int, error) {
+    if name == "" {
+        return 0, nil
+    }
+
+    user, err := database.ListUserActionByName(db, name)
+
+    if err != nil {
+        return 0, err
+    }
+
+    return user.ID, nil
+}
+
+func writeTempFile(content string) (string, error) {
+    file, err := os.CreateTemp("", "go_todo-*.txt")
+
+    if err != nil {
+        return "", err
+    }
+    defer file.Close()
+
+    if _, err := file.WriteString(content); err != nil {
+        os.Remove(file.Name())
+        return "", err
+    }
+
+    return file.Name(), nil
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi. The editor
+// goes through the shell so it can come with arguments, e.g. "code --wait".
+func runEditor(path string) error {
+    editor := os.Getenv("VISUAL")
+
+    if editor == "" {
+        editor = os.Getenv("EDITOR")
+    }
+
+    if editor == "" {
+        editor = "vi"
+    }
+
+    cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+    cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+    err := cmd.Run()
+
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) {
+        return fmt.Errorf("%s exited with %d", editor, exitErr.ExitCode())
+    }
+
+    return err
+}
@@ -0,0 +1,250 @@
+package filter
+
+import (
+	"fmt"
+	"go_todo/database"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type kind int
+
+const (
+    kindNumber kind = iota
+    kindText
+    kindBool
+    kindPriority
+    kindDate
+    kindTags
+    kindUser
+)
+
+type field struct {
+    column string
+    kind kind
+}
+
+var fields = map[string]field{
+    "id": {"id", kindNumber},
+    "name": {"name", kindText},
+    "project": {"project", kindText},
+    "recurrence": {"recurrence", kindText},
+    "completed": {"completed", kindBool},
+    "done": {"completed", kindBool},
+    "priority": {"priority", kindPriority},
+    "due": {"due_at", kindDate},
+    "tag": {"tags", kindTags},
+    "tags": {"tags", kindTags},
+    "assignee": {"assignee_id", kindUser},
+    "owner": {"owner_id", kindUser},
+}
+
+var operators = map[kind][]string{
+    kindNumber: {"=", "!=", "<", "<=", ">", ">="},
+    kindText: {"=", "!=", ":", "~"},
+    kindBool: {"=", "!=", ":"},
+    kindPriority: {"=", "!=", ":", "<", "<=", ">", ">="},
+    kindDate: {"=", "!=", ":", "<", "<=", ">", ">="},
+    kindTags: {"=", "!=", ":"},
+    kindUser: {"=", "!=", ":"},
+}
+
+func fieldNames() string {
+    names := make([]string, 0, len(fields))
+
+    for name := range fields {
+        names = append(names, name)
+    }
+    slices.Sort(names)
+
+    return strings.Join(names, ", ")
+}
+
+// condition writes format with its values bound in order.
+func condition(format string, values ...any) database.Condition {
+    return func (arg func (any) string) string {
+        placeholders := make([]any, len(values))
+
+        for idx, value := range values {
+            placeholders[idx] = arg(value)
+        }
+
+        return fmt.Sprintf(format, placeholders...)
+    }
+}
+
+func (p *parser) isSet(f field, name token) (database.Condition, error) {
+    switch f.kind {
+    case kindNumber:
+        return nil, p.errorf(p.peek().pos, "Expected an operator after '%s', got %s", name.text, describe(p.peek()))
+    case kindText, kindTags:
+        return condition(fmt.Sprintf("(%s != '')", f.column)), nil
+    case kindBool:
+        return condition(fmt.Sprintf("(%s)", f.column)), nil
+    case kindPriority:
+        return condition(fmt.Sprintf("(%s > %d)", f.column, database.PriorityNone)), nil
+    default:
+        return condition(fmt.Sprintf("(%s IS NOT NULL)", f.column)), nil
+    }
+}
+
+func (p *parser) compare(f field, name token, operator token, value token) (database.Condition, error) {
+    if !slices.Contains(operators[f.kind], operator.text) {
+        return nil, p.errorf(
+            operator.pos, "Field '%s' can't be compared with '%s', expected one of %s",
+            name.text, operator.text, strings.Join(operators[f.kind], " "),
+        )
+    }
+
+    op := operator.text
+
+    if op == "!=" {
+        equal, err := p.compare(f, name, token{tokenOperator, "=", operator.pos}, value)
+
+        if err != nil {
+            return nil, err
+        }
+
+        return negate(equal), nil
+    }
+
+    if op == ":" && f.kind != kindText {
+        op = "="
+    }
+
+    switch f.kind {
+    case kindNumber:
+        number, err := strconv.Atoi(value.text)
+
+        if err != nil {
+            return nil, p.errorf(value.pos, "Expected a number, got %s", describe(value))
+        }
+
+        return condition(fmt.Sprintf("(%s %s %%s)", f.column, op), number), nil
+    case kindText:
+        return p.compareText(f, op, value)
+    case kindBool:
+        switch strings.ToLower(value.text) {
+        case "true", "yes":
+            return condition(fmt.Sprintf("(%s = %%s)", f.column), true), nil
+        case "false", "no":
+            return condition(fmt.Sprintf("(%s = %%s)", f.column), false), nil
+        }
+
+        return nil, p.errorf(value.pos, "Expected true, false, yes or no, got %s", describe(value))
+    case kindPriority:
+        priority := slices.Index(database.PriorityNames, strings.ToLower(value.text))
+
+        if priority == -1 {
+            return nil, p.errorf(value.pos, "Expected a priority, one of %s, got %s", strings.Join(database.PriorityNames, ", "), describe(value))
+        }
+
+        return condition(fmt.Sprintf("(%s %s %%s)", f.column, op), priority), nil
+    case kindDate:
+        return p.compareDate(f, op, value)
+    case kindTags:
+        return condition(fmt.Sprintf("(instr(',' || %s || ',', ',' || %%s || ',') > 0)", f.column), strings.ToLower(value.text)), nil
+    default:
+        return condition(fmt.Sprintf("(%s IS NOT NULL AND %s IN (SELECT id FROM users WHERE name = %%s))", f.column, f.column), value.text), nil
+    }
+}
+
+func (p *parser) compareText(f field, op string, value token) (database.Condition, error) {
+    switch op {
+    case ":":
+        return condition(fmt.Sprintf("(instr(lower(%s), lower(%%s)) > 0)", f.column), value.text), nil
+    case "~":
+        if _, err := regexp.Compile(value.text); err != nil {
+            return nil, p.errorf(value.pos, "Invalid regular expression, %s", err)
+        }
+
+        // The function behind REGEXP, PostgreSQL has no such operator.
+        return condition(fmt.Sprintf("(regexp(%%s, %s))", f.column), value.text), nil
+    default:
+        return condition(fmt.Sprintf("(lower(%s) = lower(%%s))", f.column), value.text), nil
+    }
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d+)([hdwmy])$`)
+
+// compareDate compares with a whole day or an instant, which is a day whose
+// end is its start.
+func (p *parser) compareDate(f field, op string, value token) (database.Condition, error) {
+    start, end, ok := p.resolveDate(strings.ToLower(value.text))
+
+    if !ok {
+        return nil, p.errorf(value.pos, "Expected a date like today, 2026-11-02 or +7d, got %s", describe(value))
+    }
+
+    // Bound in UTC like the due dates are stored.
+    start, end = start.UTC(), end.UTC()
+    bound := fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s %%s %%%%s)", f.column)
+
+    if start.Equal(end) {
+        return condition(fmt.Sprintf(bound, op), start), nil
+    }
+
+    switch op {
+    case "<":
+        return condition(fmt.Sprintf(bound, "<"), start), nil
+    case "<=":
+        return condition(fmt.Sprintf(bound, "<"), end), nil
+    case ">":
+        return condition(fmt.Sprintf(bound, ">="), end), nil
+    case ">=":
+        return condition(fmt.Sprintf(bound, ">="), start), nil
+    default:
+        return condition(fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s >= %%s AND %[1]s < %%s)", f.column), start, end), nil
+    }
+}
+
+func (p *parser) resolveDate(text string) (time.Time, time.Time, bool) {
+    today := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+    day := func (start time.Time) (time.Time, time.Time, bool) {
+        return start, start.AddDate(0, 0, 1), true
+    }
+
+    switch text {
+    case "now":
+        return p.now, p.now, true
+    case "today":
+        return day(today)
+    case "tomorrow":
+        return day(today.AddDate(0, 0, 1))
+    case "yesterday":
+        return day(today.AddDate(0, 0, -1))
+    }
+
+    if date, err := time.ParseInLocation("2006-01-02", text, p.now.Location()); err == nil {
+        return day(date)
+    }
+
+    match := offsetPattern.FindStringSubmatch(text)
+
+    if match == nil {
+        return time.Time{}, time.Time{}, false
+    }
+
+    n, _ := strconv.Atoi(match[2])
+
+    if match[1] == "-" {
+        n = -n
+    }
+
+    switch match[3] {
+    case "h":
+        instant := p.now.Add(time.Duration(n) * time.Hour)
+        return instant, instant, true
+    case "d":
+        return day(today.AddDate(0, 0, n))
+    case "w":
+        return day(today.AddDate(0, 0, 7 * n))
+    case "m":
+        return day(today.AddDate(0, n, 0))
+    default:
+        return day(today.AddDate(n, 0, 0))
+    }
+}
@@ -0,0 +1,312 @@
+// Package filter compiles the expressions go_todo list -where selects tasks
+// with into SQL conditions, e.g.
+//
+//   priority>=high and tag:backend and due<+7d and not completed
+//
+// A condition is a field, an operator and a value:
+//
+//   - id compares with =, !=, <, <=, >, >=
+//   - name, project and recurrence: = ignores case, : contains the value and
+//     ~ matches a regular expression
+//   - completed or done: true, false, yes or no
+//   - priority: none, low, medium or high, compared in that order
+//   - due: now, today, tomorrow, yesterday, 2026-11-02 or an offset from now
+//     like +7d, -2w, +1m, +1y or +3h. Days are whole, due=today is any time
+//     today and due<=+7d is up to the end of that day
+//   - tag or tags: : and = have the tag
+//   - assignee or owner: = or : a user name
+//
+// != is the opposite of =. A field alone is true when it's set, e.g. due or
+// completed. Conditions combine with not, and, or and parentheses, and binds
+// tighter than or. Values with spaces or operators go between double quotes.
+//
+// Values are always bound as parameters, never written into the SQL.
+package filter
+
+import (
+	"fmt"
+	"go_todo/database"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Error is a mistake in an expression, Pos is the offset in runes of the part
+// in error.
+type Error struct {
+    Text string
+    Pos int
+    Message string
+}
+
+// Error points at the mistake under the expression.
+func (e *Error) Error() string {
+    text := strings.ReplaceAll(e.Text, "\t", " ")
+    return fmt.Sprintf("%s at column %d\n  %s\n  %s^", e.Message, e.Pos+1, text, strings.Repeat(" ", e.Pos))
+}
+
+type tokenKind int
+
+const (
+    tokenEnd tokenKind = iota
+    tokenWord
+    tokenString
+    tokenOperator
+    tokenOpen
+    tokenClose
+)
+
+type token struct {
+    kind tokenKind
+    text string
+    pos int
+}
+
+// operatorRunes end words, "!" only appears in "!=".
+const operatorRunes = "=!<>:~"
+
+func lex(text string) ([]token, error) {
+    runes := []rune(text)
+    tokens := make([]token, 0)
+
+    for idx := 0; idx < len(runes); {
+        r := runes[idx]
+        start := idx
+
+        switch {
+        case unicode.IsSpace(r):
+            idx++
+            continue
+        case r == '(':
+            tokens = append(tokens, token{tokenOpen, "(", start})
+            idx++
+        case r == ')':
+            tokens = append(tokens, token{tokenClose, ")", start})
+            idx++
+        case r == '"':
+            value := strings.Builder{}
+            idx++
+
+            for idx < len(runes) && runes[idx] != '"' {
+                if runes[idx] == '\\' && idx+1 < len(runes) {
+                    idx++
+                }
+                value.WriteRune(runes[idx])
+                idx++
+            }
+
+            if idx == len(runes) {
+                return nil, &Error{text, start, "Unterminated string"}
+            }
+
+            tokens = append(tokens, token{tokenString, value.String(), start})
+            idx++
+        case strings.ContainsRune(operatorRunes, r):
+            idx++
+
+            if idx < len(runes) && runes[idx] == '=' && strings.ContainsRune("!<>", r) {
+                idx++
+            } else if r == '!' {
+                return nil, &Error{text, start, "Unexpected '!', use != or not"}
+            }
+
+            tokens = append(tokens, token{tokenOperator, string(runes[start:idx]), start})
+        default:
+            for idx < len(runes) && !unicode.IsSpace(runes[idx]) && !strings.ContainsRune(`()"`+operatorRunes, runes[idx]) {
+                idx++
+            }
+
+            tokens = append(tokens, token{tokenWord, string(runes[start:idx]), start})
+        }
+    }
+
+    return append(tokens, token{tokenEnd, "", len(runes)}), nil
+}
+
+type parser struct {
+    text string
+    tokens []token
+    pos int
+    now time.Time
+}
+
+// Parse compiles text into a condition on the tasks table, relative dates are
+// resolved against now and in its location.
+func Parse(text string, now time.Time) (database.Condition, error) {
+    tokens, err := lex(text)
+
+    if err != nil {
+        return nil, err
+    }
+
+    p := &parser{text: text, tokens: tokens, now: now}
+
+    if p.peek().kind == tokenEnd {
+        return nil, p.errorf(p.peek().pos, "Empty filter")
+    }
+
+    condition, err := p.parseOr()
+
+    if err != nil {
+        return nil, err
+    }
+
+    if tok := p.peek(); tok.kind != tokenEnd {
+        return nil, p.errorf(tok.pos, "Expected and, or or the end of the filter, got %s", describe(tok))
+    }
+
+    return condition, nil
+}
+
+func (p *parser) peek() token {
+    return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+    tok := p.tokens[p.pos]
+
+    if tok.kind != tokenEnd {
+        p.pos++
+    }
+
+    return tok
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+    return &Error{p.text, pos, fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether the next token is the unquoted word.
+func (p *parser) keyword(word string) bool {
+    tok := p.peek()
+    return tok.kind == tokenWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) parseOr() (database.Condition, error) {
+    return p.parseBinary("or", "OR", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (database.Condition, error) {
+    return p.parseBinary("and", "AND", p.parseNot)
+}
+
+func (p *parser) parseBinary(keyword string, operator string, operand func () (database.Condition, error)) (database.Condition, error) {
+    left, err := operand()
+
+    if err != nil {
+        return nil, err
+    }
+
+    for p.keyword(keyword) {
+        p.next()
+        right, err := operand()
+
+        if err != nil {
+            return nil, err
+        }
+
+        left = join(operator, left, right)
+    }
+
+    return left, nil
+}
+
+func (p *parser) parseNot() (database.Condition, error) {
+    if !p.keyword("not") {
+        return p.parsePrimary()
+    }
+
+    p.next()
+    condition, err := p.parseNot()
+
+    if err != nil {
+        return nil, err
+    }
+
+    return negate(condition), nil
+}
+
+func (p *parser) parsePrimary() (database.Condition, error) {
+    tok := p.next()
+
+    switch tok.kind {
+    case tokenOpen:
+        condition, err := p.parseOr()
+
+        if err != nil {
+            return nil, err
+        }
+
+        if closing := p.next(); closing.kind != tokenClose {
+            return nil, p.errorf(closing.pos, "Expected ')' to close the '(' at column %d, got %s", tok.pos+1, describe(closing))
+        }
+
+        return condition, nil
+    case tokenWord:
+        if isKeyword(tok.text) {
+            return nil, p.errorf(tok.pos, "Expected a condition, got '%s'", tok.text)
+        }
+
+        f, ok := fields[strings.ToLower(tok.text)]
+
+        if !ok {
+            return nil, p.errorf(tok.pos, "Unknown field '%s', expected one of %s", tok.text, fieldNames())
+        }
+
+        if p.peek().kind != tokenOperator {
+            return p.isSet(f, tok)
+        }
+
+        operator := p.next()
+        value := p.next()
+
+        if value.kind != tokenWord && value.kind != tokenString {
+            return nil, p.errorf(value.pos, "Expected a value after '%s', got %s", operator.text, describe(value))
+        }
+
+        return p.compare(f, tok, operator, value)
+    default:
+        return nil, p.errorf(tok.pos, "Expected a condition, got %s", describe(tok))
+    }
+}
+
+func isKeyword(word string) bool {
+    word = strings.ToLower(word)
+    return word == "and" || word == "or" || word == "not"
+}
+
+func describe(tok token) string {
+    switch tok.kind {
+    case tokenEnd:
+        return "the end of the filter"
+    case tokenString:
+        return fmt.Sprintf("\"%s\"", tok.text)
+    default:
+        return fmt.Sprintf("'%s'", tok.text)
+    }
+}
+
+// And combines two conditions, either may be nil.
+func And(left database.Condition, right database.Condition) database.Condition {
+    if left == nil {
+        return right
+    }
+
+    if right == nil {
+        return left
+    }
+
+    return join("AND", left, right)
+}
+
+func join(operator string, left database.Condition, right database.Condition) database.Condition {
+    return func (arg func (any) string) string {
+        return fmt.Sprintf("(%s %s %s)", left(arg), operator, right(arg))
+    }
+}
+
+func negate(condition database.Condition) database.Condition {
+    return func (arg func (any) string) string {
+        return fmt.Sprintf("NOT %s", condition(arg))
+    }
+}
@@ -0,0 +1,251 @@
+package filter
+
+import (
+	"database/sql"
+	"fmt"
+	"go_todo/database"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// now is a Monday.
+var now = time.Date(2026, time.October, 19, 14, 30, 0, 0, time.UTC)
+
+func day(month time.Month, day int) time.Time {
+    return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func compile(condition database.Condition) (string, []any) {
+    args := make([]any, 0)
+    sql := condition(func (value any) string {
+        args = append(args, value)
+        return fmt.Sprintf("$%d", len(args))
+    })
+
+    return sql, args
+}
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        text string
+        sql string
+        args []any
+    }{
+        {"id=3", "(id = $1)", []any{3}},
+        {"id >= 10", "(id >= $1)", []any{10}},
+        {"name:rent", "(instr(lower(name), lower($1)) > 0)", []any{"rent"}},
+        {`name = "Pay rent"`, "(lower(name) = lower($1))", []any{"Pay rent"}},
+        {`name~"^Pay (rent|bills)$"`, "(regexp($1, name))", []any{"^Pay (rent|bills)$"}},
+        {"project!=home", "NOT (lower(project) = lower($1))", []any{"home"}},
+        {"completed", "(completed)", []any{}},
+        {"done:no", "(completed = $1)", []any{false}},
+        {"priority>=high", "(priority >= $1)", []any{database.PriorityHigh}},
+        {"priority:Low", "(priority = $1)", []any{database.PriorityLow}},
+        {"priority", "(priority > 0)", []any{}},
+        {"tag:Backend", "(instr(',' || tags || ',', ',' || $1 || ',') > 0)", []any{"backend"}},
+        {"tags", "(tags != '')", []any{}},
+        {"assignee=alice", "(assignee_id IS NOT NULL AND assignee_id IN (SELECT id FROM users WHERE name = $1))", []any{"alice"}},
+        {"due", "(due_at IS NOT NULL)", []any{}},
+        {"due=today", "(due_at IS NOT NULL AND due_at >= $1 AND due_at < $2)", []any{day(10, 19), day(10, 20)}},
+        {"due<today", "(due_at IS NOT NULL AND due_at < $1)", []any{day(10, 19)}},
+        {"due<=tomorrow", "(due_at IS NOT NULL AND due_at < $1)", []any{day(10, 21)}},
+        {"due>2026-11-02", "(due_at IS NOT NULL AND due_at >= $1)", []any{day(11, 3)}},
+        {"due<+7d", "(due_at IS NOT NULL AND due_at < $1)", []any{day(10, 26)}},
+        {"due>=-1w", "(due_at IS NOT NULL AND due_at >= $1)", []any{day(10, 12)}},
+        {"due<+1m", "(due_at IS NOT NULL AND due_at < $1)", []any{day(11, 19)}},
+        {"due<+3h", "(due_at IS NOT NULL AND due_at < $1)", []any{now.Add(3 * time.Hour)}},
+        {"due<=now", "(due_at IS NOT NULL AND due_at <= $1)", []any{now}},
+        {
+            "priority>=high and tag:backend and due<+7d and not completed",
+            "((((priority >= $1) AND (instr(',' || tags || ',', ',' || $2 || ',') > 0)) AND (due_at IS NOT NULL AND due_at < $3)) AND NOT (completed))",
+            []any{database.PriorityHigh, "backend", day(10, 26)},
+        },
+        {
+            "tag:home or tag:work and not done",
+            "((instr(',' || tags || ',', ',' || $1 || ',') > 0) OR ((instr(',' || tags || ',', ',' || $2 || ',') > 0) AND NOT (completed)))",
+            []any{"home", "work"},
+        },
+        {
+            "(tag:home OR tag:work) AND NOT NOT done",
+            "(((instr(',' || tags || ',', ',' || $1 || ',') > 0) OR (instr(',' || tags || ',', ',' || $2 || ',') > 0)) AND NOT NOT (completed))",
+            []any{"home", "work"},
+        },
+        {`project:"and"`, "(instr(lower(project), lower($1)) > 0)", []any{"and"}},
+    }
+
+    for _, test := range tests {
+        t.Run(test.text, func (t *testing.T) {
+            condition, err := Parse(test.text, now)
+
+            if err != nil {
+                t.Fatalf("unexpected error, %s", err)
+            }
+
+            sql, args := compile(condition)
+
+            if sql != test.sql {
+                t.Errorf("expected:\n%s\ngot:\n%s", test.sql, sql)
+            }
+
+            if !reflect.DeepEqual(args, test.args) {
+                t.Errorf("expected the arguments %v, got %v", test.args, args)
+            }
+        })
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    tests := []struct {
+        text string
+        message string
+        pos int
+    }{
+        {"", "Empty filter", 0},
+        {"prio>=high", "Unknown field 'prio', expected one of assignee, completed, done, due, id, name, owner, priority, project, recurrence, tag, tags", 0},
+        {"priority>=urgent", "Expected a priority, one of none, low, medium, high, got 'urgent'", 10},
+        {"tag<backend", "Field 'tag' can't be compared with '<', expected one of = != :", 3},
+        {"due<soon", "Expected a date like today, 2026-11-02 or +7d, got 'soon'", 4},
+        {"id>=", "Expected a value after '>=', got the end of the filter", 4},
+        {"id", "Expected an operator after 'id', got the end of the filter", 2},
+        {"id=x", "Expected a number, got 'x'", 3},
+        {"done=maybe", "Expected true, false, yes or no, got 'maybe'", 5},
+        {"name~\"(\"", "Invalid regular expression, error parsing regexp: missing closing ): `(`", 5},
+        {"done and and due", "Expected a condition, got 'and'", 9},
+        {"done due", "Expected and, or or the end of the filter, got 'due'", 5},
+        {"(done or due", "Expected ')' to close the '(' at column 1, got the end of the filter", 12},
+        {"done)", "Expected and, or or the end of the filter, got ')'", 4},
+        {"not", "Expected a condition, got the end of the filter", 3},
+        {"=high", "Expected a condition, got '='", 0},
+        {"name!rent", "Unexpected '!', use != or not", 4},
+        {`name="rent`, "Unterminated string", 5},
+    }
+
+    for _, test := range tests {
+        t.Run(test.text, func (t *testing.T) {
+            _, err := Parse(test.text, now)
+            filterErr, ok := err.(*Error)
+
+            if !ok {
+                t.Fatalf("expected a filter error, got %v", err)
+            }
+
+            if filterErr.Message != test.message || filterErr.Pos != test.pos {
+                t.Errorf("expected %q at %d, got %q at %d", test.message, test.pos, filterErr.Message, filterErr.Pos)
+            }
+        })
+    }
+
+    t.Run("Should point at the mistake", func (t *testing.T) {
+        _, err := Parse("done and priority>urgent", now)
+        want := "Expected a priority, one of none, low, medium, high, got 'urgent' at column 19\n" +
+            "  done and priority>urgent\n" +
+            "                    ^"
+
+        if err == nil || err.Error() != want {
+            t.Errorf("expected:\n%s\ngot:\n%v", want, err)
+        }
+    })
+}
+
+func TestParseSort(t *testing.T) {
+    t.Run("Should read the columns and their order", func (t *testing.T) {
+        keys, err := ParseSort("priority,desc,due,name,ASC")
+        want := []database.SortKey{{Column: "priority", Desc: true}, {Column: "due"}, {Column: "name"}}
+
+        if err != nil || !reflect.DeepEqual(keys, want) {
+            t.Errorf("expected %v, got %v, %v", want, keys, err)
+        }
+    })
+
+    t.Run("Should refuse unknown columns and misplaced orders", func (t *testing.T) {
+        for _, text := range []string{"desc", "id,asc,desc", "owner_id", "id,"} {
+            if _, err := ParseSort(text); err == nil {
+                t.Errorf("expected an error for %q", text)
+            }
+        }
+    })
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
+
+func TestListTasks(t *testing.T) {
+    tx := getDBTransaction(t)
+    defer tx.Rollback()
+
+    if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
+        t.Fatalf("error while clearing the tasks, %s", err)
+    }
+
+    actor, err := database.EnsureUserAction(tx, "tester")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s", err)
+    }
+
+    local := now.In(time.Local)
+    yesterday, inAWeek := local.AddDate(0, 0, -1), local.AddDate(0, 0, 7)
+
+    for _, props := range []database.AddTaskProp{
+        {Name: "Pay rent", DueAt: &yesterday, Priority: database.PriorityHigh, Tags: []string{"finance"}},
+        {Name: "Fix the build", DueAt: &inAWeek, Tags: []string{"backend", "ci"}},
+        {Name: "Paint the fence", Completed: true},
+    } {
+        if _, err := database.AddTaskAction(tx, actor, props); err != nil {
+            t.Fatalf("error while adding a task, %s", err)
+        }
+    }
+
+    tests := []struct {
+        where string
+        names []string
+    }{
+        {"due<today", []string{"Pay rent"}},
+        {"due<=+7d and due>=today", []string{"Fix the build"}},
+        {"not due<today", []string{"Fix the build", "Paint the fence"}},
+        {"tag:ci or name~\"^Pa.*fence$\"", []string{"Fix the build", "Paint the fence"}},
+        {"tag:back", []string{}},
+        {"name:PAY and priority>low", []string{"Pay rent"}},
+    }
+
+    for _, test := range tests {
+        t.Run(test.where, func (t *testing.T) {
+            condition, err := Parse(test.where, local)
+
+            if err != nil {
+                t.Fatalf("unexpected error, %s", err)
+            }
+
+            tasks, err := database.ListTasksAction(tx, actor, database.ListTaskProps{Where: condition})
+
+            if err != nil {
+                t.Fatalf("error while listing tasks, %s", err)
+            }
+
+            names := make([]string, 0)
+            for _, task := range tasks {
+                names = append(names, task.Name)
+            }
+
+            if !reflect.DeepEqual(names, test.names) {
+                t.Errorf("expected %v, got %v", test.names, names)
+            }
+        })
+    }
+}
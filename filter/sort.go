@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"fmt"
+	"go_todo/database"
+	"slices"
+	"strings"
+)
+
+// ParseSort reads sort keys like "priority,desc,due", an order applies to the
+// column before it and columns are ascending by default.
+func ParseSort(text string) ([]database.SortKey, error) {
+    keys := make([]database.SortKey, 0)
+    ordered := false
+
+    for _, word := range strings.Split(strings.ToLower(text), ",") {
+        word = strings.TrimSpace(word)
+
+        if word == "asc" || word == "desc" {
+            if len(keys) == 0 || ordered {
+                return nil, fmt.Errorf("'%s' must follow a column", word)
+            }
+
+            keys[len(keys)-1].Desc = word == "desc"
+            ordered = true
+            continue
+        }
+
+        if _, ok := database.SortColumns[word]; !ok {
+            return nil, fmt.Errorf("unknown column '%s', expected one of %s", word, sortColumns())
+        }
+
+        keys = append(keys, database.SortKey{Column: word})
+        ordered = false
+    }
+
+    return keys, nil
+}
+
+func sortColumns() string {
+    columns := make([]string, 0, len(database.SortColumns))
+
+    for column := range database.SortColumns {
+        columns = append(columns, column)
+    }
+    slices.Sort(columns)
+
+    return strings.Join(columns, ", ")
+}
@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"fmt"
+	"go_todo/database"
+	"time"
+)
+
+// ApplyView narrows props down to a saved view, its filter is combined with
+// props.Where and its sort is used unless props are sorted already.
+func ApplyView(props *database.ListTaskProps, view database.View, now time.Time) error {
+    if view.Filter != "" {
+        condition, err := Parse(view.Filter, now)
+
+        if err != nil {
+            return fmt.Errorf("The filter of view @%s is invalid, %w", view.Name, err)
+        }
+
+        props.Where = And(condition, props.Where)
+    }
+
+    if view.Sort != "" && len(props.SortBy) == 0 {
+        keys, err := ParseSort(view.Sort)
+
+        if err != nil {
+            return fmt.Errorf("The sort of view @%s is invalid, %w", view.Name, err)
+        }
+
+        props.SortBy = keys
+    }
+
+    return nil
+}
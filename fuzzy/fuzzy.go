@@ -0,0 +1,69 @@
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+    matchScore = 1
+    consecutiveBonus = 5
+    wordStartBonus = 8
+)
+
+// Score matches pattern as a case insensitive subsequence of text, matches
+// on consecutive characters and at the start of words score higher.
+func Score(pattern string, text string) (int, bool) {
+    patternRunes := []rune(strings.ToLower(pattern))
+    textRunes := []rune(strings.ToLower(text))
+
+    score := 0
+    next := 0
+    previous := -2
+
+    for idx := 0; idx < len(textRunes) && next < len(patternRunes); idx++ {
+        if textRunes[idx] != patternRunes[next] {
+            continue
+        }
+
+        score += matchScore
+
+        if previous == idx - 1 {
+            score += consecutiveBonus
+        }
+
+        if idx == 0 || !unicode.IsLetter(textRunes[idx-1]) && !unicode.IsDigit(textRunes[idx-1]) {
+            score += wordStartBonus
+        }
+
+        previous = idx
+        next++
+    }
+
+    if next < len(patternRunes) {
+        return 0, false
+    }
+
+    return score, true
+}
+
+// Filter returns the indexes of the texts matching pattern, best matches
+// first and ties in their original order.
+func Filter(pattern string, texts []string) []int {
+    matches := make([]int, 0, len(texts))
+    scores := make(map[int]int)
+
+    for idx, text := range texts {
+        if score, ok := Score(pattern, text); ok {
+            matches = append(matches, idx)
+            scores[idx] = score
+        }
+    }
+
+    sort.SliceStable(matches, func (i, j int) bool {
+        return scores[matches[i]] > scores[matches[j]]
+    })
+
+    return matches
+}
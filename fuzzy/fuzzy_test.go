@@ -0,0 +1,40 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+    if _, ok := Score("rnt", "Pay rent"); !ok {
+        t.Error("expected a subsequence to match")
+    }
+
+    if _, ok := Score("tnr", "Pay rent"); ok {
+        t.Error("expected characters out of order not to match")
+    }
+
+    consecutive, _ := Score("ren", "Pay rent")
+    scattered, _ := Score("ren", "Pay rather soon")
+
+    if consecutive <= scattered {
+        t.Errorf("expected consecutive matches to score higher, got %d and %d\n", consecutive, scattered)
+    }
+}
+
+func TestFilter(t *testing.T) {
+    texts := []string{"Walk the dog", "Pay rent", "Read a book", "Rent a car"}
+
+    // Pay rent and Rent a car score the same, ties keep their order.
+    if got, want := Filter("rent", texts), []int{1, 3}; !reflect.DeepEqual(got, want) {
+        t.Errorf("expected %v, got %v\n", want, got)
+    }
+
+    if got, want := Filter("rac", texts), []int{3}; !reflect.DeepEqual(got, want) {
+        t.Errorf("expected %v, got %v\n", want, got)
+    }
+
+    if got := Filter("", texts); len(got) != len(texts) {
+        t.Errorf("expected an empty pattern to match everything, got %v\n", got)
+    }
+}
@@ -2,4 +2,10 @@ module go_todo
 
 go 1.21.3
 
-require github.com/mattn/go-sqlite3 v1.14.18
+require (
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.18
+	golang.org/x/term v0.29.0
+)
+
+require golang.org/x/sys v0.30.0 // indirect
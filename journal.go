@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/webhook"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func undoCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "undo",
+        Summary: "Undo your last change, or the last n changes",
+        Args: "[<n>]",
+        MaxArgs: 1,
+        Run: func (ctx *cli.Context) error { return replayOperations(db, ctx, true) },
+    }
+}
+
+func redoCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "redo",
+        Summary: "Redo the last undone change, or the last n of them",
+        Args: "[<n>]",
+        MaxArgs: 1,
+        Run: func (ctx *cli.Context) error { return replayOperations(db, ctx, false) },
+    }
+}
+
+func historyCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "history",
+        Summary: "List your last changes, the latest first",
+        Flags: []cli.Flag{
+            {Name: "limit", Short: "n", Kind: cli.Int, Placeholder: "count", Usage: "show at most count changes, 20 by default"},
+        },
+        ReadOnly: true,
+        Run: func (ctx *cli.Context) error { return listOperations(db, ctx) },
+    }
+}
+
+// replayOperations undoes or redoes the operations all together or not at
+// all.
+func replayOperations(db database.DB, ctx *cli.Context, undo bool) error {
+    count := 1
+
+    if len(ctx.Args()) == 1 {
+        n, err := strconv.Atoi(ctx.Args()[0])
+
+        if err != nil || n < 1 {
+            return ctx.Usagef("Expected a number of changes, got '%s'", ctx.Args()[0])
+        }
+
+        count = n
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    tx, err := beginTransaction(db)
+
+    if err != nil {
+        return err
+    }
+
+    replay, verb := database.RedoAction, "Redid"
+    if undo {
+        replay, verb = database.UndoAction, "Undid"
+    }
+
+    operations, err := replay(tx, actor, count)
+
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    for _, operation := range operations {
+        from, to := operation.Before, operation.After
+        if undo {
+            from, to = to, from
+        }
+        notifyTransitions(tx, from, to)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+
+    for _, operation := range operations {
+        fmt.Printf("%s %s\n", verb, describeOperation(operation))
+    }
+
+    return nil
+}
+
+// notifyTransitions fires the webhooks for tasks going from their from images
+// to their to images.
+func notifyTransitions(db database.DB, from []database.Task, to []database.Task) {
+    for _, transition := range webhook.TransitionsBetween(from, to) {
+        notifyWebhooks(db, transition.Event, transition.Task)
+    }
+}
+
+func listOperations(db database.DB, ctx *cli.Context) error {
+    limit := 20
+
+    if value, ok := ctx.Int("limit"); ok {
+        limit = value
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    operations, err := database.ListOperationsAction(db, actor, limit)
+
+    if err != nil {
+        return err
+    }
+
+    if len(operations) == 0 {
+        fmt.Println("No changes yet.")
+        return nil
+    }
+
+    for _, operation := range operations {
+        undone := ""
+        if operation.Undone {
+            undone = " (undone)"
+        }
+
+        fmt.Printf("%d. %s %s%s\n", operation.ID, operation.CreatedAt.Local().Format("2006-01-02 15:04"), describeOperation(operation), undone)
+    }
+
+    return nil
+}
+
+func describeOperation(operation database.Operation) string {
+    switch operation.Action {
+    case database.OperationAdd:
+        return "adding " + describeTasks(operation.After)
+    case database.OperationRestore:
+        return "restoring " + describeTasks(operation.After)
+    case database.OperationDelete:
+        return "deleting " + describeTasks(operation.Before)
+    case database.OperationPurge:
+        return "purging " + describeTasks(operation.Before)
+    case database.OperationArchive:
+        return "archiving " + describeTasks(operation.After)
+    case database.OperationUnarchive:
+        return "unarchiving " + describeTasks(operation.After)
+    }
+
+    if len(operation.Before) != 1 || len(operation.After) != 1 {
+        return "updating " + describeTasks(operation.After)
+    }
+
+    return fmt.Sprintf("updating %s: %s", describeTasks(operation.After), strings.Join(changedFields(operation.Before[0], operation.After[0]), ", "))
+}
+
+func describeTasks(tasks []database.Task) string {
+    if len(tasks) == 1 {
+        return fmt.Sprintf("task %d '%s'", tasks[0].ID, tasks[0].Name)
+    }
+
+    names := make([]string, len(tasks))
+
+    for idx, task := range tasks {
+        names[idx] = fmt.Sprintf("%d '%s'", task.ID, task.Name)
+    }
+
+    return fmt.Sprintf("%d tasks %s", len(tasks), strings.Join(names, ", "))
+}
+
+// changedFields names the fields an update changed, in the order add shows
+// them.
+func changedFields(before database.Task, after database.Task) []string {
+    fields := make([]string, 0)
+    changed := func (name string, differ bool) {
+        if differ {
+            fields = append(fields, name)
+        }
+    }
+
+    changed("name", before.Name != after.Name)
+    changed("due", !sameTime(before.DueAt, after.DueAt))
+    changed("priority", before.Priority != after.Priority)
+    changed("tags", strings.Join(before.Tags, ",") != strings.Join(after.Tags, ","))
+    changed("project", before.Project != after.Project)
+    changed("recurrence", before.Recurrence != after.Recurrence)
+    changed("notes", before.Notes != after.Notes)
+    changed("assignee", !sameID(before.AssigneeID, after.AssigneeID))
+    changed("completed", before.Completed != after.Completed)
+
+    if len(fields) == 0 {
+        fields = append(fields, "nothing")
+    }
+
+    return fields
+}
+
+func sameTime(left *time.Time, right *time.Time) bool {
+    if left == nil || right == nil {
+        return left == right
+    }
+
+    return left.Equal(*right)
+}
+
+func sameID(left *int, right *int) bool {
+    if left == nil || right == nil {
+        return left == right
+    }
+
+    return *left == *right
+}
@@ -0,0 +1,331 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const Version = "2.0"
+
+const (
+    CodeParseError = -32700
+    CodeInvalidRequest = -32600
+    CodeMethodNotFound = -32601
+    CodeInvalidParams = -32602
+    CodeInternalError = -32603
+    CodeServerError = -32000
+)
+
+type Request struct {
+    JSONRPC string `json:"jsonrpc"`
+    ID json.RawMessage `json:"id,omitempty"`
+    Method string `json:"method"`
+    Params json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response.
+func (r Request) IsNotification() bool {
+    return len(r.ID) == 0
+}
+
+type Response struct {
+    JSONRPC string `json:"jsonrpc"`
+    ID json.RawMessage `json:"id"`
+    Result json.RawMessage `json:"result,omitempty"`
+    Error *Error `json:"error,omitempty"`
+}
+
+type Error struct {
+    Code int `json:"code"`
+    Message string `json:"message"`
+    Data any `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+func InvalidParams(err error) *Error {
+    return &Error{Code: CodeInvalidParams, Message: "Invalid params", Data: err.Error()}
+}
+
+type HandlerFunc func(params json.RawMessage) (any, error)
+
+type Server struct {
+    methods map[string]HandlerFunc
+    mu sync.Mutex
+    encoder *json.Encoder
+}
+
+func NewServer() *Server {
+    return &Server{methods: make(map[string]HandlerFunc)}
+}
+
+func (s *Server) Register(method string, handler HandlerFunc) {
+    s.methods[method] = handler
+}
+
+// MaxMessageSize bounds the lines ServeConn reads.
+const MaxMessageSize = 16 << 20
+
+// ServeConn answers the requests read from rw, one per line, until it is
+// closed. A line which isn't JSON is answered with a parse error and the
+// next ones are served as usual.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+    scanner := bufio.NewScanner(rw)
+    scanner.Buffer(make([]byte, 0, 64 * 1024), MaxMessageSize)
+
+    s.mu.Lock()
+    s.encoder = json.NewEncoder(rw)
+    s.mu.Unlock()
+
+    for scanner.Scan() {
+        line := bytes.TrimSpace(scanner.Bytes())
+
+        if len(line) == 0 {
+            continue
+        }
+
+        // The scanner reuses its buffer for the next line.
+        response, ok := s.Handle(append(json.RawMessage(nil), line...))
+
+        if !ok {
+            continue
+        }
+
+        if err := s.write(response); err != nil {
+            return err
+        }
+    }
+
+    return scanner.Err()
+}
+
+// Notify sends a notification to the client of the connection being served.
+func (s *Server) Notify(method string, params any) error {
+    encodedParams, err := json.Marshal(params)
+
+    if err != nil {
+        return err
+    }
+
+    return s.write(Request{JSONRPC: Version, Method: method, Params: encodedParams})
+}
+
+func (s *Server) write(message any) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.encoder == nil {
+        return errors.New("jsonrpc: no connection is being served")
+    }
+
+    return s.encoder.Encode(message)
+}
+
+// Handle answers a single message or a batch, it returns false when no
+// response must be sent back.
+func (s *Server) Handle(message json.RawMessage) (any, bool) {
+    message = bytes.TrimSpace(message)
+
+    if !json.Valid(message) {
+        return errorResponse(nil, &Error{Code: CodeParseError, Message: "Parse error"}), true
+    }
+
+    if len(message) == 0 || message[0] != '[' {
+        return s.handleRequest(message)
+    }
+
+    batch := make([]json.RawMessage, 0)
+    json.Unmarshal(message, &batch)
+
+    if len(batch) == 0 {
+        return errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "Invalid Request"}), true
+    }
+
+    responses := make([]any, 0, len(batch))
+
+    for _, request := range batch {
+        if response, ok := s.handleRequest(request); ok {
+            responses = append(responses, response)
+        }
+    }
+
+    if len(responses) == 0 {
+        return nil, false
+    }
+
+    return responses, true
+}
+
+func (s *Server) handleRequest(message json.RawMessage) (any, bool) {
+    request := Request{}
+
+    if err := json.Unmarshal(message, &request); err != nil || !validRequest(request) {
+        id := request.ID
+        if !validID(id) {
+            id = nil
+        }
+        return errorResponse(id, &Error{Code: CodeInvalidRequest, Message: "Invalid Request"}), true
+    }
+
+    response := s.call(request)
+
+    if request.IsNotification() {
+        return nil, false
+    }
+
+    return response, true
+}
+
+func validRequest(request Request) bool {
+    if request.JSONRPC != Version || request.Method == "" || !validID(request.ID) {
+        return false
+    }
+
+    params := bytes.TrimSpace(request.Params)
+
+    return len(params) == 0 || params[0] == '{' || params[0] == '['
+}
+
+// validID accepts the identifiers allowed by the specification: strings,
+// numbers and null.
+func validID(id json.RawMessage) bool {
+    if len(id) == 0 {
+        return true
+    }
+
+    var value any
+
+    if err := json.Unmarshal(id, &value); err != nil {
+        return false
+    }
+
+    switch value.(type) {
+        case nil, string, float64:
+            return true
+        default:
+            return false
+    }
+}
+
+func (s *Server) call(request Request) Response {
+    handler, ok := s.methods[request.Method]
+
+    if !ok {
+        return errorResponse(request.ID, &Error{Code: CodeMethodNotFound, Message: "Method not found", Data: request.Method})
+    }
+
+    result, err := handler(request.Params)
+
+    if err != nil {
+        var rpcErr *Error
+        if errors.As(err, &rpcErr) {
+            return errorResponse(request.ID, rpcErr)
+        }
+        return errorResponse(request.ID, &Error{Code: CodeServerError, Message: err.Error()})
+    }
+
+    encoded, err := json.Marshal(result)
+
+    if err != nil {
+        return errorResponse(request.ID, &Error{Code: CodeInternalError, Message: "Internal error", Data: err.Error()})
+    }
+
+    return Response{JSONRPC: Version, ID: request.ID, Result: encoded}
+}
+
+func errorResponse(id json.RawMessage, err *Error) Response {
+    if len(id) == 0 {
+        id = json.RawMessage("null")
+    }
+    return Response{JSONRPC: Version, ID: id, Error: err}
+}
+
+// DecodeParams unmarshals params into dest rejecting unknown fields, the
+// returned error is ready to be answered to the client.
+func DecodeParams(params json.RawMessage, dest any) error {
+    if len(params) == 0 {
+        return nil
+    }
+
+    decoder := json.NewDecoder(bytes.NewReader(params))
+    decoder.DisallowUnknownFields()
+
+    if err := decoder.Decode(dest); err != nil {
+        return InvalidParams(err)
+    }
+
+    return nil
+}
+
+type Client struct {
+    mu sync.Mutex
+    conn io.ReadWriteCloser
+    encoder *json.Encoder
+    decoder *json.Decoder
+    nextID int
+}
+
+func NewClient(conn io.ReadWriteCloser) *Client {
+    return &Client{
+        conn: conn,
+        encoder: json.NewEncoder(conn),
+        decoder: json.NewDecoder(conn),
+    }
+}
+
+// Call sends a request and waits for its response, calls are serialized so a
+// client can be shared between goroutines.
+func (c *Client) Call(method string, params any, result any) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.nextID++
+    id := json.RawMessage(fmt.Sprintf("%d", c.nextID))
+
+    request := Request{JSONRPC: Version, ID: id, Method: method}
+
+    if params != nil {
+        encodedParams, err := json.Marshal(params)
+
+        if err != nil {
+            return err
+        }
+
+        request.Params = encodedParams
+    }
+
+    if err := c.encoder.Encode(request); err != nil {
+        return err
+    }
+
+    response := Response{}
+
+    if err := c.decoder.Decode(&response); err != nil {
+        return err
+    }
+
+    if !bytes.Equal(response.ID, id) {
+        return fmt.Errorf("jsonrpc: expected response to request %s, got %s", id, response.ID)
+    }
+
+    if response.Error != nil {
+        return response.Error
+    }
+
+    if result == nil {
+        return nil
+    }
+
+    return json.Unmarshal(response.Result, result)
+}
+
+func (c *Client) Close() error {
+    return c.conn.Close()
+}
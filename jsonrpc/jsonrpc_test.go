@@ -0,0 +1,339 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The cases below are the examples of the JSON-RPC 2.0 specification,
+// https://www.jsonrpc.org/specification#examples. Error messages and data are
+// implementation defined so only the error codes are compared.
+func TestSpecificationExamples(t *testing.T) {
+    server := newSpecServer()
+
+    cases := []struct {
+        name string
+        request string
+        response string
+    }{
+        {
+            "rpc call with positional parameters",
+            `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+            `{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+        },
+        {
+            "rpc call with positional parameters in reverse order",
+            `{"jsonrpc": "2.0", "method": "subtract", "params": [23, 42], "id": 2}`,
+            `{"jsonrpc": "2.0", "result": -19, "id": 2}`,
+        },
+        {
+            "rpc call with named parameters",
+            `{"jsonrpc": "2.0", "method": "subtract", "params": {"subtrahend": 23, "minuend": 42}, "id": 3}`,
+            `{"jsonrpc": "2.0", "result": 19, "id": 3}`,
+        },
+        {
+            "rpc call with named parameters in reverse order",
+            `{"jsonrpc": "2.0", "method": "subtract", "params": {"minuend": 42, "subtrahend": 23}, "id": 4}`,
+            `{"jsonrpc": "2.0", "result": 19, "id": 4}`,
+        },
+        {
+            "a notification",
+            `{"jsonrpc": "2.0", "method": "update", "params": [1,2,3,4,5]}`,
+            ``,
+        },
+        {
+            "a notification without params",
+            `{"jsonrpc": "2.0", "method": "foobar"}`,
+            ``,
+        },
+        {
+            "rpc call of non-existent method",
+            `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`,
+            `{"jsonrpc": "2.0", "error": {"code": -32601}, "id": "1"}`,
+        },
+        {
+            "rpc call with invalid JSON",
+            `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`,
+            `{"jsonrpc": "2.0", "error": {"code": -32700}, "id": null}`,
+        },
+        {
+            "rpc call with invalid Request object",
+            `{"jsonrpc": "2.0", "method": 1, "params": "bar"}`,
+            `{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}`,
+        },
+        {
+            "rpc call Batch, invalid JSON",
+            `[
+                {"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"},
+                {"jsonrpc": "2.0", "method"
+            ]`,
+            `{"jsonrpc": "2.0", "error": {"code": -32700}, "id": null}`,
+        },
+        {
+            "rpc call with an empty Array",
+            `[]`,
+            `{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}`,
+        },
+        {
+            "rpc call with an invalid Batch (but not empty)",
+            `[1]`,
+            `[{"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}]`,
+        },
+        {
+            "rpc call with invalid Batch",
+            `[1,2,3]`,
+            `[
+                {"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+                {"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+                {"jsonrpc": "2.0", "error": {"code": -32600}, "id": null}
+            ]`,
+        },
+        {
+            "rpc call Batch",
+            `[
+                {"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"},
+                {"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+                {"jsonrpc": "2.0", "method": "subtract", "params": [42,23], "id": "2"},
+                {"foo": "boo"},
+                {"jsonrpc": "2.0", "method": "foo.get", "params": {"name": "myself"}, "id": "5"},
+                {"jsonrpc": "2.0", "method": "get_data", "id": "9"}
+            ]`,
+            `[
+                {"jsonrpc": "2.0", "result": 7, "id": "1"},
+                {"jsonrpc": "2.0", "result": 19, "id": "2"},
+                {"jsonrpc": "2.0", "error": {"code": -32600}, "id": null},
+                {"jsonrpc": "2.0", "error": {"code": -32601}, "id": "5"},
+                {"jsonrpc": "2.0", "result": ["hello", 5], "id": "9"}
+            ]`,
+        },
+        {
+            "rpc call Batch (all notifications)",
+            `[
+                {"jsonrpc": "2.0", "method": "notify_sum", "params": [1,2,4]},
+                {"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}
+            ]`,
+            ``,
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func (t *testing.T) {
+            response, ok := server.Handle(json.RawMessage(c.request))
+
+            if c.response == "" {
+                if ok {
+                    t.Errorf("expected no response, got %v\n", response)
+                }
+                return
+            }
+
+            if !ok {
+                t.Fatal("expected a response, got none")
+            }
+
+            encoded, err := json.Marshal(response)
+
+            if err != nil {
+                t.Fatalf("error while encoding response, %s\n", err)
+            }
+
+            got := normalize(t, string(encoded))
+            want := normalize(t, c.response)
+
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("expected:\n%v\ngot:\n%v\n", want, got)
+            }
+        })
+    }
+}
+
+func TestRequestValidation(t *testing.T) {
+    server := newSpecServer()
+
+    cases := []struct {
+        name string
+        request string
+    }{
+        {"wrong version", `{"jsonrpc": "1.0", "method": "sum", "params": [1], "id": 1}`},
+        {"missing version", `{"method": "sum", "params": [1], "id": 1}`},
+        {"object id", `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": {}}`},
+        {"scalar params", `{"jsonrpc": "2.0", "method": "sum", "params": 1, "id": 1}`},
+        {"empty method", `{"jsonrpc": "2.0", "method": "", "id": 1}`},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func (t *testing.T) {
+            response, ok := server.Handle(json.RawMessage(c.request))
+
+            if !ok {
+                t.Fatal("expected an error response, got none")
+            }
+
+            if res := response.(Response); res.Error == nil || res.Error.Code != CodeInvalidRequest {
+                t.Errorf("expected an Invalid Request error, got %+v\n", res)
+            }
+        })
+    }
+}
+
+func TestHandlerErrors(t *testing.T) {
+    server := NewServer()
+    server.Register("fails", func (params json.RawMessage) (any, error) {
+        return nil, errors.New("boom")
+    })
+    server.Register("strict", func (params json.RawMessage) (any, error) {
+        p := struct{ Name string `json:"name"` }{}
+        return nil, DecodeParams(params, &p)
+    })
+
+    t.Run("Should answer application errors with a server error", func (t *testing.T) {
+        response, _ := server.Handle(json.RawMessage(`{"jsonrpc": "2.0", "method": "fails", "id": 1}`))
+
+        if res := response.(Response); res.Error == nil || res.Error.Code != CodeServerError || res.Error.Message != "boom" {
+            t.Errorf("expected a server error with the handler message, got %+v\n", res.Error)
+        }
+    })
+
+    t.Run("Should answer unknown params with an invalid params error", func (t *testing.T) {
+        response, _ := server.Handle(json.RawMessage(`{"jsonrpc": "2.0", "method": "strict", "params": {"asdf": 1}, "id": 1}`))
+
+        if res := response.(Response); res.Error == nil || res.Error.Code != CodeInvalidParams {
+            t.Errorf("expected an invalid params error, got %+v\n", res.Error)
+        }
+    })
+}
+
+func TestServeConn(t *testing.T) {
+    server := newSpecServer()
+    server.Register("notify_me", func (params json.RawMessage) (any, error) {
+        return "done", server.Notify("changed", []int{1})
+    })
+
+    input := strings.NewReader(`{"jsonrpc": "2.0", "method": "notify_me", "id": 1}` + "\n" +
+        `{"jsonrpc": "2.0", "method": "update", "params": [1]}` + "\n" +
+        `go_todo list` + "\n" +
+        `[{"jsonrpc": "2.0", "method": "sum", "params": [1,2], "id": 2}]` + "\n")
+    var output bytes.Buffer
+
+    if err := server.ServeConn(struct{ io.Reader; io.Writer }{input, &output}); err != nil {
+        t.Fatalf("error while serving, %s\n", err)
+    }
+
+    lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+    want := []string{
+        `{"jsonrpc":"2.0","method":"changed","params":[1]}`,
+        `{"jsonrpc":"2.0","id":1,"result":"done"}`,
+        `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+        `[{"jsonrpc":"2.0","id":2,"result":3}]`,
+    }
+
+    if !reflect.DeepEqual(lines, want) {
+        t.Errorf("expected:\n%s\ngot:\n%s\n", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+    }
+}
+
+func TestClient(t *testing.T) {
+    serverConn, clientConn := net.Pipe()
+    defer serverConn.Close()
+
+    go newSpecServer().ServeConn(serverConn)
+
+    client := NewClient(clientConn)
+    defer client.Close()
+
+    var result int
+
+    if err := client.Call("subtract", []int{42, 23}, &result); err != nil {
+        t.Fatalf("error while calling subtract, %s\n", err)
+    }
+
+    if result != 19 {
+        t.Errorf("expected 19, got %d\n", result)
+    }
+
+    err := client.Call("foobar", nil, nil)
+
+    var rpcErr *Error
+    if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+        t.Errorf("expected a method not found error, got %v\n", err)
+    }
+}
+
+func newSpecServer() *Server {
+    server := NewServer()
+
+    server.Register("subtract", func (params json.RawMessage) (any, error) {
+        var positional []int
+        if err := json.Unmarshal(params, &positional); err == nil && len(positional) == 2 {
+            return positional[0] - positional[1], nil
+        }
+
+        named := struct {
+            Minuend int `json:"minuend"`
+            Subtrahend int `json:"subtrahend"`
+        }{}
+        if err := DecodeParams(params, &named); err != nil {
+            return nil, err
+        }
+        return named.Minuend - named.Subtrahend, nil
+    })
+
+    sum := func (params json.RawMessage) (any, error) {
+        var numbers []int
+        if err := DecodeParams(params, &numbers); err != nil {
+            return nil, err
+        }
+        total := 0
+        for _, number := range numbers {
+            total += number
+        }
+        return total, nil
+    }
+
+    noop := func (params json.RawMessage) (any, error) {
+        return nil, nil
+    }
+
+    server.Register("sum", sum)
+    server.Register("notify_sum", sum)
+    server.Register("notify_hello", noop)
+    server.Register("update", noop)
+    server.Register("get_data", func (params json.RawMessage) (any, error) {
+        return []any{"hello", 5}, nil
+    })
+
+    return server
+}
+
+func normalize(t testing.TB, message string) any {
+    t.Helper()
+    var decoded any
+
+    if err := json.Unmarshal([]byte(message), &decoded); err != nil {
+        t.Fatalf("error while decoding %s, %s\n", message, err)
+    }
+
+    stripErrorDetails(decoded)
+
+    return decoded
+}
+
+func stripErrorDetails(message any) {
+    switch value := message.(type) {
+        case []any:
+            for _, item := range value {
+                stripErrorDetails(item)
+            }
+        case map[string]any:
+            if rpcErr, ok := value["error"].(map[string]any); ok {
+                delete(rpcErr, "message")
+                delete(rpcErr, "data")
+            }
+    }
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go_todo/cli"
 	"go_todo/database"
 	taskAction "go_todo/database"
+	"go_todo/filter"
+	"go_todo/quickadd"
+	"go_todo/store"
+	"go_todo/webhook"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -16,290 +24,764 @@ func main() {
     if len(args) == 1 {
         fmt.Printf("Welcome to To-do, Go!\n\n")
 
-        printHelp()
+        fmt.Print(newApp(nil).Help())
         return
     }
 
-    db, err := taskAction.OpenDatabase("./")
+    if dsn := os.Getenv(StoreEnvVar); database.IsPostgresDSN(dsn) {
+        if err := runOnPostgres(dsn, args[1:]); err != nil {
+            fmt.Printf("error while opening the database: %s\n", err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    sqlDB, err := openDatabase("./")
 
     if err != nil {
         fmt.Printf("error while opening the database: %s\n", err)
+        os.Exit(1)
     }
 
-    switch args[1]  {
-        case "a":
-            addTask(db, args[1:])
-        case "l":
-            listTasks(db, args[1:])
-        case "d":
-            deleteTasks(db, args[1:])
-        case "u":
-            updateTask(db, args[1:])
-        case "help":
-            help(args[1:])
-        default:
-            fmt.Printf("%s\n\n", fmt.Sprintf("Option %s doesn't exist", args[1]))
-            printHelp()
+    // Other processes may be writing, see database.ConnectionParams.
+    db := database.WithRetry(sqlDB)
+    app := newApp(db)
+
+    if changesTasks(app, args[1:]) {
+        autoSnapshot("./")
+        autoArchive(db)
     }
+
+    app.Execute(args[1:])
+    deliverWebhooks(db)
 }
 
-const UsageStrAddTask = "Usage: go_todo a -name <name> [-completed <true|false>]"
-func addTask(db taskAction.DB, args []string) {
-    if len(args) == 1 {
-        fmt.Println(UsageStrAddTask)
-        return
+// changesTasks tells whether the command named by args may change the tasks,
+// the automatic snapshots and archiving are skipped for the read-only ones,
+// completion included, and when args name no command.
+func changesTasks(app *cli.Command, args []string) bool {
+    cmd, _ := app.Find(args)
+
+    return cmd.Run != nil && !cmd.ReadOnly
+}
+
+// newApp defines every command of the CLI, db is only used once a command
+// runs.
+func newApp(db database.DB) *cli.Command {
+    app := &cli.Command{
+        Name: "go_todo",
+        Subcommands: []*cli.Command{
+            {
+                Name: "add",
+                Aliases: []string{"a"},
+                Summary: "Add tasks, e.g. add Pay rent tomorrow 9am #finance !high every month",
+                Args: "[<text>]",
+                MaxArgs: -1,
+                Flags: []cli.Flag{
+                    {Name: "name", Short: "n", Usage: "name of the task, the text isn't parsed"},
+                    {Name: "completed", Short: "c", Kind: cli.Bool, Usage: "create the task as done"},
+                    {Name: "notes", Usage: "longer description of the task, found by search"},
+                    {Name: "assignee", Placeholder: "user", Usage: "assign the task to a user", Complete: userCompletions(db)},
+                    {Name: "dry-run", Kind: cli.Switch, Usage: "show how the task would be created without creating it"},
+                    {Name: "from-file", Placeholder: "path", Usage: "add a task per line of a file, - reads stdin, all of them or none"},
+                },
+                Run: func (ctx *cli.Context) error { return addTask(db, ctx) },
+            },
+            {
+                Name: "list",
+                Aliases: []string{"l", "ls"},
+                Summary: "List tasks, or the tasks of a saved view with @name",
+                Args: "[@view]",
+                MaxArgs: 1,
+                Flags: append(
+                    []cli.Flag{
+                        {Name: "sort", Short: "s", Synopsis: "<column>[,asc|desc][,<column>...]", Usage: "sort by columns, e.g. priority,desc,due"},
+                        {Name: "format", Short: "f", Placeholder: "text|json|ids", Usage: "output format, text by default"},
+                        {Name: "archived", Kind: cli.Switch, Usage: "list the archived tasks instead"},
+                    },
+                    taskFilterFlags(db)...,
+                ),
+                CompleteArgs: func (args []string) []cli.Completion { return viewCompletions(db) },
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error { return listTasks(db, ctx) },
+            },
+            {
+                Name: "delete",
+                Aliases: []string{"d", "rm"},
+                Summary: "Move tasks to the trash, by ID or by name",
+                Args: "<...id|name>",
+                MinArgs: 1,
+                MaxArgs: -1,
+                CompleteArgs: taskCompletions(db),
+                Run: func (ctx *cli.Context) error { return deleteTasks(db, ctx) },
+            },
+            {
+                Name: "update",
+                Aliases: []string{"u"},
+                Summary: "Update a task by ID or by name, or all the tasks matching -where",
+                Args: "[<id|name>]",
+                MaxArgs: 1,
+                CompleteArgs: taskCompletions(db),
+                Flags: []cli.Flag{
+                    {Name: "name", Short: "n", Usage: "rename the task"},
+                    {Name: "completed", Short: "c", Kind: cli.Bool, Usage: "mark the task as done or pending"},
+                    {Name: "notes", Usage: "replace the notes of the task"},
+                    {
+                        Name: "project",
+                        Usage: "move the task to a project, an empty one takes it out of its project",
+                        Complete: projectCompletions(db),
+                    },
+                    {Name: "where", Short: "w", Placeholder: "filter", Usage: "update every task matching a filter at once, e.g. 'tag:work and not done'"},
+                    {
+                        Name: "assignee",
+                        Placeholder: "user|none",
+                        Usage: "assign the task to a user, none unassigns it",
+                        Complete: userCompletions(db, "none"),
+                    },
+                },
+                Run: func (ctx *cli.Context) error { return updateTask(db, ctx) },
+            },
+            showCommand(db),
+            doneCommand(db),
+            sqlStoreOnly(undoCommand(db)),
+            sqlStoreOnly(redoCommand(db)),
+            sqlStoreOnly(historyCommand(db)),
+            sqlStoreOnly(trashCommand(db)),
+            sqlStoreOnly(archiveCommand(db)),
+            sqlStoreOnly(unarchiveCommand(db)),
+            webhooksCommand(db),
+            tokenCommand(db),
+            userCommand(db),
+            shareCommand(db),
+            viewCommand(db),
+            sqlStoreOnly(searchCommand(db)),
+            taskDBOnly(daemonCommand()),
+            taskDBOnly(backupCommand()),
+            taskDBOnly(restoreCommand()),
+            taskDBOnly(doctorCommand(db)),
+            sqlStoreOnly(rpcCommand(db)),
+            sqlStoreOnly(serveCommand(db)),
+            sqlStoreOnly(editCommand(db)),
+            sqlStoreOnly(tuiCommand(db)),
+            shellCommand(db),
+        },
+    }
+
+    app.Subcommands = append(
+        app.Subcommands,
+        completionCommand(app),
+        completeCommand(app),
+        &cli.Command{
+            Name: "help",
+            Aliases: []string{"h"},
+            Summary: "Describe a command",
+            Args: "[<command> [<subcommand>]]",
+            MaxArgs: 2,
+            CompleteArgs: func (args []string) []cli.Completion {
+                return app.Complete(append(args, ""))
+            },
+            ReadOnly: true,
+            Run: func (ctx *cli.Context) error { return help(app, ctx) },
+        },
+    )
+
+    return app
+}
+
+func addTask(db taskAction.DB, ctx *cli.Context) error {
+    name, hasName := ctx.String("name")
+    text := strings.Join(ctx.Args(), " ")
+
+    if path, ok := ctx.String("from-file"); ok {
+        if hasName || text != "" {
+            return ctx.Usagef("Provide either -from-file or a task, not both")
+        }
+
+        return addTasksFromFile(db, ctx, path)
     }
 
-    props := taskAction.AddTaskProp{}
+    if hasName && text != "" {
+        return ctx.Usagef("Provide either -name or a text, not both")
+    }
 
-    optionValueMap, err := GetOptionValue(args, []string{"-name", "-completed"})
+    if !hasName && text == "" {
+        return ctx.Usagef("Parameter -name is required")
+    }
+
+    if hasName && strings.TrimSpace(name) == "" {
+        return ctx.Usagef("Parameter -name can't be empty")
+    }
+
+    props, assigneeName, err := addTaskProps(db, ctx, name, text)
 
     if err != nil {
-        fmt.Println(err)
-        return
+        return err
     }
 
-    if taskName, ok := optionValueMap["-name"]; !ok {
-        fmt.Println(UsageStrAddTask)
-        return
-    } else {
-        props.Name = taskName
+    if dryRun, _ := ctx.Bool("dry-run"); dryRun {
+        printAddTaskProp(props, assigneeName)
+        return nil
     }
 
-    if taskStatus, ok := optionValueMap["-completed"]; ok {
-        if taskStatus == "true" {
-            props.Completed = true
-        } else if taskStatus == "false" {
-            props.Completed = false
-        } else {
-            fmt.Println(UsageStrAddTask)
-            return
-        }
+    tasks, err := taskStore(db)
+
+    if err != nil {
+        return err
     }
-    
-    task, err := taskAction.AddTaskAction(db, props)
+
+    actor, err := currentUser(db)
 
     if err != nil {
-        fmt.Printf("error while creating task: %s\n", err)
-        return
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
     }
 
+    added, err := tasks.AddTasks(actor, []taskAction.AddTaskProp{props})
+
+    if err != nil {
+        return fmt.Errorf("couldn't create task, %w", err)
+    }
+
+    task := added[0]
     fmt.Printf("Task with ID: %d created!\n", task.ID)
+
+    notifyWebhooks(db, webhook.EventCreated, task)
+
+    return nil
 }
 
-const DefaultListUsageStr = "Usage: go_todo l [-sort <id|name>,<asc,desc>] [-completed <true|false>]"
-func listTasks(db database.DB, args []string) {
-    props := database.ListTaskProps{}
-    optionValueMap, err := GetOptionValue(args, []string{"-sort", "-completed"})
+// addTaskProps builds the task named name, or the one text describes, with
+// the flags of add. It returns the name of the assignee along.
+func addTaskProps(db taskAction.DB, ctx *cli.Context, name string, text string) (taskAction.AddTaskProp, string, error) {
+    props := taskAction.AddTaskProp{Name: name}
+    assigneeName, hasAssignee := ctx.String("assignee")
+
+    if text != "" {
+        parsed, err := quickadd.Parse(text, time.Now())
+
+        if err != nil {
+            return props, "", err
+        }
+
+        if parsed.Assignee != "" && hasAssignee {
+            return props, "", ctx.Usagef("Provide either @%s or -assignee, not both", parsed.Assignee)
+        }
+
+        if parsed.Assignee != "" {
+            assigneeName, hasAssignee = parsed.Assignee, true
+        }
+
+        props = parsed.AddTaskProp
+    }
+
+    props.Completed, _ = ctx.Bool("completed")
+    props.Notes, _ = ctx.String("notes")
+
+    if hasAssignee {
+        assignee, err := taskAction.ListUserActionByName(db, assigneeName)
+
+        if err != nil {
+            return props, "", err
+        }
+
+        props.AssigneeID = &assignee.ID
+    }
+
+    return props, assigneeName, nil
+}
+
+// addTasksFromFile adds a task per non-empty line, written like the text of
+// add. A line that can't be added fails them all.
+func addTasksFromFile(db taskAction.DB, ctx *cli.Context, path string) error {
+    var content []byte
+    var err error
+
+    if path == "-" {
+        content, err = io.ReadAll(os.Stdin)
+    } else {
+        content, err = os.ReadFile(path)
+    }
+
     if err != nil {
-        fmt.Println(err)
-        return
+        return err
     }
-    if sortVal, ok := optionValueMap["-sort"]; ok {
-        colOrd := strings.Split(sortVal, ",") 
 
-        if !Include([]string{"id", "name"}, colOrd[0]){
-            fmt.Println(DefaultListUsageStr)
-            return
+    props := make([]taskAction.AddTaskProp, 0)
+    assignees := make([]string, 0)
+
+    for idx, line := range strings.Split(string(content), "\n") {
+        if strings.TrimSpace(line) == "" {
+            continue
         }
 
-        if !Include([]string{"asc", "desc"}, colOrd[1]) {
-            fmt.Println(DefaultListUsageStr)
-            return
+        prop, assigneeName, err := addTaskProps(db, ctx, "", strings.TrimSpace(line))
+
+        if err != nil {
+            return fmt.Errorf("line %d, %w", idx + 1, err)
         }
-        sortingParameters := [2]string{colOrd[0], colOrd[1]}
-        props.SortBy = &sortingParameters
+
+        props = append(props, prop)
+        assignees = append(assignees, assigneeName)
     }
 
-    if filterVal, ok := optionValueMap["-completed"]; ok {
-        if !Include([]string{"true", "false"}, filterVal){
-            fmt.Println(DefaultListUsageStr)
-            return
+    if dryRun, _ := ctx.Bool("dry-run"); dryRun {
+        for idx, prop := range props {
+            if idx > 0 {
+                fmt.Println()
+            }
+            printAddTaskProp(prop, assignees[idx])
         }
+        return nil
+    }
 
-        if filterVal == "true" {
-            val := true
-            props.WhereCompleted = &val
-        } else {
-            val := false
-            props.WhereCompleted = &val
-        }
+    tasks, err := taskStore(db)
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    added, err := tasks.AddTasks(actor, props)
+
+    if err != nil {
+        return fmt.Errorf("couldn't create the tasks, %w", err)
+    }
+
+    fmt.Println(fmt.Sprintf("Added %d tasks.", len(added)))
+
+    for _, task := range added {
+        notifyWebhooks(db, webhook.EventCreated, task)
     }
-    printTasksList(db, props)
+
+    return nil
 }
 
-const DefaultDeleteUsageStr = "Usage: go_todo d <...ids>"
-func deleteTasks(db database.DB, args []string) {
-    if len(args) == 1 {
-        fmt.Println(DefaultDeleteUsageStr)
-        return
+// taskFilterFlags select the tasks commands like list and edit work on, see
+// taskFilterProps.
+func taskFilterFlags(db database.DB) []cli.Flag {
+    return []cli.Flag{
+        {Name: "completed", Short: "c", Kind: cli.Bool, Usage: "only done or pending tasks"},
+        {Name: "assignee", Placeholder: "user", Usage: "only the tasks assigned to a user", Complete: userCompletions(db)},
+        {Name: "mine", Short: "m", Kind: cli.Switch, Usage: "only your own tasks"},
+        {Name: "project", Usage: "only the tasks of a project", Complete: projectCompletions(db)},
+        {Name: "tag", Usage: "only the tasks carrying a tag", Complete: tagCompletions(db)},
+        {Name: "where", Short: "w", Placeholder: "filter", Usage: "only the tasks matching a filter, e.g. 'priority>=high and due<+7d'"},
     }
+}
 
-    ids := make([]int, len(args))
+func taskFilterProps(db database.DB, ctx *cli.Context) (database.ListTaskProps, error) {
+    props := database.ListTaskProps{}
+
+    props.OnlyMine, _ = ctx.Bool("mine")
+
+    if completed, ok := ctx.Bool("completed"); ok {
+        props.WhereCompleted = &completed
+    }
+
+    if assigneeName, ok := ctx.String("assignee"); ok {
+        assignee, err := database.ListUserActionByName(db, assigneeName)
 
-    for idx, arg := range args[1:] {
-        id, err := strconv.Atoi(arg)
         if err != nil {
-            fmt.Println(fmt.Sprintf("Error: '%s' isn't a numeric character", arg))
-            return
+            return props, err
         }
-        ids[idx] = id
+
+        props.WhereAssigneeID = &assignee.ID
+    }
+
+    if project, ok := ctx.String("project"); ok {
+        props.WhereProject = &project
+    }
+
+    if tag, ok := ctx.String("tag"); ok {
+        tag = strings.ToLower(tag)
+        props.WhereTag = &tag
     }
 
-    deleteCount, err := database.DeleteTaskBulkAction(db, ids)
+    if where, ok := ctx.String("where"); ok {
+        condition, err := filter.Parse(where, time.Now())
+
+        if err != nil {
+            return props, err
+        }
+
+        props.Where = condition
+    }
+
+    return props, nil
+}
+
+func listTasks(db database.DB, ctx *cli.Context) error {
+    props, err := taskFilterProps(db, ctx)
 
     if err != nil {
-        fmt.Println("Error:", err)
-        return
+        return err
     }
 
-    fmt.Println(fmt.Sprintf("Deleted %d tasks.", deleteCount))
+    props.Archived, _ = ctx.Bool("archived")
+
+    if sortVal, ok := ctx.String("sort"); ok {
+        keys, err := filter.ParseSort(sortVal)
+
+        if err != nil {
+            return ctx.Usagef("Invalid value '%s' for -sort, %s", sortVal, err)
+        }
+
+        props.SortBy = keys
+    }
+
+    format := ""
+
+    if len(ctx.Args()) == 1 {
+        view, err := viewArg(db, ctx)
+
+        if err != nil {
+            return err
+        }
+
+        if err := filter.ApplyView(&props, view, time.Now()); err != nil {
+            return err
+        }
+
+        format = view.Format
+    }
+
+    if value, ok := ctx.String("format"); ok {
+        if !Include(database.ViewFormats, value) {
+            return ctx.Usagef("Invalid value '%s' for -format, expected %s", value, strings.Join(database.ViewFormats, "|"))
+        }
+
+        format = value
+    }
+
+    return printTasksList(db, props, format)
 }
 
-const DefaultUpdateUsageStr = "Usage: go_todo u <id> <-name string>|<-completed true|false>"
-func updateTask(db database.DB, args []string) {
-    if len(args) < 2 {
-        fmt.Println(DefaultUpdateUsageStr)
-        return
+func deleteTasks(db database.DB, ctx *cli.Context) error {
+    tasks, err := taskStore(db)
+
+    if err != nil {
+        return err
     }
 
-    idToUpdate, err := strconv.Atoi(args[1])
+    actor, err := currentUser(db)
 
     if err != nil {
-        fmt.Println(DefaultUpdateUsageStr)
-        return
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
     }
 
-    optionValueMap, err := GetOptionValue(args, []string{"-name", "-completed"})
+    ids, err := resolveTaskIDs(tasks, actor, ctx.Args())
 
     if err != nil {
-        fmt.Println(err)
-        return
+        return err
     }
 
-    nameVal, isNamePresent := optionValueMap["-name"]
-    completedVal, isCompletedPresent := optionValueMap["-completed"]
+    deletedTasks := make([]database.Task, 0)
 
-    if !isNamePresent && !isCompletedPresent {
-        fmt.Println(DefaultUpdateUsageStr)
-        return
+    for _, id := range ids {
+        if task, err := tasks.GetTask(actor, id); err == nil {
+            deletedTasks = append(deletedTasks, task)
+        }
     }
 
-    if isCompletedPresent && !Include([]string{"true", "false"}, completedVal){
-        fmt.Println(DefaultUpdateUsageStr)
-        return
+    deleteCount, err := tasks.DeleteTasks(actor, ids)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Deleted %d tasks.", deleteCount))
+
+    for _, task := range deletedTasks {
+        if _, err := tasks.GetTask(actor, task.ID); err == nil {
+            continue
+        }
+        notifyWebhooks(db, webhook.EventDeleted, task)
+    }
+
+    return nil
+}
+
+func updateTask(db database.DB, ctx *cli.Context) error {
+    where, hasWhere := ctx.String("where")
+
+    if !hasWhere && len(ctx.Args()) == 0 {
+        return ctx.Usagef("Missing arguments")
+    }
+
+    if hasWhere && len(ctx.Args()) > 0 {
+        return ctx.Usagef("Provide either a task or -where, not both")
+    }
+
+    if !ctx.IsSet("name") && !ctx.IsSet("completed") && !ctx.IsSet("notes") && !ctx.IsSet("assignee") && !ctx.IsSet("project") {
+        return ctx.Usagef("Nothing to update, provide -name, -completed, -notes, -assignee or -project")
+    }
+
+    if hasWhere && ctx.IsSet("name") {
+        return ctx.Usagef("Tasks matching -where can't all be renamed")
     }
 
     props := database.UpdateTaskProp{}
 
-    if isNamePresent {
-       props.Name = &nameVal 
+    if name, ok := ctx.String("name"); ok {
+        if strings.TrimSpace(name) == "" {
+            return ctx.Usagef("Parameter -name can't be empty")
+        }
+
+        props.Name = &name
+    }
+
+    if completed, ok := ctx.Bool("completed"); ok {
+        props.Completed = &completed
+    }
+
+    if notes, ok := ctx.String("notes"); ok {
+        props.Notes = &notes
+    }
+
+    if project, ok := ctx.String("project"); ok {
+        props.Project = &project
     }
 
-    if isCompletedPresent {
-        if completedVal == "true" {
-            val := true
-            props.Completed = &val
-        } else {
-            val := false
-            props.Completed = &val
+    if assigneeName, ok := ctx.String("assignee"); ok {
+        unassigned := 0
+        props.AssigneeID = &unassigned
+
+        if assigneeName != "none" {
+            assignee, err := database.ListUserActionByName(db, assigneeName)
+
+            if err != nil {
+                return err
+            }
+
+            props.AssigneeID = &assignee.ID
         }
     }
 
-    updatedTask, err := database.UpdateTaskAction(db, idToUpdate, props)
+    tasks, err := taskStore(db)
 
     if err != nil {
-        if fmt.Sprintf("%s", err) == "Task doesn't exist" {
-            fmt.Println(fmt.Sprintf("Error: %s", err))
-            return
-        }
-        fmt.Println(err)
+        return err
     }
 
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    if hasWhere {
+        return updateTasksWhere(db, tasks, actor, where, props)
+    }
+
+    ids, err := resolveTaskIDs(tasks, actor, ctx.Args())
+
+    if err != nil {
+        return err
+    }
+
+    previousTask, _ := tasks.GetTask(actor, ids[0])
+
+    updated, err := tasks.UpdateTasks(actor, ids, props)
+
+    if err != nil {
+        return err
+    }
+
+    updatedTask := updated[0]
+
     fmt.Println(fmt.Sprintf("Task %d updated", updatedTask.ID))
+
+    for _, event := range webhook.EventsForUpdate(previousTask, updatedTask) {
+        notifyWebhooks(db, event, updatedTask)
+    }
+
+    return nil
 }
 
-const DefaultHelpUsageStr = "Usage: go_todo help <a|l|d|u>"
-func help(args []string) {
-    if len(args) == 1 {
-        fmt.Println(DefaultHelpUsageStr)
-        return
+// updateTasksWhere updates every task matching the filter or none of them.
+func updateTasksWhere(db database.DB, tasks store.TaskStore, actor database.User, where string, props database.UpdateTaskProp) error {
+    condition, err := filter.Parse(where, time.Now())
+
+    if err != nil {
+        return err
     }
 
-    switch args[1] {
-        case "a":
-            fmt.Println(UsageStrAddTask)
-        case "l":
-            fmt.Println(DefaultListUsageStr)
-        case "d":
-            fmt.Println(DefaultDeleteUsageStr)
-        case "u":
-            fmt.Println(DefaultUpdateUsageStr)
-        default: 
-            fmt.Println(fmt.Sprintf("Option %s not recognized", args[1]))
+    previous := make(map[int]database.Task)
+    var updated []database.Task
+
+    err = tasks.Transaction(func (tx store.TaskStore) error {
+        matching, err := tx.ListTasks(actor, database.ListTaskProps{Where: condition})
+
+        if err != nil {
+            return err
+        }
+
+        ids := make([]int, len(matching))
+
+        for idx, task := range matching {
+            previous[task.ID] = task
+            ids[idx] = task.ID
+        }
+
+        updated, err = tx.UpdateTasks(actor, ids, props)
+
+        return err
+    })
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Updated %d tasks.", len(updated)))
+
+    for _, task := range updated {
+        for _, event := range webhook.EventsForUpdate(previous[task.ID], task) {
+            notifyWebhooks(db, event, task)
+        }
     }
+
+    return nil
 }
 
-func printTasksList(db database.DB, props database.ListTaskProps) {
-    tasks, err := database.ListTasksAction(db, props)
+func help(app *cli.Command, ctx *cli.Context) error {
+    cmd := app
+
+    for _, name := range ctx.Args() {
+        sub := cmd.Subcommand(name)
+
+        if sub == nil {
+            return cmd.UnknownCommand(name)
+        }
+
+        cmd = sub
+    }
+
+    fmt.Print(cmd.Help())
+
+    return nil
+}
+
+// printTasksList prints the tasks as text by default, as a JSON array or as
+// their IDs one per line.
+func printTasksList(db database.DB, props database.ListTaskProps, format string) error {
+    tasksStore, err := taskStore(db)
     if err != nil {
-        fmt.Println(err)
-        return
+        return err
+    }
+    actor, err := currentUser(db)
+    if err != nil {
+        return err
+    }
+    tasks, err := tasksStore.ListTasks(actor, props)
+    if err != nil {
+        return err
+    }
+    switch format {
+    case "json":
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(tasks)
+    case "ids":
+        for _, task := range tasks {
+            fmt.Println(task.ID)
+        }
+        return nil
     }
+    names := userNames(db)
     for _, task := range tasks {
+        details := taskDetails(task)
+        if task.AssigneeID != nil {
+            details += fmt.Sprintf(" (@%s)", names[*task.AssigneeID])
+        }
         if task.Completed {
-            fmt.Printf("%d.[x] - %s\n", task.ID, task.Name)
+            fmt.Printf("%d.[x] - %s%s\n", task.ID, task.Name, details)
             continue
         }
-        fmt.Printf("%d.[ ] - %s\n", task.ID, task.Name)
+        fmt.Printf("%d.[ ] - %s%s\n", task.ID, task.Name, details)
     }
+    return nil
 }
 
-func Include(set []string, val string) bool {
-    isPresent := false
-    for _, setVal := range set {
-        if setVal == val {
-            isPresent = true
-            break
-        }
+// taskDetails writes the priority, due date, recurrence, project and tags of
+// a task the way add would read them.
+func taskDetails(task database.Task) string {
+    details := ""
+
+    if task.Priority != database.PriorityNone {
+        details += " !" + database.PriorityNames[task.Priority]
     }
 
-    return isPresent
+    if task.DueAt != nil {
+        details += " " + quickadd.FormatDue(*task.DueAt)
+    }
+
+    if task.Recurrence != "" {
+        details += " " + task.Recurrence
+    }
+
+    if task.Project != "" {
+        details += " +" + task.Project
+    }
+
+    for _, tag := range task.Tags {
+        details += " #" + tag
+    }
+
+    return details
 }
 
-func GetOptionValue(args []string, allowedParameters []string) (map[string]string, error) {
-    maps := make(map[string]string)
+func printAddTaskProp(props database.AddTaskProp, assignee string) {
+    due := "-"
+    if props.DueAt != nil {
+        due = props.DueAt.Format("Mon ") + quickadd.FormatDue(*props.DueAt)
+    }
 
-    lastOption := ""
+    fields := [][2]string{
+        {"Name", props.Name},
+        {"Due", due},
+        {"Priority", database.PriorityNames[props.Priority]},
+        {"Tags", strings.Join(props.Tags, ", ")},
+        {"Project", props.Project},
+        {"Recurrence", props.Recurrence},
+        {"Notes", props.Notes},
+        {"Assignee", assignee},
+        {"Completed", strconv.FormatBool(props.Completed)},
+    }
 
-    for _, arg := range args {
-        if arg[0] == '-' {
-            if Include(allowedParameters, arg) {
-                lastOption = arg
-                continue
-            }
-            return nil, errors.New(fmt.Sprintf("Parameter %s not recognized", arg))
+    for _, field := range fields {
+        if field[1] == "" {
+            field[1] = "-"
         }
+        fmt.Printf("%-12s%s\n", field[0]+":", field[1])
+    }
+}
 
-        if lastOption != "" {
-            maps[lastOption] = arg
-            lastOption = ""
-            continue
+func parseIDs(args []string) ([]int, error) {
+    ids := make([]int, len(args))
+
+    for idx, arg := range args {
+        id, err := strconv.Atoi(arg)
+        if err != nil {
+            return nil, errors.New(fmt.Sprintf("'%s' isn't a numeric character", arg))
         }
+        ids[idx] = id
     }
 
-    return maps, nil
+    return ids, nil
 }
 
-func printHelp() {
-    fmt.Printf("Usage: go_todo <option>\n\n")
-
-    fmt.Println("a - Add tasks")
-    fmt.Println("l - List tasks")
-    fmt.Println("d - Delete tasks")
-    fmt.Println("u - Update task")
-
-    fmt.Printf("\n")
+func Include(set []string, val string) bool {
+    isPresent := false
+    for _, setVal := range set {
+        if setVal == val {
+            isPresent = true
+            break
+        }
+    }
 
-    fmt.Println("For more information about a option: go_todo help <a|l|d|u>")
+    return isPresent
 }
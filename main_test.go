@@ -3,12 +3,22 @@ package main
 import (
 	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"go_todo/cli"
 	"go_todo/database"
+	"go_todo/repl"
+	"go_todo/webhook"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestInclude (t *testing.T) {
@@ -31,61 +41,37 @@ func TestInclude (t *testing.T) {
     })
 }
 
-func TestGetOptionValue(t *testing.T) {
-    allowedParameters := []string{"-name", "-completed"}
-
-    t.Run("Should fail if there's parameters not allowed in the parameter list", func (t *testing.T) {
-        args := []string{"-name", "test", "-asdf", "fdsa"}
-
-        _, err := GetOptionValue(args, allowedParameters)
-
-        if err == nil {
-            t.Fatalf("Should have failed with string 'Parameter -asdf not recognized', got %s\n", err)
-        }
-    })
-
-    t.Run("Should return a map mapping the provided options to their respective values", func(t *testing.T) {
-        args := []string{"-name", "test"}
-
-        maps, err := GetOptionValue(args, allowedParameters)
-
-        if err != nil {
-            t.Fatalf("failed with %s\n", err)
-        }
-
-        nameOp, ok := maps["-name"]
-
-        if !ok {
-            t.Error("maps should have the option -name")
-        }
-
-        if nameOp == "" {
-            t.Errorf("nameOp should be %s, got empty string\n", "test")
-        }
-    })
-}
-
 func TestAddTask(t *testing.T) {
     db := getDBTransaction(t)
     defer db.Rollback()
 
-    defaultUsageStr := fmt.Sprintf("%s\n", UsageStrAddTask)
-
     t.Run("Should print usage to stdout if there's not enough parameters", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        addTask(db, []string{"a"})
+        newApp(db).Execute([]string{"a"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Parameter -name is required", "add")
+
+        if got !=  want {
+            t.Errorf("expected message to be %s, got %s", want, got)
+        }
+    })
+
+    t.Run("Should refuse an empty name", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "-name", " "})
         got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Parameter -name can't be empty", "add")
 
-        if got !=  defaultUsageStr {
-            t.Errorf("expected message to be %s, got %s", defaultUsageStr, got)
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 
     t.Run("Should print not recognized parameter to stdout if any", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        addTask(db, []string{"a", "-asdf", "fdsa"})
+        newApp(db).Execute([]string{"a", "-asdf", "fdsa"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := "Parameter -asdf not recognized\n"
+        want := usageError("Parameter -asdf not recognized", "add")
 
         if got !=  want {
             t.Errorf("expected message to be %s, got %s", want, got)
@@ -94,27 +80,29 @@ func TestAddTask(t *testing.T) {
 
     t.Run("Should print usage to stdout if there's no -name parameter", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        addTask(db, []string{"a", "-completed", "false"})
+        newApp(db).Execute([]string{"a", "-completed", "false"})
         got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Parameter -name is required", "add")
 
-        if got !=  defaultUsageStr {
-            t.Errorf("expected message to be %s, got %s", defaultUsageStr, got)
+        if got !=  want {
+            t.Errorf("expected message to be %s, got %s", want, got)
         }
     })
 
     t.Run("Should print usage to stdout if when passing -completed parameter with a not allowed string", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        addTask(db, []string{"a", "-name", "test", "-completed", "asdf"})
+        newApp(db).Execute([]string{"a", "-name", "test", "-completed", "asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Invalid value 'asdf' for -completed, expected true or false", "add")
 
-        if got !=  defaultUsageStr {
-            t.Errorf("expected message to be %s, got %s", defaultUsageStr, got)
+        if got !=  want {
+            t.Errorf("expected message to be %s, got %s", want, got)
         }
     })
 
     t.Run("Should print the created ID to stdout if everything is ok", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        addTask(db, []string{"a", "-name", "test", "-completed", "true"})
+        newApp(db).Execute([]string{"a", "-name", "test", "-completed", "true"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "Task with ID: 1 created!\n"
 
@@ -122,7 +110,7 @@ func TestAddTask(t *testing.T) {
             t.Errorf("expected message to be %s, got %s", want, got)
         }
         
-        createdTask, err := database.ListTaskActionByID(db, uint(1))
+        createdTask, err := database.ListTaskActionByID(db, mockActor(t, db), uint(1))
 
         if err != nil {
             t.Errorf("error while fetching created task, %s\n", err)
@@ -132,13 +120,72 @@ func TestAddTask(t *testing.T) {
             t.Errorf("expected created task to have the same ID of the received by the message, got %d\n", createdTask.ID)
         }
     })
+
+    t.Run("Should show how a text would be interpreted without creating the task", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "-dry-run", "Pay rent 2030-01-02 9am #finance !high every month +flat"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Name:       Pay rent\n" +
+            "Due:        Wed 2030-01-02 09:00\n" +
+            "Priority:   high\n" +
+            "Tags:       finance\n" +
+            "Project:    flat\n" +
+            "Recurrence: every month\n" +
+            "Notes:      -\n" +
+            "Assignee:   -\n" +
+            "Completed:  false\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        if tasks, _ := database.ListTasksAction(db, mockActor(t, db), database.ListTaskProps{}); len(tasks) != 1 {
+            t.Errorf("expected no task to be created, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should create a task from a text", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "Pay", "rent", "2030-01-02", "9am", "#finance", "!high", "every", "month", "+flat"})
+        newApp(db).Execute([]string{"l"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Task with ID: 2 created!\n" +
+            "1.[x] - test\n" +
+            "2.[ ] - Pay rent !high 2030-01-02 09:00 every month +flat #finance\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should print the error of a text which can't be interpreted", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "Pay rent !low !high"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Error: Priority given twice, '!low' and '!high'\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should print usage when both a text and -name are provided", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "-name", "test", "Pay rent"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Provide either -name or a text, not both", "add")
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
 }
 
 func TestListTasks (t *testing.T) {
     db := getDBTransaction(t)
     defer db.Rollback()
     mockTask(t, db)
-    _, err := database.AddTaskAction(db, database.AddTaskProp{
+    _, err := database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{
         Name: "Test 2",
         Completed: true,
     })
@@ -149,7 +196,7 @@ func TestListTasks (t *testing.T) {
 
     t.Run("Should list tasks in ascending order", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{})
+        newApp(db).Execute([]string{"l"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "1.[ ] - Test\n2.[x] - Test 2\n"
 
@@ -160,9 +207,9 @@ func TestListTasks (t *testing.T) {
 
     t.Run("Should print not recognized parameter to stdout if any", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-asdf"})
+        newApp(db).Execute([]string{"l", "-asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := "Parameter -asdf not recognized\n"
+        want := usageError("Parameter -asdf not recognized", "list")
         if got != want {
             t.Error("expected:", want, "got:", got)
         }
@@ -170,25 +217,27 @@ func TestListTasks (t *testing.T) {
 
     t.Run("Should print usage to stdout when receiving a unexpected column parameter", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-sort", "asdf,desc"})
+        newApp(db).Execute([]string{"l", "-sort", "asdf,desc"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultListUsageStr) {
-            t.Error("expected:", DefaultListUsageStr, "got:", got)
+        want := usageError("Invalid value 'asdf,desc' for -sort, unknown column 'asdf', expected one of completed, due, id, name, priority, project", "list")
+        if got != want {
+            t.Error("expected:", want, "got:", got)
         }
     })
 
     t.Run("Should print usage to stdout when receiving a unexpected sorting parameter", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-sort", "name,asdf"})
+        newApp(db).Execute([]string{"l", "-sort", "name,asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultListUsageStr) {
-            t.Error("expected:", DefaultListUsageStr, "got:", got)
+        want := usageError("Invalid value 'name,asdf' for -sort, unknown column 'asdf', expected one of completed, due, id, name, priority, project", "list")
+        if got != want {
+            t.Error("expected:", want, "got:", got)
         }
     })
 
     t.Run("Should list task in descending order", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-sort", "id,desc"})
+        newApp(db).Execute([]string{"l", "-sort", "id,desc"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "2.[x] - Test 2\n1.[ ] - Test\n"
         if got != want {
@@ -198,22 +247,45 @@ func TestListTasks (t *testing.T) {
 
     t.Run("Should print usage to stdout when receiving a unexpected filtering parameter", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-completed", "asdf"})
+        newApp(db).Execute([]string{"l", "-completed", "asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultListUsageStr) {
-            t.Error("expected:", DefaultListUsageStr, "got:", got)
+        want := usageError("Invalid value 'asdf' for -completed, expected true or false", "list")
+        if got != want {
+            t.Error("expected:", want, "got:", got)
         }
     })
 
     t.Run("Should list only the task with completed = false", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        listTasks(db, []string{"-completed", "false"})
+        newApp(db).Execute([]string{"l", "-completed", "false"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "1.[ ] - Test\n"
         if got != want {
             t.Error("expected:", want, "got:", got)
         }
     })
+
+    t.Run("Should list only the tasks matching the filter", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"l", "-where", "name:\"test 2\" or not (done or id>1)"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "1.[ ] - Test\n2.[x] - Test 2\n"
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should point at the mistake in the filter", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"l", "-w", "done and priority>urgent"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Error: Expected a priority, one of none, low, medium, high, got 'urgent' at column 19\n" +
+            "  done and priority>urgent\n" +
+            "                    ^\n"
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
 }
 
 func TestDeleteTasks(t *testing.T) {
@@ -222,19 +294,19 @@ func TestDeleteTasks(t *testing.T) {
 
     t.Run("Should print usage if it wasn't provided any task ID", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        deleteTasks(db, []string{"d"})
+        newApp(db).Execute([]string{"d"})
         got := mockTearDownStdout(t, oldStdout, r, w)
 
-        if got != fmt.Sprintf("%s\n", DefaultDeleteUsageStr) {
+        if got != usageError("Missing arguments", "delete") {
             t.Error("should have printed usage, got:", got)
         }
     })
 
-    t.Run("Should return an error if there's not a numeric character in the provided ID list", func (t *testing.T) {
+    t.Run("Should return an error if a name in the provided list matches no task", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        deleteTasks(db, []string{"d", "1", "2", ","})
+        newApp(db).Execute([]string{"d", "1", "2", ","})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := "Error: ',' isn't a numeric character\n"
+        want := "Error: No task matches ','\n"
 
         if got != want {
             t.Error("should have failed with:", want, "got:", got)
@@ -246,7 +318,7 @@ func TestDeleteTasks(t *testing.T) {
         task1 := mockTask(t, db)
         task2 := mockTask(t, db)
         oldStdout, r, w := mockTearUpStdout(t)
-        deleteTasks(db, []string{"d", "1", "2"})
+        newApp(db).Execute([]string{"d", "1", "2"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "Deleted 2 tasks.\n"
 
@@ -254,13 +326,13 @@ func TestDeleteTasks(t *testing.T) {
             t.Error("should have printed:", want, "got:", got)
         }
 
-        _, err := database.ListTaskActionByID(db, uint(task1.ID))
+        _, err := database.ListTaskActionByID(db, mockActor(t, db), uint(task1.ID))
 
         if err == nil {
             t.Error("should have failed with ErrNoRow as the task should be deleted", err)
         }
 
-        _, errTask2 := database.ListTaskActionByID(db, uint(task2.ID))
+        _, errTask2 := database.ListTaskActionByID(db, mockActor(t, db), uint(task2.ID))
 
         if errTask2 == nil {
             t.Error("should have failed with ErrNoRow as the task should be deleted", errTask2)
@@ -275,27 +347,43 @@ func TestUpdateTask(t *testing.T) {
 
     t.Run("Should print usage if missing task ID to update", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u"})
+        newApp(db).Execute([]string{"u"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultUpdateUsageStr) {
+        if got != usageError("Missing arguments", "update") {
             t.Error("should have printed default usage string, got:", got)
         }
     })
 
-    t.Run("Should print usage if provided ID isn't a numeric character", func (t *testing.T) {
+    t.Run("Should print an error if the provided name matches no task", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", "asdf"})
+        newApp(db).Execute([]string{"u", "asdf", "-name", "test"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultUpdateUsageStr) {
-            t.Error("should have printed default usage string, got:", got)
+        want := "Error: No task matches 'asdf'\n"
+        if got != want {
+            t.Error("should have printed:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should refuse to blank the name of a task", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"u", strconv.Itoa(task.ID), "-name", ""})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Parameter -name can't be empty", "update")
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        if listed, _ := database.ListTaskActionByID(db, mockActor(t, db), uint(task.ID)); listed.Name != task.Name {
+            t.Errorf("expected the name kept, got %q\n", listed.Name)
         }
     })
 
     t.Run("Should print unrecognized parameter if any", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", "69", "-asdf"})
+        newApp(db).Execute([]string{"u", "69", "-asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := "Parameter -asdf not recognized\n"
+        want := usageError("Parameter -asdf not recognized", "update")
         if got != want {
             t.Error("should have printed:", want, "got:", got)
         }
@@ -303,25 +391,25 @@ func TestUpdateTask(t *testing.T) {
 
     t.Run("Should print usage if missing -name or -completed parameters", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", strconv.Itoa(task.ID)})
+        newApp(db).Execute([]string{"u", strconv.Itoa(task.ID)})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultUpdateUsageStr) {
+        if got != usageError("Nothing to update, provide -name, -completed, -notes, -assignee or -project", "update") {
             t.Error("should have printed default usage string, got:", got)
         }
     })
 
     t.Run("Should print usage if value of parameter -completed is not as expected", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", strconv.Itoa(task.ID), "-completed", "asdf"})
+        newApp(db).Execute([]string{"u", strconv.Itoa(task.ID), "-completed", "asdf"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        if got != fmt.Sprintf("%s\n", DefaultUpdateUsageStr) {
+        if got != usageError("Invalid value 'asdf' for -completed, expected true or false", "update") {
             t.Error("should have printed default usage string, got:", got)
         }
     })
 
     t.Run("Should print error of unexisting task if the provided task ID wasn't present in the data set", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", "69", "-name", "test"})
+        newApp(db).Execute([]string{"u", "69", "-name", "test"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := "Error: Task doesn't exist\n"
         if got != want{
@@ -332,14 +420,14 @@ func TestUpdateTask(t *testing.T) {
 
     t.Run("Should update the provided task and print its ID if everything is ok", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        updateTask(db, []string{"u", strconv.Itoa(task.ID), "-completed", "true"})
+        newApp(db).Execute([]string{"u", strconv.Itoa(task.ID), "-completed", "true"})
         got := mockTearDownStdout(t, oldStdout, r, w)
         want := fmt.Sprintf("Task %d updated\n", task.ID)
         if got != want{
             t.Error("should have printed:", want, "got:", got)
         }
 
-        updatedTask, err := database.ListTaskActionByID(db, uint(task.ID))
+        updatedTask, err := database.ListTaskActionByID(db, mockActor(t, db), uint(task.ID))
 
         if err != nil {
             t.Fatal("error while listing updated task", err)
@@ -351,138 +439,1271 @@ func TestUpdateTask(t *testing.T) {
     })
 }
 
-func TestHelp (t *testing.T) {
-    t.Run("Should print usage if there was no option provided", func (t *testing.T) {
+func TestTaskReferences(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockActor(t, db)
+    rent, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Pay rent", Notes: "Before the 5th"})
+    car, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Rent a car"})
+    fence, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Paint the fence"})
+
+    t.Run("Should show a task found by a part of its name", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help"})
+        newApp(db).Execute([]string{"show", "pay"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := fmt.Sprintf("%s\n", DefaultHelpUsageStr)
+        want := fmt.Sprintf("ID:         %d\nName:       Pay rent\nDue:        -\nPriority:   none\nTags:       -\n", rent.ID) +
+            "Project:    -\nRecurrence: -\nNotes:      Before the 5th\nAssignee:   -\nCompleted:  false\n" +
+            fmt.Sprintf("Created:    %s\nUpdated:    %s\n", rent.CreatedAt.Local().Format("2006-01-02 15:04"), rent.UpdatedAt.Local().Format("2006-01-02 15:04"))
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 
-    t.Run("Should print unexistent option if the provided option doesn't exist", func (t *testing.T) {
+    t.Run("Should list the candidates when a name is ambiguous", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help", "asdf"})
+        newApp(db).Execute([]string{"done", "rent"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := "Option asdf not recognized\n"
+        want := fmt.Sprintf(
+            "Error: 'rent' matches 2 tasks, use an ID or a longer name:\n  %d. Pay rent\n  %d. Rent a car\n",
+            rent.ID, car.ID,
+        )
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 
-    t.Run("Should print the default usage of add option", func (t *testing.T) {
+    t.Run("Should mark the tasks found by fuzzy names as done", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help", "a"})
+        newApp(db).Execute([]string{"done", "pntfnc", strconv.Itoa(fence.ID)})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := fmt.Sprintf("%s\n", UsageStrAddTask)
+        want := fmt.Sprintf("Task %[1]d done: Paint the fence\nTask %[1]d is already done\n", fence.ID)
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 
-    t.Run("Should print the default usage of list option", func (t *testing.T) {
+    t.Run("Should update and delete tasks found by their name", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help", "l"})
+        newApp(db).Execute([]string{"u", "a car", "-n", "Rent a van"})
+        newApp(db).Execute([]string{"d", "rent a van", "pay rent"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := fmt.Sprintf("%s\n", DefaultListUsageStr)
+        want := fmt.Sprintf("Task %d updated\nDeleted 2 tasks.\n", car.ID)
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 
-    t.Run("Should print the default usage of delete option", func (t *testing.T) {
+    t.Run("Should ask which task is meant", func (t *testing.T) {
+        ambiguous := &database.AmbiguousTaskError{Ref: "pa", Candidates: []database.Task{rent, fence}}
+        out := &strings.Builder{}
+        task, err := chooseTask(strings.NewReader("2\n"), out, ambiguous)
+        want := fmt.Sprintf("'pa' matches 2 tasks:\n  1) %d. Pay rent\n  2) %d. Paint the fence\nWhich one? [1-2] ", rent.ID, fence.ID)
+
+        if err != nil || task.ID != fence.ID {
+            t.Errorf("expected task %d, got %d, %v", fence.ID, task.ID, err)
+        }
+
+        if out.String() != want {
+            t.Errorf("expected: %q, got: %q", want, out.String())
+        }
+
+        if _, err := chooseTask(strings.NewReader("3\n"), out, ambiguous); err == nil {
+            t.Error("expected an error for a choice out of range")
+        }
+    })
+}
+
+func TestShowHistory(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockActor(t, db)
+    task, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Water the plants"})
+
+    t.Run("Should list the changes of a task grouped by when they were made", func (t *testing.T) {
+        newApp(db).Execute([]string{"u", strconv.Itoa(task.ID), "-c", "true", "-notes", "Twice a week"})
+
+        changes, _ := database.ListTaskHistoryAction(db, actor, task.ID)
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"show", "-history", strconv.Itoa(task.ID)})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := fmt.Sprintf("  %s by %s\n    name: none -> 'Water the plants'\n", changes[0].ChangedAt.Local().Format("2006-01-02 15:04"), actor.Name)
+
+        if !strings.Contains(got, "History:\n") || !strings.Contains(got, want) {
+            t.Fatalf("expected the creation in %q", got)
+        }
+
+        if !strings.HasSuffix(got, "    completed: no -> yes\n    notes: none -> 'Twice a week'\n") {
+            t.Errorf("expected the update at the end of %q", got)
+        }
+    })
+
+    t.Run("Should find a trashed task by ID and by name", func (t *testing.T) {
+        database.DeleteTaskBulkAction(db, actor, []int{task.ID})
+
+        for _, ref := range []string{strconv.Itoa(task.ID), "plants"} {
+            oldStdout, r, w := mockTearUpStdout(t)
+            newApp(db).Execute([]string{"show", "-history", ref})
+            got := mockTearDownStdout(t, oldStdout, r, w)
+
+            if !strings.Contains(got, "Deleted:") || !strings.HasSuffix(got, "    deleted: no -> yes\n") {
+                t.Errorf("expected the deletion of %q in %q", ref, got)
+            }
+        }
+    })
+}
+
+func TestBulkCommands(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockActor(t, db)
+    folder := t.TempDir()
+    broken, file := filepath.Join(folder, "broken.txt"), filepath.Join(folder, "tasks.txt")
+    os.WriteFile(broken, []byte("Write report #work\nCall @nobody\n"), 0o644)
+    os.WriteFile(file, []byte("Write report #work\n\nCall client #work !high\nBuy milk\n"), 0o644)
+
+    t.Run("Should add the tasks of a file all together or none", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help", "d"})
+        newApp(db).Execute([]string{"a", "-from-file", broken})
+        newApp(db).Execute([]string{"a", "-from-file", file})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := fmt.Sprintf("%s\n", DefaultDeleteUsageStr)
+        want := "Error: line 2, User doesn't exist\nAdded 3 tasks.\n"
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        if tasks, _ := database.ListTasksAction(db, actor, database.ListTaskProps{}); len(tasks) != 3 || tasks[1].Priority != database.PriorityHigh {
+            t.Errorf("expected the 3 tasks of the file, got %+v\n", tasks)
         }
     })
 
-    t.Run("Should print the default usage of update option", func (t *testing.T) {
+    t.Run("Should update the tasks matching a filter", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"u", "-where", "tag:work", "-c", "true", "-project", "q4"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != "Updated 2 tasks.\n" {
+            t.Errorf("expected: %q, got: %q", "Updated 2 tasks.\n", got)
+        }
+
+        completed := true
+        tasks, _ := database.ListTasksAction(db, actor, database.ListTaskProps{WhereCompleted: &completed})
+
+        if len(tasks) != 2 || tasks[0].Project != "q4" || tasks[1].Project != "q4" {
+            t.Errorf("expected the work tasks to be completed and moved, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should refuse a task along -where", func (t *testing.T) {
         oldStdout, r, w := mockTearUpStdout(t)
-        help([]string{"help", "u"})
+        newApp(db).Execute([]string{"u", "milk", "-where", "tag:work", "-c", "true"})
         got := mockTearDownStdout(t, oldStdout, r, w)
-        want := fmt.Sprintf("%s\n", DefaultUpdateUsageStr)
+        want := usageError("Provide either a task or -where, not both", "update")
 
         if got != want {
-            t.Errorf("expected: %s, got: %s", want, got)
+            t.Errorf("expected: %q, got: %q", want, got)
         }
     })
 }
 
-func mockTearUpStdout(t testing.TB) (oldStdout *os.File, r *os.File, w *os.File){
-    t.Helper()
-    // capturing the original stdout
-    oldStdout = os.Stdout
-    // r is a read file connected to the w write file, so bytes writen to w can be read from r
-    r, w, err := os.Pipe()
-    // changing os.Stdout to the new created write file
-    os.Stdout = w
+func TestTaskStores(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
 
-    if err != nil {
-        t.Fatal("error while acquiring pair of files")
-    }
+    actor := mockActor(t, db)
+    before, _ := database.ListTasksAction(db, actor, database.ListTaskProps{WithArchived: true})
+    path := filepath.Join(t.TempDir(), "tasks.json")
+    t.Setenv(StoreEnvVar, "json:"+path)
 
-    return oldStdout, r, w
-}
+    t.Run("Should keep the tasks in the configured store", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "Pay rent #home"})
+        newApp(db).Execute([]string{"a", "-n", "Buy milk"})
+        newApp(db).Execute([]string{"done", "rent"})
+        newApp(db).Execute([]string{"u", "milk", "-project", "errands"})
+        newApp(db).Execute([]string{"d", "1"})
+        newApp(db).Execute([]string{"l"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Task with ID: 1 created!\nTask with ID: 2 created!\nTask 1 done: Pay rent\nTask 2 updated\nDeleted 1 tasks.\n2.[ ] - Buy milk +errands\n"
 
-func mockTearDownStdout(t testing.TB, oldStdout *os.File, r *os.File, w *os.File) string { 
-    t.Helper()
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
 
-    outputCopy := make(chan string)
-    go func () {
-        var buff bytes.Buffer
-        // Will block until there are bytes available to be read from r
-        io.Copy(&buff, r)
-        // If anythin that writes to stdout is executed, we'll copy the data to our copy
-        outputCopy <- buff.String()
-    }()
+        if content, err := os.ReadFile(path); err != nil || !strings.Contains(string(content), "Buy milk") {
+            t.Errorf("expected the tasks in %s, got %q and %v\n", path, content, err)
+        }
 
-    // If there's nothing to write to stdout, io.Copy will be able to recognize that the write end of the
-    // file is closed and that no more data will be write.
-    w.Close()
-    os.Stdout = oldStdout
+        if after, _ := database.ListTasksAction(db, actor, database.ListTaskProps{WithArchived: true}); len(after) != len(before) {
+            t.Errorf("expected task.db untouched, it went from %d to %d tasks\n", len(before), len(after))
+        }
+    })
 
-    return <-outputCopy
-}
+    t.Run("Should refuse the commands working on task.db", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"undo"})
+        newApp(db).Execute([]string{"show", "-history", "2"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := fmt.Sprintf(
+            "Error: undo needs a SQL store, GO_TODO_STORE is 'json:%[1]s'\nError: show -history needs a SQL store, GO_TODO_STORE is 'json:%[1]s'\n",
+            path,
+        )
 
-func getDBTransaction(t testing.TB) (*sql.Tx) {
-    t.Helper()
-    db, err := database.OpenDatabase("./")
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
 
-    if err != nil {
-        t.Fatalf("error while connecting to the database, %s\n", err)
-    }
-    defer db.Close()
+    t.Run("Should refuse the memory store", func (t *testing.T) {
+        t.Setenv(StoreEnvVar, "memory")
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "Pay rent"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Error: invalid GO_TODO_STORE, the memory store doesn't outlive a command, use sqlite, json:<path> or postgres://...\n"
 
-    tx, err := db.Begin()
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
 
-    if err != nil {
-        t.Fatalf("error while acquiring transaction, %s\n", err)
-    }
+    t.Run("Should refuse the commands working on the task.db file on PostgreSQL", func (t *testing.T) {
+        t.Setenv(StoreEnvVar, "postgres://todo@localhost/todo")
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"doctor"})
+        newApp(db).Execute([]string{"backup", filepath.Join(t.TempDir(), "backup.db")})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Error: doctor needs task.db, GO_TODO_STORE is 'postgres://todo@localhost/todo'\nError: backup needs task.db, GO_TODO_STORE is 'postgres://todo@localhost/todo'\n"
 
-    return tx
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
 }
 
-func mockTask(t testing.TB, db *sql.Tx) database.Task {
-    t.Helper()
-    task, err := database.AddTaskAction(db, database.AddTaskProp{
-        Name: "Test",
-        Completed: false,
+func TestRunOnPostgres(t *testing.T) {
+    t.Run("Should stop when the database is unreachable", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        err := runOnPostgres("postgres://nobody@127.0.0.1:1/db?sslmode=disable&connect_timeout=2", []string{"l"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if err == nil {
+            t.Error("expected an error for an unreachable database")
+        }
+
+        if got != "" {
+            t.Errorf("expected no command to run, got: %q", got)
+        }
     })
+}
 
-    if err != nil {
-        t.Fatalf("error while mocking task, %s\n", err)
-    }
+func TestUndoRedo(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
 
-    return task
+    first := mockTask(t, db)
+    second := mockTask(t, db)
+
+    t.Run("Should undo a bulk delete", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"d", strconv.Itoa(first.ID), strconv.Itoa(second.ID)})
+        newApp(db).Execute([]string{"undo"})
+        newApp(db).Execute([]string{"l"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := fmt.Sprintf(
+            "Deleted 2 tasks.\nUndid deleting 2 tasks %[1]d 'Test', %[2]d 'Test'\n%[1]d.[ ] - Test\n%[2]d.[ ] - Test\n",
+            first.ID, second.ID,
+        )
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should redo and list the history", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"u", strconv.Itoa(first.ID), "-n", "Renamed", "-c", "true"})
+        newApp(db).Execute([]string{"undo"})
+        newApp(db).Execute([]string{"redo"})
+        newApp(db).Execute([]string{"redo"})
+        newApp(db).Execute([]string{"undo", "2"})
+        newApp(db).Execute([]string{"history", "-n", "2"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+        want := []string{
+            fmt.Sprintf("Task %d updated", first.ID),
+            fmt.Sprintf("Undid updating task %d 'Renamed': name, completed", first.ID),
+            fmt.Sprintf("Redid updating task %d 'Renamed': name, completed", first.ID),
+            "Error: Nothing to redo",
+            fmt.Sprintf("Undid updating task %d 'Renamed': name, completed", first.ID),
+            fmt.Sprintf("Undid adding task %d 'Test'", second.ID),
+        }
+
+        if len(lines) != len(want) + 2 || strings.Join(lines[:len(want)], "\n") != strings.Join(want, "\n") {
+            t.Fatalf("expected: %q, got: %q", want, lines)
+        }
+
+        if !strings.HasSuffix(lines[len(want)], fmt.Sprintf("updating task %d 'Renamed': name, completed (undone)", first.ID)) {
+            t.Errorf("expected the undone update in the history, got: %q", lines[len(want)])
+        }
+    })
+
+    t.Run("Should print usage for an invalid number of changes", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"undo", "0"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Expected a number of changes, got '0'", "undo") {
+            t.Error("should have printed usage, got:", got)
+        }
+    })
+}
+
+func TestTrash(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    task := mockTask(t, db)
+
+    t.Run("Should list, restore and purge the deleted tasks", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"d", strconv.Itoa(task.ID)})
+        newApp(db).Execute([]string{"trash", "ls"})
+        newApp(db).Execute([]string{"trash", "restore", strconv.Itoa(task.ID)})
+        newApp(db).Execute([]string{"trash", "ls"})
+        newApp(db).Execute([]string{"d", strconv.Itoa(task.ID)})
+        newApp(db).Execute([]string{"trash", "empty", "-older-than", "1d"})
+        newApp(db).Execute([]string{"trash", "empty"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+        want := []string{
+            "Deleted 1 tasks.",
+            fmt.Sprintf("%d.[ ] - Test (deleted ", task.ID),
+            "Restored 1 tasks.",
+            "The trash is empty.",
+            "Deleted 1 tasks.",
+            "Purged 0 tasks.",
+            "Purged 1 tasks.",
+        }
+
+        if len(lines) != len(want) {
+            t.Fatalf("expected: %q, got: %q", want, lines)
+        }
+
+        for idx := range want {
+            if !strings.HasPrefix(lines[idx], want[idx]) {
+                t.Errorf("expected: %q, got: %q", want[idx], lines[idx])
+            }
+        }
+    })
+
+    t.Run("Should print usage for an invalid age", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"trash", "empty", "-older-than", "soon"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Invalid value 'soon' for -older-than, expected an age like 30d, 2w or 12h", "trash", "empty") {
+            t.Error("should have printed usage, got:", got)
+        }
+    })
+}
+
+func TestArchive(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockActor(t, db)
+    rent, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Pay rent", Completed: true})
+    fence, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Paint the fence", Completed: true})
+    milk, _ := database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Buy milk"})
+
+    if _, err := db.Exec("UPDATE tasks SET completed_at = $1 WHERE id = $2", time.Now().AddDate(0, 0, -20).UTC(), rent.ID); err != nil {
+        t.Fatalf("error while backdating the task, %s", err)
+    }
+
+    t.Run("Should archive the tasks completed long enough ago", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"archive", "-older-than", "14d"})
+        newApp(db).Execute([]string{"l"})
+        newApp(db).Execute([]string{"l", "-archived"})
+        newApp(db).Execute([]string{"search", "rent"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := fmt.Sprintf(
+            "Archived 1 tasks.\n%[2]d.[x] - Paint the fence\n%[3]d.[ ] - Buy milk\n%[1]d.[x] - Pay rent\n%[1]d.[x] - Pay *rent* (archived)\n",
+            rent.ID, fence.ID, milk.ID,
+        )
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should archive and unarchive the given tasks", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"archive", "milk"})
+        newApp(db).Execute([]string{"unarchive", strconv.Itoa(rent.ID), strconv.Itoa(milk.ID)})
+        newApp(db).Execute([]string{"l", "-archived", "-f", "ids"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Archived 1 tasks.\nUnarchived 2 tasks.\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should refuse tasks and filters together", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"archive", "milk", "-older-than", "14d"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Provide either tasks or -older-than, not both", "archive") {
+            t.Error("should have printed usage, got:", got)
+        }
+    })
+
+    t.Run("Should archive on startup when asked to", func (t *testing.T) {
+        t.Setenv(AutoArchiveEnvVar, "14d")
+        autoArchive(db)
+
+        tasks, _ := database.ListTasksAction(db, actor, database.ListTaskProps{Archived: true})
+
+        if len(tasks) != 1 || tasks[0].ID != rent.ID {
+            t.Errorf("expected the old completed task to be archived, got %+v\n", tasks)
+        }
+    })
+}
+
+func TestChangesTasks(t *testing.T) {
+    app := newApp(nil)
+
+    t.Run("Should run the housekeeping before the commands changing the tasks", func (t *testing.T) {
+        for _, args := range [][]string{{"add", "Buy milk"}, {"u", "1", "-c", "true"}, {"trash", "empty"}, {"share", "add", "bob"}} {
+            if !changesTasks(app, args) {
+                t.Errorf("expected %q to change the tasks", args)
+            }
+        }
+    })
+
+    t.Run("Should skip the housekeeping for completion and the read-only commands", func (t *testing.T) {
+        for _, args := range [][]string{{"__complete", "add", ""}, {"ls"}, {"show", "1"}, {"trash", "list"}, {"help"}, {"share"}, {}} {
+            if changesTasks(app, args) {
+                t.Errorf("expected %q to leave the tasks alone", args)
+            }
+        }
+    })
+}
+
+func TestSnapshots(t *testing.T) {
+    folder := t.TempDir()
+    policy, ok := parseSnapshotPolicy("daily=2, weekly=1")
+
+    if !ok || policy.daily != 2 || policy.weekly != 1 {
+        t.Fatalf("expected 2 daily and 1 weekly snapshots, got %+v\n", policy)
+    }
+
+    if _, ok := parseSnapshotPolicy("hourly=3"); ok {
+        t.Error("expected an error for an unknown kind of snapshot")
+    }
+
+    t.Run("Should take the missing snapshots and rotate the oldest ones", func (t *testing.T) {
+        for _, day := range []string{"2026-10-17", "2026-10-18", "2026-10-19", "2026-10-19"} {
+            now, _ := time.Parse("2006-01-02", day)
+
+            if err := rotateSnapshots("./", folder, policy, now); err != nil {
+                t.Fatalf("error while taking snapshots, %s\n", err)
+            }
+        }
+
+        entries, _ := os.ReadDir(folder)
+        names := make([]string, len(entries))
+
+        for idx, entry := range entries {
+            names[idx] = entry.Name()
+        }
+
+        want := "daily-2026-10-18.db,daily-2026-10-19.db,weekly-2026-W43.db"
+
+        if got := strings.Join(names, ","); got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should delete the snapshots of a kind kept zero times", func (t *testing.T) {
+        now, _ := time.Parse("2006-01-02", "2026-10-20")
+
+        if err := rotateSnapshots("./", folder, snapshotPolicy{daily: 0, weekly: 1}, now); err != nil {
+            t.Fatalf("error while taking snapshots, %s\n", err)
+        }
+
+        entries, _ := os.ReadDir(folder)
+        names := make([]string, len(entries))
+
+        for idx, entry := range entries {
+            names[idx] = entry.Name()
+        }
+
+        want := "weekly-2026-W43.db"
+
+        if got := strings.Join(names, ","); got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+}
+
+func TestDoctor(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor := mockActor(t, db)
+    db.Exec("PRAGMA defer_foreign_keys = ON;")
+    db.Exec("INSERT INTO shares (owner_id, user_id, permission) VALUES ($1, 424242, 'read');", actor.ID)
+
+    t.Run("Should report the problems found", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"doctor"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "integrity: ok\nschema: ok\nforeign keys: 1 problems\n" +
+            "  1 rows of shares reference missing users in user_id, fix: delete the rows\n" +
+            "orphans: ok\nRun doctor -fix to fix 1 problems.\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should fix them", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"doctor", "-fix"})
+        newApp(db).Execute([]string{"doctor"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "integrity: ok\nschema: ok\nforeign keys: 1 problems\n" +
+            "  1 rows of shares reference missing users in user_id, fixed: delete the rows\n" +
+            "orphans: ok\nFixed 1 problems.\n" +
+            "integrity: ok\nschema: ok\nforeign keys: ok\norphans: ok\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+}
+
+func TestHelp (t *testing.T) {
+    t.Run("Should print every command if there was no option provided", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := newApp(nil).Help()
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+
+    t.Run("Should print unexistent option if the provided option doesn't exist", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help", "asdf"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Error: Option asdf doesn't exist\n"
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+
+    t.Run("Should print the default usage of add option", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help", "a"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := newApp(nil).Lookup("add").Help()
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+
+    t.Run("Should print the default usage of list option", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help", "l"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := newApp(nil).Lookup("list").Help()
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+
+    t.Run("Should print the default usage of delete option", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help", "d"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := newApp(nil).Lookup("delete").Help()
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+
+    t.Run("Should print the default usage of update option", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(nil).Execute([]string{"help", "u"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := newApp(nil).Lookup("update").Help()
+
+        if got != want {
+            t.Errorf("expected: %s, got: %s", want, got)
+        }
+    })
+}
+
+// usageError is the output of a command line rejected by the command at path.
+func usageError(message string, path ...string) string {
+    return fmt.Sprintf("%s\n%s\n", message, newApp(nil).Lookup(path...).UsageLine())
+}
+
+func TestEditTasks(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    first := mockTask(t, db)
+    second, _ := database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Second"})
+
+    // The editor replaces the file with the content given to edit.
+    edit := func (t *testing.T, content string) string {
+        t.Helper()
+        edited := filepath.Join(t.TempDir(), "edited")
+        os.WriteFile(edited, []byte(content), 0600)
+        t.Setenv("VISUAL", "")
+        t.Setenv("EDITOR", fmt.Sprintf("cp '%s'", edited))
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"edit"})
+        return mockTearDownStdout(t, oldStdout, r, w)
+    }
+
+    t.Run("Should report when nothing changed", func (t *testing.T) {
+        t.Setenv("VISUAL", "")
+        t.Setenv("EDITOR", "true")
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"edit"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if want := "No changes.\n"; got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should abort on lines which can't be read", func (t *testing.T) {
+        got := edit(t, fmt.Sprintf("%d [x] Test\n[ ] New !low !high\n", first.ID))
+
+        if !strings.HasPrefix(got, "Error: line 2: Priority given twice, '!low' and '!high'\nNothing was changed, the edits are kept in ") {
+            t.Errorf("expected the parse error, got: %q", got)
+        }
+
+        if tasks, _ := database.ListTasksAction(db, mockActor(t, db), database.ListTaskProps{}); len(tasks) != 2 || tasks[0].Completed {
+            t.Errorf("expected the tasks to be left as they were, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should apply the changes and print a summary", func (t *testing.T) {
+        got := edit(t, fmt.Sprintf("%d [x] Test !high\n[ ] Third 2030-01-02 #home\n", first.ID))
+        // Deletions come first, the deleted task stays in the trash with its ID.
+        want := fmt.Sprintf(
+            "Deleted task %d: Second\nUpdated task %d: Test\nCreated task %d: Third\n1 created, 1 updated, 1 deleted.\n",
+            second.ID, first.ID, second.ID + 1,
+        )
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"l"})
+        got = mockTearDownStdout(t, oldStdout, r, w)
+        want = fmt.Sprintf("%d.[x] - Test !high\n%d.[ ] - Third 2030-01-02 #home\n", first.ID, second.ID + 1)
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+}
+
+func TestShell(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    task := mockTask(t, db)
+    id := strconv.Itoa(task.ID)
+
+    runShell := func (t *testing.T, session *shellSession, script string) string {
+        t.Helper()
+        history, _ := repl.OpenHistory("")
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        err := session.run(strings.NewReader(script), os.Stdout, history)
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if err != nil {
+            t.Fatalf("unexpected error, %s\n", err)
+        }
+
+        return got
+    }
+
+    t.Run("Should run commands until exit", func (t *testing.T) {
+        got := runShell(t, &shellSession{db: db}, "u "+id+" -n 'Walk the dog'\n\nls\nexit\nd "+id+"\n")
+        want := fmt.Sprintf("Task %d updated\n%d.[ ] - Walk the dog\n", task.ID, task.ID)
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should report unterminated quotes", func (t *testing.T) {
+        got := runShell(t, &shellSession{db: db}, "a -n 'Buy milk\n")
+        want := "Error: unterminated quote\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should discard the changes of a rolled back transaction", func (t *testing.T) {
+        got := runShell(t, &shellSession{db: db}, "begin\nd "+id+"\nrollback\nls\n")
+        want := fmt.Sprintf(
+            "Transaction started, commit or rollback to end it.\nDeleted 1 tasks.\nChanges rolled back.\n%d.[ ] - Walk the dog\n",
+            task.ID,
+        )
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should roll back a transaction left pending", func (t *testing.T) {
+        session := &shellSession{db: db}
+        session.begin()
+
+        got := runShell(t, session, "u "+id+" -c true\n")
+        want := fmt.Sprintf("Task %d updated\nUncommitted changes rolled back.\n", task.ID)
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        if stored, _ := database.ListTaskActionByID(db, mockActor(t, db), uint(task.ID)); stored.Completed {
+            t.Error("expected the update to be rolled back")
+        }
+    })
+
+    t.Run("Should keep the changes of a committed transaction", func (t *testing.T) {
+        got := runShell(t, &shellSession{db: db}, "begin\nu "+id+" -c true\ncommit\nbegin\n")
+        want := fmt.Sprintf(
+            "Transaction started, commit or rollback to end it.\nTask %d updated\nChanges committed.\nTransaction started, commit or rollback to end it.\nUncommitted changes rolled back.\n",
+            task.ID,
+        )
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+
+        if stored, _ := database.ListTaskActionByID(db, mockActor(t, db), uint(task.ID)); !stored.Completed {
+            t.Error("expected the update to be committed")
+        }
+    })
+
+    t.Run("Should hold webhooks back until the commit", func (t *testing.T) {
+        session := &shellSession{db: db}
+        session.begin()
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        session.execute([]string{"u", id, "-n", "Held back"})
+        mockTearDownStdout(t, oldStdout, r, w)
+
+        if len(session.tx.notifications) != 1 || session.tx.notifications[0].event != webhook.EventUpdated {
+            t.Errorf("expected a queued rename notification, got %+v\n", session.tx.notifications)
+        }
+
+        session.rollback()
+    })
+
+    t.Run("Should refuse to commit without a transaction", func (t *testing.T) {
+        got := runShell(t, &shellSession{db: db}, "commit\n")
+        want := "Error: No transaction in progress\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should leave out the commands which don't return", func (t *testing.T) {
+        app := (&shellSession{db: db}).app()
+
+        for _, sub := range app.Subcommands {
+            if Include([]string{"tui", "rpc", "serve", "daemon", "shell"}, sub.Name) {
+                t.Errorf("expected %s to be left out of the shell\n", sub.Name)
+            }
+        }
+    })
+
+    t.Run("Should complete the shell commands", func (t *testing.T) {
+        got := (&shellSession{db: db}).app().Complete([]string{"co"})
+        want := []cli.Completion{{Value: "commit", Description: "Save the changes of the transaction"}}
+
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("expected: %v, got: %v", want, got)
+        }
+    })
+}
+
+func mockTearUpStdout(t testing.TB) (oldStdout *os.File, r *os.File, w *os.File){
+    t.Helper()
+    // capturing the original stdout
+    oldStdout = os.Stdout
+    // r is a read file connected to the w write file, so bytes writen to w can be read from r
+    r, w, err := os.Pipe()
+    // changing os.Stdout to the new created write file
+    os.Stdout = w
+
+    if err != nil {
+        t.Fatal("error while acquiring pair of files")
+    }
+
+    return oldStdout, r, w
+}
+
+func mockTearDownStdout(t testing.TB, oldStdout *os.File, r *os.File, w *os.File) string { 
+    t.Helper()
+
+    outputCopy := make(chan string)
+    go func () {
+        var buff bytes.Buffer
+        // Will block until there are bytes available to be read from r
+        io.Copy(&buff, r)
+        // If anythin that writes to stdout is executed, we'll copy the data to our copy
+        outputCopy <- buff.String()
+    }()
+
+    // If there's nothing to write to stdout, io.Copy will be able to recognize that the write end of the
+    // file is closed and that no more data will be write.
+    w.Close()
+    os.Stdout = oldStdout
+
+    return <-outputCopy
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("./")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
+
+func mockActor(t testing.TB, db database.DB) database.User {
+    t.Helper()
+    actor, err := currentUser(db)
+
+    if err != nil {
+        t.Fatalf("error while resolving the current user, %s\n", err)
+    }
+
+    return actor
+}
+
+func mockTask(t testing.TB, db *sql.Tx) database.Task {
+    t.Helper()
+    task, err := database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{
+        Name: "Test",
+        Completed: false,
+    })
+
+    if err != nil {
+        t.Fatalf("error while mocking task, %s\n", err)
+    }
+
+    return task
+}
+
+func TestWebhooks(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    t.Run("Should print usage if there's no subcommand", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != newApp(nil).Lookup("webhooks").Help() {
+            t.Error("should have printed usage, got:", got)
+        }
+    })
+
+    t.Run("Should print usage when adding a webhook with an unknown event", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks", "add", "-url", "http://localhost", "-events", "created,asdf"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Invalid value 'asdf' for -events, expected created|updated|completed|deleted", "webhooks", "add") {
+            t.Error("should have printed add usage, got:", got)
+        }
+    })
+
+    t.Run("Should create and list webhooks", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks", "add", "-url", "http://localhost", "-events", "created,deleted", "-secret", "s3cr3t"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "Webhook with ID: 1 created! Secret: s3cr3t\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+
+        oldStdout, r, w = mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks", "list"})
+        got = mockTearDownStdout(t, oldStdout, r, w)
+        want = "1. http://localhost [created,deleted]\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+
+        database.DeleteWebhookAction(db, 1)
+    })
+
+    t.Run("Should queue the events and deliver them on demand", func (t *testing.T) {
+        received := make(chan string, 1)
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            received <- r.Header.Get(webhook.EventHeader)
+        }))
+        defer server.Close()
+
+        hook, _ := database.AddWebhookAction(db, database.AddWebhookProp{URL: server.URL, Secret: "s3cr3t", Events: []string{webhook.EventCreated}})
+        defer database.DeleteWebhookAction(db, hook.ID)
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"a", "-n", "Queued"})
+        mockTearDownStdout(t, oldStdout, r, w)
+
+        if len(received) != 0 {
+            t.Fatal("expected the event to wait in the queue")
+        }
+
+        oldStdout, r, w = mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks", "deliveries", "-queued", "true"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if !strings.Contains(got, fmt.Sprintf("webhook %d - created - next attempt", hook.ID)) {
+            t.Errorf("expected the queued event, got: %q", got)
+        }
+
+        oldStdout, r, w = mockTearUpStdout(t)
+        newApp(db).Execute([]string{"webhooks", "deliver"})
+        got = mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != "Delivered 1 queued event(s)\n" || len(received) != 1 {
+            t.Errorf("expected the event delivered, got: %q", got)
+        }
+    })
+}
+
+func TestTokens(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    t.Run("Should print usage when creating a token without a name", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"token", "create", "-scopes", "read"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Parameter -name is required", "token", "create") {
+            t.Error("should have printed create usage, got:", got)
+        }
+    })
+
+    t.Run("Should print usage when creating a token with an unknown scope", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"token", "create", "-name", "ci", "-scopes", "read,root"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if got != usageError("Invalid value 'root' for -scopes, expected read|write|admin", "token", "create") {
+            t.Error("should have printed create usage, got:", got)
+        }
+    })
+
+    t.Run("Should create tokens acting as the current user", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"token", "create", "-name", "cli"})
+        mockTearDownStdout(t, oldStdout, r, w)
+
+        tokens, _ := database.ListTokensAction(db)
+
+        if len(tokens) == 0 || tokens[len(tokens) - 1].UserID == nil || *tokens[len(tokens) - 1].UserID != mockActor(t, db).ID {
+            t.Errorf("expected the token to belong to the current user, got %+v\n", tokens)
+        }
+    })
+
+    t.Run("Should revoke tokens", func (t *testing.T) {
+        token, _, err := database.AddTokenAction(db, database.AddTokenProp{Name: "ci", UserID: mockActor(t, db).ID, Scopes: []string{"read"}})
+
+        if err != nil {
+            t.Fatalf("error while mocking token, %s\n", err)
+        }
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"token", "revoke", strconv.Itoa(token.ID)})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := fmt.Sprintf("Token %d revoked\n", token.ID)
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+}
+
+func TestListTasksByAssignee(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    bob, err := database.AddUserAction(db, "bob")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s\n", err)
+    }
+
+    mockTask(t, db)
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Bob's", AssigneeID: &bob.ID})
+
+    t.Run("Should only list the tasks assigned to the provided user", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"l", "-assignee", "bob"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "2.[ ] - Bob's (@bob)\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should not list tasks of other users with -mine", func (t *testing.T) {
+        t.Setenv(UserEnvVar, "bob")
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"l", "-mine"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "2.[ ] - Bob's (@bob)\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+}
+
+func TestViews(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+    mockTask(t, db)
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Urgent", Priority: database.PriorityHigh})
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Done", Priority: database.PriorityHigh, Completed: true})
+
+    run := func (t *testing.T, args ...string) string {
+        t.Helper()
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute(args)
+        return mockTearDownStdout(t, oldStdout, r, w)
+    }
+
+    t.Run("Should save and list views", func (t *testing.T) {
+        got := run(t, "view", "save", "urgent", "-where", "priority>=high", "-sort", "id,desc")
+        want := "View @urgent saved\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+
+        run(t, "view", "save", "@ids", "-format", "ids")
+        got = run(t, "view", "ls")
+        want = "@ids -format ids\n@urgent -where \"priority>=high\" -sort id,desc\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should refuse views with an invalid filter", func (t *testing.T) {
+        got := run(t, "view", "save", "broken", "-where", "priority>=")
+        want := "Error: Expected a value after '>=', got the end of the filter at column 11\n  priority>=\n            ^\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should list the tasks of a view", func (t *testing.T) {
+        got := run(t, "l", "@urgent")
+        want := "3.[x] - Done !high\n2.[ ] - Urgent !high\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should combine the view with the flags", func (t *testing.T) {
+        got := run(t, "l", "@urgent", "-where", "not done", "-format", "json")
+        tasks := make([]database.Task, 0)
+
+        if err := json.Unmarshal([]byte(got), &tasks); err != nil || len(tasks) != 1 || tasks[0].Name != "Urgent" {
+            t.Errorf("expected the pending urgent task as JSON, got %s", got)
+        }
+
+        got = run(t, "l", "@ids", "-sort", "id,desc")
+        want := "3\n2\n1\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should report unknown views", func (t *testing.T) {
+        got := run(t, "l", "@nope")
+        want := "Error: View 'nope' doesn't exist\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+
+        got = run(t, "l", "urgent")
+        want = usageError("Expected a view like @today, got 'urgent'", "list")
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+    })
+
+    t.Run("Should delete views", func (t *testing.T) {
+        got := run(t, "view", "rm", "@urgent")
+        want := "View @urgent deleted\n"
+
+        if got != want {
+            t.Error("expected:", want, "got:", got)
+        }
+
+        if got := run(t, "view", "ls"); got != "@ids -format ids\n" {
+            t.Error("expected only @ids to be left, got:", got)
+        }
+    })
+}
+
+func TestSearchTasks(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Pay rent", Notes: "Transfer it to the landlord"})
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Call the landlord"})
+
+    t.Run("Should print the matching tasks with the matches highlighted", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"search", "landlord"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "2.[ ] - Call the *landlord*\n1.[ ] - Pay rent\n    Transfer it to the *landlord*\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should say when nothing matched", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"find", "-n", "1", "groceries"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "No matching tasks.\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+}
+
+func TestCompletion(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    mockTask(t, db)
+    database.AddTaskAction(db, mockActor(t, db), database.AddTaskProp{Name: "Second"})
+
+    t.Run("Should complete task IDs with their names", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"__complete", "d", "1", ""})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "2\tSecond\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should complete flags of a subcommand", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"__complete", "u", "1", "-as"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "-assignee\tassign the task to a user, none unassigns it\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should complete the projects and the tags of the tasks", func (t *testing.T) {
+        actor := mockActor(t, db)
+        database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Ship", Project: "launch", Tags: []string{"work", "urgent"}})
+        database.AddTaskAction(db, actor, database.AddTaskProp{Name: "Plan", Project: "backlog", Tags: []string{"work"}})
+
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"__complete", "list", "-project", ""})
+        newApp(db).Execute([]string{"__complete", "u", "1", "-project", "la"})
+        newApp(db).Execute([]string{"__complete", "list", "-tag", ""})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := "backlog\t\nlaunch\t\nlaunch\t\nurgent\t\nwork\t\n"
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should list the tasks of a project or carrying a tag", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"list", "-project", "backlog", "-format", "ids"})
+        newApp(db).Execute([]string{"list", "-tag", "Urgent", "-format", "ids"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+
+        if lines := strings.Fields(got); len(lines) != 2 || lines[0] == lines[1] {
+            t.Errorf("expected a task of each, got: %q", got)
+        }
+    })
+
+    t.Run("Should print usage for an unknown shell", func (t *testing.T) {
+        oldStdout, r, w := mockTearUpStdout(t)
+        newApp(db).Execute([]string{"completion", "powershell"})
+        got := mockTearDownStdout(t, oldStdout, r, w)
+        want := usageError("Unsupported shell 'powershell', expected one of bash|zsh|fish", "completion")
+
+        if got != want {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
 }
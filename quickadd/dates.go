@@ -0,0 +1,427 @@
+package quickadd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Abbreviations which are also common words, like sun and sat, aren't
+// recognized.
+var weekdays = map[string]time.Weekday{
+    "sunday": time.Sunday,
+    "monday": time.Monday, "mon": time.Monday,
+    "tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+    "wednesday": time.Wednesday, "wed": time.Wednesday,
+    "thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+    "friday": time.Friday, "fri": time.Friday,
+    "saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+    "january": time.January, "jan": time.January,
+    "february": time.February, "feb": time.February,
+    "march": time.March, "mar": time.March,
+    "april": time.April, "apr": time.April,
+    "may": time.May,
+    "june": time.June, "jun": time.June,
+    "july": time.July, "jul": time.July,
+    "august": time.August, "aug": time.August,
+    "september": time.September, "sep": time.September, "sept": time.September,
+    "october": time.October, "oct": time.October,
+    "november": time.November, "nov": time.November,
+    "december": time.December, "dec": time.December,
+}
+
+// units are the lengths relative dates and recurrences are expressed in.
+var units = map[string]string{
+    "day": "day", "days": "day",
+    "week": "week", "weeks": "week",
+    "month": "month", "months": "month",
+    "year": "year", "years": "year",
+}
+
+// clockUnits only make sense relative to now.
+var clockUnits = map[string]time.Duration{
+    "hour": time.Hour, "hours": time.Hour, "hr": time.Hour, "hrs": time.Hour,
+    "minute": time.Minute, "minutes": time.Minute, "min": time.Minute, "mins": time.Minute,
+}
+
+var recurrenceWords = map[string]string{
+    "daily": "day",
+    "weekly": "week",
+    "monthly": "month",
+    "yearly": "year",
+    "annually": "year",
+}
+
+var prepositions = []string{"due", "on", "by", "at"}
+
+var (
+    twelveHourPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+    twentyFourHourPattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+    bareClockPattern = regexp.MustCompile(`^\d{1,2}(?::\d{2})?$`)
+    dayPattern = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+    yearPattern = regexp.MustCompile(`^\d{4}$`)
+    isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+func (p *parser) matchRecurrence() (int, error) {
+    word, _ := p.word(0)
+
+    if unit, ok := recurrenceWords[word]; ok {
+        return 1, p.setRecurrence(1, 1, unit)
+    }
+
+    if word != "every" {
+        return 0, nil
+    }
+
+    next, _ := p.word(1)
+
+    if weekday, ok := weekdays[next]; ok {
+        if err := p.set("Recurrence", 2); err != nil {
+            return 0, err
+        }
+        p.parsed.Recurrence = "every " + strings.ToLower(weekday.String())
+        return 2, nil
+    }
+
+    if unit, ok := units[next]; ok {
+        return 2, p.setRecurrence(2, 1, unit)
+    }
+
+    count, ok := parseCount(next)
+
+    if next == "other" {
+        count, ok = 2, true
+    }
+
+    if unit, isUnit := units[p.wordAt(2)]; ok && isUnit {
+        return 3, p.setRecurrence(3, count, unit)
+    }
+
+    return 0, nil
+}
+
+func (p *parser) setRecurrence(n int, count int, unit string) error {
+    if err := p.set("Recurrence", n); err != nil {
+        return err
+    }
+
+    p.parsed.Recurrence = "every " + unit
+
+    if count != 1 {
+        p.parsed.Recurrence = fmt.Sprintf("every %d %ss", count, unit)
+    }
+
+    return nil
+}
+
+func (p *parser) wordAt(offset int) string {
+    word, _ := p.word(offset)
+    return word
+}
+
+// matchDue reads a date or a time of the day, optionally introduced by a
+// preposition which is only dropped from the name along with the date.
+func (p *parser) matchDue() (int, error) {
+    skipped := 0
+
+    for skipped < 2 && includes(prepositions, p.wordAt(skipped)) {
+        skipped++
+    }
+
+    start := p.pos
+    p.pos += skipped
+    n, err := p.matchDate()
+
+    if err == nil && n == 0 {
+        n, err = p.matchClock()
+    }
+
+    p.pos = start
+
+    if err != nil || n == 0 {
+        return 0, err
+    }
+
+    return skipped + n, nil
+}
+
+// matchDate reads a day at the current position.
+func (p *parser) matchDate() (int, error) {
+    today := startOfDay(p.now)
+    word := p.wordAt(0)
+
+    switch word {
+        case "today", "tod":
+            return p.setDate(1, today)
+        case "tomorrow", "tmr", "tmrw":
+            return p.setDate(1, today.AddDate(0, 0, 1))
+        case "next":
+            next := p.wordAt(1)
+
+            if weekday, ok := weekdays[next]; ok {
+                return p.setDate(2, nextWeekday(today, weekday))
+            }
+
+            switch next {
+                case "week":
+                    return p.setDate(2, nextWeekday(today, time.Monday))
+                case "month":
+                    return p.setDate(2, time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()))
+                case "year":
+                    return p.setDate(2, time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location()))
+            }
+
+            return 0, nil
+        case "in":
+            return p.matchIn(today)
+    }
+
+    if weekday, ok := weekdays[word]; ok {
+        return p.setDate(1, nextWeekday(today, weekday))
+    }
+
+    // 2026-02-29 is refused like feb 29 2026 rather than kept in the name.
+    if isoDatePattern.MatchString(word) {
+        date, err := time.ParseInLocation("2006-01-02", word, p.now.Location())
+
+        if err != nil {
+            return 0, fmt.Errorf("Invalid date '%s'", p.source(1))
+        }
+
+        return p.setDate(1, date)
+    }
+
+    // nov 2 [2027] or 2 nov [2027]
+    month, monthFirst := months[word]
+    dayWord := p.wordAt(1)
+
+    if !monthFirst {
+        var ok bool
+        if month, ok = months[p.wordAt(1)]; !ok {
+            return 0, nil
+        }
+        dayWord = word
+    }
+
+    match := dayPattern.FindStringSubmatch(dayWord)
+
+    if match == nil {
+        return 0, nil
+    }
+
+    day, _ := strconv.Atoi(match[1])
+    year, n := today.Year(), 2
+    explicitYear := yearPattern.MatchString(p.wordAt(2))
+
+    if explicitYear {
+        year, _ = strconv.Atoi(p.wordAt(2))
+        n = 3
+    }
+
+    date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+
+    if date.Day() != day {
+        return 0, fmt.Errorf("Invalid date '%s'", p.source(n))
+    }
+
+    // Dates without a year are the next ones to come.
+    if !explicitYear && date.Before(today) {
+        date = date.AddDate(1, 0, 0)
+    }
+
+    return p.setDate(n, date)
+}
+
+// matchIn reads "in <count> <unit>", count can also be a or an.
+func (p *parser) matchIn(today time.Time) (int, error) {
+    count, ok := parseCount(p.wordAt(1))
+
+    if !ok {
+        return 0, nil
+    }
+
+    unitWord := p.wordAt(2)
+
+    if duration, ok := clockUnits[unitWord]; ok {
+        if err := p.set("Due date", 3); err != nil {
+            return 0, err
+        }
+        exact := p.now.Add(time.Duration(count) * duration).Truncate(time.Minute)
+        p.exact = &exact
+        return 3, nil
+    }
+
+    switch units[unitWord] {
+        case "day":
+            return p.setDate(3, today.AddDate(0, 0, count))
+        case "week":
+            return p.setDate(3, today.AddDate(0, 0, 7*count))
+        case "month":
+            return p.setDate(3, today.AddDate(0, count, 0))
+        case "year":
+            return p.setDate(3, today.AddDate(count, 0, 0))
+    }
+
+    return 0, nil
+}
+
+func (p *parser) setDate(n int, date time.Time) (int, error) {
+    if err := p.set("Due date", n); err != nil {
+        return 0, err
+    }
+
+    p.date = &date
+
+    return n, nil
+}
+
+// matchClock reads a time of the day at the current position.
+func (p *parser) matchClock() (int, error) {
+    word := p.wordAt(0)
+    n := 1
+
+    if word == "noon" {
+        return p.setClock(1, 12, 0)
+    }
+
+    // 9 am is read as 9am.
+    if suffix := p.wordAt(1); (suffix == "am" || suffix == "pm") && bareClockPattern.MatchString(word) {
+        word += suffix
+        n = 2
+    }
+
+    if match := twelveHourPattern.FindStringSubmatch(word); match != nil {
+        hour, _ := strconv.Atoi(match[1])
+        minute, _ := strconv.Atoi(match[2])
+
+        if hour < 1 || hour > 12 || minute > 59 {
+            return 0, fmt.Errorf("Invalid time '%s'", p.source(n))
+        }
+
+        hour %= 12
+
+        if match[3] == "pm" {
+            hour += 12
+        }
+
+        return p.setClock(n, hour, minute)
+    }
+
+    if match := twentyFourHourPattern.FindStringSubmatch(word); match != nil {
+        hour, _ := strconv.Atoi(match[1])
+        minute, _ := strconv.Atoi(match[2])
+
+        if hour > 23 || minute > 59 {
+            return 0, fmt.Errorf("Invalid time '%s'", p.source(1))
+        }
+
+        return p.setClock(1, hour, minute)
+    }
+
+    return 0, nil
+}
+
+func (p *parser) setClock(n int, hour int, minute int) (int, error) {
+    if err := p.set("Time", n); err != nil {
+        return 0, err
+    }
+
+    p.clock = &[2]int{hour, minute}
+
+    return n, nil
+}
+
+// resolveDue combines the date and the time of the day. A time alone is the
+// next time it's reached, a recurrence alone starts on its first occurrence.
+func (p *parser) resolveDue() error {
+    if p.exact != nil {
+        if p.date != nil || p.clock != nil {
+            return fmt.Errorf("Due date given twice, '%s' and '%s'", p.sources["Due date"], p.sources["Time"])
+        }
+        p.parsed.DueAt = p.exact
+        return nil
+    }
+
+    date := p.date
+    today := startOfDay(p.now)
+
+    if date == nil && p.parsed.Recurrence != "" {
+        first := today
+
+        if weekday, ok := weekdays[strings.TrimPrefix(p.parsed.Recurrence, "every ")]; ok {
+            first = nextWeekday(today.AddDate(0, 0, -1), weekday)
+        }
+
+        date = &first
+    }
+
+    if date == nil && p.clock == nil {
+        return nil
+    }
+
+    if date == nil {
+        due := today.Add(time.Duration(p.clock[0])*time.Hour + time.Duration(p.clock[1])*time.Minute)
+
+        if !due.After(p.now) {
+            due = due.AddDate(0, 0, 1)
+        }
+
+        p.parsed.DueAt = &due
+        return nil
+    }
+
+    due := *date
+
+    if p.clock != nil {
+        due = time.Date(due.Year(), due.Month(), due.Day(), p.clock[0], p.clock[1], 0, 0, due.Location())
+    }
+
+    p.parsed.DueAt = &due
+
+    return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextWeekday is the first weekday strictly after day.
+func nextWeekday(day time.Time, weekday time.Weekday) time.Time {
+    days := (int(weekday) - int(day.Weekday()) + 7) % 7
+
+    if days == 0 {
+        days = 7
+    }
+
+    return day.AddDate(0, 0, days)
+}
+
+func parseCount(word string) (int, bool) {
+    if word == "a" || word == "an" {
+        return 1, true
+    }
+
+    count, err := strconv.Atoi(word)
+
+    if err != nil || count < 1 {
+        return 0, false
+    }
+
+    return count, true
+}
+
+func includes(set []string, value string) bool {
+    for _, candidate := range set {
+        if candidate == value {
+            return true
+        }
+    }
+
+    return false
+}
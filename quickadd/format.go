@@ -0,0 +1,60 @@
+package quickadd
+
+import (
+	"go_todo/database"
+	"strings"
+	"time"
+)
+
+// FormatDue writes a due date the way Parse reads it, the time of the day is
+// left out at midnight.
+func FormatDue(dueAt time.Time) string {
+    if dueAt.Hour() == 0 && dueAt.Minute() == 0 {
+        return dueAt.Format("2006-01-02")
+    }
+    return dueAt.Format("2006-01-02 15:04")
+}
+
+// Format writes props as a text Parse reads back into the same props. The
+// name is quoted when some of its words would be interpreted.
+func Format(props database.AddTaskProp, assignee string) string {
+    name := props.Name
+
+    if parsed, err := Parse(name, time.Now()); err != nil || !isPlainName(parsed, name) {
+        name = `"` + name + `"`
+    }
+
+    text := []string{name}
+
+    if props.Priority != database.PriorityNone {
+        text = append(text, "!"+database.PriorityNames[props.Priority])
+    }
+
+    if props.DueAt != nil {
+        text = append(text, FormatDue(*props.DueAt))
+    }
+
+    if props.Recurrence != "" {
+        text = append(text, props.Recurrence)
+    }
+
+    if props.Project != "" {
+        text = append(text, "+"+props.Project)
+    }
+
+    for _, tag := range props.Tags {
+        text = append(text, "#"+tag)
+    }
+
+    if assignee != "" {
+        text = append(text, "@"+assignee)
+    }
+
+    return strings.Join(text, " ")
+}
+
+// isPlainName is true when parsing name only gave back the name.
+func isPlainName(parsed Parsed, name string) bool {
+    return parsed.Name == name && parsed.DueAt == nil && parsed.Priority == database.PriorityNone &&
+        len(parsed.Tags) == 0 && parsed.Project == "" && parsed.Recurrence == "" && parsed.Assignee == ""
+}
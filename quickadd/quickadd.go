@@ -0,0 +1,247 @@
+// Package quickadd reads a task out of free text, e.g.
+// "Pay rent tomorrow 9am #finance !high every month".
+//
+// Words are matched case-insensitively and removed from the name:
+//
+//   - #tag adds a tag, +project sets the project and @user the assignee
+//   - !low, !medium or !high sets the priority, as do !, !! and !!!
+//   - dates: today, tomorrow, a weekday, next week|month|year, in 3 days,
+//     2026-11-02, nov 2, 2nd november 2027, optionally after due, on or by
+//   - times: 9am, 9:30pm, 21:00, noon, optionally after at
+//   - recurrence: daily, weekly, monthly, yearly, every 2 weeks, every monday
+//
+// Text between double quotes is always part of the name.
+package quickadd
+
+import (
+	"fmt"
+	"go_todo/database"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Parsed is what Parse understood of a text.
+type Parsed struct {
+    database.AddTaskProp
+    // Assignee is the name given with @, the caller resolves it.
+    Assignee string
+}
+
+type token struct {
+    text string
+    // literal tokens were quoted and never interpreted.
+    literal bool
+}
+
+type parser struct {
+    now time.Time
+    tokens []token
+    pos int
+    name []string
+    parsed Parsed
+
+    // The due date is assembled from a date and a time of the day, or given
+    // at once by expressions like "in 2 hours".
+    date *time.Time
+    clock *[2]int
+    exact *time.Time
+    // sources keep the words each part was read from for error messages.
+    sources map[string]string
+}
+
+type matcher func (p *parser) (int, error)
+
+var matchers = []matcher{
+    (*parser).matchMarker,
+    (*parser).matchRecurrence,
+    (*parser).matchDue,
+}
+
+// Parse interprets text, relative dates are resolved against now and in its
+// location.
+func Parse(text string, now time.Time) (Parsed, error) {
+    p := &parser{now: now, tokens: tokenize(text), sources: make(map[string]string)}
+
+    for p.pos < len(p.tokens) {
+        consumed := 0
+
+        for _, match := range matchers {
+            n, err := match(p)
+
+            if err != nil {
+                return Parsed{}, err
+            }
+
+            if n > 0 {
+                consumed = n
+                break
+            }
+        }
+
+        if consumed == 0 {
+            p.name = append(p.name, p.tokens[p.pos].text)
+            consumed = 1
+        }
+
+        p.pos += consumed
+    }
+
+    p.parsed.Name = strings.Join(p.name, " ")
+
+    if p.parsed.Name == "" {
+        return Parsed{}, fmt.Errorf("Task name is empty")
+    }
+
+    if err := p.resolveDue(); err != nil {
+        return Parsed{}, err
+    }
+
+    return p.parsed, nil
+}
+
+// tokenize splits text on blanks, quoted text is kept as a single literal
+// token.
+func tokenize(text string) []token {
+    tokens := make([]token, 0)
+    current := strings.Builder{}
+    quoted := false
+
+    flush := func (literal bool) {
+        if current.Len() > 0 || literal {
+            tokens = append(tokens, token{current.String(), literal})
+        }
+        current.Reset()
+    }
+
+    for _, r := range text {
+        switch {
+            case r == '"' && quoted:
+                flush(true)
+                quoted = false
+            case r == '"':
+                flush(false)
+                quoted = true
+            case unicode.IsSpace(r) && !quoted:
+                flush(false)
+            default:
+                current.WriteRune(r)
+        }
+    }
+
+    // An unterminated quote keeps its content literal.
+    flush(quoted)
+
+    return tokens
+}
+
+// word returns the lowercased token at offset from the current position
+// without trailing commas, ok is false past the end and for literal tokens.
+func (p *parser) word(offset int) (string, bool) {
+    idx := p.pos + offset
+
+    if idx >= len(p.tokens) || p.tokens[idx].literal {
+        return "", false
+    }
+
+    return strings.TrimRight(strings.ToLower(p.tokens[idx].text), ","), true
+}
+
+// source is the original text of n tokens from the current position.
+func (p *parser) source(n int) string {
+    words := make([]string, 0, n)
+
+    for _, token := range p.tokens[p.pos:p.pos+n] {
+        words = append(words, token.text)
+    }
+
+    return strings.Join(words, " ")
+}
+
+// set records that part was read from the next n tokens, a part can only be
+// given once.
+func (p *parser) set(part string, n int) error {
+    if previous, ok := p.sources[part]; ok {
+        return fmt.Errorf("%s given twice, '%s' and '%s'", part, previous, p.source(n))
+    }
+
+    p.sources[part] = p.source(n)
+
+    return nil
+}
+
+var priorityMarkers = map[string]int{
+    "!": database.PriorityLow,
+    "!!": database.PriorityMedium,
+    "!!!": database.PriorityHigh,
+    "!low": database.PriorityLow,
+    "!l": database.PriorityLow,
+    "!medium": database.PriorityMedium,
+    "!med": database.PriorityMedium,
+    "!m": database.PriorityMedium,
+    "!high": database.PriorityHigh,
+    "!h": database.PriorityHigh,
+    "!urgent": database.PriorityHigh,
+}
+
+func (p *parser) matchMarker() (int, error) {
+    word, ok := p.word(0)
+
+    if !ok {
+        return 0, nil
+    }
+
+    if priority, ok := priorityMarkers[word]; ok {
+        if err := p.set("Priority", 1); err != nil {
+            return 0, err
+        }
+        p.parsed.Priority = priority
+        return 1, nil
+    }
+
+    if len(word) < 2 || !isIdentifier(word[1:]) {
+        return 0, nil
+    }
+
+    value := word[1:]
+
+    switch word[0] {
+        case '#':
+            for _, tag := range p.parsed.Tags {
+                if tag == value {
+                    return 1, nil
+                }
+            }
+            p.parsed.Tags = append(p.parsed.Tags, value)
+        case '+':
+            if err := p.set("Project", 1); err != nil {
+                return 0, err
+            }
+            p.parsed.Project = value
+        case '@':
+            if err := p.set("Assignee", 1); err != nil {
+                return 0, err
+            }
+            // User names keep their case.
+            p.parsed.Assignee = strings.TrimRight(p.tokens[p.pos].text, ",")[1:]
+        default:
+            return 0, nil
+    }
+
+    return 1, nil
+}
+
+// isIdentifier is true for the values of markers, they start with a letter so
+// "issue #12" stays in the name.
+func isIdentifier(value string) bool {
+    for idx, r := range value {
+        switch {
+            case unicode.IsLetter(r):
+            case idx > 0 && (unicode.IsDigit(r) || strings.ContainsRune("-_/", r)):
+            default:
+                return false
+        }
+    }
+
+    return true
+}
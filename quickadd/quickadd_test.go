@@ -0,0 +1,200 @@
+package quickadd
+
+import (
+	"go_todo/database"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// now is a Monday afternoon.
+var now = time.Date(2026, time.October, 19, 14, 30, 0, 0, time.UTC)
+
+func day(month time.Month, day int, clock ...int) *time.Time {
+    return at(2026, month, day, clock...)
+}
+
+func at(year int, month time.Month, day int, clock ...int) *time.Time {
+    hour, minute := 0, 0
+
+    if len(clock) > 0 {
+        hour = clock[0]
+    }
+
+    if len(clock) > 1 {
+        minute = clock[1]
+    }
+
+    date := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+
+    return &date
+}
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        text string
+        want Parsed
+    }{
+        // Names
+        {"Pay rent", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent"}}},
+        {"  Pay   rent  ", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent"}}},
+        {`Watch "next friday" tonight`, Parsed{AddTaskProp: database.AddTaskProp{Name: "Watch next friday tonight"}}},
+        {`Read "Tomorrow" tomorrow`, Parsed{AddTaskProp: database.AddTaskProp{Name: "Read Tomorrow", DueAt: day(time.October, 20)}}},
+        {"Fix issue #12", Parsed{AddTaskProp: database.AddTaskProp{Name: "Fix issue #12"}}},
+        {"Email bob@example.com", Parsed{AddTaskProp: database.AddTaskProp{Name: "Email bob@example.com"}}},
+        {"Meet at home", Parsed{AddTaskProp: database.AddTaskProp{Name: "Meet at home"}}},
+        {"Pay due invoices", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay due invoices"}}},
+        {"Buy 2 apples", Parsed{AddTaskProp: database.AddTaskProp{Name: "Buy 2 apples"}}},
+        {"Put in a shift", Parsed{AddTaskProp: database.AddTaskProp{Name: "Put in a shift"}}},
+        {"Buy sun cream", Parsed{AddTaskProp: database.AddTaskProp{Name: "Buy sun cream"}}},
+        {"Hooray!!", Parsed{AddTaskProp: database.AddTaskProp{Name: "Hooray!!"}}},
+
+        // Relative dates
+        {"Pay rent today", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 19)}}},
+        {"Pay rent tomorrow", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 20)}}},
+        {"Pay rent TMRW", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 20)}}},
+        {"Tomorrow pay rent", Parsed{AddTaskProp: database.AddTaskProp{Name: "pay rent", DueAt: day(time.October, 20)}}},
+        {"Call mom friday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Call mom", DueAt: day(time.October, 23)}}},
+        {"Call mom on Fri", Parsed{AddTaskProp: database.AddTaskProp{Name: "Call mom", DueAt: day(time.October, 23)}}},
+        {"Call mom monday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Call mom", DueAt: day(time.October, 26)}}},
+        {"Call mom next wednesday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Call mom", DueAt: day(time.October, 21)}}},
+        {"Plan next week", Parsed{AddTaskProp: database.AddTaskProp{Name: "Plan", DueAt: day(time.October, 26)}}},
+        {"Plan next month", Parsed{AddTaskProp: database.AddTaskProp{Name: "Plan", DueAt: day(time.November, 1)}}},
+        {"Plan next year", Parsed{AddTaskProp: database.AddTaskProp{Name: "Plan", DueAt: at(2027, time.January, 1)}}},
+        {"Renew passport in 3 days", Parsed{AddTaskProp: database.AddTaskProp{Name: "Renew passport", DueAt: day(time.October, 22)}}},
+        {"Renew passport in a week", Parsed{AddTaskProp: database.AddTaskProp{Name: "Renew passport", DueAt: day(time.October, 26)}}},
+        {"Renew passport in 2 months", Parsed{AddTaskProp: database.AddTaskProp{Name: "Renew passport", DueAt: day(time.December, 19)}}},
+        {"Renew passport in 1 year", Parsed{AddTaskProp: database.AddTaskProp{Name: "Renew passport", DueAt: at(2027, time.October, 19)}}},
+        {"Check oven in 45 minutes", Parsed{AddTaskProp: database.AddTaskProp{Name: "Check oven", DueAt: day(time.October, 19, 15, 15)}}},
+        {"Check oven in an hour", Parsed{AddTaskProp: database.AddTaskProp{Name: "Check oven", DueAt: day(time.October, 19, 15, 30)}}},
+        {"Pay rent due tomorrow", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 20)}}},
+        {"Pay rent due by friday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 23)}}},
+
+        // Absolute dates
+        {"Vote 2026-11-03", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote", DueAt: day(time.November, 3)}}},
+        {"Vote nov 3", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote", DueAt: day(time.November, 3)}}},
+        {"Vote November 3rd", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote", DueAt: day(time.November, 3)}}},
+        {"Vote 3 nov", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote", DueAt: day(time.November, 3)}}},
+        {"Vote 3rd of", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote 3rd of"}}},
+        {"Vote on nov 3, 2027", Parsed{AddTaskProp: database.AddTaskProp{Name: "Vote", DueAt: at(2027, time.November, 3)}}},
+        {"Celebrate jan 1", Parsed{AddTaskProp: database.AddTaskProp{Name: "Celebrate", DueAt: at(2027, time.January, 1)}}},
+        {"Celebrate oct 19", Parsed{AddTaskProp: database.AddTaskProp{Name: "Celebrate", DueAt: day(time.October, 19)}}},
+        {"Leap feb 29 2028", Parsed{AddTaskProp: database.AddTaskProp{Name: "Leap", DueAt: at(2028, time.February, 29)}}},
+
+        // Times
+        {"Standup 9am", Parsed{AddTaskProp: database.AddTaskProp{Name: "Standup", DueAt: day(time.October, 20, 9)}}},
+        {"Standup 4pm", Parsed{AddTaskProp: database.AddTaskProp{Name: "Standup", DueAt: day(time.October, 19, 16)}}},
+        {"Standup at 4:45 PM", Parsed{AddTaskProp: database.AddTaskProp{Name: "Standup", DueAt: day(time.October, 19, 16, 45)}}},
+        {"Standup 12am tomorrow", Parsed{AddTaskProp: database.AddTaskProp{Name: "Standup", DueAt: day(time.October, 20, 0)}}},
+        {"Lunch noon", Parsed{AddTaskProp: database.AddTaskProp{Name: "Lunch", DueAt: day(time.October, 20, 12)}}},
+        {"Call 21:05", Parsed{AddTaskProp: database.AddTaskProp{Name: "Call", DueAt: day(time.October, 19, 21, 5)}}},
+        {"Pay rent tomorrow 9am", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 20, 9)}}},
+        {"Pay rent at 9:30am on friday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.October, 23, 9, 30)}}},
+
+        // Markers
+        {"Pay rent #finance", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Tags: []string{"finance"}}}},
+        {"Pay #Finance rent #home #finance", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Tags: []string{"finance", "home"}}}},
+        {"Pay rent +Flat", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Project: "flat"}}},
+        {"Pay rent @Alice", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent"}, Assignee: "Alice"}},
+        {"Pay rent !high", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Priority: database.PriorityHigh}}},
+        {"Pay rent !M", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Priority: database.PriorityMedium}}},
+        {"Pay rent !", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Priority: database.PriorityLow}}},
+        {"Pay rent !!!", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", Priority: database.PriorityHigh}}},
+        {"Pay rent !later", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent !later"}}},
+
+        // Recurrence
+        {"Water plants daily", Parsed{AddTaskProp: database.AddTaskProp{Name: "Water plants", DueAt: day(time.October, 19), Recurrence: "every day"}}},
+        {"Water plants every 3 days", Parsed{AddTaskProp: database.AddTaskProp{Name: "Water plants", DueAt: day(time.October, 19), Recurrence: "every 3 days"}}},
+        {"Clean every other week", Parsed{AddTaskProp: database.AddTaskProp{Name: "Clean", DueAt: day(time.October, 19), Recurrence: "every 2 weeks"}}},
+        {"Gym every monday", Parsed{AddTaskProp: database.AddTaskProp{Name: "Gym", DueAt: day(time.October, 19), Recurrence: "every monday"}}},
+        {"Gym every thursday 7pm", Parsed{AddTaskProp: database.AddTaskProp{Name: "Gym", DueAt: day(time.October, 22, 19), Recurrence: "every thursday"}}},
+        {"Pay rent nov 1 monthly", Parsed{AddTaskProp: database.AddTaskProp{Name: "Pay rent", DueAt: day(time.November, 1), Recurrence: "every month"}}},
+        {"Every day counts", Parsed{AddTaskProp: database.AddTaskProp{Name: "counts", DueAt: day(time.October, 19), Recurrence: "every day"}}},
+        {"Every one of them", Parsed{AddTaskProp: database.AddTaskProp{Name: "Every one of them"}}},
+
+        // Everything at once
+        {
+            "Pay rent tomorrow 9am #finance !high every month",
+            Parsed{AddTaskProp: database.AddTaskProp{
+                Name: "Pay rent",
+                DueAt: day(time.October, 20, 9),
+                Priority: database.PriorityHigh,
+                Tags: []string{"finance"},
+                Recurrence: "every month",
+            }},
+        },
+        {
+            "@bob +garden #outside Mow the lawn saturday at 10am !low",
+            Parsed{
+                AddTaskProp: database.AddTaskProp{
+                    Name: "Mow the lawn",
+                    DueAt: day(time.October, 24, 10),
+                    Priority: database.PriorityLow,
+                    Tags: []string{"outside"},
+                    Project: "garden",
+                },
+                Assignee: "bob",
+            },
+        },
+    }
+
+    for _, test := range tests {
+        t.Run(test.text, func (t *testing.T) {
+            got, err := Parse(test.text, now)
+
+            if err != nil {
+                t.Fatalf("unexpected error, %s", err)
+            }
+
+            if !reflect.DeepEqual(got, test.want) {
+                t.Errorf("expected: %+v, got: %+v", describe(test.want), describe(got))
+            }
+        })
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    tests := []struct {
+        text string
+        want string
+    }{
+        {"", "Task name is empty"},
+        {"tomorrow #home !high", "Task name is empty"},
+        {"Pay rent tomorrow friday", "Due date given twice, 'tomorrow' and 'friday'"},
+        {"Pay rent 9am at 10am", "Time given twice, '9am' and '10am'"},
+        {"Pay rent in 2 hours 9am", "Due date given twice, 'in 2 hours' and '9am'"},
+        {"Pay rent !low !high", "Priority given twice, '!low' and '!high'"},
+        {"Pay rent +home +work", "Project given twice, '+home' and '+work'"},
+        {"Pay rent @alice @bob", "Assignee given twice, '@alice' and '@bob'"},
+        {"Pay rent daily every monday", "Recurrence given twice, 'daily' and 'every monday'"},
+        {"Pay rent feb 30", "Invalid date 'feb 30'"},
+        {"Fix 2026-02-29", "Invalid date '2026-02-29'"},
+        {"Fix 2026-13-01", "Invalid date '2026-13-01'"},
+        {"Pay rent 13pm", "Invalid time '13pm'"},
+        {"Pay rent 24:00", "Invalid time '24:00'"},
+    }
+
+    for _, test := range tests {
+        t.Run(test.text, func (t *testing.T) {
+            _, err := Parse(test.text, now)
+
+            if err == nil || err.Error() != test.want {
+                t.Errorf("expected: %s, got: %v", test.want, err)
+            }
+        })
+    }
+}
+
+// describe prints the due date instead of its address.
+func describe(parsed Parsed) any {
+    due := "none"
+
+    if parsed.DueAt != nil {
+        due = parsed.DueAt.Format(time.RFC3339)
+    }
+
+    return struct {
+        Parsed
+        Due string
+    }{parsed, due}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/store"
+	"go_todo/webhook"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+func showCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "show",
+        Summary: "Show the details of tasks, by ID or by name",
+        Args: "<...id|name>",
+        MinArgs: 1,
+        MaxArgs: -1,
+        Flags: []cli.Flag{
+            {Name: "history", Kind: cli.Switch, Usage: "list every change made to the tasks"},
+        },
+        CompleteArgs: taskCompletions(db),
+        ReadOnly: true,
+        Run: func (ctx *cli.Context) error { return showTasks(db, ctx) },
+    }
+}
+
+func doneCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "done",
+        Summary: "Mark tasks as done, by ID or by name, e.g. done rent",
+        Args: "<...id|name>",
+        MinArgs: 1,
+        MaxArgs: -1,
+        CompleteArgs: taskCompletions(db),
+        Run: func (ctx *cli.Context) error { return completeTasks(db, ctx) },
+    }
+}
+
+func showTasks(db database.DB, ctx *cli.Context) error {
+    history, _ := ctx.Bool("history")
+
+    // The history is recorded in the database along the tasks.
+    if config := os.Getenv(StoreEnvVar); history && !isSQLStore(config) {
+        return fmt.Errorf("show -history needs a SQL store, %s is '%s'", StoreEnvVar, config)
+    }
+
+    tasks, err := taskStore(db)
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    names := userNames(db)
+
+    for idx, ref := range ctx.Args() {
+        var task database.Task
+
+        // The tasks in the trash keep their history.
+        if history {
+            task, err = resolveTaskWithTrashed(db, actor, ref)
+        } else {
+            task, err = resolveTask(tasks, actor, ref)
+        }
+
+        if err != nil {
+            return err
+        }
+
+        if idx > 0 {
+            fmt.Println()
+        }
+
+        assignee := ""
+        if task.AssigneeID != nil {
+            assignee = names[*task.AssigneeID]
+        }
+
+        fmt.Printf("%-12s%d\n", "ID:", task.ID)
+        printAddTaskProp(database.AddTaskProp{
+            Name: task.Name,
+            Completed: task.Completed,
+            DueAt: task.DueAt,
+            Priority: task.Priority,
+            Tags: task.Tags,
+            Project: task.Project,
+            Recurrence: task.Recurrence,
+            Notes: task.Notes,
+        }, assignee)
+
+        stamps := []struct {
+            label string
+            at *time.Time
+        }{{"Created:", task.CreatedAt}, {"Updated:", task.UpdatedAt}, {"Done:", task.CompletedAt}, {"Deleted:", task.DeletedAt}}
+
+        for _, stamp := range stamps {
+            if stamp.at != nil {
+                fmt.Printf("%-12s%s\n", stamp.label, stamp.at.Local().Format("2006-01-02 15:04"))
+            }
+        }
+
+        if !history {
+            continue
+        }
+
+        changes, err := database.ListTaskHistoryAction(db, actor, task.ID)
+
+        if err != nil {
+            return err
+        }
+
+        printTaskHistory(changes, names)
+    }
+
+    return nil
+}
+
+// printTaskHistory lists the changes grouped by when and by whom they were
+// made.
+func printTaskHistory(changes []database.TaskChange, names map[int]string) {
+    fmt.Println("History:")
+
+    if len(changes) == 0 {
+        fmt.Println("  No changes recorded.")
+        return
+    }
+
+    for idx, change := range changes {
+        if idx == 0 || !change.ChangedAt.Equal(changes[idx-1].ChangedAt) || !sameID(change.ActorID, changes[idx-1].ActorID) {
+            actor := "unknown"
+            if change.ActorID != nil && names[*change.ActorID] != "" {
+                actor = names[*change.ActorID]
+            }
+
+            fmt.Printf("  %s by %s\n", change.ChangedAt.Local().Format("2006-01-02 15:04"), actor)
+        }
+
+        fmt.Printf("    %s: %s -> %s\n", change.Field, formatChangeValue(change.Field, change.Old, names), formatChangeValue(change.Field, change.New, names))
+    }
+}
+
+func formatChangeValue(field string, value *string, names map[int]string) string {
+    switch field {
+    case "completed", "archived", "deleted":
+        if value == nil {
+            return "no"
+        }
+        return "yes"
+    }
+
+    if value == nil {
+        return "none"
+    }
+
+    switch field {
+    case "assignee":
+        if id, err := strconv.Atoi(*value); err == nil && names[id] != "" {
+            return names[id]
+        }
+    case "due":
+        if due, err := time.Parse(time.RFC3339, *value); err == nil {
+            return due.Local().Format("2006-01-02 15:04")
+        }
+    case "priority":
+        return *value
+    }
+
+    return fmt.Sprintf("'%s'", *value)
+}
+
+func completeTasks(db database.DB, ctx *cli.Context) error {
+    tasks, err := taskStore(db)
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    completed := true
+
+    for _, ref := range ctx.Args() {
+        task, err := resolveTask(tasks, actor, ref)
+
+        if err != nil {
+            return err
+        }
+
+        if task.Completed {
+            fmt.Println(fmt.Sprintf("Task %d is already done", task.ID))
+            continue
+        }
+
+        updated, err := tasks.UpdateTasks(actor, []int{task.ID}, database.UpdateTaskProp{Completed: &completed})
+
+        if err != nil {
+            return err
+        }
+
+        updatedTask := updated[0]
+
+        fmt.Println(fmt.Sprintf("Task %d done: %s", updatedTask.ID, updatedTask.Name))
+
+        for _, event := range webhook.EventsForUpdate(task, updatedTask) {
+            notifyWebhooks(db, event, updatedTask)
+        }
+    }
+
+    return nil
+}
+
+// resolveTaskIDs reads IDs as they are, even when no such task exists, and
+// resolves names to the ID of their task.
+func resolveTaskIDs(tasks store.TaskStore, actor database.User, refs []string) ([]int, error) {
+    ids := make([]int, len(refs))
+
+    for idx, ref := range refs {
+        if database.IsTaskID(ref) {
+            ids[idx], _ = strconv.Atoi(ref)
+            continue
+        }
+
+        task, err := resolveTask(tasks, actor, ref)
+
+        if err != nil {
+            return nil, err
+        }
+
+        ids[idx] = task.ID
+    }
+
+    return ids, nil
+}
+
+// resolveTask finds the task ref designates, asking which one is meant when
+// several tasks match and the command runs in a terminal.
+func resolveTask(tasks store.TaskStore, actor database.User, ref string) (database.Task, error) {
+    get := func (id int) (database.Task, error) { return tasks.GetTask(actor, id) }
+    list := func () ([]database.Task, error) { return tasks.ListTasks(actor, database.ListTaskProps{}) }
+
+    return chooseAmbiguous(database.ResolveTask(ref, get, list))
+}
+
+// resolveTaskWithTrashed is resolveTask among the tasks of the database,
+// the ones in the trash included.
+func resolveTaskWithTrashed(db database.DB, actor database.User, ref string) (database.Task, error) {
+    get := func (id int) (database.Task, error) {
+        task, err := database.ListTaskActionByIDWithTrashed(db, actor, uint(id))
+
+        if err == sql.ErrNoRows {
+            return database.Task{}, database.ErrTaskNotFound
+        }
+
+        return task, err
+    }
+    list := func () ([]database.Task, error) {
+        tasks, err := database.ListTasksAction(db, actor, database.ListTaskProps{})
+
+        if err != nil {
+            return nil, err
+        }
+
+        trashed, err := database.ListTasksAction(db, actor, database.ListTaskProps{Trashed: true})
+
+        return append(tasks, trashed...), err
+    }
+
+    return chooseAmbiguous(database.ResolveTask(ref, get, list))
+}
+
+// chooseAmbiguous asks which task is meant when err is an ambiguous reference
+// and the command runs in a terminal.
+func chooseAmbiguous(task database.Task, err error) (database.Task, error) {
+    ambiguous, ok := err.(*database.AmbiguousTaskError)
+
+    if !ok || !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+        return task, err
+    }
+
+    return chooseTask(os.Stdin, os.Stdout, ambiguous)
+}
+
+// chooseTask lists the candidates and reads the number of the chosen one.
+func chooseTask(in io.Reader, out io.Writer, ambiguous *database.AmbiguousTaskError) (database.Task, error) {
+    candidates := ambiguous.Candidates
+
+    fmt.Fprintf(out, "'%s' matches %d tasks:\n", ambiguous.Ref, len(candidates))
+
+    for idx, task := range candidates {
+        fmt.Fprintf(out, "  %d) %d. %s%s\n", idx + 1, task.ID, task.Name, taskDetails(task))
+    }
+
+    fmt.Fprintf(out, "Which one? [1-%d] ", len(candidates))
+
+    line, err := bufio.NewReader(in).ReadString('\n')
+
+    if err != nil && line == "" {
+        fmt.Fprintln(out)
+        return database.Task{}, errors.New("No task chosen")
+    }
+
+    choice, err := strconv.Atoi(strings.TrimSpace(line))
+
+    if err != nil || choice < 1 || choice > len(candidates) {
+        return database.Task{}, errors.New("No task chosen")
+    }
+
+    return candidates[choice-1], nil
+}
@@ -0,0 +1,209 @@
+package repl
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/tui"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Editor edits one line at a time on a terminal in raw mode, it keeps the
+// cursor position, browses the history with the arrows and completes the
+// word under the cursor with tab.
+type Editor struct {
+    Prompt string
+    Complete func(words []string) []cli.Completion
+    History *History
+
+    out io.Writer
+    line []rune
+    pos int
+    // browsing is the history entry shown counting back from the latest,
+    // -1 while editing a new line.
+    browsing int
+    // draft is the new line, restored when browsing back past the latest
+    // entry.
+    draft []rune
+}
+
+func NewEditor(out io.Writer, prompt string) *Editor {
+    return &Editor{Prompt: prompt, out: out, browsing: -1}
+}
+
+// Start begins a new line.
+func (e *Editor) Start() {
+    e.line, e.pos, e.browsing, e.draft = nil, 0, -1, nil
+    e.redraw()
+}
+
+func (e *Editor) Line() string {
+    return string(e.line)
+}
+
+// Update applies key to the line, done is true once the line is entered and
+// err is io.EOF when the input is closed with Ctrl-D on an empty line.
+func (e *Editor) Update(key tui.Key) (line string, done bool, err error) {
+    switch key.Code {
+        case tui.KeyRune:
+            e.insert(key.Rune)
+        case tui.KeyEnter:
+            fmt.Fprint(e.out, "\r\n")
+            return string(e.line), true, nil
+        case tui.KeyCtrlC:
+            fmt.Fprint(e.out, "^C\r\n")
+            e.Start()
+        case tui.KeyCtrlD:
+            if len(e.line) == 0 {
+                fmt.Fprint(e.out, "\r\n")
+                return "", false, io.EOF
+            }
+            e.delete(e.pos)
+        case tui.KeyBackspace:
+            if e.pos > 0 {
+                e.pos--
+                e.delete(e.pos)
+            }
+        case tui.KeyDelete:
+            e.delete(e.pos)
+        case tui.KeyLeft:
+            e.pos = max(e.pos-1, 0)
+        case tui.KeyRight:
+            e.pos = min(e.pos+1, len(e.line))
+        case tui.KeyHome:
+            e.pos = 0
+        case tui.KeyEnd:
+            e.pos = len(e.line)
+        case tui.KeyUp:
+            e.browse(e.browsing + 1)
+        case tui.KeyDown:
+            e.browse(e.browsing - 1)
+        case tui.KeyTab:
+            e.complete()
+    }
+
+    e.redraw()
+
+    return "", false, nil
+}
+
+func (e *Editor) insert(r rune) {
+    e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+    e.pos++
+}
+
+func (e *Editor) delete(pos int) {
+    if pos < len(e.line) {
+        e.line = append(e.line[:pos], e.line[pos+1:]...)
+    }
+}
+
+func (e *Editor) setLine(line string) {
+    e.line = []rune(line)
+    e.pos = len(e.line)
+}
+
+func (e *Editor) browse(entry int) {
+    if e.History == nil || entry < -1 || entry >= len(e.History.entries) {
+        return
+    }
+
+    if e.browsing == -1 {
+        e.draft = append([]rune{}, e.line...)
+    }
+
+    e.browsing = entry
+
+    if entry == -1 {
+        e.setLine(string(e.draft))
+        return
+    }
+
+    e.setLine(e.History.entries[len(e.History.entries)-1-entry])
+}
+
+// complete replaces the word before the cursor by its only completion or by
+// the prefix its completions share, the completions are listed when there's
+// nothing left to add.
+func (e *Editor) complete() {
+    if e.Complete == nil {
+        return
+    }
+
+    before, after := string(e.line[:e.pos]), string(e.line[e.pos:])
+    words, start, state := scan(before)
+
+    if !state.inWord {
+        words = append(words, "")
+        start = len(before)
+    }
+
+    completions := e.Complete(words)
+
+    if len(completions) == 0 {
+        return
+    }
+
+    current := words[len(words)-1]
+    replacement := commonPrefix(completions)
+
+    if len(completions) == 1 {
+        replacement = completions[0].Value
+    }
+
+    if len(completions) > 1 && replacement == current {
+        e.list(completions)
+        return
+    }
+
+    completed := before[:start] + Quote(replacement)
+
+    if len(completions) == 1 {
+        completed += " "
+    }
+
+    e.line = []rune(completed + after)
+    e.pos = utf8.RuneCountInString(completed)
+}
+
+func (e *Editor) list(completions []cli.Completion) {
+    width := 0
+
+    for _, completion := range completions {
+        width = max(width, utf8.RuneCountInString(completion.Value))
+    }
+
+    fmt.Fprint(e.out, "\r\n")
+
+    for _, completion := range completions {
+        if completion.Description == "" {
+            fmt.Fprintf(e.out, "%s\r\n", completion.Value)
+            continue
+        }
+        fmt.Fprintf(e.out, "%-*s   %s\r\n", width, completion.Value, completion.Description)
+    }
+}
+
+func commonPrefix(completions []cli.Completion) string {
+    prefix := completions[0].Value
+
+    for _, completion := range completions[1:] {
+        for !strings.HasPrefix(completion.Value, prefix) {
+            _, size := utf8.DecodeLastRuneInString(prefix)
+            prefix = prefix[:len(prefix)-size]
+        }
+    }
+
+    return prefix
+}
+
+// redraw rewrites the prompt and the line then moves the cursor back in
+// place.
+func (e *Editor) redraw() {
+    fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.Prompt, string(e.line))
+
+    if back := len(e.line) - e.pos; back > 0 {
+        fmt.Fprintf(e.out, "\x1b[%dD", back)
+    }
+}
@@ -0,0 +1,94 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// HistorySize is how many lines are kept, older lines are dropped from the
+// history file when it's opened.
+const HistorySize = 1000
+
+// History keeps the lines entered in previous sessions, every line is
+// appended to the history file as soon as it's entered.
+type History struct {
+    path string
+    entries []string
+}
+
+// OpenHistory reads the history stored at path, a missing file is an empty
+// history and an empty path keeps the history in memory only.
+func OpenHistory(path string) (*History, error) {
+    history := &History{path: path, entries: make([]string, 0)}
+
+    if path == "" {
+        return history, nil
+    }
+
+    file, err := os.Open(path)
+
+    if errors.Is(err, os.ErrNotExist) {
+        return history, nil
+    }
+
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+
+    for scanner.Scan() {
+        history.entries = append(history.entries, scanner.Text())
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    if len(history.entries) > HistorySize {
+        history.entries = history.entries[len(history.entries)-HistorySize:]
+        content := strings.Join(history.entries, "\n") + "\n"
+
+        if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+            return nil, err
+        }
+    }
+
+    return history, nil
+}
+
+// Add records line unless it's blank or repeats the previous line.
+func (h *History) Add(line string) error {
+    if strings.TrimSpace(line) == "" || strings.ContainsRune(line, '\n') {
+        return nil
+    }
+
+    if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+        return nil
+    }
+
+    h.entries = append(h.entries, line)
+
+    if h.path == "" {
+        return nil
+    }
+
+    file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    _, err = file.WriteString(line + "\n")
+
+    return err
+}
+
+// Entries returns the lines from the oldest to the latest.
+func (h *History) Entries() []string {
+    return h.entries
+}
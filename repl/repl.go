@@ -0,0 +1,144 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go_todo/cli"
+	"go_todo/tui"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Shell reads commands until the input ends or Execute asks to stop. On a
+// terminal lines are edited with history and completion, any other input is
+// read one line at a time without prompting, e.g. go_todo shell < script.
+type Shell struct {
+    // Prompt is called before reading each line.
+    Prompt func() string
+    // Execute runs the words of a line, it returns false to end the session.
+    Execute func(args []string) bool
+    Complete func(words []string) []cli.Completion
+    History *History
+}
+
+func (s *Shell) Run(in io.Reader, out io.Writer) error {
+    inFile, isFile := in.(*os.File)
+    outFile, isOutFile := out.(*os.File)
+
+    if isFile && isOutFile && term.IsTerminal(int(inFile.Fd())) && term.IsTerminal(int(outFile.Fd())) {
+        return s.runTerminal(inFile, outFile)
+    }
+
+    scanner := bufio.NewScanner(in)
+
+    for scanner.Scan() {
+        if !s.execute(out, scanner.Text()) {
+            return nil
+        }
+    }
+
+    return scanner.Err()
+}
+
+func (s *Shell) runTerminal(in *os.File, out *os.File) error {
+    editor := NewEditor(out, "")
+    editor.Complete = s.Complete
+    editor.History = s.History
+    reader := &keyReader{in: in}
+
+    for {
+        line, err := s.readLine(editor, reader)
+
+        if errors.Is(err, io.EOF) {
+            return nil
+        }
+
+        if err != nil {
+            return err
+        }
+
+        if s.History != nil {
+            if err := s.History.Add(line); err != nil {
+                fmt.Fprintf(out, "Error: couldn't save the history, %s\n", err)
+            }
+        }
+
+        if !s.execute(out, line) {
+            return nil
+        }
+    }
+}
+
+// readLine only keeps the terminal in raw mode while the line is edited so
+// commands print their output as usual.
+func (s *Shell) readLine(editor *Editor, reader *keyReader) (string, error) {
+    state, err := term.MakeRaw(int(reader.in.Fd()))
+
+    if err != nil {
+        return "", err
+    }
+    defer term.Restore(int(reader.in.Fd()), state)
+
+    if s.Prompt != nil {
+        editor.Prompt = s.Prompt()
+    }
+    editor.Start()
+
+    for {
+        key, err := reader.next()
+
+        if err != nil {
+            return "", err
+        }
+
+        line, done, err := editor.Update(key)
+
+        if done || err != nil {
+            return line, err
+        }
+    }
+}
+
+func (s *Shell) execute(out io.Writer, line string) bool {
+    args, err := Split(line)
+
+    if err != nil {
+        fmt.Fprintf(out, "Error: %s\n", err)
+        return true
+    }
+
+    if len(args) == 0 {
+        return true
+    }
+
+    return s.Execute(args)
+}
+
+// keyReader hands out keys one at a time, keys read past the end of a line,
+// e.g. when pasting several lines, wait for the next line.
+type keyReader struct {
+    in *os.File
+    pending []tui.Key
+}
+
+func (r *keyReader) next() (tui.Key, error) {
+    buffer := make([]byte, 256)
+
+    for len(r.pending) == 0 {
+        read, err := r.in.Read(buffer)
+
+        if err != nil {
+            return tui.Key{}, err
+        }
+
+        r.pending = tui.DecodeKeys(buffer[:read])
+    }
+
+    key := r.pending[0]
+    r.pending = r.pending[1:]
+
+    return key, nil
+}
@@ -0,0 +1,170 @@
+package repl
+
+import (
+	"go_todo/cli"
+	"go_todo/tui"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+    t.Run("Should split words like a shell", func (t *testing.T) {
+        got, err := Split(`  a -n 'Buy milk' "say \"hi\"" walk\ the\ dog ''`)
+        want := []string{"a", "-n", "Buy milk", `say "hi"`, "walk the dog", ""}
+
+        if err != nil || !reflect.DeepEqual(got, want) {
+            t.Errorf("expected: %q, got: %q (%v)", want, got, err)
+        }
+    })
+
+    t.Run("Should reject unterminated quotes", func (t *testing.T) {
+        if _, err := Split(`a -n "Buy milk`); err != ErrUnterminatedQuote {
+            t.Errorf("expected: %v, got: %v", ErrUnterminatedQuote, err)
+        }
+    })
+
+    t.Run("Should quote what it would split", func (t *testing.T) {
+        got, _ := Split(Quote(`it's "done" \o/`))
+        want := []string{`it's "done" \o/`}
+
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+}
+
+func TestHistory(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "history")
+
+    history, err := OpenHistory(path)
+
+    if err != nil {
+        t.Fatalf("unexpected error, %s\n", err)
+    }
+
+    for _, line := range []string{"ls", "ls", " ", "a -n milk", "ls"} {
+        history.Add(line)
+    }
+
+    t.Run("Should persist the lines entered", func (t *testing.T) {
+        reopened, _ := OpenHistory(path)
+        want := []string{"ls", "a -n milk", "ls"}
+
+        if got := reopened.Entries(); !reflect.DeepEqual(got, want) {
+            t.Errorf("expected: %q, got: %q", want, got)
+        }
+    })
+
+    t.Run("Should only keep the latest lines", func (t *testing.T) {
+        lines := make([]string, HistorySize+10)
+        for idx := range lines {
+            lines[idx] = "ls " + strings.Repeat("x", idx)
+        }
+        os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+        OpenHistory(path)
+        reopened, _ := OpenHistory(path)
+
+        if got := reopened.Entries(); len(got) != HistorySize || got[0] != lines[10] {
+            t.Errorf("expected the %d latest lines, got %d starting with %q", HistorySize, len(got), got[0])
+        }
+    })
+}
+
+func TestEditor(t *testing.T) {
+    newEditor := func () *Editor {
+        editor := NewEditor(io.Discard, "> ")
+        editor.History, _ = OpenHistory("")
+        editor.History.Add("ls")
+        editor.History.Add("a -n milk")
+        editor.Complete = func (words []string) []cli.Completion {
+            app := &cli.Command{
+                Name: "go_todo",
+                Subcommands: []*cli.Command{
+                    {Name: "update", Flags: []cli.Flag{{Name: "name"}, {Name: "notes"}}},
+                    {Name: "undo"},
+                    {Name: "list"},
+                },
+            }
+            return app.Complete(words)
+        }
+        editor.Start()
+        return editor
+    }
+
+    typeKeys := func (editor *Editor, input string) (string, bool) {
+        for _, key := range tui.DecodeKeys([]byte(input)) {
+            if line, done, _ := editor.Update(key); done {
+                return line, true
+            }
+        }
+        return editor.Line(), false
+    }
+
+    t.Run("Should edit in the middle of the line", func (t *testing.T) {
+        line, done := typeKeys(newEditor(), "ls-c\x1b[D\x1b[D \x1b[Ftrue\x1b[H\x1b[3~l\r")
+
+        if want := "ls -ctrue"; !done || line != want {
+            t.Errorf("expected: %q, got: %q", want, line)
+        }
+    })
+
+    t.Run("Should browse the history", func (t *testing.T) {
+        editor := newEditor()
+
+        if line, _ := typeKeys(editor, "draft\x1b[A\x1b[A\x1b[A"); line != "ls" {
+            t.Errorf("expected: %q, got: %q", "ls", line)
+        }
+
+        if line, _ := typeKeys(editor, "\x1b[B\x1b[B"); line != "draft" {
+            t.Errorf("expected: %q, got: %q", "draft", line)
+        }
+    })
+
+    t.Run("Should complete a single candidate", func (t *testing.T) {
+        if line, _ := typeKeys(newEditor(), "l\t"); line != "list " {
+            t.Errorf("expected: %q, got: %q", "list ", line)
+        }
+    })
+
+    t.Run("Should complete the shared prefix", func (t *testing.T) {
+        if line, _ := typeKeys(newEditor(), "update -\t"); line != "update -n" {
+            t.Errorf("expected: %q, got: %q", "update -n", line)
+        }
+
+        if line, _ := typeKeys(newEditor(), "u\tp\t"); line != "update " {
+            t.Errorf("expected: %q, got: %q", "update ", line)
+        }
+    })
+
+    t.Run("Should list the candidates when nothing can be added", func (t *testing.T) {
+        output := &strings.Builder{}
+        editor := newEditor()
+        editor.out = output
+
+        typeKeys(editor, "u\t")
+
+        if !strings.Contains(output.String(), "\r\nupdate\r\nundo\r\n") {
+            t.Errorf("expected the candidates to be listed, got %q", output.String())
+        }
+    })
+
+    t.Run("Should end the input on an empty line only", func (t *testing.T) {
+        editor := newEditor()
+        typeKeys(editor, "ls\x1b[H")
+
+        if _, _, err := editor.Update(tui.Key{Code: tui.KeyCtrlD}); err != nil || editor.Line() != "s" {
+            t.Errorf("expected ctrl-d to delete a character, got %q (%v)", editor.Line(), err)
+        }
+
+        typeKeys(editor, "\x03")
+
+        if _, _, err := editor.Update(tui.Key{Code: tui.KeyCtrlD}); err != io.EOF {
+            t.Errorf("expected: %v, got: %v", io.EOF, err)
+        }
+    })
+}
@@ -0,0 +1,88 @@
+package repl
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Split breaks a line into words the way a shell would: blanks separate the
+// words, single and double quotes group them and a backslash escapes the next
+// character outside of single quotes.
+func Split(line string) ([]string, error) {
+    words, _, state := scan(line)
+
+    if state.quote != 0 {
+        return nil, ErrUnterminatedQuote
+    }
+
+    return words, nil
+}
+
+type scanState struct {
+    // quote is the quote the line ends in, 0 outside of quotes.
+    quote rune
+    // inWord is true when the line ends in the middle of a word.
+    inWord bool
+}
+
+// scan splits line and also returns where the last word starts, which is the
+// part of the line a completion replaces.
+func scan(line string) ([]string, int, scanState) {
+    words := make([]string, 0)
+    word := strings.Builder{}
+    state := scanState{}
+    start := len(line)
+    escaped := false
+
+    for idx, r := range line {
+        if !state.inWord && !unicode.IsSpace(r) {
+            state.inWord = true
+            start = idx
+        }
+
+        switch {
+            case escaped:
+                word.WriteRune(r)
+                escaped = false
+            case r == '\\' && state.quote != '\'':
+                escaped = true
+            case state.quote != 0 && r == state.quote:
+                state.quote = 0
+            case state.quote != 0:
+                word.WriteRune(r)
+            case r == '\'' || r == '"':
+                state.quote = r
+            case unicode.IsSpace(r):
+                if state.inWord {
+                    words = append(words, word.String())
+                    word.Reset()
+                    state.inWord = false
+                }
+            default:
+                word.WriteRune(r)
+        }
+    }
+
+    if state.inWord {
+        words = append(words, word.String())
+    }
+
+    return words, start, state
+}
+
+// Quote escapes the characters Split would interpret in word.
+func Quote(word string) string {
+    quoted := strings.Builder{}
+
+    for _, r := range word {
+        if unicode.IsSpace(r) || strings.ContainsRune(`\'"`, r) {
+            quoted.WriteRune('\\')
+        }
+        quoted.WriteRune(r)
+    }
+
+    return quoted.String()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/rpc"
+	"io"
+	"os"
+)
+
+func rpcCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "rpc",
+        Summary: "Speak JSON-RPC 2.0 on stdin/stdout for editor integrations, one message per line",
+        Run: func (ctx *cli.Context) error {
+            serveRPC(db)
+            return nil
+        },
+    }
+}
+
+func serveRPC(db database.DB) {
+    actor, err := currentUser(db)
+
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error while resolving the current user: %s\n", err)
+        return
+    }
+
+    defer startWebhookDelivery(db)()
+
+    stdio := struct {
+        io.Reader
+        io.Writer
+    }{os.Stdin, os.Stdout}
+
+    if err := rpc.NewServer(db, actor).ServeConn(stdio); err != nil {
+        fmt.Fprintf(os.Stderr, "error while serving: %s\n", err)
+    }
+}
@@ -0,0 +1,362 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"go_todo/filter"
+	"go_todo/jsonrpc"
+	"go_todo/webhook"
+	"time"
+)
+
+// NotificationPrefix namespaces the change notifications sent to the client,
+// e.g. "tasks.created".
+const NotificationPrefix = "tasks."
+
+type service struct {
+    db database.DB
+    actor database.User
+    server *jsonrpc.Server
+}
+
+// NewServer exposes the task actions performed as actor, mutations are
+// notified to the client and to the registered webhooks.
+func NewServer(db database.DB, actor database.User) *jsonrpc.Server {
+    server := jsonrpc.NewServer()
+    s := &service{db: db, actor: actor, server: server}
+
+    server.Register("tasks.add", s.addTask)
+    server.Register("tasks.get", s.getTask)
+    server.Register("tasks.history", s.taskHistory)
+    server.Register("tasks.list", s.listTasks)
+    server.Register("tasks.update", s.updateTask)
+    server.Register("tasks.delete", s.deleteTasks)
+    server.Register("users.list", s.listUsers)
+    server.Register("users.whoami", s.whoami)
+    server.Register("views.list", s.listViews)
+    server.Register("views.save", s.saveView)
+    server.Register("views.delete", s.deleteView)
+
+    return server
+}
+
+type AddTaskParams struct {
+    Name string `json:"name"`
+    Completed bool `json:"completed"`
+    Notes string `json:"notes"`
+    Assignee *string `json:"assignee"`
+}
+
+func (s *service) addTask(params json.RawMessage) (any, error) {
+    p := AddTaskParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    if p.Name == "" {
+        return nil, jsonrpc.InvalidParams(errors.New("name is required"))
+    }
+
+    props := database.AddTaskProp{Name: p.Name, Completed: p.Completed, Notes: p.Notes}
+
+    if p.Assignee != nil {
+        assignee, err := database.ListUserActionByName(s.db, *p.Assignee)
+        if err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+        props.AssigneeID = &assignee.ID
+    }
+
+    task, err := database.AddTaskAction(s.db, s.actor, props)
+
+    if err != nil {
+        return nil, err
+    }
+
+    s.notify(webhook.EventCreated, task)
+
+    return task, nil
+}
+
+type GetTaskParams struct {
+    ID int `json:"id"`
+    // Ref is an ID or a fragment of the name, used instead of ID when set.
+    Ref *string `json:"ref"`
+}
+
+func (s *service) getTask(params json.RawMessage) (any, error) {
+    p := GetTaskParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    if p.Ref != nil {
+        return s.resolveTask(*p.Ref)
+    }
+
+    task, err := database.ListTaskActionByID(s.db, s.actor, uint(p.ID))
+
+    if err != nil {
+        return nil, errors.New("Task doesn't exist")
+    }
+
+    return task, nil
+}
+
+type TaskHistoryParams struct {
+    ID int `json:"id"`
+}
+
+func (s *service) taskHistory(params json.RawMessage) (any, error) {
+    p := TaskHistoryParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    changes, err := database.ListTaskHistoryAction(s.db, s.actor, p.ID)
+
+    if err != nil {
+        return nil, jsonrpc.InvalidParams(err)
+    }
+
+    return changes, nil
+}
+
+// resolveTask lists the candidates in the error data when ref is ambiguous.
+func (s *service) resolveTask(ref string) (any, error) {
+    task, err := database.ResolveTaskAction(s.db, s.actor, ref)
+
+    if ambiguous, ok := err.(*database.AmbiguousTaskError); ok {
+        return nil, &jsonrpc.Error{
+            Code: jsonrpc.CodeInvalidParams,
+            Message: fmt.Sprintf("'%s' matches %d tasks", ambiguous.Ref, len(ambiguous.Candidates)),
+            Data: ambiguous.Candidates,
+        }
+    }
+
+    if err != nil {
+        return nil, jsonrpc.InvalidParams(err)
+    }
+
+    return task, nil
+}
+
+type ListTasksParams struct {
+    Completed *bool `json:"completed"`
+    Assignee *string `json:"assignee"`
+    Mine bool `json:"mine"`
+    Sort *[2]string `json:"sort"`
+    // Where is a filter expression, see the filter package.
+    Where *string `json:"where"`
+    // View is the name of a saved view, combined with the other filters.
+    View *string `json:"view"`
+    // Trashed lists the deleted tasks instead.
+    Trashed bool `json:"trashed"`
+}
+
+func (s *service) listTasks(params json.RawMessage) (any, error) {
+    p := ListTasksParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    props := database.ListTaskProps{WhereCompleted: p.Completed, OnlyMine: p.Mine, Trashed: p.Trashed}
+
+    if p.Sort != nil {
+        column, order := p.Sort[0], p.Sort[1]
+
+        if _, ok := database.SortColumns[column]; !ok || (order != "asc" && order != "desc") {
+            return nil, jsonrpc.InvalidParams(errors.New("sort must be [column, asc|desc]"))
+        }
+
+        props.SortBy = []database.SortKey{{Column: column, Desc: order == "desc"}}
+    }
+
+    if p.Where != nil {
+        condition, err := filter.Parse(*p.Where, time.Now())
+        if err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+        props.Where = condition
+    }
+
+    if p.View != nil {
+        view, err := database.ListViewActionByName(s.db, s.actor, *p.View)
+        if err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+        if err := filter.ApplyView(&props, view, time.Now()); err != nil {
+            return nil, err
+        }
+    }
+
+    if p.Assignee != nil {
+        assignee, err := database.ListUserActionByName(s.db, *p.Assignee)
+        if err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+        props.WhereAssigneeID = &assignee.ID
+    }
+
+    return database.ListTasksAction(s.db, s.actor, props)
+}
+
+type UpdateTaskParams struct {
+    ID int `json:"id"`
+    Name *string `json:"name"`
+    Completed *bool `json:"completed"`
+    Notes *string `json:"notes"`
+    // An empty assignee unassigns the task.
+    Assignee *string `json:"assignee"`
+}
+
+func (s *service) updateTask(params json.RawMessage) (any, error) {
+    p := UpdateTaskParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    props := database.UpdateTaskProp{Name: p.Name, Completed: p.Completed, Notes: p.Notes}
+
+    if p.Assignee != nil {
+        unassigned := 0
+        props.AssigneeID = &unassigned
+
+        if *p.Assignee != "" {
+            assignee, err := database.ListUserActionByName(s.db, *p.Assignee)
+            if err != nil {
+                return nil, jsonrpc.InvalidParams(err)
+            }
+            props.AssigneeID = &assignee.ID
+        }
+    }
+
+    previous, _ := database.ListTaskActionByID(s.db, s.actor, uint(p.ID))
+
+    task, err := database.UpdateTaskAction(s.db, s.actor, p.ID, props)
+
+    if err != nil {
+        return nil, err
+    }
+
+    for _, event := range webhook.EventsForUpdate(previous, task) {
+        s.notify(event, task)
+    }
+
+    return task, nil
+}
+
+type DeleteTasksParams struct {
+    IDs []int `json:"ids"`
+}
+
+type DeleteTasksResult struct {
+    Deleted int `json:"deleted"`
+}
+
+func (s *service) deleteTasks(params json.RawMessage) (any, error) {
+    p := DeleteTasksParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    if len(p.IDs) == 0 {
+        return nil, jsonrpc.InvalidParams(errors.New("ids is required"))
+    }
+
+    deleted := make([]database.Task, 0)
+
+    for _, id := range p.IDs {
+        if task, err := database.ListTaskActionByID(s.db, s.actor, uint(id)); err == nil {
+            deleted = append(deleted, task)
+        }
+    }
+
+    delCount, err := database.DeleteTaskBulkAction(s.db, s.actor, p.IDs)
+
+    if err != nil {
+        return nil, err
+    }
+
+    for _, task := range deleted {
+        if _, err := database.ListTaskActionByID(s.db, s.actor, uint(task.ID)); err == nil {
+            continue
+        }
+        s.notify(webhook.EventDeleted, task)
+    }
+
+    return DeleteTasksResult{Deleted: delCount}, nil
+}
+
+func (s *service) listUsers(params json.RawMessage) (any, error) {
+    return database.ListUsersAction(s.db)
+}
+
+func (s *service) whoami(params json.RawMessage) (any, error) {
+    return s.actor, nil
+}
+
+func (s *service) listViews(params json.RawMessage) (any, error) {
+    return database.ListViewsAction(s.db, s.actor)
+}
+
+func (s *service) saveView(params json.RawMessage) (any, error) {
+    p := database.View{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    if p.Filter != "" {
+        if _, err := filter.Parse(p.Filter, time.Now()); err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+    }
+
+    if p.Sort != "" {
+        if _, err := filter.ParseSort(p.Sort); err != nil {
+            return nil, jsonrpc.InvalidParams(err)
+        }
+    }
+
+    view, err := database.SaveViewAction(s.db, s.actor, p)
+
+    if err != nil {
+        return nil, jsonrpc.InvalidParams(err)
+    }
+
+    return view, nil
+}
+
+type DeleteViewParams struct {
+    Name string `json:"name"`
+}
+
+func (s *service) deleteView(params json.RawMessage) (any, error) {
+    p := DeleteViewParams{}
+
+    if err := jsonrpc.DecodeParams(params, &p); err != nil {
+        return nil, err
+    }
+
+    if err := database.DeleteViewAction(s.db, s.actor, p.Name); err != nil {
+        return nil, jsonrpc.InvalidParams(err)
+    }
+
+    return true, nil
+}
+
+// notify tells the client about a change and queues the webhook events,
+// they are delivered in the background.
+func (s *service) notify(event string, task database.Task) {
+    s.server.Notify(NotificationPrefix+event, task)
+    webhook.NewDispatcher(s.db).Dispatch(event, task)
+}
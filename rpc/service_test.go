@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"go_todo/database"
+	"go_todo/jsonrpc"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTaskMethods(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor, err := database.EnsureUserAction(db, "tester")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s\n", err)
+    }
+
+    server := NewServer(db, actor)
+
+    t.Run("Should add a task", func (t *testing.T) {
+        task := database.Task{}
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.add", "params": {"name": "From the editor"}, "id": 1}`, &task)
+
+        if task.ID != 1 || task.Name != "From the editor" {
+            t.Errorf("expected the created task, got %+v\n", task)
+        }
+    })
+
+    t.Run("Should reject a task without a name", func (t *testing.T) {
+        res := handle(t, server, `{"jsonrpc": "2.0", "method": "tasks.add", "params": {}, "id": 1}`)
+
+        if res.Error == nil || res.Error.Code != jsonrpc.CodeInvalidParams {
+            t.Errorf("expected an invalid params error, got %+v\n", res.Error)
+        }
+    })
+
+    t.Run("Should update and get a task", func (t *testing.T) {
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.update", "params": {"id": 1, "completed": true}, "id": 2}`, nil)
+
+        task := database.Task{}
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.get", "params": {"id": 1}, "id": 3}`, &task)
+
+        if !task.Completed {
+            t.Errorf("expected the task to be completed, got %+v\n", task)
+        }
+    })
+
+    t.Run("Should list tasks with filters", func (t *testing.T) {
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.add", "params": {"name": "Second"}, "id": 4}`, nil)
+
+        tasks := make([]database.Task, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.list", "params": {"completed": false, "sort": ["id", "desc"]}, "id": 5}`, &tasks)
+
+        if len(tasks) != 1 || tasks[0].Name != "Second" {
+            t.Errorf("expected only the pending task, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should list the tasks matching a filter", func (t *testing.T) {
+        tasks := make([]database.Task, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.list", "params": {"where": "done or name:sec", "sort": ["completed", "desc"]}, "id": 5}`, &tasks)
+
+        if len(tasks) != 2 || !tasks[0].Completed || tasks[1].Name != "Second" {
+            t.Errorf("expected both tasks, the completed one first, got %+v\n", tasks)
+        }
+    })
+
+    t.Run("Should save views and list their tasks", func (t *testing.T) {
+        call(t, server, `{"jsonrpc": "2.0", "method": "views.save", "params": {"name": "pending", "filter": "not done"}, "id": 5}`, nil)
+
+        views := make([]database.View, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "views.list", "id": 5}`, &views)
+
+        if len(views) != 1 || views[0].Filter != "not done" {
+            t.Errorf("expected the pending view, got %+v\n", views)
+        }
+
+        tasks := make([]database.Task, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.list", "params": {"view": "pending"}, "id": 5}`, &tasks)
+
+        if len(tasks) != 1 || tasks[0].Name != "Second" {
+            t.Errorf("expected only the pending task, got %+v\n", tasks)
+        }
+
+        call(t, server, `{"jsonrpc": "2.0", "method": "views.delete", "params": {"name": "pending"}, "id": 5}`, nil)
+    })
+
+    t.Run("Should get a task by a part of its name", func (t *testing.T) {
+        task := database.Task{}
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.get", "params": {"ref": "sec"}, "id": 5}`, &task)
+
+        if task.Name != "Second" {
+            t.Errorf("expected the second task, got %+v\n", task)
+        }
+
+        res := handle(t, server, `{"jsonrpc": "2.0", "method": "tasks.get", "params": {"ref": "e"}, "id": 5}`)
+
+        if res.Error == nil || res.Error.Code != jsonrpc.CodeInvalidParams {
+            t.Fatalf("expected an invalid params error, got %+v\n", res.Error)
+        }
+
+        if candidates, ok := res.Error.Data.([]database.Task); !ok || len(candidates) != 2 {
+            t.Errorf("expected both tasks as candidates, got %+v\n", res.Error.Data)
+        }
+    })
+
+    t.Run("Should list the history of a task", func (t *testing.T) {
+        changes := make([]database.TaskChange, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.history", "params": {"id": 1}, "id": 5}`, &changes)
+
+        if len(changes) != 2 || changes[0].Field != "name" || changes[1].Field != "completed" || changes[1].Old != nil {
+            t.Errorf("expected the task to be named then completed, got %+v\n", changes)
+        }
+
+        res := handle(t, server, `{"jsonrpc": "2.0", "method": "tasks.history", "params": {"id": 42}, "id": 5}`)
+
+        if res.Error == nil || res.Error.Code != jsonrpc.CodeInvalidParams {
+            t.Errorf("expected an invalid params error, got %+v\n", res.Error)
+        }
+    })
+
+    t.Run("Should answer batches", func (t *testing.T) {
+        response, ok := server.Handle(json.RawMessage(`[
+            {"jsonrpc": "2.0", "method": "tasks.delete", "params": {"ids": [1, 2]}, "id": 6},
+            {"jsonrpc": "2.0", "method": "tasks.list", "id": 7}
+        ]`))
+
+        if !ok {
+            t.Fatal("expected a batch response")
+        }
+
+        encoded, _ := json.Marshal(response)
+        responses := make([]struct {
+            ID int `json:"id"`
+            Result json.RawMessage `json:"result"`
+        }, 0)
+        json.Unmarshal(encoded, &responses)
+
+        if len(responses) != 2 {
+            t.Fatalf("expected 2 responses, got %s\n", encoded)
+        }
+
+        if string(responses[0].Result) != `{"deleted":2}` || string(responses[1].Result) != `[]` {
+            t.Errorf("expected the tasks to be deleted before listing, got %s\n", encoded)
+        }
+    })
+
+    t.Run("Should list the deleted tasks in the trash", func (t *testing.T) {
+        tasks := make([]database.Task, 0)
+        call(t, server, `{"jsonrpc": "2.0", "method": "tasks.list", "params": {"trashed": true}, "id": 8}`, &tasks)
+
+        if len(tasks) != 2 || tasks[0].DeletedAt == nil {
+            t.Errorf("expected both deleted tasks, got %+v\n", tasks)
+        }
+    })
+}
+
+func TestChangeNotifications(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    actor, _ := database.EnsureUserAction(db, "tester")
+    server := NewServer(db, actor)
+
+    input := strings.NewReader(strings.Join([]string{
+        `{"jsonrpc": "2.0", "method": "tasks.add", "params": {"name": "Notified"}, "id": 1}`,
+        `{"jsonrpc": "2.0", "method": "tasks.update", "params": {"id": 1, "completed": true}, "id": 2}`,
+        `{"jsonrpc": "2.0", "method": "tasks.delete", "params": {"ids": [1]}}`,
+    }, "\n"))
+    var output bytes.Buffer
+
+    if err := server.ServeConn(struct{ io.Reader; io.Writer }{input, &output}); err != nil {
+        t.Fatalf("error while serving, %s\n", err)
+    }
+
+    methods := make([]string, 0)
+
+    for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+        message := jsonrpc.Request{}
+        json.Unmarshal([]byte(line), &message)
+        if message.Method != "" {
+            methods = append(methods, message.Method)
+        }
+    }
+
+    want := "tasks.created,tasks.updated,tasks.completed,tasks.deleted"
+
+    if strings.Join(methods, ",") != want {
+        t.Errorf("expected notifications %s, got %s\n", want, strings.Join(methods, ","))
+    }
+}
+
+func call(t testing.TB, server *jsonrpc.Server, request string, result any) {
+    t.Helper()
+    res := handle(t, server, request)
+
+    if res.Error != nil {
+        t.Fatalf("unexpected error response, %+v\n", res.Error)
+    }
+
+    if result != nil {
+        if err := json.Unmarshal(res.Result, result); err != nil {
+            t.Fatalf("error while decoding result, %s\n", err)
+        }
+    }
+}
+
+func handle(t testing.TB, server *jsonrpc.Server, request string) jsonrpc.Response {
+    t.Helper()
+    response, ok := server.Handle(json.RawMessage(request))
+
+    if !ok {
+        t.Fatal("expected a response, got none")
+    }
+
+    return response.(jsonrpc.Response)
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+func searchCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "search",
+        Aliases: []string{"find"},
+        Summary: "Search the names and notes of tasks, e.g. search \"pay rent\" bill*",
+        Args: "<query>",
+        MinArgs: 1,
+        MaxArgs: -1,
+        Flags: []cli.Flag{
+            {Name: "limit", Short: "n", Kind: cli.Int, Placeholder: "count", Usage: "show at most count tasks, 20 by default"},
+        },
+        ReadOnly: true,
+        Run: func (ctx *cli.Context) error { return searchTasks(db, ctx) },
+    }
+}
+
+func searchTasks(db database.DB, ctx *cli.Context) error {
+    props := database.SearchProps{
+        Query: strings.Join(ctx.Args(), " "),
+        Highlight: [2]string{"*", "*"},
+        Limit: 20,
+        Scan: database.IsPostgresDSN(os.Getenv(StoreEnvVar)),
+    }
+
+    if limit, ok := ctx.Int("limit"); ok {
+        props.Limit = limit
+    }
+
+    if term.IsTerminal(int(os.Stdout.Fd())) {
+        props.Highlight = [2]string{"\x1b[1m", "\x1b[0m"}
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    results, err := database.SearchTasksAction(db, actor, props)
+
+    if err != nil {
+        return err
+    }
+
+    if len(results) == 0 {
+        fmt.Println("No matching tasks.")
+        return nil
+    }
+
+    for _, result := range results {
+        checkbox := "[ ]"
+        if result.Completed {
+            checkbox = "[x]"
+        }
+
+        archived := ""
+        if result.ArchivedAt != nil {
+            archived = " (archived)"
+        }
+
+        fmt.Printf("%d.%s - %s%s%s\n", result.ID, checkbox, result.Highlighted, taskDetails(result.Task), archived)
+
+        if result.Snippet != "" {
+            fmt.Printf("    %s\n", strings.ReplaceAll(result.Snippet, "\n", " "))
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/api"
+	"go_todo/cli"
+	"go_todo/database"
+	"net/http"
+)
+
+func serveCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "serve",
+        Summary: "Serve the HTTP API, requests act as the user of their bearer token, see token create",
+        Flags: []cli.Flag{
+            {Name: "addr", Placeholder: "host:port", Usage: "address to listen on, localhost:8080 by default"},
+        },
+        Run: func (ctx *cli.Context) error { return serveAPI(db, ctx) },
+    }
+}
+
+func serveAPI(db database.DB, ctx *cli.Context) error {
+    addr := "localhost:8080"
+
+    if value, ok := ctx.String("addr"); ok {
+        addr = value
+    }
+
+    defer startWebhookDelivery(db)()
+
+    fmt.Printf("Listening on http://%s\n", addr)
+
+    return http.ListenAndServe(addr, api.NewHandler(db))
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/repl"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const HistoryEnvVar = "GO_TODO_HISTORY"
+
+// shellExcluded are the commands which make no sense from within the shell,
+// or which would keep it busy until interrupted.
+var shellExcluded = []string{"shell", "daemon", "completion", "tui", "rpc", "serve"}
+
+func shellCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "shell",
+        Summary: "Run commands interactively over a single connection",
+        Flags: []cli.Flag{
+            {Name: "transaction", Short: "t", Kind: cli.Switch, Usage: "start in a transaction, see begin"},
+        },
+        Run: func (ctx *cli.Context) error {
+            history, err := repl.OpenHistory(historyPath())
+
+            if err != nil {
+                fmt.Printf("Error: couldn't read the history, %s\n", err)
+                history, _ = repl.OpenHistory("")
+            }
+
+            conn, release, err := database.PinConnection(db)
+
+            if err != nil {
+                return err
+            }
+            defer release()
+
+            session := &shellSession{db: conn}
+            defer startWebhookDelivery(db)()
+
+            if transaction, _ := ctx.Bool("transaction"); transaction {
+                if err := session.begin(); err != nil {
+                    return err
+                }
+            }
+
+            return session.run(os.Stdin, os.Stdout, history)
+        },
+    }
+}
+
+// historyPath is GO_TODO_HISTORY falling back to ~/.go_todo_history, the
+// history isn't saved when neither is available.
+func historyPath() string {
+    if path := os.Getenv(HistoryEnvVar); path != "" {
+        return path
+    }
+
+    home, err := os.UserHomeDir()
+
+    if err != nil {
+        return ""
+    }
+
+    return filepath.Join(home, ".go_todo_history")
+}
+
+type shellSession struct {
+    // db is a single connection, so that the session sees its own temporary
+    // state and its transaction whichever line runs.
+    db database.DB
+    // tx is the transaction in progress, nil when changes are saved right
+    // away.
+    tx *transaction
+    done bool
+}
+
+func (s *shellSession) run(in io.Reader, out io.Writer, history *repl.History) error {
+    shell := &repl.Shell{
+        Prompt: s.prompt,
+        Execute: s.execute,
+        Complete: func (words []string) []cli.Completion {
+            return s.app().Complete(words)
+        },
+        History: history,
+    }
+
+    err := shell.Run(in, out)
+
+    if s.tx != nil {
+        if rollbackErr := s.rollback(); rollbackErr != nil {
+            return rollbackErr
+        }
+        fmt.Println("Uncommitted changes rolled back.")
+    }
+
+    return err
+}
+
+func (s *shellSession) prompt() string {
+    if s.tx != nil {
+        return "go_todo*> "
+    }
+    return "go_todo> "
+}
+
+func (s *shellSession) execute(args []string) bool {
+    s.app().Execute(args)
+    return !s.done
+}
+
+// app is rebuilt for every line so commands go through the transaction
+// while one is in progress.
+func (s *shellSession) app() *cli.Command {
+    var db database.DB = s.db
+
+    if s.tx != nil {
+        db = s.tx
+    }
+
+    app := newApp(db)
+    subcommands := make([]*cli.Command, 0, len(app.Subcommands))
+
+    for _, sub := range app.Subcommands {
+        if !Include(shellExcluded, sub.Name) {
+            subcommands = append(subcommands, sub)
+        }
+    }
+
+    app.Subcommands = append(subcommands, s.builtins()...)
+
+    return app
+}
+
+func (s *shellSession) builtins() []*cli.Command {
+    return []*cli.Command{
+        {
+            Name: "begin",
+            Summary: "Start a transaction, changes are only saved once committed",
+            Run: func (ctx *cli.Context) error {
+                if err := s.begin(); err != nil {
+                    return err
+                }
+                fmt.Println("Transaction started, commit or rollback to end it.")
+                return nil
+            },
+        },
+        {
+            Name: "commit",
+            Summary: "Save the changes of the transaction",
+            Run: func (ctx *cli.Context) error {
+                if err := s.commit(); err != nil {
+                    return err
+                }
+                fmt.Println("Changes committed.")
+                return nil
+            },
+        },
+        {
+            Name: "rollback",
+            Summary: "Discard the changes of the transaction",
+            Run: func (ctx *cli.Context) error {
+                if err := s.rollback(); err != nil {
+                    return err
+                }
+                fmt.Println("Changes rolled back.")
+                return nil
+            },
+        },
+        {
+            Name: "exit",
+            Aliases: []string{"quit", "q"},
+            Summary: "Leave the shell, a transaction in progress is rolled back",
+            Run: func (ctx *cli.Context) error {
+                s.done = true
+                return nil
+            },
+        },
+    }
+}
+
+func (s *shellSession) begin() error {
+    if s.tx != nil {
+        return errors.New("A transaction is already in progress")
+    }
+
+    tx, err := beginTransaction(s.db)
+
+    if err != nil {
+        return err
+    }
+
+    s.tx = tx
+
+    return nil
+}
+
+func (s *shellSession) commit() error {
+    if s.tx == nil {
+        return errors.New("No transaction in progress")
+    }
+
+    err := s.tx.Commit()
+    s.tx = nil
+
+    return err
+}
+
+func (s *shellSession) rollback() error {
+    if s.tx == nil {
+        return errors.New("No transaction in progress")
+    }
+
+    err := s.tx.Rollback()
+    s.tx = nil
+
+    return err
+}
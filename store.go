@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/store"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StoreEnvVar selects where add, list, show, done, update and delete keep the
+// tasks, e.g. json:tasks.json, see store.Open. The users, shares, views and
+// the journal stay in task.db, unless a PostgreSQL database replaces it
+// altogether.
+//
+// A JSON store can't run list -where, list @view, search, edit, undo, redo,
+// history, show -history, trash, archive, unarchive, tui, rpc and serve,
+// which need a SQL store. The memory store is refused, it would be empty for
+// every command, it's meant for the tests and the programs embedding the
+// store package.
+const StoreEnvVar = "GO_TODO_STORE"
+
+// openedStores keeps the stores other than SQLite for the life of the
+// process, so that the commands of a shell share a JSON store.
+var openedStores = map[string]store.TaskStore{}
+var openedStoresMu sync.Mutex
+
+// taskStore opens the store GO_TODO_STORE selects, the tasks table of db by
+// default.
+func taskStore(db database.DB) (store.TaskStore, error) {
+    config := os.Getenv(StoreEnvVar)
+
+    if isSQLStore(config) {
+        return store.NewSQLStore(db), nil
+    }
+
+    if kind, _, _ := strings.Cut(config, ":"); kind == "memory" {
+        return nil, fmt.Errorf("invalid %s, the memory store doesn't outlive a command, use sqlite, json:<path> or postgres://...", StoreEnvVar)
+    }
+
+    openedStoresMu.Lock()
+    defer openedStoresMu.Unlock()
+
+    if opened, ok := openedStores[config]; ok {
+        return opened, nil
+    }
+
+    opened, err := store.Open(config, db)
+
+    if err != nil {
+        return nil, fmt.Errorf("invalid %s, %w", StoreEnvVar, err)
+    }
+
+    openedStores[config] = opened
+
+    return opened, nil
+}
+
+// isSQLStore tells whether the tasks are in a SQL database along the other
+// tables, task.db or a PostgreSQL database.
+func isSQLStore(config string) bool {
+    return config == "" || config == "sqlite" || database.IsPostgresDSN(config)
+}
+
+// sqlStoreOnly makes cmd and its subcommands fail when the tasks aren't kept
+// in a SQL database, since they work on its tables directly.
+func sqlStoreOnly(cmd *cli.Command) *cli.Command {
+    return requireStore(cmd, isSQLStore, "a SQL store")
+}
+
+// taskDBOnly makes cmd and its subcommands fail when a PostgreSQL database
+// replaces task.db, since they work on the file itself.
+func taskDBOnly(cmd *cli.Command) *cli.Command {
+    return requireStore(cmd, func (config string) bool { return !database.IsPostgresDSN(config) }, "task.db")
+}
+
+func requireStore(cmd *cli.Command, accepts func (config string) bool, what string) *cli.Command {
+    if run := cmd.Run; run != nil {
+        cmd.Run = func (ctx *cli.Context) error {
+            if config := os.Getenv(StoreEnvVar); !accepts(config) {
+                return fmt.Errorf("%s needs %s, %s is '%s'", cmd.Name, what, StoreEnvVar, config)
+            }
+
+            return run(ctx)
+        }
+    }
+
+    for _, sub := range cmd.Subcommands {
+        requireStore(sub, accepts, what)
+    }
+
+    return cmd
+}
+
+// runOnPostgres runs a command with every table in the PostgreSQL database at
+// dsn instead of task.db, so that a team shares its users along the tasks.
+// Nothing runs when the database can't be opened.
+func runOnPostgres(dsn string, args []string) error {
+    postgres, err := database.OpenPostgres(dsn)
+
+    if err != nil {
+        return err
+    }
+    defer postgres.Close()
+
+    app := newApp(postgres)
+
+    if changesTasks(app, args) {
+        autoArchive(postgres)
+    }
+
+    app.Execute(args)
+    deliverWebhooks(postgres)
+
+    return nil
+}
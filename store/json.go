@@ -0,0 +1,102 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONStore keeps the tasks in a JSON file read on every call and rewritten
+// after every change. The file is replaced at once so that it's never seen
+// half written, and every call holds a lock on <path>.lock from reading it to
+// writing it so that processes sharing the file don't lose each other's
+// changes. Like the memory store, it knows nothing of the shares.
+type JSONStore struct {
+    atomicStore
+    path string
+    mu sync.Mutex
+}
+
+func NewJSONStore(path string) *JSONStore {
+    s := &JSONStore{path: path}
+    s.atomicStore = atomicStore{run: s.run}
+    return s
+}
+
+func (s *JSONStore) run(fn func (TaskStore) error, write bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    unlock, err := lockFile(s.path + ".lock")
+
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
+    state, err := s.load()
+
+    if err != nil {
+        return err
+    }
+
+    if err := (&stateStore{state: state}).Transaction(fn); err != nil {
+        return err
+    }
+
+    if !write {
+        return nil
+    }
+
+    return s.save(state)
+}
+
+// load reads the file, a missing one holds no tasks.
+func (s *JSONStore) load() (*taskState, error) {
+    state := &taskState{}
+    content, err := os.ReadFile(s.path)
+
+    if errors.Is(err, os.ErrNotExist) {
+        return state, nil
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(content, state); err != nil {
+        return nil, err
+    }
+
+    return state, nil
+}
+
+// save writes a temporary file next to the store then renames it over the
+// store.
+func (s *JSONStore) save(state *taskState) error {
+    content, err := json.MarshalIndent(state, "", "  ")
+
+    if err != nil {
+        return err
+    }
+
+    file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+
+    if err != nil {
+        return err
+    }
+    defer os.Remove(file.Name())
+
+    if _, err := file.Write(append(content, '\n')); err != nil {
+        file.Close()
+        return err
+    }
+
+    if err := file.Close(); err != nil {
+        return err
+    }
+
+    return os.Rename(file.Name(), s.path)
+}
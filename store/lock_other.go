@@ -0,0 +1,28 @@
+//go:build !unix
+
+package store
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockFile waits for the file at path to be created by this process and
+// removes it to unlock, a process that dies holding it leaves it behind.
+func lockFile(path string) (func () error, error) {
+    for {
+        file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+
+        if err == nil {
+            file.Close()
+            return func () error { return os.Remove(path) }, nil
+        }
+
+        if !errors.Is(err, os.ErrExist) {
+            return nil, err
+        }
+
+        time.Sleep(10 * time.Millisecond)
+    }
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile waits for an exclusive lock on the file at path, created when
+// missing, the lock goes away with the process holding it.
+func lockFile(path string) (func () error, error) {
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+        file.Close()
+        return nil, err
+    }
+
+    return func () error {
+        defer file.Close()
+        return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+    }, nil
+}
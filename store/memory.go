@@ -0,0 +1,505 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// taskState is what the memory and JSON stores keep, NextID is never reused
+// so that a purged task can't be confused with a new one.
+type taskState struct {
+    NextID int `json:"next_id"`
+    Tasks []database.Task `json:"tasks"`
+}
+
+func (s *taskState) clone() *taskState {
+    return &taskState{NextID: s.NextID, Tasks: append([]database.Task(nil), s.Tasks...)}
+}
+
+// stateStore implements TaskStore over a state, without any locking. Tasks
+// without an owner are everyone's, otherwise their owner and assignee can read
+// and update them and only their owner can delete them. The shares live in
+// SQLite and aren't taken into account.
+type stateStore struct {
+    state *taskState
+}
+
+func (s *stateStore) AddTasks(actor database.User, props []database.AddTaskProp) ([]database.Task, error) {
+    tasks := make([]database.Task, 0, len(props))
+    now := currentTimestamp()
+
+    for _, prop := range props {
+        if err := database.ValidateTags(prop.Tags); err != nil {
+            return nil, err
+        }
+
+        s.state.NextID++
+        ownerID := actor.ID
+        task := database.Task{
+            ID: s.state.NextID,
+            Name: prop.Name,
+            Completed: prop.Completed,
+            OwnerID: &ownerID,
+            AssigneeID: copyInt(prop.AssigneeID),
+            DueAt: storedTime(prop.DueAt),
+            Priority: prop.Priority,
+            Tags: copyTags(prop.Tags),
+            Project: prop.Project,
+            Recurrence: prop.Recurrence,
+            Notes: prop.Notes,
+            CreatedAt: &now,
+            UpdatedAt: &now,
+        }
+
+        if prop.Completed {
+            task.CompletedAt = &now
+        }
+
+        s.state.Tasks = append(s.state.Tasks, task)
+        tasks = append(tasks, loadedTask(task))
+    }
+
+    return tasks, nil
+}
+
+func (s *stateStore) GetTask(actor database.User, id int) (database.Task, error) {
+    idx := s.find(actor, id)
+
+    if idx < 0 {
+        return database.Task{}, ErrNotFound
+    }
+
+    return loadedTask(s.state.Tasks[idx]), nil
+}
+
+// find is the index of the task with the given ID, -1 when it's in the trash
+// or the actor can't read it.
+func (s *stateStore) find(actor database.User, id int) int {
+    for idx, task := range s.state.Tasks {
+        if task.ID == id && task.DeletedAt == nil && canRead(task, actor) {
+            return idx
+        }
+    }
+
+    return -1
+}
+
+func (s *stateStore) ListTasks(actor database.User, props database.ListTaskProps) ([]database.Task, error) {
+    if props.Where != nil {
+        return nil, fmt.Errorf("%w, filters need a SQL store", ErrUnsupported)
+    }
+
+    tasks := make([]database.Task, 0)
+
+    for _, task := range s.state.Tasks {
+        if canRead(task, actor) && listed(task, actor, props) {
+            tasks = append(tasks, loadedTask(task))
+        }
+    }
+
+    if len(props.SortBy) > 0 {
+        if err := sortTasks(tasks, props.SortBy); err != nil {
+            return nil, err
+        }
+    }
+
+    return tasks, nil
+}
+
+// listed tells whether ListTasks keeps task, like the conditions
+// ListTasksAction writes.
+func listed(task database.Task, actor database.User, props database.ListTaskProps) bool {
+    switch {
+    case props.Trashed:
+        if task.DeletedAt == nil {
+            return false
+        }
+    case props.Archived:
+        if task.DeletedAt != nil || task.ArchivedAt == nil {
+            return false
+        }
+    case props.WithArchived:
+        if task.DeletedAt != nil {
+            return false
+        }
+    default:
+        if task.DeletedAt != nil || task.ArchivedAt != nil {
+            return false
+        }
+    }
+
+    if props.WhereCompleted != nil && task.Completed != *props.WhereCompleted {
+        return false
+    }
+
+    if props.WhereAssigneeID != nil && !sameID(task.AssigneeID, *props.WhereAssigneeID) {
+        return false
+    }
+
+    if props.WhereProject != nil && task.Project != *props.WhereProject {
+        return false
+    }
+
+    if props.WhereTag != nil && !slices.Contains(task.Tags, *props.WhereTag) {
+        return false
+    }
+
+    if props.CompletedBefore != nil && (!task.Completed || task.CompletedAt == nil || !task.CompletedAt.Before(*props.CompletedBefore)) {
+        return false
+    }
+
+    if props.OnlyMine && !sameID(task.OwnerID, actor.ID) && !sameID(task.AssigneeID, actor.ID) {
+        return false
+    }
+
+    return true
+}
+
+// sortTasks orders tasks like the ORDER BY of ListTasksAction, tasks without
+// a due date come last either way and ties keep the order of creation.
+func sortTasks(tasks []database.Task, keys []database.SortKey) error {
+    for _, key := range keys {
+        if _, ok := database.SortColumns[key.Column]; !ok {
+            return fmt.Errorf("Can't sort by '%s'", key.Column)
+        }
+    }
+
+    sort.SliceStable(tasks, func (i, j int) bool {
+        for _, key := range keys {
+            order := compareColumn(tasks[i], tasks[j], key.Column)
+
+            if key.Column == "due" && (tasks[i].DueAt == nil) != (tasks[j].DueAt == nil) {
+                return tasks[j].DueAt == nil
+            }
+
+            if key.Desc {
+                order = -order
+            }
+
+            if order != 0 {
+                return order < 0
+            }
+        }
+
+        return tasks[i].ID < tasks[j].ID
+    })
+
+    return nil
+}
+
+func compareColumn(a database.Task, b database.Task, column string) int {
+    switch column {
+    case "id":
+        return a.ID - b.ID
+    case "name":
+        return strings.Compare(a.Name, b.Name)
+    case "completed":
+        return boolValue(a.Completed) - boolValue(b.Completed)
+    case "priority":
+        return a.Priority - b.Priority
+    case "due":
+        if a.DueAt == nil || b.DueAt == nil {
+            return 0
+        }
+        return a.DueAt.Compare(*b.DueAt)
+    case "project":
+        return strings.Compare(a.Project, b.Project)
+    }
+
+    return 0
+}
+
+func (s *stateStore) UpdateTasks(actor database.User, IDs []int, payload database.UpdateTaskProp) ([]database.Task, error) {
+    tasks := make([]database.Task, 0, len(IDs))
+    now := currentTimestamp()
+
+    if payload.Tags != nil {
+        if err := database.ValidateTags(*payload.Tags); err != nil {
+            return nil, err
+        }
+    }
+
+    for _, id := range IDs {
+        idx := s.find(actor, id)
+
+        if idx < 0 {
+            return nil, bulkError(ErrNotFound, id, len(IDs))
+        }
+
+        task, ok := applyUpdate(s.state.Tasks[idx], payload, now)
+
+        if !ok {
+            return nil, bulkError(errors.New("Nothing to update"), id, len(IDs))
+        }
+
+        s.state.Tasks[idx] = task
+        tasks = append(tasks, loadedTask(task))
+    }
+
+    return tasks, nil
+}
+
+// applyUpdate is task with payload applied, false when payload is empty.
+func applyUpdate(task database.Task, payload database.UpdateTaskProp, now time.Time) (database.Task, bool) {
+    updated := false
+
+    if payload.Name != nil {
+        task.Name, updated = *payload.Name, true
+    }
+
+    if payload.Completed != nil {
+        task.Completed, updated = *payload.Completed, true
+
+        // Completing a completed task keeps the time it was completed at.
+        if !task.Completed {
+            task.CompletedAt = nil
+        } else if task.CompletedAt == nil {
+            task.CompletedAt = &now
+        }
+    }
+
+    if payload.AssigneeID != nil {
+        task.AssigneeID, updated = nil, true
+        if *payload.AssigneeID != 0 {
+            task.AssigneeID = copyInt(payload.AssigneeID)
+        }
+    }
+
+    if payload.DueAt != nil {
+        task.DueAt, updated = nil, true
+        if !payload.DueAt.IsZero() {
+            task.DueAt = storedTime(payload.DueAt)
+        }
+    }
+
+    if payload.Priority != nil {
+        task.Priority, updated = *payload.Priority, true
+    }
+
+    if payload.Tags != nil {
+        task.Tags, updated = copyTags(*payload.Tags), true
+    }
+
+    if payload.Project != nil {
+        task.Project, updated = *payload.Project, true
+    }
+
+    if payload.Recurrence != nil {
+        task.Recurrence, updated = *payload.Recurrence, true
+    }
+
+    if payload.Notes != nil {
+        task.Notes, updated = *payload.Notes, true
+    }
+
+    task.UpdatedAt = &now
+
+    return task, updated
+}
+
+// bulkError tells which task failed when several are updated, like the bulk
+// actions of the database package.
+func bulkError(err error, id int, count int) error {
+    if count == 1 {
+        return err
+    }
+
+    if errors.Is(err, database.ErrPermissionDenied) {
+        return fmt.Errorf("%w on task %d", err, id)
+    }
+
+    return fmt.Errorf("couldn't update task %d, %w", id, err)
+}
+
+func (s *stateStore) DeleteTasks(actor database.User, IDs []int) (int, error) {
+    deleted := 0
+    now := currentTimestamp()
+
+    for _, id := range IDs {
+        idx := s.find(actor, id)
+
+        if idx < 0 || !canDelete(s.state.Tasks[idx], actor) {
+            continue
+        }
+
+        s.state.Tasks[idx].DeletedAt = &now
+        s.state.Tasks[idx].UpdatedAt = &now
+        deleted++
+    }
+
+    return deleted, nil
+}
+
+// Transaction runs fn on a copy of the state, which replaces the state once
+// fn succeeded.
+func (s *stateStore) Transaction(fn func (TaskStore) error) error {
+    copied := s.state.clone()
+
+    if err := fn(&stateStore{state: copied}); err != nil {
+        return err
+    }
+
+    *s.state = *copied
+
+    return nil
+}
+
+// canRead also tells whether the actor can update task.
+func canRead(task database.Task, actor database.User) bool {
+    return task.OwnerID == nil || *task.OwnerID == actor.ID || sameID(task.AssigneeID, actor.ID)
+}
+
+func canDelete(task database.Task, actor database.User) bool {
+    return task.OwnerID == nil || *task.OwnerID == actor.ID
+}
+
+func sameID(id *int, other int) bool {
+    return id != nil && *id == other
+}
+
+func boolValue(value bool) int {
+    if value {
+        return 1
+    }
+    return 0
+}
+
+// currentTimestamp is the time at the precision of CURRENT_TIMESTAMP.
+func currentTimestamp() time.Time {
+    return time.Now().UTC().Truncate(time.Second)
+}
+
+func copyInt(value *int) *int {
+    if value == nil {
+        return nil
+    }
+    copied := *value
+    return &copied
+}
+
+// storedTime keeps times in UTC, like the database does.
+func storedTime(value *time.Time) *time.Time {
+    if value == nil {
+        return nil
+    }
+    utc := value.UTC()
+    return &utc
+}
+
+// copyTags drops an empty list, a task read from the database has none.
+func copyTags(tags []string) []string {
+    if len(tags) == 0 {
+        return nil
+    }
+    return append([]string(nil), tags...)
+}
+
+// loadedTask is a copy of task as the database returns it, with the due date
+// in local time.
+func loadedTask(task database.Task) database.Task {
+    task.Tags = copyTags(task.Tags)
+
+    if task.DueAt != nil {
+        local := task.DueAt.Local()
+        task.DueAt = &local
+    }
+
+    return task
+}
+
+// atomicStore implements TaskStore with a function running each call in a
+// transaction, write tells whether the call may change the tasks.
+type atomicStore struct {
+    run func (fn func (TaskStore) error, write bool) error
+}
+
+func (s atomicStore) AddTasks(actor database.User, props []database.AddTaskProp) ([]database.Task, error) {
+    var tasks []database.Task
+
+    err := s.run(func (store TaskStore) error {
+        var err error
+        tasks, err = store.AddTasks(actor, props)
+        return err
+    }, true)
+
+    return tasks, err
+}
+
+func (s atomicStore) GetTask(actor database.User, id int) (database.Task, error) {
+    var task database.Task
+
+    err := s.run(func (store TaskStore) error {
+        var err error
+        task, err = store.GetTask(actor, id)
+        return err
+    }, false)
+
+    return task, err
+}
+
+func (s atomicStore) ListTasks(actor database.User, props database.ListTaskProps) ([]database.Task, error) {
+    var tasks []database.Task
+
+    err := s.run(func (store TaskStore) error {
+        var err error
+        tasks, err = store.ListTasks(actor, props)
+        return err
+    }, false)
+
+    return tasks, err
+}
+
+func (s atomicStore) UpdateTasks(actor database.User, IDs []int, payload database.UpdateTaskProp) ([]database.Task, error) {
+    var tasks []database.Task
+
+    err := s.run(func (store TaskStore) error {
+        var err error
+        tasks, err = store.UpdateTasks(actor, IDs, payload)
+        return err
+    }, true)
+
+    return tasks, err
+}
+
+func (s atomicStore) DeleteTasks(actor database.User, IDs []int) (int, error) {
+    var deleted int
+
+    err := s.run(func (store TaskStore) error {
+        var err error
+        deleted, err = store.DeleteTasks(actor, IDs)
+        return err
+    }, true)
+
+    return deleted, err
+}
+
+func (s atomicStore) Transaction(fn func (TaskStore) error) error {
+    return s.run(fn, true)
+}
+
+// MemoryStore keeps the tasks in memory, e.g. for tests. It's safe for
+// concurrent use and every call is atomic.
+type MemoryStore struct {
+    atomicStore
+    mu sync.Mutex
+    state taskState
+}
+
+func NewMemoryStore() *MemoryStore {
+    s := &MemoryStore{}
+    s.atomicStore = atomicStore{run: s.run}
+    return s
+}
+
+func (s *MemoryStore) run(fn func (TaskStore) error, write bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return (&stateStore{state: &s.state}).Transaction(fn)
+}
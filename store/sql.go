@@ -0,0 +1,75 @@
+package store
+
+import (
+	"database/sql"
+	"go_todo/database"
+)
+
+// SQLStore keeps the tasks in the tasks table of a SQLite or PostgreSQL
+// database through the actions of the database package, the changes are
+// journaled and their history recorded.
+type SQLStore struct {
+    db database.DB
+}
+
+func NewSQLStore(db database.DB) *SQLStore {
+    return &SQLStore{db: db}
+}
+
+// AddTasks adds a single task like AddTaskAction so that its errors aren't
+// numbered.
+func (s *SQLStore) AddTasks(actor database.User, props []database.AddTaskProp) ([]database.Task, error) {
+    if len(props) == 1 {
+        task, err := database.AddTaskAction(s.db, actor, props[0])
+
+        if err != nil {
+            return nil, err
+        }
+
+        return []database.Task{task}, nil
+    }
+
+    return database.AddTaskBulkAction(s.db, actor, props)
+}
+
+func (s *SQLStore) GetTask(actor database.User, id int) (database.Task, error) {
+    task, err := database.ListTaskActionByID(s.db, actor, uint(id))
+
+    if err == sql.ErrNoRows {
+        return database.Task{}, ErrNotFound
+    }
+
+    return task, err
+}
+
+func (s *SQLStore) ListTasks(actor database.User, props database.ListTaskProps) ([]database.Task, error) {
+    return database.ListTasksAction(s.db, actor, props)
+}
+
+func (s *SQLStore) UpdateTasks(actor database.User, IDs []int, payload database.UpdateTaskProp) ([]database.Task, error) {
+    if len(IDs) == 1 {
+        task, err := database.UpdateTaskAction(s.db, actor, IDs[0], payload)
+
+        if err != nil {
+            return nil, err
+        }
+
+        return []database.Task{task}, nil
+    }
+
+    return database.UpdateTaskIDsBulkAction(s.db, actor, IDs, payload)
+}
+
+func (s *SQLStore) DeleteTasks(actor database.User, IDs []int) (int, error) {
+    if len(IDs) == 0 {
+        return 0, nil
+    }
+
+    return database.DeleteTaskBulkAction(s.db, actor, IDs)
+}
+
+func (s *SQLStore) Transaction(fn func (TaskStore) error) error {
+    return database.InTransaction(s.db, func (tx database.DB) error {
+        return fn(NewSQLStore(tx))
+    })
+}
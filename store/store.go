@@ -0,0 +1,73 @@
+// Package store keeps the tasks behind the TaskStore interface, in the SQLite
+// database by default, in a PostgreSQL database, in memory or in a JSON file.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"strings"
+)
+
+// TaskStore adds, reads, updates and deletes tasks on behalf of an actor, who
+// only sees the tasks they can read.
+type TaskStore interface {
+    // AddTasks adds every task or none of them.
+    AddTasks(actor database.User, props []database.AddTaskProp) ([]database.Task, error)
+    GetTask(actor database.User, id int) (database.Task, error)
+    ListTasks(actor database.User, props database.ListTaskProps) ([]database.Task, error)
+    // UpdateTasks applies payload to every task or to none of them.
+    UpdateTasks(actor database.User, IDs []int, payload database.UpdateTaskProp) ([]database.Task, error)
+    // DeleteTasks moves the tasks the actor can delete to the trash and
+    // returns how many were.
+    DeleteTasks(actor database.User, IDs []int) (int, error)
+    // Transaction runs fn with a store whose changes are kept when fn
+    // succeeds and dropped otherwise.
+    Transaction(fn func (TaskStore) error) error
+}
+
+// ErrNotFound is returned for the tasks that don't exist and for the ones the
+// actor can't read.
+var ErrNotFound = database.ErrTaskNotFound
+
+// ErrUnsupported is returned for what a store can't do, e.g. list the tasks
+// matching a filter of the filter package, which only SQL stores understand.
+var ErrUnsupported = errors.New("Not supported by this task store")
+
+// Open opens the store config describes, db is the database of the other
+// commands:
+//
+//	sqlite         the tasks table of db, the default
+//	memory         an empty store in memory, for the tests and embedding
+//	json:<path>    the JSON file at path, created on the first change
+//	postgres://... the PostgreSQL database at that URL, migrated when opened
+//
+// Only the SQL stores honour the shares of the share command, the tasks of the
+// memory and JSON stores are read by their owner and assignee only.
+func Open(config string, db database.DB) (TaskStore, error) {
+    if database.IsPostgresDSN(config) {
+        postgres, err := database.OpenPostgres(config)
+
+        if err != nil {
+            return nil, err
+        }
+
+        return NewSQLStore(postgres), nil
+    }
+
+    kind, path, _ := strings.Cut(config, ":")
+
+    switch kind {
+    case "", "sqlite":
+        return NewSQLStore(db), nil
+    case "memory":
+        return NewMemoryStore(), nil
+    case "json":
+        if path == "" {
+            return nil, errors.New("json needs a path, e.g. json:tasks.json")
+        }
+        return NewJSONStore(path), nil
+    }
+
+    return nil, fmt.Errorf("Unknown task store '%s', expected sqlite, memory, json:<path> or postgres://...", config)
+}
@@ -0,0 +1,221 @@
+package store_test
+
+import (
+	"database/sql"
+	"fmt"
+	"go_todo/database"
+	"go_todo/store"
+	"go_todo/store/storetest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var owner = database.User{ID: 1, Name: "owner"}
+var other = database.User{ID: 2, Name: "other"}
+
+func TestMemoryStore(t *testing.T) {
+    storetest.Run(t, func (t *testing.T) (store.TaskStore, database.User, database.User) {
+        return store.NewMemoryStore(), owner, other
+    })
+}
+
+func TestJSONStore(t *testing.T) {
+    storetest.Run(t, func (t *testing.T) (store.TaskStore, database.User, database.User) {
+        return store.NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")), owner, other
+    })
+
+    t.Run("Should keep the tasks in the file", func (t *testing.T) {
+        path := filepath.Join(t.TempDir(), "tasks.json")
+
+        added, err := store.NewJSONStore(path).AddTasks(owner, []database.AddTaskProp{{Name: "Persisted", Tags: []string{"a"}}})
+
+        if err != nil {
+            t.Fatalf("error while adding the task, %s\n", err)
+        }
+
+        task, err := store.NewJSONStore(path).GetTask(owner, added[0].ID)
+
+        if err != nil || task.Name != "Persisted" || len(task.Tags) != 1 {
+            t.Errorf("expected the task read back from the file, got %+v and %v\n", task, err)
+        }
+    })
+
+    t.Run("Should keep the changes of every store sharing the file", func (t *testing.T) {
+        path := filepath.Join(t.TempDir(), "tasks.json")
+        errs := make(chan error)
+
+        for i := 0; i < 4; i++ {
+            go func () {
+                var err error
+
+                for j := 0; j < 10; j++ {
+                    if _, err = store.NewJSONStore(path).AddTasks(owner, []database.AddTaskProp{{Name: "Concurrent"}}); err != nil {
+                        break
+                    }
+                }
+
+                errs <- err
+            }()
+        }
+
+        for i := 0; i < 4; i++ {
+            if err := <-errs; err != nil {
+                t.Fatalf("error while adding the tasks, %s\n", err)
+            }
+        }
+
+        tasks, err := store.NewJSONStore(path).ListTasks(owner, database.ListTaskProps{})
+
+        if err != nil || len(tasks) != 40 {
+            t.Errorf("expected the 40 tasks added kept, got %d and %v\n", len(tasks), err)
+        }
+    })
+}
+
+func TestSQLStore(t *testing.T) {
+    storetest.Run(t, func (t *testing.T) (store.TaskStore, database.User, database.User) {
+        db, users := openSQLite(t)
+        return store.NewSQLStore(db), users[0], users[1]
+    })
+
+    storetest.RunShares(t, func (t *testing.T) (store.TaskStore, database.User, database.User, storetest.Share) {
+        db, users := openSQLite(t)
+        share := func (owner database.User, user database.User, permission string, project string) error {
+            return database.ShareTasksAction(db, owner, user, permission, project)
+        }
+
+        return store.NewSQLStore(db), users[0], users[1], share
+    })
+}
+
+// openSQLite opens a copy of the database of the tests with two users of its
+// own.
+func openSQLite(t *testing.T) (database.DB, []database.User) {
+    root := t.TempDir()
+
+    if err := database.BackupDatabase("../", filepath.Join(root, "task.db")); err != nil {
+        t.Fatalf("error while copying the database, %s\n", err)
+    }
+
+    db, err := database.OpenDatabase(root)
+
+    if err != nil {
+        t.Fatalf("error while opening the database, %s\n", err)
+    }
+    t.Cleanup(func () { db.Close() })
+
+    users := make([]database.User, 2)
+
+    for idx, name := range []string{"storetest-owner", "storetest-other"} {
+        if users[idx], err = database.EnsureUserAction(db, name); err != nil {
+            t.Fatalf("error while creating user %s, %s\n", name, err)
+        }
+    }
+
+    return db, users
+}
+
+// TestPostgresStore runs on a schema of its own in the PostgreSQL database
+// GO_TODO_TEST_POSTGRES names, e.g. postgres://todo@localhost/todo_test?sslmode=disable,
+// and is skipped without one.
+func TestPostgresStore(t *testing.T) {
+    dsn := os.Getenv("GO_TODO_TEST_POSTGRES")
+
+    if dsn == "" {
+        t.Skip("GO_TODO_TEST_POSTGRES isn't set")
+    }
+
+    storetest.Run(t, func (t *testing.T) (store.TaskStore, database.User, database.User) {
+        admin, err := sql.Open(database.PostgresDriverName, dsn)
+
+        if err != nil {
+            t.Fatalf("error while connecting to the database, %s\n", err)
+        }
+
+        schema := fmt.Sprintf("go_todo_store_%d", time.Now().UnixNano())
+
+        if _, err := admin.Exec("CREATE SCHEMA " + schema + ";"); err != nil {
+            admin.Close()
+            t.Fatalf("error while creating schema %s, %s\n", schema, err)
+        }
+
+        separator := "?"
+
+        if strings.Contains(dsn, "?") {
+            separator = "&"
+        }
+
+        db, err := database.OpenPostgres(dsn + separator + "search_path=" + schema)
+        t.Cleanup(func () {
+            if db != nil {
+                db.Close()
+            }
+            admin.Exec("DROP SCHEMA " + schema + " CASCADE;")
+            admin.Close()
+        })
+
+        if err != nil {
+            t.Fatalf("error while opening the database, %s\n", err)
+        }
+
+        users := make([]database.User, 2)
+
+        for idx, name := range []string{"storetest-owner", "storetest-other"} {
+            if users[idx], err = database.EnsureUserAction(db, name); err != nil {
+                t.Fatalf("error while creating user %s, %s\n", name, err)
+            }
+        }
+
+        return store.NewSQLStore(db), users[0], users[1]
+    })
+}
+
+func TestOpen(t *testing.T) {
+    t.Run("Should open the store the configuration names", func (t *testing.T) {
+        tests := []struct {
+            config string
+            want any
+        }{
+            {"", &store.SQLStore{}},
+            {"sqlite", &store.SQLStore{}},
+            {"memory", &store.MemoryStore{}},
+            {"json:tasks.json", &store.JSONStore{}},
+        }
+
+        for _, test := range tests {
+            opened, err := store.Open(test.config, nil)
+
+            if err != nil {
+                t.Errorf("%s: unexpected error %s\n", test.config, err)
+                continue
+            }
+
+            if got, want := typeName(opened), typeName(test.want); got != want {
+                t.Errorf("%s: expected a %s, got a %s\n", test.config, want, got)
+            }
+        }
+    })
+
+    t.Run("Should reject an unknown store", func (t *testing.T) {
+        for _, config := range []string{"json", "json:", "mongodb://localhost"} {
+            if _, err := store.Open(config, nil); err == nil {
+                t.Errorf("%s: expected an error\n", config)
+            }
+        }
+    })
+}
+
+func typeName(value any) string {
+    switch value.(type) {
+    case *store.SQLStore:
+        return "SQLStore"
+    case *store.MemoryStore:
+        return "MemoryStore"
+    case *store.JSONStore:
+        return "JSONStore"
+    }
+    return "unknown"
+}
@@ -0,0 +1,616 @@
+// Package storetest checks that an implementation of store.TaskStore behaves
+// like the others, run it from the tests of every store, and RunShares from
+// the tests of the stores honouring the shares.
+package storetest
+
+import (
+	"errors"
+	"go_todo/database"
+	"go_todo/filter"
+	"go_todo/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Open returns an empty store and two of its users, the second one is a
+// stranger to the tasks of the first one.
+type Open func (t *testing.T) (store.TaskStore, database.User, database.User)
+
+// Run runs the conformance suite, each test on a store of its own.
+func Run(t *testing.T, open Open) {
+    t.Run("Should add and get a task with all its fields", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        due := time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC)
+
+        added := addTasks(t, tasks, owner, database.AddTaskProp{
+            Name: "Pay rent",
+            Completed: true,
+            AssigneeID: &other.ID,
+            DueAt: &due,
+            Priority: database.PriorityHigh,
+            Tags: []string{"finance", "home"},
+            Project: "flat",
+            Recurrence: "every month",
+            Notes: "before noon",
+        })[0]
+
+        task, err := tasks.GetTask(owner, added.ID)
+
+        if err != nil {
+            t.Fatalf("error while getting the task, %s\n", err)
+        }
+
+        if !reflect.DeepEqual(task, added) {
+            t.Errorf("expected the added task %+v, got %+v\n", added, task)
+        }
+
+        if task.Name != "Pay rent" || !task.Completed || task.Priority != database.PriorityHigh || task.Project != "flat" || task.Recurrence != "every month" || task.Notes != "before noon" {
+            t.Errorf("unexpected fields %+v\n", task)
+        }
+
+        if !reflect.DeepEqual(task.Tags, []string{"finance", "home"}) {
+            t.Errorf("expected tags finance and home, got %v\n", task.Tags)
+        }
+
+        if task.OwnerID == nil || *task.OwnerID != owner.ID || task.AssigneeID == nil || *task.AssigneeID != other.ID {
+            t.Errorf("expected the task owned by %d and assigned to %d, got %v and %v\n", owner.ID, other.ID, task.OwnerID, task.AssigneeID)
+        }
+
+        if task.DueAt == nil || !task.DueAt.Equal(due) || task.DueAt.Location() != time.Local {
+            t.Errorf("expected due at %s in local time, got %v\n", due, task.DueAt)
+        }
+
+        if task.CreatedAt == nil || task.UpdatedAt == nil || task.CompletedAt == nil {
+            t.Errorf("expected the task stamped when created and completed, got %+v\n", task)
+        }
+    })
+
+    t.Run("Should keep the empty fields empty", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Water plants"})[0]
+
+        if task.AssigneeID != nil || task.DueAt != nil || task.Tags != nil || task.CompletedAt != nil || task.DeletedAt != nil || task.ArchivedAt != nil {
+            t.Errorf("expected no assignee, due date, tags or stamps, got %+v\n", task)
+        }
+    })
+
+    t.Run("Should refuse the tags with commas", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+
+        if _, err := tasks.AddTasks(owner, []database.AddTaskProp{{Name: "Split", Tags: []string{"work,home"}}}); err == nil {
+            t.Error("expected a tag with a comma to be refused when adding")
+        }
+
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Split"})[0]
+        tags := []string{"work", ""}
+
+        if _, err := tasks.UpdateTasks(owner, []int{task.ID}, database.UpdateTaskProp{Tags: &tags}); err == nil {
+            t.Error("expected an empty tag to be refused when updating")
+        }
+    })
+
+    t.Run("Should not find missing tasks or the tasks of others", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Private"})[0]
+
+        if _, err := tasks.GetTask(owner, task.ID + 1000); !errors.Is(err, store.ErrNotFound) {
+            t.Errorf("expected ErrNotFound for a missing task, got %v\n", err)
+        }
+
+        if _, err := tasks.GetTask(other, task.ID); !errors.Is(err, store.ErrNotFound) {
+            t.Errorf("expected ErrNotFound for the task of another user, got %v\n", err)
+        }
+
+        if list := created(listTasks(t, tasks, other, database.ListTaskProps{}), task); len(list) != 0 {
+            t.Errorf("expected the task of another user not listed, got %v\n", list)
+        }
+    })
+
+    t.Run("Should share a task with its assignee", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Review", AssigneeID: &other.ID})[0]
+        name := "Review the draft"
+
+        if _, err := tasks.GetTask(other, task.ID); err != nil {
+            t.Errorf("expected the assignee to read the task, got %s\n", err)
+        }
+
+        if _, err := tasks.UpdateTasks(other, []int{task.ID}, database.UpdateTaskProp{Name: &name}); err != nil {
+            t.Errorf("expected the assignee to update the task, got %s\n", err)
+        }
+
+        if deleted, _ := tasks.DeleteTasks(other, []int{task.ID}); deleted != 0 {
+            t.Errorf("expected only the owner to delete the task, the assignee deleted %d\n", deleted)
+        }
+    })
+
+    t.Run("Should list tasks in the order they were added", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        added := addTasks(t, tasks, owner, database.AddTaskProp{Name: "One"}, database.AddTaskProp{Name: "Two"}, database.AddTaskProp{Name: "Three"})
+
+        if list := created(listTasks(t, tasks, owner, database.ListTaskProps{}), added...); !sameIDs(list, added) {
+            t.Errorf("expected %v, got %v\n", ids(added), ids(list))
+        }
+    })
+
+    t.Run("Should filter the listed tasks", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        added := addTasks(
+            t,
+            tasks,
+            owner,
+            database.AddTaskProp{Name: "Done", Completed: true},
+            database.AddTaskProp{Name: "Pending"},
+            database.AddTaskProp{Name: "Assigned", AssigneeID: &other.ID},
+        )
+        done, pending := true, false
+        now := time.Now().Add(time.Minute)
+
+        tests := []struct {
+            name string
+            actor database.User
+            props database.ListTaskProps
+            want []database.Task
+        }{
+            {"completed", owner, database.ListTaskProps{WhereCompleted: &done}, added[:1]},
+            {"pending", owner, database.ListTaskProps{WhereCompleted: &pending}, added[1:]},
+            {"assignee", owner, database.ListTaskProps{WhereAssigneeID: &other.ID}, added[2:]},
+            {"mine", other, database.ListTaskProps{OnlyMine: true}, added[2:]},
+            {"completed before", owner, database.ListTaskProps{CompletedBefore: &now}, added[:1]},
+        }
+
+        for _, test := range tests {
+            if list := created(listTasks(t, tasks, test.actor, test.props), added...); !sameIDs(list, test.want) {
+                t.Errorf("%s: expected %v, got %v\n", test.name, ids(test.want), ids(list))
+            }
+        }
+    })
+
+    t.Run("Should sort the listed tasks", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        soon := time.Now().Add(time.Hour)
+        later := soon.Add(time.Hour)
+        added := addTasks(
+            t,
+            tasks,
+            owner,
+            database.AddTaskProp{Name: "b", Priority: database.PriorityLow, DueAt: &later},
+            database.AddTaskProp{Name: "a", Priority: database.PriorityHigh},
+            database.AddTaskProp{Name: "c", Priority: database.PriorityLow, DueAt: &soon},
+        )
+
+        tests := []struct {
+            keys []database.SortKey
+            want []int
+        }{
+            {[]database.SortKey{{Column: "name"}}, []int{1, 0, 2}},
+            {[]database.SortKey{{Column: "priority", Desc: true}}, []int{1, 0, 2}},
+            {[]database.SortKey{{Column: "due"}}, []int{2, 0, 1}},
+            {[]database.SortKey{{Column: "due", Desc: true}}, []int{0, 2, 1}},
+            {[]database.SortKey{{Column: "priority"}, {Column: "due"}}, []int{2, 0, 1}},
+        }
+
+        for _, test := range tests {
+            want := make([]database.Task, len(test.want))
+
+            for idx, position := range test.want {
+                want[idx] = added[position]
+            }
+
+            list := created(listTasks(t, tasks, owner, database.ListTaskProps{SortBy: test.keys}), added...)
+
+            if !sameIDs(list, want) {
+                t.Errorf("%v: expected %v, got %v\n", test.keys, ids(want), ids(list))
+            }
+        }
+
+        if _, err := tasks.ListTasks(owner, database.ListTaskProps{SortBy: []database.SortKey{{Column: "owner"}}}); err == nil {
+            t.Error("expected an error sorting by an unknown column")
+        }
+    })
+
+    t.Run("Should list the tasks matching a filter or tell it can't", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        added := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Low", Priority: database.PriorityLow}, database.AddTaskProp{Name: "High", Priority: database.PriorityHigh})
+        condition := func (arg func (value any) string) string { return "priority >= " + arg(database.PriorityHigh) }
+
+        list, err := tasks.ListTasks(owner, database.ListTaskProps{Where: condition})
+
+        if errors.Is(err, store.ErrUnsupported) {
+            t.Skip("the store doesn't support filters")
+        }
+
+        if err != nil {
+            t.Fatalf("error while listing the tasks, %s\n", err)
+        }
+
+        if list = created(list, added...); !sameIDs(list, added[1:]) {
+            t.Errorf("expected %v, got %v\n", ids(added[1:]), ids(list))
+        }
+    })
+
+    // The filters compile to the same SQL for every database, the functions
+    // and types they use must mean the same in each dialect.
+    t.Run("Should list the tasks matching filter expressions alike", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        due := time.Now().Add(48 * time.Hour)
+        added := addTasks(t, tasks, owner,
+            database.AddTaskProp{Name: "Report draft", Tags: []string{"work", "q4"}, DueAt: &due},
+            database.AddTaskProp{Name: "Quarterly report", Tags: []string{"workshop"}, Completed: true},
+            database.AddTaskProp{Name: "Groceries"},
+        )
+
+        tests := []struct {
+            expression string
+            want []database.Task
+        }{
+            {"name:REPORT", added[:2]},
+            {"name~^Rep", added[:1]},
+            {"tag:work", added[:1]},
+            {"completed", added[1:2]},
+            {"not completed and due>today and due<+7d", added[:1]},
+        }
+
+        for _, test := range tests {
+            condition, err := filter.Parse(test.expression, time.Now())
+
+            if err != nil {
+                t.Fatalf("%s: error while parsing, %s\n", test.expression, err)
+            }
+
+            list, err := tasks.ListTasks(owner, database.ListTaskProps{Where: condition})
+
+            if errors.Is(err, store.ErrUnsupported) {
+                t.Skip("the store doesn't support filters")
+            }
+
+            if err != nil {
+                t.Errorf("%s: error while listing the tasks, %s\n", test.expression, err)
+                continue
+            }
+
+            if list = created(list, added...); !sameIDs(list, test.want) {
+                t.Errorf("%s: expected %v, got %v\n", test.expression, ids(test.want), ids(list))
+            }
+        }
+    })
+
+    t.Run("Should update the fields of a task", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        due := time.Now().Add(time.Hour)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Draft", AssigneeID: &other.ID, DueAt: &due, Tags: []string{"work"}})[0]
+
+        name, notes, project, priority := "Final", "for the board", "report", database.PriorityMedium
+        tags := []string{"work", "urgent"}
+        updated := updateTasks(t, tasks, owner, database.UpdateTaskProp{Name: &name, Notes: &notes, Project: &project, Priority: &priority, Tags: &tags}, task)[0]
+
+        if updated.Name != name || updated.Notes != notes || updated.Project != project || updated.Priority != priority || !reflect.DeepEqual(updated.Tags, tags) {
+            t.Errorf("expected the fields updated, got %+v\n", updated)
+        }
+
+        unassigned := 0
+        noDue := time.Time{}
+        updated = updateTasks(t, tasks, owner, database.UpdateTaskProp{AssigneeID: &unassigned, DueAt: &noDue}, task)[0]
+
+        if updated.AssigneeID != nil || updated.DueAt != nil {
+            t.Errorf("expected no assignee and no due date, got %v and %v\n", updated.AssigneeID, updated.DueAt)
+        }
+
+        if fetched, _ := tasks.GetTask(owner, task.ID); !reflect.DeepEqual(fetched, updated) {
+            t.Errorf("expected the updated task %+v, got %+v\n", updated, fetched)
+        }
+
+        if _, err := tasks.UpdateTasks(owner, []int{task.ID}, database.UpdateTaskProp{}); err == nil {
+            t.Error("expected an error updating nothing")
+        }
+    })
+
+    t.Run("Should stamp the completion once", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Call"})[0]
+        done, pending := true, false
+
+        completed := updateTasks(t, tasks, owner, database.UpdateTaskProp{Completed: &done}, task)[0]
+
+        if !completed.Completed || completed.CompletedAt == nil {
+            t.Fatalf("expected the task completed and stamped, got %+v\n", completed)
+        }
+
+        again := updateTasks(t, tasks, owner, database.UpdateTaskProp{Completed: &done}, task)[0]
+
+        if again.CompletedAt == nil || !again.CompletedAt.Equal(*completed.CompletedAt) {
+            t.Errorf("expected the completion time kept, got %v instead of %v\n", again.CompletedAt, completed.CompletedAt)
+        }
+
+        if reopened := updateTasks(t, tasks, owner, database.UpdateTaskProp{Completed: &pending}, task)[0]; reopened.Completed || reopened.CompletedAt != nil {
+            t.Errorf("expected the task pending without a completion time, got %+v\n", reopened)
+        }
+    })
+
+    t.Run("Should update all the tasks or none of them", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        mine := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Mine"})
+        theirs := addTasks(t, tasks, other, database.AddTaskProp{Name: "Theirs"})
+        project := "shared"
+
+        if _, err := tasks.UpdateTasks(owner, []int{mine[0].ID, theirs[0].ID}, database.UpdateTaskProp{Project: &project}); err == nil {
+            t.Error("expected an error updating a task of another user")
+        }
+
+        if task, _ := tasks.GetTask(owner, mine[0].ID); task.Project != "" {
+            t.Errorf("expected the update rolled back, got project '%s'\n", task.Project)
+        }
+
+        if _, err := tasks.UpdateTasks(owner, []int{mine[0].ID + 1000}, database.UpdateTaskProp{Project: &project}); err == nil {
+            t.Error("expected an error updating a missing task")
+        }
+
+        more := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Mine too"})
+        updated := updateTasks(t, tasks, owner, database.UpdateTaskProp{Project: &project}, mine[0], more[0])
+
+        if len(updated) != 2 || updated[0].Project != project || updated[1].Project != project {
+            t.Errorf("expected both tasks moved to %s, got %+v\n", project, updated)
+        }
+    })
+
+    t.Run("Should move deleted tasks to the trash", func (t *testing.T) {
+        tasks, owner, other := open(t)
+        added := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Old"}, database.AddTaskProp{Name: "Kept"})
+
+        if deleted, err := tasks.DeleteTasks(other, []int{added[0].ID}); err != nil || deleted != 0 {
+            t.Errorf("expected another user to delete nothing, got %d and %v\n", deleted, err)
+        }
+
+        deleted, err := tasks.DeleteTasks(owner, []int{added[0].ID, added[0].ID + 1000})
+
+        if err != nil || deleted != 1 {
+            t.Fatalf("expected 1 task deleted, got %d and %v\n", deleted, err)
+        }
+
+        if _, err := tasks.GetTask(owner, added[0].ID); !errors.Is(err, store.ErrNotFound) {
+            t.Errorf("expected the deleted task not found, got %v\n", err)
+        }
+
+        if list := created(listTasks(t, tasks, owner, database.ListTaskProps{}), added...); !sameIDs(list, added[1:]) {
+            t.Errorf("expected %v listed, got %v\n", ids(added[1:]), ids(list))
+        }
+
+        trash := created(listTasks(t, tasks, owner, database.ListTaskProps{Trashed: true}), added...)
+
+        if !sameIDs(trash, added[:1]) || trash[0].DeletedAt == nil {
+            t.Errorf("expected %v in the trash, got %+v\n", ids(added[:1]), trash)
+        }
+
+        if again, _ := tasks.DeleteTasks(owner, []int{added[0].ID}); again != 0 {
+            t.Errorf("expected a deleted task not deleted again, got %d\n", again)
+        }
+
+        name := "Revived"
+
+        if _, err := tasks.UpdateTasks(owner, []int{added[0].ID}, database.UpdateTaskProp{Name: &name}); err == nil {
+            t.Error("expected an error updating a deleted task")
+        }
+    })
+
+    t.Run("Should keep the changes of a transaction that succeeds", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        var added []database.Task
+
+        err := tasks.Transaction(func (tx store.TaskStore) error {
+            var err error
+            added, err = tx.AddTasks(owner, []database.AddTaskProp{{Name: "Inside"}})
+
+            if err != nil {
+                return err
+            }
+
+            done := true
+            _, err = tx.UpdateTasks(owner, []int{added[0].ID}, database.UpdateTaskProp{Completed: &done})
+
+            return err
+        })
+
+        if err != nil {
+            t.Fatalf("error in the transaction, %s\n", err)
+        }
+
+        if task, err := tasks.GetTask(owner, added[0].ID); err != nil || !task.Completed {
+            t.Errorf("expected the task added and completed, got %+v and %v\n", task, err)
+        }
+    })
+
+    t.Run("Should drop the changes of a transaction that fails", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        kept := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Kept"})[0]
+        failure := errors.New("failure")
+        var added []database.Task
+
+        err := tasks.Transaction(func (tx store.TaskStore) error {
+            added, _ = tx.AddTasks(owner, []database.AddTaskProp{{Name: "Dropped"}})
+            tx.DeleteTasks(owner, []int{kept.ID})
+
+            if _, err := tx.GetTask(owner, kept.ID); !errors.Is(err, store.ErrNotFound) {
+                t.Errorf("expected the transaction to see its own changes, got %v\n", err)
+            }
+
+            return failure
+        })
+
+        if err != failure {
+            t.Errorf("expected the error of the transaction, got %v\n", err)
+        }
+
+        if len(added) == 1 {
+            if _, err := tasks.GetTask(owner, added[0].ID); !errors.Is(err, store.ErrNotFound) {
+                t.Errorf("expected the added task dropped, got %v\n", err)
+            }
+        }
+
+        if _, err := tasks.GetTask(owner, kept.ID); err != nil {
+            t.Errorf("expected the deleted task back, got %v\n", err)
+        }
+    })
+
+    t.Run("Should roll back a nested transaction only", func (t *testing.T) {
+        tasks, owner, _ := open(t)
+        var outer, inner []database.Task
+
+        err := tasks.Transaction(func (tx store.TaskStore) error {
+            outer, _ = tx.AddTasks(owner, []database.AddTaskProp{{Name: "Outer"}})
+
+            tx.Transaction(func (nested store.TaskStore) error {
+                inner, _ = nested.AddTasks(owner, []database.AddTaskProp{{Name: "Inner"}})
+                return errors.New("failure")
+            })
+
+            return nil
+        })
+
+        if err != nil {
+            t.Fatalf("error in the transaction, %s\n", err)
+        }
+
+        list := listTasks(t, tasks, owner, database.ListTaskProps{})
+
+        if len(created(list, outer...)) != 1 || len(created(list, inner...)) != 0 {
+            t.Errorf("expected only the outer task kept, got %v\n", ids(list))
+        }
+    })
+}
+
+// Share lets user read the tasks of owner in project, all of them when it's
+// empty, and change them with the write permission.
+type Share func (owner database.User, user database.User, permission string, project string) error
+
+// OpenShared is Open for the stores that honour the shares, share grants them.
+type OpenShared func (t *testing.T) (store.TaskStore, database.User, database.User, Share)
+
+// RunShares checks that a store honours the shares, only the SQL stores do,
+// the others are read by the owner and assignee of a task only.
+func RunShares(t *testing.T, open OpenShared) {
+    t.Run("Should let a user read the shared tasks", func (t *testing.T) {
+        tasks, owner, other, share := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Shared"})[0]
+        name := "Changed"
+
+        if err := share(owner, other, database.PermissionRead, ""); err != nil {
+            t.Fatalf("error while sharing the tasks, %s\n", err)
+        }
+
+        if _, err := tasks.GetTask(other, task.ID); err != nil {
+            t.Errorf("expected the shared task read, got %s\n", err)
+        }
+
+        if list := created(listTasks(t, tasks, other, database.ListTaskProps{}), task); !sameIDs(list, []database.Task{task}) {
+            t.Errorf("expected the shared task listed, got %v\n", ids(list))
+        }
+
+        if _, err := tasks.UpdateTasks(other, []int{task.ID}, database.UpdateTaskProp{Name: &name}); err == nil {
+            t.Error("expected an error updating a task shared for reading")
+        }
+
+        if deleted, _ := tasks.DeleteTasks(other, []int{task.ID}); deleted != 0 {
+            t.Errorf("expected a task shared for reading not deleted, got %d\n", deleted)
+        }
+    })
+
+    t.Run("Should let a user change the tasks shared for writing", func (t *testing.T) {
+        tasks, owner, other, share := open(t)
+        task := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Shared"})[0]
+        name := "Changed"
+
+        if err := share(owner, other, database.PermissionWrite, ""); err != nil {
+            t.Fatalf("error while sharing the tasks, %s\n", err)
+        }
+
+        if updated := updateTasks(t, tasks, other, database.UpdateTaskProp{Name: &name}, task); updated[0].Name != name {
+            t.Errorf("expected the shared task renamed, got %+v\n", updated[0])
+        }
+
+        if deleted, err := tasks.DeleteTasks(other, []int{task.ID}); err != nil || deleted != 1 {
+            t.Errorf("expected the shared task deleted, got %d and %v\n", deleted, err)
+        }
+    })
+
+    t.Run("Should only share the tasks of the shared project", func (t *testing.T) {
+        tasks, owner, other, share := open(t)
+        added := addTasks(t, tasks, owner, database.AddTaskProp{Name: "Pay rent", Project: "flat"}, database.AddTaskProp{Name: "Private"})
+
+        if err := share(owner, other, database.PermissionRead, "flat"); err != nil {
+            t.Fatalf("error while sharing the tasks, %s\n", err)
+        }
+
+        if list := created(listTasks(t, tasks, other, database.ListTaskProps{}), added...); !sameIDs(list, added[:1]) {
+            t.Errorf("expected %v listed, got %v\n", ids(added[:1]), ids(list))
+        }
+
+        if _, err := tasks.GetTask(other, added[1].ID); !errors.Is(err, store.ErrNotFound) {
+            t.Errorf("expected ErrNotFound for the task out of the project, got %v\n", err)
+        }
+    })
+}
+
+func addTasks(t *testing.T, tasks store.TaskStore, actor database.User, props ...database.AddTaskProp) []database.Task {
+    t.Helper()
+    added, err := tasks.AddTasks(actor, props)
+
+    if err != nil {
+        t.Fatalf("error while adding tasks, %s\n", err)
+    }
+
+    return added
+}
+
+func updateTasks(t *testing.T, tasks store.TaskStore, actor database.User, payload database.UpdateTaskProp, targets ...database.Task) []database.Task {
+    t.Helper()
+    updated, err := tasks.UpdateTasks(actor, ids(targets), payload)
+
+    if err != nil {
+        t.Fatalf("error while updating tasks, %s\n", err)
+    }
+
+    return updated
+}
+
+func listTasks(t *testing.T, tasks store.TaskStore, actor database.User, props database.ListTaskProps) []database.Task {
+    t.Helper()
+    list, err := tasks.ListTasks(actor, props)
+
+    if err != nil {
+        t.Fatalf("error while listing tasks, %s\n", err)
+    }
+
+    return list
+}
+
+// created keeps the tasks of list among the ones a test created, stores
+// opened on a copy of a database may hold others.
+func created(list []database.Task, tasks ...database.Task) []database.Task {
+    kept := make([]database.Task, 0)
+
+    for _, task := range list {
+        for _, createdTask := range tasks {
+            if task.ID == createdTask.ID {
+                kept = append(kept, task)
+                break
+            }
+        }
+    }
+
+    return kept
+}
+
+func ids(tasks []database.Task) []int {
+    list := make([]int, len(tasks))
+
+    for idx, task := range tasks {
+        list[idx] = task.ID
+    }
+
+    return list
+}
+
+func sameIDs(a []database.Task, b []database.Task) bool {
+    return reflect.DeepEqual(ids(a), ids(b))
+}
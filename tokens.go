@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"strings"
+	"time"
+)
+
+func tokenCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "token",
+        Aliases: []string{"tokens"},
+        Summary: "Manage API tokens",
+        Subcommands: []*cli.Command{
+            {
+                Name: "create",
+                Summary: "Create a token acting as the current user, its value is only shown once",
+                Flags: []cli.Flag{
+                    {Name: "name", Short: "n", Required: true, Usage: "name of the token"},
+                    {
+                        Name: "scopes",
+                        Placeholder: strings.Join(database.Scopes, ","),
+                        Repeatable: true,
+                        Usage: "scopes granted to the token, read by default",
+                    },
+                    {Name: "expires", Kind: cli.Int, Placeholder: "days", Usage: "days until the token expires"},
+                },
+                Run: func (ctx *cli.Context) error { return createToken(db, ctx) },
+            },
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List tokens",
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error { return listTokens(db) },
+            },
+            {
+                Name: "revoke",
+                Summary: "Revoke a token",
+                Args: "<id>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                Run: func (ctx *cli.Context) error { return revokeToken(db, ctx) },
+            },
+        },
+    }
+}
+
+func createToken(db database.DB, ctx *cli.Context) error {
+    name, _ := ctx.String("name")
+
+    props := database.AddTokenProp{Name: name, Scopes: []string{database.ScopeRead}}
+
+    if scopes := splitValues(ctx.Strings("scopes")); len(scopes) > 0 {
+        for _, scope := range scopes {
+            if !Include(database.Scopes, scope) {
+                return ctx.Usagef("Invalid value '%s' for -scopes, expected %s", scope, strings.Join(database.Scopes, "|"))
+            }
+        }
+        props.Scopes = scopes
+    }
+
+    if days, ok := ctx.Int("expires"); ok {
+        if days <= 0 {
+            return ctx.Usagef("Invalid value '%d' for -expires, expected a positive number of days", days)
+        }
+
+        expiresAt := time.Now().AddDate(0, 0, days)
+        props.ExpiresAt = &expiresAt
+    }
+
+    user, err := currentUser(db)
+
+    if err != nil {
+        return err
+    }
+
+    props.UserID = user.ID
+    token, value, err := database.AddTokenAction(db, props)
+
+    if err != nil {
+        return fmt.Errorf("couldn't create token, %w", err)
+    }
+
+    fmt.Printf("Token with ID: %d created! Store it now, it won't be shown again:\n%s\n", token.ID, value)
+
+    return nil
+}
+
+func listTokens(db database.DB) error {
+    tokens, err := database.ListTokensAction(db)
+
+    if err != nil {
+        return err
+    }
+
+    for _, token := range tokens {
+        status := ""
+
+        if token.RevokedAt != nil {
+            status = " (revoked)"
+        } else if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+            status = " (expired)"
+        }
+
+        expires := "never"
+        if token.ExpiresAt != nil {
+            expires = token.ExpiresAt.Local().Format("2006-01-02")
+        }
+
+        fmt.Printf("%d. %s [%s] expires: %s%s\n", token.ID, token.Name, strings.Join(token.Scopes, ","), expires, status)
+    }
+
+    return nil
+}
+
+func revokeToken(db database.DB, ctx *cli.Context) error {
+    ids, err := parseIDs(ctx.Args())
+
+    if err != nil {
+        return err
+    }
+
+    if err := database.RevokeTokenAction(db, ids[0]); err != nil {
+        return err
+    }
+
+    fmt.Printf("Token %d revoked\n", ids[0])
+
+    return nil
+}
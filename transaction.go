@@ -0,0 +1,55 @@
+package main
+
+import (
+	"go_todo/database"
+)
+
+// transaction groups the changes of several commands, webhooks are only
+// notified once it's committed.
+type transaction struct {
+    *database.Tx
+    parent database.DB
+    notifications []webhookNotification
+}
+
+type webhookNotification struct {
+    event string
+    task database.Task
+}
+
+// beginTransaction starts a transaction on db, or a savepoint when db is
+// already a transaction.
+func beginTransaction(db database.DB) (*transaction, error) {
+    tx, err := database.Begin(db)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &transaction{Tx: tx, parent: db}, nil
+}
+
+func (t *transaction) queueWebhook(event string, task database.Task) {
+    t.notifications = append(t.notifications, webhookNotification{event, task})
+}
+
+// Commit saves the changes then notifies the webhooks, through the parent
+// transaction when there's one.
+func (t *transaction) Commit() error {
+    if err := t.Tx.Commit(); err != nil {
+        return err
+    }
+
+    for _, notification := range t.notifications {
+        notifyWebhooks(t.parent, notification.event, notification.task)
+    }
+
+    t.notifications = nil
+
+    return nil
+}
+
+func (t *transaction) Rollback() error {
+    t.notifications = nil
+    return t.Tx.Rollback()
+}
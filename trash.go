@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/webhook"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+func trashCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "trash",
+        Summary: "Browse the deleted tasks, they stay in the trash until it's emptied",
+        Subcommands: []*cli.Command{
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List the deleted tasks",
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error { return listTrash(db) },
+            },
+            {
+                Name: "restore",
+                Summary: "Take tasks out of the trash",
+                Args: "<...ids>",
+                MinArgs: 1,
+                MaxArgs: -1,
+                CompleteArgs: trashCompletions(db),
+                Run: func (ctx *cli.Context) error { return restoreTrash(db, ctx) },
+            },
+            {
+                Name: "empty",
+                Summary: "Delete the tasks in the trash for good",
+                Flags: []cli.Flag{
+                    {Name: "older-than", Placeholder: "age", Usage: "only the tasks deleted more than age ago, e.g. 30d, 2w or 12h"},
+                },
+                Run: func (ctx *cli.Context) error { return emptyTrash(db, ctx) },
+            },
+        },
+    }
+}
+
+func listTrash(db database.DB) error {
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    tasks, err := database.ListTasksAction(db, actor, database.ListTaskProps{Trashed: true})
+
+    if err != nil {
+        return err
+    }
+
+    if len(tasks) == 0 {
+        fmt.Println("The trash is empty.")
+        return nil
+    }
+
+    for _, task := range tasks {
+        checkbox := "[ ]"
+        if task.Completed {
+            checkbox = "[x]"
+        }
+
+        fmt.Printf("%d.%s - %s%s (deleted %s)\n", task.ID, checkbox, task.Name, taskDetails(task), task.DeletedAt.Local().Format("2006-01-02 15:04"))
+    }
+
+    return nil
+}
+
+func restoreTrash(db database.DB, ctx *cli.Context) error {
+    ids, err := parseIDs(ctx.Args())
+
+    if err != nil {
+        return err
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    restored, err := database.RestoreTaskBulkAction(db, actor, ids)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Restored %d tasks.", restored))
+
+    for _, id := range ids {
+        if task, err := database.ListTaskActionByID(db, actor, uint(id)); err == nil {
+            notifyWebhooks(db, webhook.EventCreated, task)
+        }
+    }
+
+    return nil
+}
+
+func emptyTrash(db database.DB, ctx *cli.Context) error {
+    before := time.Time{}
+
+    if age, ok := ctx.String("older-than"); ok {
+        cutoff, ok := parseAge(age, time.Now())
+
+        if !ok {
+            return ctx.Usagef("Invalid value '%s' for -older-than, expected an age like 30d, 2w or 12h", age)
+        }
+
+        before = cutoff
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return fmt.Errorf("couldn't resolve the current user, %w", err)
+    }
+
+    purged, err := database.PurgeTrashAction(db, actor, before)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(fmt.Sprintf("Purged %d tasks.", purged))
+
+    return nil
+}
+
+var agePattern = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// parseAge returns the time age ago, in hours, days, weeks, months or years.
+func parseAge(age string, now time.Time) (time.Time, bool) {
+    match := agePattern.FindStringSubmatch(age)
+
+    if match == nil {
+        return time.Time{}, false
+    }
+
+    n, _ := strconv.Atoi(match[1])
+
+    switch match[2] {
+    case "h":
+        return now.Add(-time.Duration(n) * time.Hour), true
+    case "d":
+        return now.AddDate(0, 0, -n), true
+    case "w":
+        return now.AddDate(0, 0, -7 * n), true
+    case "m":
+        return now.AddDate(0, -n, 0), true
+    default:
+        return now.AddDate(-n, 0, 0), true
+    }
+}
+
+func trashCompletions(db database.DB) func (args []string) []cli.Completion {
+    return func (args []string) []cli.Completion {
+        actor, err := currentUser(db)
+
+        if err != nil {
+            return nil
+        }
+
+        tasks, err := database.ListTasksAction(db, actor, database.ListTaskProps{Trashed: true})
+
+        if err != nil {
+            return nil
+        }
+
+        completions := make([]cli.Completion, 0, len(tasks))
+
+        for _, task := range tasks {
+            id := strconv.Itoa(task.ID)
+
+            if !Include(args, id) {
+                completions = append(completions, cli.Completion{Value: id, Description: task.Name})
+            }
+        }
+
+        return completions
+    }
+}
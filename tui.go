@@ -0,0 +1,26 @@
+package main
+
+import (
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/tui"
+	"os"
+)
+
+func tuiCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "tui",
+        Summary: "Browse, filter, add, toggle and delete tasks interactively",
+        Run: func (ctx *cli.Context) error {
+            actor, err := currentUser(db)
+
+            if err != nil {
+                return err
+            }
+
+            defer startWebhookDelivery(db)()
+
+            return tui.Run(db, actor, os.Stdin, os.Stdout)
+        },
+    }
+}
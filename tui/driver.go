@@ -0,0 +1,31 @@
+package tui
+
+// Driver runs a model without a terminal, it feeds keys to the model and
+// renders the frames a terminal of the given size would show.
+type Driver struct {
+    Model *Model
+    Width int
+    Height int
+}
+
+func NewDriver(model *Model, width int, height int) *Driver {
+    return &Driver{Model: model, Width: width, Height: height}
+}
+
+func (d *Driver) Press(keys ...Key) *Driver {
+    for _, key := range keys {
+        d.Frame()
+        d.Model.Update(key)
+    }
+    return d
+}
+
+// Type feeds input as if it was typed on the terminal, e.g. "/rent\r" filters
+// by rent and returns to the list.
+func (d *Driver) Type(input string) *Driver {
+    return d.Press(DecodeKeys([]byte(input))...)
+}
+
+func (d *Driver) Frame() string {
+    return d.Model.Render(d.Width, d.Height)
+}
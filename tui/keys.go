@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+type KeyCode int
+
+const (
+    KeyRune KeyCode = iota
+    KeyUp
+    KeyDown
+    KeyLeft
+    KeyRight
+    KeyPageUp
+    KeyPageDown
+    KeyHome
+    KeyEnd
+    KeyEnter
+    KeyEscape
+    KeyBackspace
+    KeyDelete
+    KeyTab
+    KeyCtrlC
+    KeyCtrlD
+)
+
+type Key struct {
+    Code KeyCode
+    // Rune is only set for KeyRune.
+    Rune rune
+}
+
+func Rune(r rune) Key {
+    return Key{Code: KeyRune, Rune: r}
+}
+
+var escapeSequences = []struct {
+    sequence []byte
+    code KeyCode
+}{
+    {[]byte("\x1b[A"), KeyUp},
+    {[]byte("\x1b[B"), KeyDown},
+    {[]byte("\x1b[C"), KeyRight},
+    {[]byte("\x1b[D"), KeyLeft},
+    {[]byte("\x1bOA"), KeyUp},
+    {[]byte("\x1bOB"), KeyDown},
+    {[]byte("\x1bOC"), KeyRight},
+    {[]byte("\x1bOD"), KeyLeft},
+    {[]byte("\x1b[5~"), KeyPageUp},
+    {[]byte("\x1b[6~"), KeyPageDown},
+    {[]byte("\x1b[H"), KeyHome},
+    {[]byte("\x1b[F"), KeyEnd},
+    {[]byte("\x1b[1~"), KeyHome},
+    {[]byte("\x1b[4~"), KeyEnd},
+    {[]byte("\x1b[3~"), KeyDelete},
+}
+
+// DecodeKeys turns the bytes read from a terminal in raw mode into keys, an
+// escape byte which doesn't start a known sequence is the escape key.
+func DecodeKeys(input []byte) []Key {
+    keys := make([]Key, 0, len(input))
+
+    for len(input) > 0 {
+        if input[0] == 0x1b {
+            key := Key{Code: KeyEscape}
+            size := 1
+
+            for _, escape := range escapeSequences {
+                if bytes.HasPrefix(input, escape.sequence) {
+                    key, size = Key{Code: escape.code}, len(escape.sequence)
+                    break
+                }
+            }
+
+            keys = append(keys, key)
+            input = input[size:]
+            continue
+        }
+
+        switch input[0] {
+            case '\r', '\n':
+                keys = append(keys, Key{Code: KeyEnter})
+            case 0x7f, 0x08:
+                keys = append(keys, Key{Code: KeyBackspace})
+            case 0x03:
+                keys = append(keys, Key{Code: KeyCtrlC})
+            case 0x04:
+                keys = append(keys, Key{Code: KeyCtrlD})
+            case '\t':
+                keys = append(keys, Key{Code: KeyTab})
+            default:
+                r, size := utf8.DecodeRune(input)
+                keys = append(keys, Rune(r))
+                input = input[size:]
+                continue
+        }
+
+        input = input[1:]
+    }
+
+    return keys
+}
@@ -0,0 +1,607 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"go_todo/fuzzy"
+	"go_todo/webhook"
+	"strings"
+)
+
+type Mode int
+
+const (
+    ModeList Mode = iota
+    ModeFilter
+    ModeAdd
+    ModeEdit
+)
+
+// detailHeight is the number of lines of the pane describing the selected
+// task.
+const detailHeight = 4
+
+const listHints = "j/k move  / filter  a add  e edit  space toggle  d delete  u undo  r redo  q quit"
+
+// Model holds the state of the task browser, keys update it through the
+// database actions and Render draws it, independently of any terminal.
+type Model struct {
+    db database.DB
+    actor database.User
+
+    tasks []database.Task
+    visible []database.Task
+    names map[int]string
+
+    cursor int
+    offset int
+    pageSize int
+
+    mode Mode
+    filter string
+    input []rune
+    message string
+    // undoable and redoable count the changes of the session that can be
+    // undone and redone, through the journal of the actor.
+    undoable int
+    redoable int
+    done bool
+}
+
+func New(db database.DB, actor database.User) (*Model, error) {
+    m := &Model{db: db, actor: actor, pageSize: 1}
+
+    if err := m.reload(); err != nil {
+        return nil, err
+    }
+
+    return m, nil
+}
+
+// Done reports whether the user asked to quit.
+func (m *Model) Done() bool {
+    return m.done
+}
+
+func (m *Model) reload() error {
+    tasks, err := database.ListTasksAction(m.db, m.actor, database.ListTaskProps{})
+
+    if err != nil {
+        return err
+    }
+
+    m.tasks = tasks
+    m.names = make(map[int]string)
+
+    if users, err := database.ListUsersAction(m.db); err == nil {
+        for _, user := range users {
+            m.names[user.ID] = user.Name
+        }
+    }
+
+    m.refilter()
+
+    return nil
+}
+
+// refilter applies the fuzzy filter keeping the selected task under the
+// cursor when it's still visible.
+func (m *Model) refilter() {
+    selected, hasSelected := m.selected()
+
+    if m.filter == "" {
+        m.visible = m.tasks
+    } else {
+        names := make([]string, len(m.tasks))
+        for idx, task := range m.tasks {
+            names[idx] = task.Name
+        }
+
+        m.visible = make([]database.Task, 0)
+        for _, idx := range fuzzy.Filter(m.filter, names) {
+            m.visible = append(m.visible, m.tasks[idx])
+        }
+    }
+
+    if hasSelected {
+        for idx, task := range m.visible {
+            if task.ID == selected.ID {
+                m.cursor = idx
+                return
+            }
+        }
+    }
+
+    m.moveCursor(0)
+}
+
+func (m *Model) selected() (database.Task, bool) {
+    if m.cursor < 0 || m.cursor >= len(m.visible) {
+        return database.Task{}, false
+    }
+    return m.visible[m.cursor], true
+}
+
+func (m *Model) moveCursor(delta int) {
+    m.cursor += delta
+
+    if m.cursor >= len(m.visible) {
+        m.cursor = len(m.visible) - 1
+    }
+
+    if m.cursor < 0 {
+        m.cursor = 0
+    }
+}
+
+func (m *Model) Update(key Key) {
+    m.message = ""
+
+    if key.Code == KeyCtrlC {
+        m.done = true
+        return
+    }
+
+    var err error
+
+    switch m.mode {
+        case ModeList:
+            err = m.updateList(key)
+        case ModeFilter:
+            m.updateFilter(key)
+        case ModeAdd, ModeEdit:
+            err = m.updatePrompt(key)
+    }
+
+    if err != nil {
+        m.message = fmt.Sprintf("Error: %s", err)
+    }
+}
+
+func (m *Model) updateList(key Key) error {
+    switch key.Code {
+        case KeyUp:
+            m.moveCursor(-1)
+        case KeyDown:
+            m.moveCursor(1)
+        case KeyPageUp:
+            m.moveCursor(-m.pageSize)
+        case KeyPageDown:
+            m.moveCursor(m.pageSize)
+        case KeyHome:
+            m.moveCursor(-len(m.visible))
+        case KeyEnd:
+            m.moveCursor(len(m.visible))
+        case KeyEnter:
+            return m.updateList(Rune('e'))
+        case KeyEscape:
+            m.filter = ""
+            m.refilter()
+        case KeyRune:
+            switch key.Rune {
+                case 'k':
+                    m.moveCursor(-1)
+                case 'j':
+                    m.moveCursor(1)
+                case 'g':
+                    m.moveCursor(-len(m.visible))
+                case 'G':
+                    m.moveCursor(len(m.visible))
+                case '/':
+                    m.mode, m.input = ModeFilter, []rune(m.filter)
+                case 'a':
+                    m.mode, m.input = ModeAdd, []rune{}
+                case 'e':
+                    if task, ok := m.selected(); ok {
+                        m.mode, m.input = ModeEdit, []rune(task.Name)
+                    }
+                case ' ', 'x':
+                    return m.toggle()
+                case 'd':
+                    return m.delete()
+                case 'u':
+                    return m.undo()
+                case 'r':
+                    return m.redo()
+                case 'q':
+                    m.done = true
+            }
+    }
+
+    return nil
+}
+
+func (m *Model) updateFilter(key Key) {
+    switch key.Code {
+        case KeyEnter:
+            m.mode = ModeList
+            return
+        case KeyEscape:
+            m.mode, m.input = ModeList, []rune{}
+        case KeyBackspace:
+            if len(m.input) > 0 {
+                m.input = m.input[:len(m.input)-1]
+            }
+        case KeyRune:
+            m.input = append(m.input, key.Rune)
+        default:
+            return
+    }
+
+    m.filter = string(m.input)
+    m.refilter()
+}
+
+func (m *Model) updatePrompt(key Key) error {
+    switch key.Code {
+        case KeyEscape:
+            m.mode = ModeList
+        case KeyBackspace:
+            if len(m.input) > 0 {
+                m.input = m.input[:len(m.input)-1]
+            }
+        case KeyRune:
+            m.input = append(m.input, key.Rune)
+        case KeyEnter:
+            name := strings.TrimSpace(string(m.input))
+
+            if name == "" {
+                return errors.New("the name can't be empty")
+            }
+
+            mode := m.mode
+            m.mode = ModeList
+
+            if mode == ModeAdd {
+                return m.add(name)
+            }
+            return m.rename(name)
+    }
+
+    return nil
+}
+
+// record counts a change of the session, the changes undone before can't be
+// redone anymore.
+func (m *Model) record() {
+    m.undoable++
+    m.redoable = 0
+}
+
+func (m *Model) undo() error {
+    if m.undoable == 0 {
+        m.message = "Nothing to undo"
+        return nil
+    }
+
+    operations, err := database.UndoAction(m.db, m.actor, 1)
+
+    if err != nil {
+        return err
+    }
+
+    m.undoable--
+    m.redoable++
+
+    return m.replayed("Undid", operations, true)
+}
+
+func (m *Model) redo() error {
+    if m.redoable == 0 {
+        m.message = "Nothing to redo"
+        return nil
+    }
+
+    operations, err := database.RedoAction(m.db, m.actor, 1)
+
+    if err != nil {
+        return err
+    }
+
+    m.redoable--
+    m.undoable++
+
+    return m.replayed("Redid", operations, false)
+}
+
+// replayed notifies the webhooks of the undone or redone operations and
+// describes them.
+func (m *Model) replayed(verb string, operations []database.Operation, undo bool) error {
+    descriptions := make([]string, len(operations))
+
+    for idx, operation := range operations {
+        from, to := operation.Before, operation.After
+        if undo {
+            from, to = to, from
+        }
+
+        for _, transition := range webhook.TransitionsBetween(from, to) {
+            notify(m.db, transition.Event, transition.Task)
+        }
+
+        descriptions[idx] = describe(operation)
+    }
+
+    m.message = verb + " " + strings.Join(descriptions, ", ")
+
+    return m.reload()
+}
+
+// describe names an operation of the journal, e.g. "deletion of task 3".
+func describe(operation database.Operation) string {
+    tasks := operation.After
+    if len(tasks) == 0 {
+        tasks = operation.Before
+    }
+
+    change := "change"
+
+    switch operation.Action {
+        case database.OperationAdd:
+            change = "creation"
+        case database.OperationDelete:
+            change = "deletion"
+        case database.OperationUpdate:
+            change = "edit"
+    }
+
+    if len(tasks) == 1 {
+        return fmt.Sprintf("%s of task %d", change, tasks[0].ID)
+    }
+
+    return fmt.Sprintf("%s of %d tasks", change, len(tasks))
+}
+
+func (m *Model) add(name string) error {
+    task, err := m.create(name)
+
+    if err != nil {
+        return err
+    }
+
+    m.record()
+
+    m.message = fmt.Sprintf("Task %d created", task.ID)
+
+    return nil
+}
+
+func (m *Model) create(name string) (database.Task, error) {
+    task, err := database.AddTaskAction(m.db, m.actor, database.AddTaskProp{Name: name})
+
+    if err != nil {
+        return database.Task{}, err
+    }
+
+    notify(m.db, webhook.EventCreated, task)
+
+    if err := m.reload(); err != nil {
+        return database.Task{}, err
+    }
+
+    for idx, visible := range m.visible {
+        if visible.ID == task.ID {
+            m.cursor = idx
+        }
+    }
+
+    return task, nil
+}
+
+func (m *Model) rename(name string) error {
+    task, ok := m.selected()
+
+    if !ok || task.Name == name {
+        return nil
+    }
+
+    if err := m.update(task.ID, database.UpdateTaskProp{Name: &name}); err != nil {
+        return err
+    }
+
+    m.record()
+
+    m.message = fmt.Sprintf("Task %d updated", task.ID)
+
+    return nil
+}
+
+func (m *Model) toggle() error {
+    task, ok := m.selected()
+
+    if !ok {
+        return nil
+    }
+
+    completed := !task.Completed
+
+    if err := m.update(task.ID, database.UpdateTaskProp{Completed: &completed}); err != nil {
+        return err
+    }
+
+    m.record()
+
+    return nil
+}
+
+func (m *Model) update(taskID int, props database.UpdateTaskProp) error {
+    previous, _ := database.ListTaskActionByID(m.db, m.actor, uint(taskID))
+
+    task, err := database.UpdateTaskAction(m.db, m.actor, taskID, props)
+
+    if err != nil {
+        return err
+    }
+
+    for _, event := range webhook.EventsForUpdate(previous, task) {
+        notify(m.db, event, task)
+    }
+
+    return m.reload()
+}
+
+func (m *Model) delete() error {
+    task, ok := m.selected()
+
+    if !ok {
+        return nil
+    }
+
+    if err := m.remove(task); err != nil {
+        return err
+    }
+
+    m.record()
+
+    m.message = fmt.Sprintf("Task %d deleted, u to undo", task.ID)
+
+    return nil
+}
+
+func (m *Model) remove(task database.Task) error {
+    deleted, err := database.DeleteTaskBulkAction(m.db, m.actor, []int{task.ID})
+
+    if err != nil {
+        return err
+    }
+
+    if deleted == 0 {
+        return database.ErrPermissionDenied
+    }
+
+    notify(m.db, webhook.EventDeleted, task)
+
+    return m.reload()
+}
+
+// notify queues the webhook events, they are delivered in the background.
+// Printing failures would break the screen so they are ignored here.
+func notify(db database.DB, event string, task database.Task) {
+    webhook.NewDispatcher(db).Dispatch(event, task)
+}
+
+// Render draws the model as plain text lines, each of them at most width
+// runes long, filling height lines.
+func (m *Model) Render(width int, height int) string {
+    lines := make([]string, 0, height)
+
+    header := fmt.Sprintf("go_todo - %d/%d tasks", len(m.visible), len(m.tasks))
+    if m.filter != "" {
+        header += " matching " + m.filter
+    }
+    lines = append(lines, header)
+
+    m.pageSize = height - detailHeight - 3
+    if m.pageSize < 1 {
+        m.pageSize = 1
+    }
+
+    if m.cursor < m.offset {
+        m.offset = m.cursor
+    }
+    if m.cursor >= m.offset + m.pageSize {
+        m.offset = m.cursor - m.pageSize + 1
+    }
+    if m.offset > 0 && m.offset + m.pageSize > len(m.visible) {
+        m.offset = max(0, len(m.visible) - m.pageSize)
+    }
+
+    for idx := m.offset; idx < m.offset + m.pageSize; idx++ {
+        switch {
+            case idx < len(m.visible):
+                lines = append(lines, m.renderTask(idx))
+            case idx == 0:
+                lines = append(lines, "  No tasks, a to add one")
+            default:
+                lines = append(lines, "")
+        }
+    }
+
+    lines = append(lines, strings.Repeat("─", width))
+    lines = append(lines, m.renderDetail()...)
+    lines = append(lines, m.renderFooter())
+
+    for idx, line := range lines {
+        lines[idx] = truncate(line, width)
+    }
+
+    return strings.Join(lines, "\n")
+}
+
+func (m *Model) renderTask(idx int) string {
+    task := m.visible[idx]
+
+    marker := "  "
+    if idx == m.cursor {
+        marker = "> "
+    }
+
+    status := " "
+    if task.Completed {
+        status = "x"
+    }
+
+    assignee := ""
+    if task.AssigneeID != nil {
+        assignee = fmt.Sprintf(" (@%s)", m.names[*task.AssigneeID])
+    }
+
+    return fmt.Sprintf("%s%d.[%s] - %s%s", marker, task.ID, status, task.Name, assignee)
+}
+
+func (m *Model) renderDetail() []string {
+    task, ok := m.selected()
+
+    if !ok {
+        return make([]string, detailHeight)
+    }
+
+    status := "to do"
+    if task.Completed {
+        status = "done"
+    }
+
+    owner, assignee := "nobody", "nobody"
+    if task.OwnerID != nil {
+        owner = m.names[*task.OwnerID]
+    }
+    if task.AssigneeID != nil {
+        assignee = m.names[*task.AssigneeID]
+    }
+
+    return []string{
+        fmt.Sprintf("Task %d", task.ID),
+        "Name: " + task.Name,
+        "Status: " + status,
+        fmt.Sprintf("Owner: %s  Assignee: %s", owner, assignee),
+    }
+}
+
+func (m *Model) renderFooter() string {
+    switch m.mode {
+        case ModeFilter:
+            return "Filter: " + string(m.input) + "_"
+        case ModeAdd:
+            return "New task: " + string(m.input) + "_"
+        case ModeEdit:
+            task, _ := m.selected()
+            return fmt.Sprintf("Edit task %d: %s_", task.ID, string(m.input))
+    }
+
+    if m.message != "" {
+        return m.message
+    }
+
+    return listHints
+}
+
+func truncate(line string, width int) string {
+    runes := []rune(line)
+
+    if len(runes) <= width {
+        return line
+    }
+
+    return string(runes[:width])
+}
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+    enterAlternateScreen = "\x1b[?1049h\x1b[?25l"
+    leaveAlternateScreen = "\x1b[?25h\x1b[?1049l"
+    clearScreen = "\x1b[H\x1b[2J"
+)
+
+// Run browses the tasks of actor on the terminal until the user quits.
+func Run(db database.DB, actor database.User, in *os.File, out *os.File) error {
+    if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+        return errors.New("the tui needs a terminal")
+    }
+
+    model, err := New(db, actor)
+
+    if err != nil {
+        return err
+    }
+
+    state, err := term.MakeRaw(int(in.Fd()))
+
+    if err != nil {
+        return err
+    }
+    defer term.Restore(int(in.Fd()), state)
+
+    fmt.Fprint(out, enterAlternateScreen)
+    defer fmt.Fprint(out, leaveAlternateScreen)
+
+    buffer := make([]byte, 64)
+
+    for !model.Done() {
+        width, height, err := term.GetSize(int(out.Fd()))
+
+        if err != nil {
+            width, height = 80, 24
+        }
+
+        // Raw mode doesn't translate newlines into carriage returns.
+        frame := model.Render(width, height)
+        fmt.Fprint(out, clearScreen + strings.ReplaceAll(frame, "\n", "\r\n"))
+
+        read, err := in.Read(buffer)
+
+        if err != nil {
+            return err
+        }
+
+        for _, key := range DecodeKeys(buffer[:read]) {
+            model.Update(key)
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"database/sql"
+	"flag"
+	"go_todo/database"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the frame snapshots in testdata")
+
+func TestDecodeKeys(t *testing.T) {
+    got := DecodeKeys([]byte("a\x1b[A\x1b[6~\x1bé\r\x7f\x1b[3~\x03\x04"))
+    want := []Key{
+        Rune('a'),
+        {Code: KeyUp},
+        {Code: KeyPageDown},
+        {Code: KeyEscape},
+        Rune('é'),
+        {Code: KeyEnter},
+        {Code: KeyBackspace},
+        {Code: KeyDelete},
+        {Code: KeyCtrlC},
+        {Code: KeyCtrlD},
+    }
+
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("expected %v, got %v\n", want, got)
+    }
+}
+
+func TestBrowse(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    driver := newTestDriver(t, db, "Pay rent", "Walk the dog", "Buy milk", "Call mom", "Renew passport", "Read a book")
+
+    assertSnapshot(t, "initial", driver.Frame())
+
+    driver.Type("jj")
+    assertSnapshot(t, "moved", driver.Frame())
+
+    driver.Press(Key{Code: KeyPageDown})
+    assertSnapshot(t, "scrolled", driver.Frame())
+
+    driver.Type("/rn")
+    assertSnapshot(t, "filtering", driver.Frame())
+
+    driver.Type("\r")
+    assertSnapshot(t, "filtered", driver.Frame())
+
+    driver.Type("\x1b")
+    if frame := driver.Frame(); frame == snapshot(t, "filtered") {
+        t.Error("expected escape to clear the filter")
+    }
+}
+
+func TestEdit(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    driver := newTestDriver(t, db, "Pay rent", "Walk the dog")
+
+    driver.Type(" j")
+    driver.Type("e\x7f\x7f\x7fcat\r")
+    assertSnapshot(t, "edited", driver.Frame())
+
+    driver.Type("aBuy milk")
+    assertSnapshot(t, "adding", driver.Frame())
+
+    driver.Type("\r")
+    assertSnapshot(t, "added", driver.Frame())
+
+    driver.Type("kd")
+    assertSnapshot(t, "deleted", driver.Frame())
+
+    tasks, _ := database.ListTasksAction(db, driver.Model.actor, database.ListTaskProps{})
+
+    if len(tasks) != 2 || tasks[0].Name != "Pay rent" || !tasks[0].Completed || tasks[1].Name != "Buy milk" {
+        t.Errorf("expected the changes to be saved, got %+v\n", tasks)
+    }
+}
+
+func TestUndo(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    driver := newTestDriver(t, db, "Pay rent", "Walk the dog")
+    initial := driver.Frame()
+
+    driver.Type(" jeWalk the cat\rdaNew\r")
+    driver.Type("uuuu")
+
+    // k clears the undo message and brings the cursor back to the first task.
+    if got, want := driver.Type("k").Frame(), initial; got != want {
+        t.Errorf("expected every change to be undone, expected:\n%s\ngot:\n%s\n", want, got)
+    }
+
+    driver.Type("u")
+    assertSnapshot(t, "nothing_to_undo", driver.Frame())
+
+    trash, _ := database.ListTasksAction(db, driver.Model.actor, database.ListTaskProps{Trashed: true})
+
+    if len(trash) != 0 {
+        t.Errorf("expected the undone creation to leave nothing in the trash, got %+v\n", trash)
+    }
+
+    driver.Type("rr")
+    tasks, _ := database.ListTasksAction(db, driver.Model.actor, database.ListTaskProps{})
+
+    if len(tasks) != 2 || !tasks[0].Completed || tasks[1].Name != "Walk the dogWalk the cat" {
+        t.Errorf("expected the toggle and the edit redone, got %+v\n", tasks)
+    }
+
+    if got := driver.Type("rrr").Model.message; got != "Nothing to redo" {
+        t.Errorf("expected nothing left to redo, got %q\n", got)
+    }
+}
+
+func newTestDriver(t testing.TB, db database.DB, names ...string) *Driver {
+    t.Helper()
+    actor, err := database.EnsureUserAction(db, "tester")
+
+    if err != nil {
+        t.Fatalf("error while mocking user, %s\n", err)
+    }
+
+    for _, name := range names {
+        if _, err := database.AddTaskAction(db, actor, database.AddTaskProp{Name: name}); err != nil {
+            t.Fatalf("error while mocking task, %s\n", err)
+        }
+    }
+
+    model, err := New(db, actor)
+
+    if err != nil {
+        t.Fatalf("error while creating the model, %s\n", err)
+    }
+
+    return NewDriver(model, 48, 12)
+}
+
+func snapshot(t testing.TB, name string) string {
+    t.Helper()
+    content, err := os.ReadFile(filepath.Join("testdata", name+".golden"))
+
+    if err != nil {
+        t.Fatalf("error while reading snapshot %s, %s\n", name, err)
+    }
+
+    return string(content)
+}
+
+// assertSnapshot compares frame to testdata/<name>.golden, go test -update
+// rewrites the snapshots.
+func assertSnapshot(t testing.TB, name string, frame string) {
+    t.Helper()
+
+    if *update {
+        if err := os.WriteFile(filepath.Join("testdata", name+".golden"), []byte(frame), 0644); err != nil {
+            t.Fatalf("error while writing snapshot %s, %s\n", name, err)
+        }
+        return
+    }
+
+    if want := snapshot(t, name); frame != want {
+        t.Errorf("frame %s doesn't match its snapshot, expected:\n%s\ngot:\n%s\n", name, want, frame)
+    }
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
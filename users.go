@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"os"
+	"os/user"
+)
+
+const UserEnvVar = "GO_TODO_USER"
+
+// currentUser resolves the acting user from GO_TODO_USER falling back to the
+// operating system user, the user is created on its first use.
+func currentUser(db database.DB) (database.User, error) {
+    name := os.Getenv(UserEnvVar)
+
+    if name == "" {
+        if osUser, err := user.Current(); err == nil {
+            name = osUser.Username
+        }
+    }
+
+    if name == "" {
+        name = "default"
+    }
+
+    return database.EnsureUserAction(db, name)
+}
+
+func userNames(db database.DB) map[int]string {
+    names := make(map[int]string)
+    users, err := database.ListUsersAction(db)
+
+    if err != nil {
+        return names
+    }
+
+    for _, user := range users {
+        names[user.ID] = user.Name
+    }
+
+    return names
+}
+
+func userCommand(db database.DB) *cli.Command {
+    return &cli.Command{
+        Name: "user",
+        Aliases: []string{"users"},
+        Summary: "Manage users",
+        Subcommands: []*cli.Command{
+            {
+                Name: "add",
+                Summary: "Create a user",
+                Args: "<name>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                Run: func (ctx *cli.Context) error {
+                    created, err := database.AddUserAction(db, ctx.Arg(0))
+
+                    if err != nil {
+                        return fmt.Errorf("couldn't create user, %w", err)
+                    }
+
+                    fmt.Printf("User %s created!\n", created.Name)
+
+                    return nil
+                },
+            },
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List users",
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error {
+                    users, err := database.ListUsersAction(db)
+
+                    if err != nil {
+                        return err
+                    }
+
+                    for _, user := range users {
+                        fmt.Printf("%d. %s\n", user.ID, user.Name)
+                    }
+
+                    return nil
+                },
+            },
+            {
+                Name: "whoami",
+                Summary: fmt.Sprintf("Print the current user, set %s to act as someone else", UserEnvVar),
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error {
+                    actor, err := currentUser(db)
+
+                    if err != nil {
+                        return err
+                    }
+
+                    fmt.Println(actor.Name)
+
+                    return nil
+                },
+            },
+        },
+    }
+}
+
+func shareCommand(db database.DB) *cli.Command {
+    withActor := func (run func (*cli.Context, database.User) error) func (*cli.Context) error {
+        return func (ctx *cli.Context) error {
+            actor, err := currentUser(db)
+            if err != nil {
+                return err
+            }
+            return run(ctx, actor)
+        }
+    }
+
+    return &cli.Command{
+        Name: "share",
+        Summary: "Share your tasks with other users",
+        Subcommands: []*cli.Command{
+            {
+                Name: "add",
+                Summary: "Share your tasks with a user",
+                Args: "<user> <read|write>",
+                MinArgs: 2,
+                MaxArgs: 2,
+                Flags: []cli.Flag{
+                    {Name: "project", Usage: "only share the tasks of a project", Complete: projectCompletions(db)},
+                },
+                CompleteArgs: func (args []string) []cli.Completion {
+                    if len(args) == 0 {
+                        return userCompletions(db)()
+                    }
+                    return []cli.Completion{{Value: database.PermissionRead}, {Value: database.PermissionWrite}}
+                },
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    permission := ctx.Arg(1)
+
+                    if !Include([]string{database.PermissionRead, database.PermissionWrite}, permission) {
+                        return ctx.Usagef("Invalid permission '%s', expected read|write", permission)
+                    }
+
+                    with, err := database.ListUserActionByName(db, ctx.Arg(0))
+
+                    if err != nil {
+                        return err
+                    }
+
+                    project, _ := ctx.String("project")
+
+                    if err := database.ShareTasksAction(db, actor, with, permission, project); err != nil {
+                        return err
+                    }
+
+                    fmt.Printf("Tasks shared with %s (%s)\n", with.Name, shareScope(permission, project))
+
+                    return nil
+                }),
+            },
+            {
+                Name: "revoke",
+                Summary: "Stop sharing your tasks with a user",
+                Args: "<user>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                Flags: []cli.Flag{
+                    {Name: "project", Usage: "only stop sharing the tasks of a project", Complete: projectCompletions(db)},
+                },
+                CompleteArgs: func (args []string) []cli.Completion { return userCompletions(db)() },
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    with, err := database.ListUserActionByName(db, ctx.Arg(0))
+
+                    if err != nil {
+                        return err
+                    }
+
+                    project, _ := ctx.String("project")
+
+                    if err := database.UnshareTasksAction(db, actor, with, project); err != nil {
+                        return err
+                    }
+
+                    if project != "" {
+                        fmt.Printf("Tasks of project %s no longer shared with %s\n", project, with.Name)
+                    } else {
+                        fmt.Printf("Tasks no longer shared with %s\n", with.Name)
+                    }
+
+                    return nil
+                }),
+            },
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List the users your tasks are shared with",
+                ReadOnly: true,
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    shares, err := database.ListSharesAction(db, actor)
+
+                    if err != nil {
+                        return err
+                    }
+
+                    for _, share := range shares {
+                        fmt.Printf("%s (%s)\n", share.User.Name, shareScope(share.Permission, share.Project))
+                    }
+
+                    return nil
+                }),
+            },
+        },
+    }
+}
+
+// shareScope describes what a share grants, e.g. "read, project flat".
+func shareScope(permission string, project string) string {
+    if project == "" {
+        return permission
+    }
+
+    return fmt.Sprintf("%s, project %s", permission, project)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/filter"
+	"strings"
+	"time"
+)
+
+func viewCommand(db database.DB) *cli.Command {
+    withActor := func (run func (*cli.Context, database.User) error) func (*cli.Context) error {
+        return func (ctx *cli.Context) error {
+            actor, err := currentUser(db)
+            if err != nil {
+                return err
+            }
+            return run(ctx, actor)
+        }
+    }
+
+    return &cli.Command{
+        Name: "view",
+        Aliases: []string{"views"},
+        Summary: "Save filters to list tasks with, e.g. go_todo l @today",
+        Subcommands: []*cli.Command{
+            {
+                Name: "save",
+                Summary: "Save a view, replacing the one with the same name",
+                Args: "<name>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                Flags: []cli.Flag{
+                    {Name: "where", Short: "w", Placeholder: "filter", Usage: "only the tasks matching a filter, e.g. 'due<=today and not done'"},
+                    {Name: "sort", Short: "s", Synopsis: "<column>[,asc|desc][,<column>...]", Usage: "sort by columns, e.g. priority,desc,due"},
+                    {Name: "format", Short: "f", Placeholder: "text|json|ids", Usage: "output format, text by default"},
+                },
+                CompleteArgs: func (args []string) []cli.Completion { return viewCompletions(db) },
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    view := database.View{Name: strings.TrimPrefix(ctx.Arg(0), "@")}
+                    view.Filter, _ = ctx.String("where")
+                    view.Sort, _ = ctx.String("sort")
+                    view.Format, _ = ctx.String("format")
+
+                    if view.Filter != "" {
+                        if _, err := filter.Parse(view.Filter, time.Now()); err != nil {
+                            return err
+                        }
+                    }
+
+                    if view.Sort != "" {
+                        if _, err := filter.ParseSort(view.Sort); err != nil {
+                            return ctx.Usagef("Invalid value '%s' for -sort, %s", view.Sort, err)
+                        }
+                    }
+
+                    saved, err := database.SaveViewAction(db, actor, view)
+
+                    if err != nil {
+                        return err
+                    }
+
+                    fmt.Printf("View @%s saved\n", saved.Name)
+
+                    return nil
+                }),
+            },
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List your views",
+                ReadOnly: true,
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    views, err := database.ListViewsAction(db, actor)
+
+                    if err != nil {
+                        return err
+                    }
+
+                    for _, view := range views {
+                        fmt.Println(describeView(view))
+                    }
+
+                    return nil
+                }),
+            },
+            {
+                Name: "delete",
+                Aliases: []string{"rm"},
+                Summary: "Delete a view",
+                Args: "<name>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                CompleteArgs: func (args []string) []cli.Completion { return viewCompletions(db) },
+                Run: withActor(func (ctx *cli.Context, actor database.User) error {
+                    name := strings.TrimPrefix(ctx.Arg(0), "@")
+
+                    if err := database.DeleteViewAction(db, actor, name); err != nil {
+                        return err
+                    }
+
+                    fmt.Printf("View @%s deleted\n", name)
+
+                    return nil
+                }),
+            },
+        },
+    }
+}
+
+// viewArg resolves the @name argument of list.
+func viewArg(db database.DB, ctx *cli.Context) (database.View, error) {
+    name, ok := strings.CutPrefix(ctx.Arg(0), "@")
+
+    if !ok {
+        return database.View{}, ctx.Usagef("Expected a view like @today, got '%s'", ctx.Arg(0))
+    }
+
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return database.View{}, err
+    }
+
+    return database.ListViewActionByName(db, actor, name)
+}
+
+// describeView writes a view as the flags it was saved with.
+func describeView(view database.View) string {
+    description := "@" + view.Name
+
+    if view.Filter != "" {
+        description += fmt.Sprintf(" -where %q", view.Filter)
+    }
+
+    if view.Sort != "" {
+        description += " -sort " + view.Sort
+    }
+
+    if view.Format != "" {
+        description += " -format " + view.Format
+    }
+
+    return description
+}
+
+func viewCompletions(db database.DB) []cli.Completion {
+    actor, err := currentUser(db)
+
+    if err != nil {
+        return nil
+    }
+
+    views, err := database.ListViewsAction(db, actor)
+
+    if err != nil {
+        return nil
+    }
+
+    completions := make([]cli.Completion, 0, len(views))
+
+    for _, view := range views {
+        completions = append(completions, cli.Completion{Value: "@" + view.Name, Description: view.Filter})
+    }
+
+    return completions
+}
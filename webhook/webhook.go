@@ -0,0 +1,414 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_todo/database"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+    EventCreated = "created"
+    EventUpdated = "updated"
+    EventCompleted = "completed"
+    EventDeleted = "deleted"
+    EventTest = "test"
+)
+
+var Events = []string{EventCreated, EventUpdated, EventCompleted, EventDeleted}
+
+const SignatureHeader = "X-Go-Todo-Signature"
+const EventHeader = "X-Go-Todo-Event"
+
+type Payload struct {
+    Event string `json:"event"`
+    Task database.Task `json:"task"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// Sign returns the value of the signature header for body, receivers should
+// compute the same HMAC with their copy of the secret and compare both.
+func Sign(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func NewSecret() (string, error) {
+    buff := make([]byte, 16)
+
+    if _, err := rand.Read(buff); err != nil {
+        return "", err
+    }
+
+    return hex.EncodeToString(buff), nil
+}
+
+// EventsForUpdate returns the events fired by an update, "completed" is only
+// fired when the task goes from to-do to done.
+func EventsForUpdate(before database.Task, after database.Task) []string {
+    events := []string{EventUpdated}
+
+    if !before.Completed && after.Completed {
+        events = append(events, EventCompleted)
+    }
+
+    return events
+}
+
+// Transition is an event fired for a task.
+type Transition struct {
+    Event string
+    Task database.Task
+}
+
+// TransitionsBetween returns the events fired by tasks going from their from
+// images to their to images, e.g. when a change is undone. Tasks in the trash
+// are as good as deleted.
+func TransitionsBetween(from []database.Task, to []database.Task) []Transition {
+    from, to = untrashed(from), untrashed(to)
+    previous := make(map[int]database.Task)
+    transitions := make([]Transition, 0)
+
+    for _, task := range from {
+        previous[task.ID] = task
+    }
+
+    for _, task := range to {
+        before, ok := previous[task.ID]
+        delete(previous, task.ID)
+
+        if !ok {
+            transitions = append(transitions, Transition{EventCreated, task})
+            continue
+        }
+
+        for _, event := range EventsForUpdate(before, task) {
+            transitions = append(transitions, Transition{event, task})
+        }
+    }
+
+    for _, task := range from {
+        if _, ok := previous[task.ID]; ok {
+            transitions = append(transitions, Transition{EventDeleted, task})
+        }
+    }
+
+    return transitions
+}
+
+func untrashed(tasks []database.Task) []database.Task {
+    kept := make([]database.Task, 0, len(tasks))
+
+    for _, task := range tasks {
+        if task.DeletedAt == nil {
+            kept = append(kept, task)
+        }
+    }
+
+    return kept
+}
+
+// Dispatcher queues the events and delivers them apart from the changes
+// firing them, each delivery is a single attempt and the failed ones are
+// retried later with an exponential backoff.
+type Dispatcher struct {
+    DB database.DB
+    Client *http.Client
+    // MaxAttempts is the number of failed deliveries after which an event
+    // becomes a dead letter.
+    MaxAttempts int
+    // BaseDelay is the delay before the first retry, it doubles after every
+    // failed one.
+    BaseDelay time.Duration
+    Now func () time.Time
+}
+
+func NewDispatcher(db database.DB) *Dispatcher {
+    return &Dispatcher{
+        DB: db,
+        Client: &http.Client{Timeout: 2 * time.Second},
+        MaxAttempts: 4,
+        BaseDelay: 30 * time.Second,
+        Now: time.Now,
+    }
+}
+
+// Dispatch queues event for every webhook subscribed to it, see Deliver.
+func (d *Dispatcher) Dispatch(event string, task database.Task) error {
+    webhooks, err := database.ListWebhooksForEventAction(d.DB, event)
+
+    if err != nil {
+        return err
+    }
+
+    if len(webhooks) == 0 {
+        return nil
+    }
+
+    body, err := json.Marshal(Payload{
+        Event: event,
+        Task: task,
+        Timestamp: d.Now().UTC(),
+    })
+
+    if err != nil {
+        return err
+    }
+
+    for _, webhook := range webhooks {
+        _, err := database.AddQueuedWebhookEventAction(d.DB, database.AddQueuedWebhookEventProp{
+            WebhookID: webhook.ID,
+            Event: event,
+            Payload: string(body),
+            NextAttemptAt: d.Now(),
+        })
+
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Deliver attempts the delivery of the queued events that are due until ctx
+// is done, and returns how many were delivered. A failing webhook doesn't
+// stop the delivery to the remaining ones.
+func (d *Dispatcher) Deliver(ctx context.Context) (int, error) {
+    delivered := 0
+    errs := make([]error, 0)
+    // The events failing again are due later, this stops at the ones seen.
+    seen := make(map[int]bool)
+
+    for ctx.Err() == nil {
+        events, err := database.ListDueWebhookEventsAction(d.DB, d.Now(), 20)
+
+        if err != nil {
+            return delivered, errors.Join(append(errs, err)...)
+        }
+
+        fresh := 0
+
+        for _, event := range events {
+            if ctx.Err() != nil || seen[event.ID] {
+                continue
+            }
+            seen[event.ID] = true
+            fresh++
+
+            ok, err := d.deliverQueued(ctx, event)
+
+            if err != nil {
+                errs = append(errs, err)
+            }
+
+            if ok {
+                delivered++
+            }
+        }
+
+        if fresh == 0 {
+            break
+        }
+    }
+
+    return delivered, errors.Join(errs...)
+}
+
+// Run delivers the queue every interval until ctx is done, the failures are
+// left in the queue and in the dead letters.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+    for {
+        d.Deliver(ctx)
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(interval):
+        }
+    }
+}
+
+func (d *Dispatcher) deliverQueued(ctx context.Context, event database.QueuedWebhookEvent) (bool, error) {
+    now := d.Now()
+    // An interrupted delivery is attempted again once the claim expires.
+    claimed, err := database.ClaimQueuedWebhookEventAction(d.DB, event.ID, now, now.Add(2 * d.Client.Timeout + time.Second))
+
+    if err != nil || !claimed {
+        return false, err
+    }
+
+    webhook, err := database.ListWebhookActionByID(d.DB, event.WebhookID)
+
+    // The event of a deleted webhook is dropped, on other errors it's left
+    // queued and attempted again once the claim expires.
+    if errors.Is(err, database.ErrWebhookNotFound) {
+        return false, database.DeleteQueuedWebhookEventAction(d.DB, event.ID)
+    }
+
+    if err != nil {
+        return false, err
+    }
+
+    attempts := event.Attempts + 1
+    statusCode, sendErr := d.post(ctx, webhook, event.Event, []byte(event.Payload))
+
+    if sendErr == nil {
+        _, err := database.AddWebhookDeliveryAction(d.DB, database.AddWebhookDeliveryProp{
+            WebhookID: webhook.ID,
+            Event: event.Event,
+            Payload: event.Payload,
+            StatusCode: statusCode,
+            Attempts: attempts,
+        })
+
+        if err != nil {
+            return false, err
+        }
+
+        return true, database.DeleteQueuedWebhookEventAction(d.DB, event.ID)
+    }
+
+    if attempts < d.MaxAttempts {
+        next := d.Now().Add(d.BaseDelay << (attempts - 1))
+        err := database.RetryQueuedWebhookEventAction(d.DB, event.ID, sendErr.Error(), next)
+
+        return false, errors.Join(fmt.Errorf("webhook %d: %w", webhook.ID, sendErr), err)
+    }
+
+    _, err = database.AddWebhookDeadLetterAction(d.DB, database.AddWebhookDeadLetterProp{
+        WebhookID: webhook.ID,
+        Event: event.Event,
+        Payload: event.Payload,
+        LastError: sendErr.Error(),
+        Attempts: attempts,
+    })
+
+    if err == nil {
+        err = database.DeleteQueuedWebhookEventAction(d.DB, event.ID)
+    }
+
+    return false, errors.Join(fmt.Errorf("webhook %d: %w", webhook.ID, sendErr), err)
+}
+
+// TestTask is the task of the test events, shaped like the real ones.
+func TestTask(now time.Time) database.Task {
+    due := now.UTC().Truncate(time.Second).AddDate(0, 0, 1)
+    created := now.UTC().Truncate(time.Second)
+    owner := 1
+
+    return database.Task{
+        ID: 1,
+        Name: "Test task",
+        OwnerID: &owner,
+        DueAt: &due,
+        Priority: database.PriorityMedium,
+        Tags: []string{"test"},
+        Project: "webhooks",
+        Notes: "Sent by go_todo webhooks test",
+        CreatedAt: &created,
+        UpdatedAt: &created,
+    }
+}
+
+// Test sends a test event to webhook regardless of its event filters, right
+// away and once.
+func (d *Dispatcher) Test(webhook database.Webhook) (database.WebhookDelivery, error) {
+    body, err := json.Marshal(Payload{
+        Event: EventTest,
+        Task: TestTask(d.Now()),
+        Timestamp: d.Now().UTC(),
+    })
+
+    if err != nil {
+        return database.WebhookDelivery{}, err
+    }
+
+    statusCode, err := d.post(context.Background(), webhook, EventTest, body)
+
+    if err != nil {
+        return database.WebhookDelivery{}, fmt.Errorf("webhook %d: %w", webhook.ID, err)
+    }
+
+    return database.AddWebhookDeliveryAction(d.DB, database.AddWebhookDeliveryProp{
+        WebhookID: webhook.ID,
+        Event: EventTest,
+        Payload: string(body),
+        StatusCode: statusCode,
+        Attempts: 1,
+    })
+}
+
+// Replay delivers a dead letter again, right away and once. The dead letter
+// is removed once delivered and records the failure otherwise.
+func (d *Dispatcher) Replay(deadLetterID int) (database.WebhookDelivery, error) {
+    deadLetter, err := database.ListWebhookDeadLetterActionByID(d.DB, deadLetterID)
+
+    if err != nil {
+        return database.WebhookDelivery{}, err
+    }
+
+    webhook, err := database.ListWebhookActionByID(d.DB, deadLetter.WebhookID)
+
+    if err != nil {
+        return database.WebhookDelivery{}, err
+    }
+
+    statusCode, sendErr := d.post(context.Background(), webhook, deadLetter.Event, []byte(deadLetter.Payload))
+
+    if sendErr != nil {
+        err := database.FailWebhookDeadLetterAction(d.DB, deadLetter.ID, sendErr.Error())
+
+        return database.WebhookDelivery{}, errors.Join(fmt.Errorf("webhook %d: %w", webhook.ID, sendErr), err)
+    }
+
+    delivery, err := database.AddWebhookDeliveryAction(d.DB, database.AddWebhookDeliveryProp{
+        WebhookID: webhook.ID,
+        Event: deadLetter.Event,
+        Payload: deadLetter.Payload,
+        StatusCode: statusCode,
+        Attempts: deadLetter.Attempts + 1,
+    })
+
+    if err != nil {
+        return database.WebhookDelivery{}, err
+    }
+
+    return delivery, database.DeleteWebhookDeadLetterAction(d.DB, deadLetter.ID)
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook database.Webhook, event string, body []byte) (int, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+
+    if err != nil {
+        return 0, err
+    }
+
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set(EventHeader, event)
+    req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+    res, err := d.Client.Do(req)
+
+    if err != nil {
+        return 0, err
+    }
+    defer res.Body.Close()
+    io.Copy(io.Discard, res.Body)
+
+    if res.StatusCode < 200 || res.StatusCode > 299 {
+        return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+    }
+
+    return res.StatusCode, nil
+}
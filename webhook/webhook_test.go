@@ -0,0 +1,361 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"go_todo/database"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+    got := Sign("secret", []byte("{}"))
+    want := "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13"
+
+    if got != want {
+        t.Errorf("expected signature to be %s, got %s\n", want, got)
+    }
+
+    if got == Sign("other secret", []byte("{}")) {
+        t.Error("signatures with different secrets should differ")
+    }
+}
+
+func TestEventsForUpdate(t *testing.T) {
+    t.Run("Should only fire updated if the task wasn't completed by the update", func (t *testing.T) {
+        events := EventsForUpdate(database.Task{Completed: true}, database.Task{Completed: true})
+
+        if len(events) != 1 || events[0] != EventUpdated {
+            t.Errorf("expected [updated], got %v\n", events)
+        }
+    })
+
+    t.Run("Should fire completed if the task was completed by the update", func (t *testing.T) {
+        events := EventsForUpdate(database.Task{Completed: false}, database.Task{Completed: true})
+
+        if len(events) != 2 || events[1] != EventCompleted {
+            t.Errorf("expected [updated completed], got %v\n", events)
+        }
+    })
+}
+
+func TestDispatch(t *testing.T) {
+    db := getDBTransaction(t)
+    defer db.Rollback()
+
+    t.Run("Should queue the event and deliver a signed payload to subscribed webhooks", func (t *testing.T) {
+        received := make(chan *http.Request, 1)
+        var body []byte
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            body, _ = io.ReadAll(r.Body)
+            received <- r
+        }))
+        defer server.Close()
+
+        hook := mockWebhook(t, db, server.URL, []string{EventCreated})
+        task := database.Task{ID: 1, Name: "Test"}
+        dispatcher := newTestDispatcher(db)
+
+        if err := dispatcher.Dispatch(EventCreated, task); err != nil {
+            t.Fatalf("error while dispatching event, %s\n", err)
+        }
+
+        if len(received) != 0 {
+            t.Fatal("expected the event to be queued, not delivered")
+        }
+
+        if queued, _ := database.ListQueuedWebhookEventsAction(db); len(queued) != 1 || queued[0].WebhookID != hook.ID {
+            t.Fatalf("expected one queued event for webhook %d, got %v\n", hook.ID, queued)
+        }
+
+        if delivered, err := dispatcher.Deliver(context.Background()); err != nil || delivered != 1 {
+            t.Fatalf("expected one delivery, got %d, %v\n", delivered, err)
+        }
+
+        req := <-received
+
+        if req.Header.Get(EventHeader) != EventCreated {
+            t.Errorf("expected event header to be %s, got %s\n", EventCreated, req.Header.Get(EventHeader))
+        }
+
+        if req.Header.Get(SignatureHeader) != Sign(hook.Secret, body) {
+            t.Errorf("signature header doesn't match the payload, got %s\n", req.Header.Get(SignatureHeader))
+        }
+
+        payload := Payload{}
+        if err := json.Unmarshal(body, &payload); err != nil {
+            t.Fatalf("error while decoding payload, %s\n", err)
+        }
+
+        if payload.Task.Name != "Test" {
+            t.Errorf("expected payload task name to be Test, got %s\n", payload.Task.Name)
+        }
+
+        deliveries, err := database.ListWebhookDeliveriesAction(db)
+
+        if err != nil {
+            t.Fatalf("error while listing deliveries, %s\n", err)
+        }
+
+        if len(deliveries) != 1 || deliveries[0].WebhookID != hook.ID {
+            t.Errorf("expected one delivery for webhook %d, got %v\n", hook.ID, deliveries)
+        }
+
+        if queued, _ := database.ListQueuedWebhookEventsAction(db); len(queued) != 0 {
+            t.Errorf("expected the queue to be empty, got %v\n", queued)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should not queue events the webhook isn't subscribed to", func (t *testing.T) {
+        hook := mockWebhook(t, db, "http://localhost", []string{EventDeleted})
+
+        if err := newTestDispatcher(db).Dispatch(EventCreated, database.Task{}); err != nil {
+            t.Fatalf("error while dispatching event, %s\n", err)
+        }
+
+        if queued, _ := database.ListQueuedWebhookEventsAction(db); len(queued) != 0 {
+            t.Errorf("expected no queued event, got %v\n", queued)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should retry later with backoff and dead letter the payload if every attempt fails", func (t *testing.T) {
+        calls := 0
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            calls++
+            w.WriteHeader(http.StatusInternalServerError)
+        }))
+        defer server.Close()
+
+        hook := mockWebhook(t, db, server.URL, []string{EventDeleted})
+        dispatcher := newTestDispatcher(db)
+        now := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+        dispatcher.Now = func () time.Time { return now }
+
+        dispatcher.Dispatch(EventDeleted, database.Task{ID: 1})
+
+        if _, err := dispatcher.Deliver(context.Background()); err == nil {
+            t.Fatal("expected the delivery to fail")
+        }
+
+        queued, _ := database.ListQueuedWebhookEventsAction(db)
+
+        if calls != 1 || len(queued) != 1 || queued[0].Attempts != 1 || !queued[0].NextAttemptAt.Equal(now.Add(dispatcher.BaseDelay)) {
+            t.Fatalf("expected one attempt and a retry in %s, got %d calls and %+v\n", dispatcher.BaseDelay, calls, queued)
+        }
+
+        dispatcher.Deliver(context.Background())
+
+        if calls != 1 {
+            t.Errorf("expected no attempt before the retry is due, got %d calls\n", calls)
+        }
+
+        now = now.Add(dispatcher.BaseDelay)
+        dispatcher.Deliver(context.Background())
+        queued, _ = database.ListQueuedWebhookEventsAction(db)
+
+        if calls != 2 || len(queued) != 1 || !queued[0].NextAttemptAt.Equal(now.Add(2 * dispatcher.BaseDelay)) {
+            t.Fatalf("expected the delay to double, got %d calls and %+v\n", calls, queued)
+        }
+
+        now = now.Add(2 * dispatcher.BaseDelay)
+        dispatcher.Deliver(context.Background())
+
+        if calls != dispatcher.MaxAttempts {
+            t.Errorf("expected %d attempts, got %d\n", dispatcher.MaxAttempts, calls)
+        }
+
+        deadLetters, err := database.ListWebhookDeadLettersAction(db)
+
+        if err != nil {
+            t.Fatalf("error while listing dead letters, %s\n", err)
+        }
+
+        if len(deadLetters) != 1 || deadLetters[0].WebhookID != hook.ID {
+            t.Fatalf("expected one dead letter for webhook %d, got %v\n", hook.ID, deadLetters)
+        }
+
+        if deadLetters[0].Attempts != dispatcher.MaxAttempts {
+            t.Errorf("expected dead letter to record %d attempts, got %d\n", dispatcher.MaxAttempts, deadLetters[0].Attempts)
+        }
+
+        if queued, _ := database.ListQueuedWebhookEventsAction(db); len(queued) != 0 {
+            t.Errorf("expected the dead letter to leave the queue, got %v\n", queued)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should not deliver the events another process claimed", func (t *testing.T) {
+        calls := 0
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            calls++
+        }))
+        defer server.Close()
+
+        hook := mockWebhook(t, db, server.URL, []string{EventCreated})
+        dispatcher := newTestDispatcher(db)
+        dispatcher.Dispatch(EventCreated, database.Task{ID: 1})
+        queued, _ := database.ListQueuedWebhookEventsAction(db)
+
+        if claimed, err := database.ClaimQueuedWebhookEventAction(db, queued[0].ID, dispatcher.Now(), dispatcher.Now().Add(time.Minute)); !claimed || err != nil {
+            t.Fatalf("expected to claim the event, got %v, %v\n", claimed, err)
+        }
+
+        if delivered, _ := dispatcher.Deliver(context.Background()); delivered != 0 || calls != 0 {
+            t.Errorf("expected no delivery, got %d and %d calls\n", delivered, calls)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should keep the events queued when the webhook can't be read", func (t *testing.T) {
+        hook := mockWebhook(t, db, "http://127.0.0.1:1", []string{EventCreated})
+        dispatcher := newTestDispatcher(unreadableWebhooks{db})
+        dispatcher.Dispatch(EventCreated, database.Task{ID: 1})
+
+        if _, err := dispatcher.Deliver(context.Background()); err == nil {
+            t.Error("expected the error reading the webhook")
+        }
+
+        if queued, _ := database.ListQueuedWebhookEventsAction(db); len(queued) != 1 {
+            t.Errorf("expected the event still queued, got %+v\n", queued)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should keep a dead letter whose replay fails and remove it once delivered", func (t *testing.T) {
+        fail := true
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            if fail {
+                w.WriteHeader(http.StatusServiceUnavailable)
+            }
+        }))
+        defer server.Close()
+
+        hook := mockWebhook(t, db, server.URL, []string{EventUpdated})
+        dispatcher := newTestDispatcher(db)
+        dispatcher.MaxAttempts = 1
+        dispatcher.Dispatch(EventUpdated, database.Task{ID: 1})
+        dispatcher.Deliver(context.Background())
+
+        deadLetters, _ := database.ListWebhookDeadLettersAction(db)
+
+        if len(deadLetters) != 1 {
+            t.Fatalf("expected one dead letter, got %d\n", len(deadLetters))
+        }
+
+        if _, err := dispatcher.Replay(deadLetters[0].ID); err == nil {
+            t.Fatal("expected the replay to fail")
+        }
+
+        failed, err := database.ListWebhookDeadLetterActionByID(db, deadLetters[0].ID)
+
+        if err != nil || failed.Attempts != deadLetters[0].Attempts + 1 {
+            t.Fatalf("expected the dead letter kept with one more attempt, got %+v, %v\n", failed, err)
+        }
+
+        fail = false
+        delivery, err := dispatcher.Replay(deadLetters[0].ID)
+
+        if err != nil {
+            t.Fatalf("error while replaying dead letter, %s\n", err)
+        }
+
+        if delivery.Payload != deadLetters[0].Payload {
+            t.Errorf("expected the replayed payload to be the dead lettered one, got %s\n", delivery.Payload)
+        }
+
+        deadLetters, _ = database.ListWebhookDeadLettersAction(db)
+
+        if len(deadLetters) != 0 {
+            t.Errorf("expected dead letter to be removed after replay, got %d\n", len(deadLetters))
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+
+    t.Run("Should send a test event with a task shaped like the real ones", func (t *testing.T) {
+        var body []byte
+        server := httptest.NewServer(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
+            body, _ = io.ReadAll(r.Body)
+        }))
+        defer server.Close()
+
+        hook := mockWebhook(t, db, server.URL, []string{EventCreated})
+
+        if _, err := newTestDispatcher(db).Test(hook); err != nil {
+            t.Fatalf("error while testing the webhook, %s\n", err)
+        }
+
+        payload := Payload{}
+        json.Unmarshal(body, &payload)
+
+        if payload.Event != EventTest || payload.Task.ID == 0 || payload.Task.Name == "" || payload.Task.DueAt == nil || len(payload.Task.Tags) == 0 {
+            t.Errorf("expected a representative task, got %+v\n", payload)
+        }
+
+        database.DeleteWebhookAction(db, hook.ID)
+    })
+}
+
+// unreadableWebhooks fails to read the webhooks by ID like a database which
+// stays busy.
+type unreadableWebhooks struct {
+    database.DB
+}
+
+func (db unreadableWebhooks) QueryRow(query string, args ...any) *sql.Row {
+    if query == database.LIST_WEBHOOK_ID_SQL {
+        return db.DB.QueryRow("SELECT * FROM unreadable_webhooks;")
+    }
+
+    return db.DB.QueryRow(query, args...)
+}
+
+func newTestDispatcher(db database.DB) *Dispatcher {
+    dispatcher := NewDispatcher(db)
+    dispatcher.MaxAttempts = 3
+    return dispatcher
+}
+
+func mockWebhook(t testing.TB, db database.DB, url string, events []string) database.Webhook {
+    t.Helper()
+    hook, err := database.AddWebhookAction(db, database.AddWebhookProp{
+        URL: url,
+        Secret: "secret",
+        Events: events,
+    })
+
+    if err != nil {
+        t.Fatalf("error while mocking webhook, %s\n", err)
+    }
+
+    return hook
+}
+
+func getDBTransaction(t testing.TB) (*sql.Tx) {
+    t.Helper()
+    db, err := database.OpenDatabase("../")
+
+    if err != nil {
+        t.Fatalf("error while connecting to the database, %s\n", err)
+    }
+    defer db.Close()
+
+    tx, err := db.Begin()
+
+    if err != nil {
+        t.Fatalf("error while acquiring transaction, %s\n", err)
+    }
+
+    return tx
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go_todo/cli"
+	"go_todo/database"
+	"go_todo/webhook"
+	"strings"
+	"time"
+)
+
+func webhooksCommand(db database.DB) *cli.Command {
+    idArg := func (run func (database.DB, int) error) func (*cli.Context) error {
+        return func (ctx *cli.Context) error {
+            ids, err := parseIDs(ctx.Args())
+            if err != nil {
+                return err
+            }
+            return run(db, ids[0])
+        }
+    }
+
+    return &cli.Command{
+        Name: "webhooks",
+        Aliases: []string{"webhook"},
+        Summary: "Manage webhooks",
+        Subcommands: []*cli.Command{
+            {
+                Name: "add",
+                Summary: "Register a webhook",
+                Flags: []cli.Flag{
+                    {Name: "url", Required: true, Usage: "endpoint receiving the events"},
+                    {
+                        Name: "events",
+                        Placeholder: strings.Join(webhook.Events, ","),
+                        Repeatable: true,
+                        Usage: "events to subscribe to, all of them by default",
+                    },
+                    {Name: "secret", Usage: "secret signing the payloads, generated by default"},
+                },
+                Run: func (ctx *cli.Context) error { return addWebhook(db, ctx) },
+            },
+            {
+                Name: "list",
+                Aliases: []string{"ls"},
+                Summary: "List webhooks",
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error { return listWebhooks(db) },
+            },
+            {
+                Name: "remove",
+                Aliases: []string{"rm"},
+                Summary: "Remove a webhook",
+                Args: "<id>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                CompleteArgs: webhookCompletions(db),
+                Run: idArg(removeWebhook),
+            },
+            {
+                Name: "test",
+                Summary: "Send a test event to a webhook",
+                Args: "<id>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                CompleteArgs: webhookCompletions(db),
+                Run: idArg(testWebhook),
+            },
+            {
+                Name: "deliver",
+                Summary: "Deliver the queued events that are due",
+                Run: func (ctx *cli.Context) error { return deliverQueuedWebhooks(db) },
+            },
+            {
+                Name: "deliveries",
+                Summary: "List deliveries",
+                Flags: []cli.Flag{
+                    {Name: "failed", Kind: cli.Bool, Usage: "list the dead letters instead"},
+                    {Name: "queued", Kind: cli.Bool, Usage: "list the events waiting to be delivered instead"},
+                },
+                ReadOnly: true,
+                Run: func (ctx *cli.Context) error { return listWebhookDeliveries(db, ctx) },
+            },
+            {
+                Name: "replay",
+                Summary: "Deliver a dead letter again",
+                Args: "<id>",
+                MinArgs: 1,
+                MaxArgs: 1,
+                Run: idArg(replayWebhookDelivery),
+            },
+        },
+    }
+}
+
+func addWebhook(db database.DB, ctx *cli.Context) error {
+    url, _ := ctx.String("url")
+
+    props := database.AddWebhookProp{URL: url, Events: webhook.Events}
+
+    if events := splitValues(ctx.Strings("events")); len(events) > 0 {
+        for _, event := range events {
+            if !Include(webhook.Events, event) {
+                return ctx.Usagef("Invalid value '%s' for -events, expected %s", event, strings.Join(webhook.Events, "|"))
+            }
+        }
+        props.Events = events
+    }
+
+    if secret, ok := ctx.String("secret"); ok {
+        props.Secret = secret
+    } else {
+        secret, err := webhook.NewSecret()
+        if err != nil {
+            return fmt.Errorf("couldn't generate webhook secret, %w", err)
+        }
+        props.Secret = secret
+    }
+
+    hook, err := database.AddWebhookAction(db, props)
+
+    if err != nil {
+        return fmt.Errorf("couldn't create webhook, %w", err)
+    }
+
+    fmt.Printf("Webhook with ID: %d created! Secret: %s\n", hook.ID, hook.Secret)
+
+    return nil
+}
+
+func listWebhooks(db database.DB) error {
+    hooks, err := database.ListWebhooksAction(db)
+
+    if err != nil {
+        return err
+    }
+
+    for _, hook := range hooks {
+        fmt.Printf("%d. %s [%s]\n", hook.ID, hook.URL, strings.Join(hook.Events, ","))
+    }
+
+    return nil
+}
+
+func removeWebhook(db database.DB, id int) error {
+    if err := database.DeleteWebhookAction(db, id); err != nil {
+        return err
+    }
+
+    fmt.Printf("Webhook %d removed\n", id)
+
+    return nil
+}
+
+func testWebhook(db database.DB, id int) error {
+    hook, err := database.ListWebhookActionByID(db, id)
+
+    if err != nil {
+        return err
+    }
+
+    delivery, err := webhook.NewDispatcher(db).Test(hook)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("Webhook %d answered with status %d after %d attempt(s)\n", hook.ID, delivery.StatusCode, delivery.Attempts)
+
+    return nil
+}
+
+func listWebhookDeliveries(db database.DB, ctx *cli.Context) error {
+    if queued, _ := ctx.Bool("queued"); queued {
+        events, err := database.ListQueuedWebhookEventsAction(db)
+        if err != nil {
+            return err
+        }
+        for _, event := range events {
+            fmt.Printf(
+                "%d. webhook %d - %s - next attempt %s - %d failed attempt(s)",
+                event.ID,
+                event.WebhookID,
+                event.Event,
+                event.NextAttemptAt.Local().Format("2006-01-02 15:04:05"),
+                event.Attempts,
+            )
+            if event.LastError != "" {
+                fmt.Printf(": %s", event.LastError)
+            }
+            fmt.Println()
+        }
+        return nil
+    }
+
+    if failed, _ := ctx.Bool("failed"); failed {
+        deadLetters, err := database.ListWebhookDeadLettersAction(db)
+        if err != nil {
+            return err
+        }
+        for _, deadLetter := range deadLetters {
+            fmt.Printf(
+                "%d. webhook %d - %s - %s - %d attempt(s): %s\n",
+                deadLetter.ID,
+                deadLetter.WebhookID,
+                deadLetter.Event,
+                deadLetter.FailedAt.Format("2006-01-02 15:04:05"),
+                deadLetter.Attempts,
+                deadLetter.LastError,
+            )
+        }
+        return nil
+    }
+
+    deliveries, err := database.ListWebhookDeliveriesAction(db)
+
+    if err != nil {
+        return err
+    }
+
+    for _, delivery := range deliveries {
+        fmt.Printf(
+            "%d. webhook %d - %s - %s - status %d\n",
+            delivery.ID,
+            delivery.WebhookID,
+            delivery.Event,
+            delivery.DeliveredAt.Format("2006-01-02 15:04:05"),
+            delivery.StatusCode,
+        )
+    }
+
+    return nil
+}
+
+func deliverQueuedWebhooks(db database.DB) error {
+    delivered, err := webhook.NewDispatcher(db).Deliver(context.Background())
+
+    fmt.Printf("Delivered %d queued event(s)\n", delivered)
+
+    return err
+}
+
+func replayWebhookDelivery(db database.DB, id int) error {
+    delivery, err := webhook.NewDispatcher(db).Replay(id)
+
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("Dead letter %d delivered with status %d\n", id, delivery.StatusCode)
+
+    return nil
+}
+
+// splitValues flattens repeated flags which may also hold comma separated
+// values, e.g. -events created,updated -events deleted.
+func splitValues(values []string) []string {
+    split := make([]string, 0)
+
+    for _, value := range values {
+        for _, item := range strings.Split(value, ",") {
+            if item != "" {
+                split = append(split, item)
+            }
+        }
+    }
+
+    return split
+}
+
+// webhookQueue holds notifications back until the changes they describe are
+// committed, see transaction.
+type webhookQueue interface {
+    queueWebhook(event string, task database.Task)
+}
+
+// notifyWebhooks queues event for the webhooks, they are delivered at the end
+// of the command, see deliverWebhooks, or by a long running command.
+func notifyWebhooks(db database.DB, event string, task database.Task) {
+    if queue, ok := db.(webhookQueue); ok {
+        queue.queueWebhook(event, task)
+        return
+    }
+
+    if err := webhook.NewDispatcher(db).Dispatch(event, task); err != nil {
+        fmt.Printf("Error: couldn't queue webhook event, %s\n", err)
+    }
+}
+
+// InlineWebhookDelivery bounds the delivery of the queue at the end of a
+// command, the events it can't deliver by then wait for the next command or
+// for webhooks deliver.
+const InlineWebhookDelivery = 2 * time.Second
+
+// WebhookDeliveryInterval is how often the long running commands, e.g. serve
+// or tui, deliver the queue.
+const WebhookDeliveryInterval = 5 * time.Second
+
+// deliverWebhooks makes one attempt at delivering the queued events that are
+// due, failures are retried later and end up in the dead letters.
+func deliverWebhooks(db database.DB) {
+    ctx, cancel := context.WithTimeout(context.Background(), InlineWebhookDelivery)
+    defer cancel()
+
+    webhook.NewDispatcher(db).Deliver(ctx)
+}
+
+// startWebhookDelivery delivers the queue in the background until the
+// returned function is called.
+func startWebhookDelivery(db database.DB) func () {
+    ctx, cancel := context.WithCancel(context.Background())
+    go webhook.NewDispatcher(db).Run(ctx, WebhookDeliveryInterval)
+
+    return cancel
+}